@@ -15,28 +15,81 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/todos": {
+        "/.well-known/caldav": {
             "get": {
-                "description": "Получения списка всех задач",
-                "produces": [
-                    "application/json"
+                "description": "Перенаправляет календарные клиенты на корень CalDAV по RFC 6764.",
+                "tags": [
+                    "calendar"
                 ],
+                "summary": "Обнаружение CalDAV",
+                "responses": {
+                    "301": {
+                        "description": "Перенаправление на /caldav/",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/caldav/{path}": {
+            "get": {
+                "description": "Календарь задач по протоколу CalDAV (RFC 4791) для Apple Reminders, Thunderbird, DAVx5 и других клиентов. Логин — пользователь, пароль — токен из ссылки подписки (GET /calendar/subscription). /caldav/ — принципал и домашний каталог, /caldav/todos/ — календарь со всеми задачами, /caldav/todos/{id}.ics — задача в формате VTODO, id — UUID. Поддерживаются OPTIONS, PROPFIND, REPORT (calendar-query по компоненту VTODO и calendar-multiget), GET, PUT и DELETE. ETag задачи — её версия; PUT и DELETE учитывают If-Match и If-None-Match, при несовпадении отвечают 412. Для синхронизации клиент сравнивает cs:getctag календаря и ETag задач и запрашивает только изменившиеся. Свойства VTODO, которых нет у задачи (напоминания, повторения), не сохраняются.",
                 "tags": [
-                    "todos"
+                    "calendar"
+                ],
+                "summary": "CalDAV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Путь внутри /caldav",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получить все задачи",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Ответ на OPTIONS, GET",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Todo"
+                            "type": "string"
+                        }
+                    },
+                    "207": {
+                        "description": "Ответ multistatus на PROPFIND и REPORT",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный логин или пароль",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "404": {
+                        "description": "Ресурс не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Задача изменилась",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -45,39 +98,77 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Создание новой задачи",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/calendar.ics": {
+            "get": {
+                "description": "Отдаёт задачи в формате iCalendar (RFC 5545) компонентами VTODO: SUMMARY, DESCRIPTION, DUE, STATUS, COMPLETED, PRIORITY (1 — high, 5 — medium, 9 — low), CATEGORIES с тегами и X-TODO-PROJECT с проектом. Доступ по токену из ссылки подписки. Принимает те же фильтры, что и список, например только невыполненные задачи проекта. Чтобы создать задачи из файла .ics, загрузите его в POST /todos/import.",
                 "produces": [
-                    "application/json"
+                    "text/calendar"
                 ],
                 "tags": [
-                    "todos"
+                    "calendar"
                 ],
-                "summary": "Создать задачу",
+                "summary": "Календарь задач",
                 "parameters": [
                     {
-                        "description": "Данные задачи",
-                        "name": "todo",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreateTodoRequest"
-                        }
+                        "type": "string",
+                        "description": "Токен из ссылки подписки",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только выполненные или только невыполненные",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "low",
+                            "medium",
+                            "high"
+                        ],
+                        "type": "string",
+                        "description": "Приоритет",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Проект",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тег",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок раньше этого момента, RFC 3339",
+                        "name": "dueBefore",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок не раньше этого момента, RFC 3339",
+                        "name": "dueAfter",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Todo"
+                            "type": "file"
                         }
                     },
                     "400": {
-                        "description": "Неверный формат ID",
+                        "description": "Неверный фильтр",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -85,8 +176,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "409": {
-                        "description": "Задача с таким айди уже существует",
+                    "401": {
+                        "description": "Неверная ссылка на календарь",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -106,22 +206,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/todos/{id}": {
+        "/calendar/subscription": {
             "get": {
-                "description": "Получение задачи по её ID",
+                "description": "Возвращает личную ссылку для подписки на задачи из календарного приложения. Пользователь берётся из заголовка, который выставляет прокси после аутентификации (по умолчанию X-User-ID). Там же логин и пароль для подключения по CalDAV. Ссылка не меняется при повторных запросах; отозвать ссылки можно только все сразу, сменив CALENDAR_SECRET.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "calendar"
                 ],
-                "summary": "Получить задачу",
+                "summary": "Ссылка на календарь",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID задачи",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Пользователь",
+                        "name": "X-User-ID",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -129,20 +229,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Todo"
-                        }
-                    },
-                    "400": {
-                        "description": "Неверный формат ID",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.CalendarSubscription"
                         }
                     },
-                    "404": {
-                        "description": "Задача не найдена",
+                    "401": {
+                        "description": "Пользователь не передан",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -150,8 +241,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -160,31 +251,52 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаление задачи по айди",
+            }
+        },
+        "/graphql": {
+            "get": {
+                "description": "Без заголовков WebSocket выполняет запрос из параметров, мутации через GET не выполняются.\nС заголовками открывает WebSocket по протоколу graphql-transport-ws (подпротокол graphql-transport-ws):\nчерез него доступны подписка todoChanged, запросы и мутации. todoChanged принимает actors, projects и lastEventId,\nкак поток /todos/events, и присылает RESET, если события могли быть пропущены.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "graphql"
                 ],
-                "summary": "Удалить задачу",
+                "summary": "Запрос GraphQL через GET или подписка по WebSocket",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID задачи",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Запрос",
+                        "name": "query",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя операции",
+                        "name": "operationName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Переменные в JSON",
+                        "name": "variables",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Задача успешно удалена"
+                    "101": {
+                        "description": "Соединение открыто"
+                    },
+                    "200": {
+                        "description": "Ответ GraphQL: data и errors",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     },
                     "400": {
-                        "description": "Неверный формат ID",
+                        "description": "Неверные параметры",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -192,8 +304,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Задача не найдена",
+                    "403": {
+                        "description": "Сайт не может открывать WebSocket",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -201,8 +313,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "405": {
+                        "description": "Мутация в GET-запросе",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -212,8 +333,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "patch": {
-                "description": "Обновление задачи по ID",
+            "post": {
+                "description": "Схема: задачи (Todo) с тегами и проектами, запросы todo, todos с курсором, project и tag, мутации createTodo,\nupdateTodo и deleteTodo. Ошибки выполнения приходят в errors с кодом в extensions.code: BAD_USER_INPUT,\nNOT_FOUND, CONFLICT, TIMEOUT, INTERNAL_SERVER_ERROR; запрос глубже GRAPHQL_MAX_DEPTH — QUERY_TOO_DEEP,\nсложнее GRAPHQL_MAX_COMPLEXITY — QUERY_TOO_COMPLEX. Сложность — оценка числа полей ответа: поле с аргументом\nfirst считается first раз. Мутации выполняются от имени пользователя из заголовка, заданного EVENTS_USER_HEADER.",
                 "consumes": [
                     "application/json"
                 ],
@@ -221,36 +342,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "graphql"
                 ],
-                "summary": "Обновить задачу",
+                "summary": "Запрос GraphQL",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID задачи",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Данные для обновления",
-                        "name": "todo",
+                        "description": "Запрос",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateTodoRequest"
+                            "$ref": "#/definitions/models.GraphQLRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Ответ GraphQL: data и errors",
                         "schema": {
-                            "$ref": "#/definitions/models.Todo"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Неверный формат ID или данных для обновления",
+                        "description": "Неверное тело запроса",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -258,17 +373,31 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Задача не найдена",
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Процесс запущен и обрабатывает запросы",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка жизнеспособности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -278,50 +407,2069 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CreateTodoRequest": {
-            "type": "object",
-            "properties": {
-                "description": {
-                    "type": "string"
-                },
-                "taskName": {
-                    "type": "string"
-                }
-            }
         },
-        "models.Todo": {
-            "type": "object",
-            "properties": {
-                "completed": {
-                    "type": "boolean"
-                },
-                "createdAt": {
-                    "type": "string"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "taskName": {
-                    "type": "string"
+        "/readyz": {
+            "get": {
+                "description": "Доступность хранилища, применённые миграции и работа фоновых задач",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис не готов принимать трафик",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
                 }
             }
         },
-        "models.UpdateTodoRequest": {
-            "type": "object",
-            "properties": {
-                "completed": {
-                    "type": "boolean"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "taskName": {
+        "/sync": {
+            "get": {
+                "description": "Задачи, созданные или изменённые после токена since, и удалённые задачи (deleted) в порядке изменений.\nБез since приходят все задачи без удалённых. Токен из ответа передаётся в следующий запрос; пока hasMore,\nизменения нужно дочитать сразу. Токен непрозрачен. Записи об удалениях хранятся ограниченное время:\nна слишком старый токен сервер отвечает 410, и задачи нужно загрузить заново без since.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Получить изменения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен из предыдущего ответа",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 500,
+                        "description": "Количество изменений, от 1 до 1000",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncPullResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный токен или limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Токен устарел",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Изменения, накопленные клиентом без связи, применяются по порядку, каждое отдельно. baseVersion — версия задачи,\nна которой клиент сделал изменение. Поле, которое на сервере после неё тоже менялось, — конфликт: при lastWriterWins\nостаётся значение, изменённое позже (по changedAt клиента), при report — значение сервера. Остальные поля применяются.\nУдаление задачи, изменённой на сервере после baseVersion, при report не применяется, при lastWriterWins — если changedAt позже.\nИзменение задачи, удалённой на сервере, не применяется. Повторная отправка тех же изменений безопасна.\nКаждое изменение получает результат: applied, conflict с полями конфликта или rejected с причиной.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Отправить изменения",
+                "parameters": [
+                    {
+                        "description": "Изменения клиента, не больше 500",
+                        "name": "changes",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncPushRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncPushResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный JSON, стратегия или слишком много изменений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos": {
+            "get": {
+                "description": "Получения списка задач в порядке создания. Фильтры объединяются по «И».",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Получить все задачи",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только выполненные или только невыполненные",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "low",
+                            "medium",
+                            "high"
+                        ],
+                        "type": "string",
+                        "description": "Приоритет",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Проект",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тег",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок раньше этого момента, RFC 3339",
+                        "name": "dueBefore",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок не раньше этого момента, RFC 3339",
+                        "name": "dueAfter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Todo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный фильтр",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создание новой задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Создать задачу",
+                "parameters": [
+                    {
+                        "description": "Данные задачи",
+                        "name": "todo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTodoRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Задача с таким айди уже существует",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/events": {
+            "get": {
+                "description": "Server-Sent Events: todo.created, todo.updated, todo.completed и todo.deleted с телом как у вебхуков.\nId события передаётся в поле id: после обрыва EventSource переподключается с Last-Event-ID и получает\nпропущенные события из буфера последних событий. Если такого события в буфере уже нет, первым приходит\nсобытие reset: задачи нужно загрузить заново. Сервер может закрыть поток, если клиент не успевает читать;\nклиент переподключается и дочитывает пропущенное. Раз в интервал приходит комментарий для поддержания соединения.\nЗадачи общие, поэтому поток получает изменения всех пользователей; actor только отбирает изменения, сделанные\nуказанными пользователями (поле actor события), и не ограничивает доступ.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Поток изменений задач",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Только изменения, сделанные этими пользователями",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "То же, что Last-Event-ID, для клиентов, которые не могут задать заголовок",
+                        "name": "lastEventId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/export": {
+            "get": {
+                "description": "Выгружает задачи в порядке создания с теми же фильтрами, что и список. Ответ пишется по мере чтения из хранилища. Колонки CSV: id, taskName, description, completed, createdAt, dueAt, priority, tags, project, completedAt; теги разделены точкой с запятой, время в UTC в формате RFC 3339. Если хранилище откажет посреди выгрузки, соединение разрывается, чтобы клиент не принял неполный файл за целый.",
+                "produces": [
+                    "text/csv",
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Выгрузить задачи",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "json",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Формат файла",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Добавить UTF-8 BOM в начало CSV, чтобы Excel распознал кодировку",
+                        "name": "bom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только выполненные или только невыполненные",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "low",
+                            "medium",
+                            "high"
+                        ],
+                        "type": "string",
+                        "description": "Приоритет",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Проект",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тег",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок раньше этого момента, RFC 3339",
+                        "name": "dueBefore",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок не раньше этого момента, RFC 3339",
+                        "name": "dueAfter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестный формат или неверный фильтр",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/import": {
+            "post": {
+                "description": "Загружает задачи из файла в поле file. Форматы: csv (колонки по заголовку, как в выгрузке; id и createdAt пропускаются), json (массив или NDJSON), ndjson, todotxt (\"x\" — выполнена, (A)/(B)/(C) — high/medium/low, +проект, @контекст становится тегом, due:2006-01-02) и ics (компоненты VTODO: SUMMARY, DESCRIPTION, DUE, STATUS или COMPLETED, PRIORITY, CATEGORIES — теги, X-TODO-PROJECT — проект; UID и остальные компоненты пропускаются). Формат берётся из поля format или из расширения файла. Задачи создаются в одной транзакции: если хотя бы одна строка не прошла проверку, не создаётся ничего и возвращается 422 с отчётом по строкам. С dryRun=true только проверяет файл и всегда отвечает 200.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Импортировать задачи",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файл с задачами, не больше 10 МБ и 10000 задач",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "json",
+                            "ndjson",
+                            "todotxt",
+                            "ics"
+                        ],
+                        "type": "string",
+                        "description": "Формат файла",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Часовой пояс IANA для сроков без времени, по умолчанию пояс сервера",
+                        "name": "timezone",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только проверить файл, не создавая задачи",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчёт о проверке при dryRun=true",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Нет файла, неизвестный формат или файл не читается",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл слишком большой",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Есть строки с ошибками, ничего не импортировано",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/quick": {
+            "post": {
+                "description": "Разбирает строку вроде \"Pay invoice tomorrow 5pm !high #finance @acme\": !high, !medium, !low — приоритет, #тег, @проект, относительные даты и время на английском и русском (\"next monday\", \"in 3 days\", \"через неделю\", \"в 5 вечера\"). Дата без времени означает 23:59. С dryRun=true задача не создаётся, а возвращаются только разобранные поля для подтверждения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Быстро создать задачу",
+                "parameters": [
+                    {
+                        "description": "Строка быстрого ввода",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.QuickAddRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только разобрать строку, не создавая задачу",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Результат разбора при dryRun=true",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuickAddResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuickAddResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Пустой текст, нет названия или неизвестный часовой пояс",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Задача с таким айди уже существует",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/search": {
+            "get": {
+                "description": "Полнотекстовый поиск по названию и описанию. Слова через пробел должны встретиться все, \"текст в кавычках\" ищется как фраза, слово* — по префиксу. Совпадения в highlight обрамлены тегом \u003cmark\u003e.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Поиск задач",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество результатов, от 1 до 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Пустой запрос или неверный limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/ws": {
+            "get": {
+                "description": "Сообщения — JSON-объекты с полем type. Клиент отправляет subscribe и unsubscribe с project, create с data\nкак в POST /todos, update с todoId, data как в PATCH /todos/{id} и необязательным ifVersion, delete с todoId\nи ping. На каждое сообщение приходит result или error (pong на ping) с тем же id; status у error — код,\nкоторым ответил бы REST API. Ответ на subscribe содержит задачи проекта и его зрителей: data.todos, data.users.\nПо подпискам приходят event с project подписки и data — телом события, как у вебхуков (задачу, перенесённую\nв другой проект, получает и прежний проект: data.previousProject), и presence с data.users, когда меняется,\nкто смотрит проект. reset означает, что события могли быть пропущены и задачи нужно загрузить заново.\nИзменения выполняются от имени пользователя из заголовка, заданного EVENTS_USER_HEADER.\nСервер пингует клиента и закрывает соединение, если тот не отвечает. Клиент, который не успевает читать\nсообщения, отключается с кодом 1013; при остановке сервера соединение закрывается с кодом 1001.",
+                "tags": [
+                    "todos"
+                ],
+                "summary": "WebSocket для совместной работы",
+                "responses": {
+                    "101": {
+                        "description": "Соединение открыто"
+                    },
+                    "400": {
+                        "description": "Не запрос на открытие WebSocket",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Сайт не может открывать WebSocket",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}": {
+            "get": {
+                "description": "Получение задачи по её ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Получить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаление задачи по айди",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Удалить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Задача успешно удалена"
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Обновление задачи по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "Обновить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные для обновления",
+                        "name": "todo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateTodoRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID или данных для обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Версия, коммит и время сборки приложения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Версия сборки",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/version.Info"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Список вебхуков в порядке создания, без секретов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получить вебхуки",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Подписка на события задач. Пустой events — все события. Каждый запрос к вебхуку подписан:\nX-Webhook-Signature: sha256=HMAC-SHA256(secret, X-Webhook-Timestamp + \".\" + тело) в hex.\nСекрет возвращается только в этом ответе; без secret в запросе он генерируется сервером.\nДоставки идут параллельно и повторяются при ошибках, поэтому порядок событий не гарантирован: сравнивайте occurredAt.\nАдреса во внутренней сети (localhost, частные и link-local) не принимаются, перенаправления не выполняются:\nответ 3xx считается неудачной попыткой.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Создать вебхук",
+                "parameters": [
+                    {
+                        "description": "Адрес и события",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный адрес, адрес во внутренней сети или неизвестное событие",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Вебхук по айди, без секрета",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получить вебхук",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаление вебхука вместе с журналом доставок. Неотправленные события ему больше не отправляются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удалить вебхук",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Вебхук удалён"
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Доставки событий вебхуку, новые первыми: число попыток, код ответа и ошибка последней попытки, срок следующей.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Журнал доставок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "succeeded",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Статус доставки",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество доставок, от 1 до 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный статус или limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "description": "Ставит доставку в очередь на немедленную отправку с полным запасом попыток. Отправляется то же событие\nс тем же X-Webhook-Delivery, так что получатель может отбросить повтор.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Отправить доставку повторно",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID доставки",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук или доставка не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время ожидания ответа от хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Result"
+                    }
+                },
+                "ready": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CalDAVAccount": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CalendarSubscription": {
+            "type": "object",
+            "properties": {
+                "caldav": {
+                    "$ref": "#/definitions/models.CalDAVAccount"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webcalUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateTodoRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high"
+                    ]
+                },
+                "project": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "taskName": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "todo.created",
+                            "todo.updated",
+                            "todo.completed",
+                            "todo.deleted"
+                        ]
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldConflict": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "type": "object"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "server": {
+                    "type": "object"
+                },
+                "winner": {
+                    "type": "string",
+                    "enum": [
+                        "client",
+                        "server"
+                    ]
+                }
+            }
+        },
+        "models.GraphQLRequest": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "models.ImportLine": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID заполняется после успешного импорта.",
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "todo": {
+                    "$ref": "#/definitions/models.CreateTodoRequest"
+                }
+            }
+        },
+        "models.ImportReport": {
+            "type": "object",
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportLine"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.QuickAddRequest": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string",
+                    "example": "Pay invoice tomorrow 5pm !high #finance @acme"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Moscow"
+                }
+            }
+        },
+        "models.QuickAddResponse": {
+            "type": "object",
+            "properties": {
+                "parsed": {
+                    "$ref": "#/definitions/models.CreateTodoRequest"
+                },
+                "todo": {
+                    "$ref": "#/definitions/models.Todo"
+                }
+            }
+        },
+        "models.SearchHighlight": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "taskName": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SearchResult": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "boolean"
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "type": "string"
+                },
+                "highlight": {
+                    "$ref": "#/definitions/models.SearchHighlight"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high"
+                    ]
+                },
+                "project": {
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "taskName": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version растёт с каждым изменением задачи. По нему строятся ETag в CalDAV.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SyncChange": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "$ref": "#/definitions/models.Tombstone"
+                },
+                "todo": {
+                    "$ref": "#/definitions/models.Todo"
+                }
+            }
+        },
+        "models.SyncPullResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SyncChange"
+                    }
+                },
+                "hasMore": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SyncPushChange": {
+            "type": "object",
+            "properties": {
+                "baseVersion": {
+                    "type": "integer"
+                },
+                "changedAt": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                }
+            }
+        },
+        "models.SyncPushRequest": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SyncPushChange"
+                    }
+                },
+                "strategy": {
+                    "type": "string",
+                    "enum": [
+                        "lastWriterWins",
+                        "report"
+                    ]
+                }
+            }
+        },
+        "models.SyncPushResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SyncPushResult"
+                    }
+                }
+            }
+        },
+        "models.SyncPushResult": {
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldConflict"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "applied",
+                        "conflict",
+                        "rejected"
+                    ]
+                },
+                "todo": {
+                    "$ref": "#/definitions/models.Todo"
+                }
+            }
+        },
+        "models.Todo": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "boolean"
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high"
+                    ]
+                },
+                "project": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "taskName": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version растёт с каждым изменением задачи. По нему строятся ETag в CalDAV.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Tombstone": {
+            "type": "object",
+            "properties": {
+                "deletedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UpdateTodoRequest": {
+            "type": "object",
+            "properties": {
+                "clearDueAt": {
+                    "description": "ClearDueAt снимает срок. Передать его вместе с dueAt нельзя.",
+                    "type": "boolean"
+                },
+                "completed": {
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "medium",
+                        "high"
+                    ]
+                },
+                "project": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "taskName": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastAttemptAt": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "responseStatus": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "succeeded",
+                        "failed"
+                    ]
+                },
+                "webhookId": {
+                    "type": "string"
+                }
+            }
+        },
+        "version.Info": {
+            "type": "object",
+            "properties": {
+                "buildTime": {
+                    "type": "string"
+                },
+                "commit": {
+                    "type": "string"
+                },
+                "goVersion": {
+                    "type": "string"
+                },
+                "version": {
                     "type": "string"
                 }
             }
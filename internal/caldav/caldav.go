@@ -0,0 +1,322 @@
+// Package caldav разбирает и записывает XML протоколов WebDAV (RFC 4918) и
+// CalDAV (RFC 4791) в том объёме, который нужен календарным клиентам для
+// синхронизации задач: PROPFIND, REPORT и ответы multistatus.
+package caldav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// ContentType — тип XML-ответов.
+const ContentType = "application/xml; charset=utf-8"
+
+// prefixes — префиксы пространств имён в ответах. Значения свойств, которые
+// собирает вызывающий код, пишутся с этими же префиксами.
+var prefixes = map[string]string{
+	NamespaceDAV:            "d",
+	NamespaceCalDAV:         "c",
+	NamespaceCalendarServer: "cs",
+}
+
+var (
+	ErrInvalidBody       = errors.New("неверное тело запроса WebDAV")
+	ErrUnsupportedReport = errors.New("отчёт не поддерживается")
+)
+
+// Свойства, которые понимает сервер.
+var (
+	PropResourceType          = xml.Name{Space: NamespaceDAV, Local: "resourcetype"}
+	PropDisplayName           = xml.Name{Space: NamespaceDAV, Local: "displayname"}
+	PropGetETag               = xml.Name{Space: NamespaceDAV, Local: "getetag"}
+	PropGetContentType        = xml.Name{Space: NamespaceDAV, Local: "getcontenttype"}
+	PropCurrentUserPrincipal  = xml.Name{Space: NamespaceDAV, Local: "current-user-principal"}
+	PropPrincipalURL          = xml.Name{Space: NamespaceDAV, Local: "principal-URL"}
+	PropCurrentUserPrivileges = xml.Name{Space: NamespaceDAV, Local: "current-user-privilege-set"}
+	PropSupportedReportSet    = xml.Name{Space: NamespaceDAV, Local: "supported-report-set"}
+	PropCalendarHomeSet       = xml.Name{Space: NamespaceCalDAV, Local: "calendar-home-set"}
+	PropSupportedComponents   = xml.Name{Space: NamespaceCalDAV, Local: "supported-calendar-component-set"}
+	PropCalendarData          = xml.Name{Space: NamespaceCalDAV, Local: "calendar-data"}
+	PropGetCTag               = xml.Name{Space: NamespaceCalendarServer, Local: "getctag"}
+)
+
+// Предусловия из ответов с ошибкой.
+var (
+	PreconditionValidData       = xml.Name{Space: NamespaceCalDAV, Local: "valid-calendar-data"}
+	PreconditionValidObject     = xml.Name{Space: NamespaceCalDAV, Local: "valid-calendar-object-resource"}
+	PreconditionComponent       = xml.Name{Space: NamespaceCalDAV, Local: "supported-calendar-component"}
+	PreconditionSupportedReport = xml.Name{Space: NamespaceDAV, Local: "supported-report"}
+)
+
+type nameList struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (l *nameList) names() []xml.Name {
+	if l == nil {
+		return nil
+	}
+	names := make([]xml.Name, len(l.Names))
+	for i, n := range l.Names {
+		names[i] = n.XMLName
+	}
+	return names
+}
+
+type propfind struct {
+	Prop *nameList `xml:"DAV: prop"`
+}
+
+// ParsePropfind возвращает запрошенные свойства. all означает allprop: так
+// понимается и пустое тело, и propname, который сервер не различает.
+func ParsePropfind(body []byte) (names []xml.Name, all bool, err error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, true, nil
+	}
+
+	var request propfind
+	if err := decodeRoot(body, xml.Name{Space: NamespaceDAV, Local: "propfind"}, &request); err != nil {
+		return nil, false, err
+	}
+	if request.Prop == nil {
+		return nil, true, nil
+	}
+
+	return request.Prop.names(), false, nil
+}
+
+// Report — разобранный REPORT calendar-query или calendar-multiget.
+type Report struct {
+	Multiget bool
+	Props    []xml.Name
+	AllProp  bool
+	// Component — компонент из comp-filter запроса calendar-query, например
+	// VTODO. Пустая строка — фильтра по компоненту нет. Остальные фильтры
+	// (time-range, prop-filter) не применяются: клиент получает все задачи и
+	// отбирает их сам.
+	Component string
+	Hrefs     []string
+}
+
+type compFilter struct {
+	Name    string       `xml:"name,attr"`
+	Filters []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type reportBody struct {
+	Prop    *nameList   `xml:"DAV: prop"`
+	AllProp *struct{}   `xml:"DAV: allprop"`
+	Filter  *compFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+	Hrefs   []string    `xml:"DAV: href"`
+}
+
+func ParseReport(body []byte) (*Report, error) {
+	root, err := rootName(body)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	switch root {
+	case xml.Name{Space: NamespaceCalDAV, Local: "calendar-query"}:
+	case xml.Name{Space: NamespaceCalDAV, Local: "calendar-multiget"}:
+		report.Multiget = true
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedReport, root.Local)
+	}
+
+	var request reportBody
+	if err := decodeRoot(body, root, &request); err != nil {
+		return nil, err
+	}
+
+	report.Props = request.Prop.names()
+	report.AllProp = request.Prop == nil
+	report.Hrefs = request.Hrefs
+
+	if filter := request.Filter; filter != nil && len(filter.Filters) > 0 {
+		report.Component = strings.ToUpper(filter.Filters[0].Name)
+	}
+
+	return report, nil
+}
+
+func rootName(body []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, ErrInvalidBody
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func decodeRoot(body []byte, root xml.Name, v any) error {
+	name, err := rootName(body)
+	if err != nil || name != root {
+		return ErrInvalidBody
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		return ErrInvalidBody
+	}
+	return nil
+}
+
+// Prop — свойство с готовым XML значения.
+type Prop struct {
+	Name  xml.Name
+	Value string
+}
+
+// Text — свойство с текстовым значением.
+func Text(name xml.Name, value string) Prop {
+	return Prop{Name: name, Value: escape(value)}
+}
+
+// Href записывает ссылку для значений вроде current-user-principal.
+func Href(href string) string {
+	return "<d:href>" + escape(href) + "</d:href>"
+}
+
+// Select отбирает из props запрошенные свойства в порядке запроса. Неизвестные
+// серверу свойства попадают в notFound и возвращаются клиенту со статусом 404.
+func Select(props []Prop, names []xml.Name, all bool) (found []Prop, notFound []xml.Name) {
+	if all {
+		return props, nil
+	}
+
+	for _, name := range names {
+		i := indexOf(props, name)
+		if i < 0 {
+			notFound = append(notFound, name)
+			continue
+		}
+		found = append(found, props[i])
+	}
+
+	return found, notFound
+}
+
+func indexOf(props []Prop, name xml.Name) int {
+	for i, prop := range props {
+		if prop.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Response — ответ об одном ресурсе в multistatus. Ненулевой Status означает
+// ответ без свойств, например 404 для ссылки из calendar-multiget.
+type Response struct {
+	Href     string
+	Status   int
+	Found    []Prop
+	NotFound []xml.Name
+}
+
+func WriteMultistatus(w io.Writer, responses []Response) error {
+	buf := bufio.NewWriter(w)
+
+	buf.WriteString(xml.Header)
+	buf.WriteString("<d:multistatus" + namespaces() + ">")
+	for _, response := range responses {
+		buf.WriteString("<d:response>" + Href(response.Href))
+
+		if response.Status != 0 {
+			buf.WriteString(status(response.Status))
+		}
+		if len(response.Found) > 0 {
+			buf.WriteString("<d:propstat><d:prop>")
+			for _, prop := range response.Found {
+				buf.WriteString(element(prop.Name, prop.Value))
+			}
+			buf.WriteString("</d:prop>" + status(http.StatusOK) + "</d:propstat>")
+		}
+		if len(response.NotFound) > 0 {
+			buf.WriteString("<d:propstat><d:prop>")
+			for _, name := range response.NotFound {
+				buf.WriteString(element(name, ""))
+			}
+			buf.WriteString("</d:prop>" + status(http.StatusNotFound) + "</d:propstat>")
+		}
+
+		buf.WriteString("</d:response>")
+	}
+	buf.WriteString("</d:multistatus>\n")
+
+	return buf.Flush()
+}
+
+// WriteError записывает тело ответа об ошибке с нарушенным предусловием.
+func WriteError(w io.Writer, precondition xml.Name) error {
+	_, err := io.WriteString(w, xml.Header+"<d:error"+namespaces()+">"+element(precondition, "")+"</d:error>\n")
+	return err
+}
+
+// ETag строит ETag задачи из её версии.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseETag возвращает версию из ETag. Слабые ETag тоже принимаются: версия
+// задачи меняется при любом изменении.
+func ParseETag(etag string) (int64, bool) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+func namespaces() string {
+	return ` xmlns:d="` + NamespaceDAV + `" xmlns:c="` + NamespaceCalDAV + `" xmlns:cs="` + NamespaceCalendarServer + `"`
+}
+
+func element(name xml.Name, value string) string {
+	tag, declaration := name.Local, ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		declaration = ` xmlns:x="` + escape(name.Space) + `"`
+	}
+
+	if value == "" {
+		return "<" + tag + declaration + "/>"
+	}
+	return "<" + tag + declaration + ">" + value + "</" + tag + ">"
+}
+
+func status(code int) string {
+	return "<d:status>HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code) + "</d:status>"
+}
+
+func escape(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,121 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePropfind(t *testing.T) {
+	names, all, err := ParsePropfind([]byte(`<?xml version="1.0"?>
+<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <prop><getetag/><C:calendar-data/><X:unknown xmlns:X="urn:x"/></prop>
+</propfind>`))
+	require.NoError(t, err)
+	assert.False(t, all)
+	assert.Equal(t, []xml.Name{PropGetETag, PropCalendarData, {Space: "urn:x", Local: "unknown"}}, names)
+
+	for _, body := range []string{"", "  \n", `<d:propfind xmlns:d="DAV:"><d:allprop/></d:propfind>`, `<d:propfind xmlns:d="DAV:"><d:propname/></d:propfind>`} {
+		_, all, err := ParsePropfind([]byte(body))
+		require.NoError(t, err, body)
+		assert.True(t, all, body)
+	}
+
+	for _, body := range []string{"не xml", `<propfind/>`, `<d:prop xmlns:d="DAV:"/>`} {
+		_, _, err := ParsePropfind([]byte(body))
+		assert.ErrorIs(t, err, ErrInvalidBody, body)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	report, err := ParseReport([]byte(`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="vtodo"><c:time-range start="20260101T000000Z"/></c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`))
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Props: []xml.Name{PropGetETag}, Component: "VTODO"}, report)
+
+	report, err = ParseReport([]byte(`<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <D:href>/caldav/todos/1.ics</D:href>
+  <D:href>/caldav/todos/2.ics</D:href>
+</C:calendar-multiget>`))
+	require.NoError(t, err)
+	assert.Equal(t, &Report{
+		Multiget: true,
+		Props:    []xml.Name{PropGetETag, PropCalendarData},
+		Hrefs:    []string{"/caldav/todos/1.ics", "/caldav/todos/2.ics"},
+	}, report)
+
+	_, err = ParseReport([]byte(`<d:sync-collection xmlns:d="DAV:"/>`))
+	assert.ErrorIs(t, err, ErrUnsupportedReport)
+
+	_, err = ParseReport([]byte(""))
+	assert.ErrorIs(t, err, ErrInvalidBody)
+}
+
+func TestSelect(t *testing.T) {
+	props := []Prop{Text(PropDisplayName, "Задачи"), Text(PropGetETag, `"1"`)}
+	unknown := xml.Name{Space: "urn:x", Local: "unknown"}
+
+	found, notFound := Select(props, []xml.Name{PropGetETag, unknown}, false)
+	assert.Equal(t, []Prop{props[1]}, found)
+	assert.Equal(t, []xml.Name{unknown}, notFound)
+
+	found, notFound = Select(props, nil, true)
+	assert.Equal(t, props, found)
+	assert.Empty(t, notFound)
+}
+
+func TestWriteMultistatus(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMultistatus(&buf, []Response{
+		{
+			Href:     "/caldav/todos/a&b.ics",
+			Found:    []Prop{{Name: PropResourceType}, Text(PropGetETag, `"3"`)},
+			NotFound: []xml.Name{PropGetCTag, {Space: "urn:x", Local: "unknown"}},
+		},
+		{Href: "/caldav/todos/missing.ics", Status: 404},
+	}))
+
+	assert.Equal(t, xml.Header+`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`+
+		`<d:response><d:href>/caldav/todos/a&amp;b.ics</d:href>`+
+		`<d:propstat><d:prop><d:resourcetype/><d:getetag>&#34;3&#34;</d:getetag></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>`+
+		`<d:propstat><d:prop><cs:getctag/><x:unknown xmlns:x="urn:x"/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat>`+
+		`</d:response>`+
+		`<d:response><d:href>/caldav/todos/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`+
+		"</d:multistatus>\n", buf.String())
+
+	var parsed struct {
+		Responses []struct {
+			Href string `xml:"DAV: href"`
+		} `xml:"DAV: response"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed), "ответ — корректный XML")
+	assert.Equal(t, "/caldav/todos/a&b.ics", parsed.Responses[0].Href)
+}
+
+func TestWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteError(&buf, PreconditionValidData))
+	assert.Contains(t, buf.String(), "<d:error ")
+	assert.Contains(t, buf.String(), "<c:valid-calendar-data/></d:error>")
+}
+
+func TestETag(t *testing.T) {
+	assert.Equal(t, `"7"`, ETag(7))
+
+	for etag, want := range map[string]int64{`"7"`: 7, `W/"7"`: 7, ` "12" `: 12} {
+		version, ok := ParseETag(etag)
+		assert.True(t, ok, etag)
+		assert.Equal(t, want, version, etag)
+	}
+
+	for _, etag := range []string{"", "7", `"abc"`, `"0"`, `"-1"`, `"`} {
+		_, ok := ParseETag(etag)
+		assert.False(t, ok, etag)
+	}
+}
@@ -0,0 +1,44 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	tokens := NewTokens("секрет")
+
+	token := tokens.Issue("alice@example.com")
+	assert.Equal(t, token, tokens.Issue("alice@example.com"), "ссылка не меняется при повторной выдаче")
+	assert.NotEqual(t, token, tokens.Issue("bob@example.com"))
+	assert.NotContains(t, token, "@", "токен безопасен для URL")
+
+	user, err := tokens.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user)
+}
+
+func TestTokens_Invalid(t *testing.T) {
+	tokens := NewTokens("секрет")
+	token := tokens.Issue("alice")
+	encodedUser, _, _ := strings.Cut(token, ".")
+	forged := encode([]byte("bob")) + token[len(encodedUser):]
+
+	for name, token := range map[string]string{
+		"Empty":       "",
+		"NoSignature": encodedUser,
+		"EmptyUser":   "." + encode(tokens.sign("")),
+		"Forged":      forged,
+		"Truncated":   token[:len(token)-2],
+		"OtherSecret": NewTokens("другой секрет").Issue("alice"),
+		"NotBase64":   "alice.!!!",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tokens.Verify(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
@@ -2,14 +2,29 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
+	Database  DatabaseConfig
+	Server    ServerConfig
+	GRPC      GRPCConfig
+	Storage   StorageConfig
+	Tracing   TracingConfig
+	Log       LogConfig
+	RateLimit RateLimitConfig
+	Calendar  CalendarConfig
+	Webhook   WebhookConfig
+	Events    EventsConfig
+	Realtime  RealtimeConfig
+	Sync      SyncConfig
+	GraphQL   GraphQLConfig
 }
 
 type DatabaseConfig struct {
@@ -21,11 +36,144 @@ type DatabaseConfig struct {
 	Name     string
 	TestName string
 	SSLMode  string
+
+	QueryTimeout time.Duration
 }
 
+// ServerConfig задаёт HTTP-сервер. TrustedProxies — адреса и подсети прокси,
+// которым можно верить в X-Forwarded-For и X-Real-IP; по умолчанию никому, и
+// адресом клиента считается адрес соединения.
 type ServerConfig struct {
 	Port string
 	Mode string
+
+	TrustedProxies []string
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownDelay     time.Duration
+	ShutdownTimeout   time.Duration
+
+	HealthCheckTimeout time.Duration
+}
+
+// GRPCConfig задаёт порт gRPC API для внутренних сервисов.
+type GRPCConfig struct {
+	Port string
+}
+
+type StorageConfig struct {
+	Backend         string
+	SQLitePath      string
+	FileDir         string
+	CompactInterval time.Duration
+}
+
+// TracingConfig описывает экспорт трейсов. Exporter: none, stdout, file или otlp.
+type TracingConfig struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	FilePath    string
+	SampleRatio float64
+	ServiceName string
+}
+
+// LogConfig задаёт уровень (debug, info, warn, error) и формат логов: json или text.
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// RateLimitConfig задаёт лимит Requests запросов за Period с запасом Burst
+// (по умолчанию равен Requests). KeyBy: ip, user или api_key. Store: memory,
+// postgres или sqlite — общий счётчик в той же базе, что и задачи.
+// IPRequests — лимит за Period на адрес для запросов с пользователем или
+// ключом: за одним адресом может быть много клиентов. По умолчанию в десять
+// раз больше Requests.
+type RateLimitConfig struct {
+	Enabled       bool
+	Requests      int
+	Period        time.Duration
+	Burst         int
+	IPRequests    int
+	KeyBy         string
+	Store         string
+	UserHeader    string
+	APIKeyHeader  string
+	PurgeInterval time.Duration
+}
+
+// CalendarConfig включает подписку на задачи из календарных приложений. Без
+// Secret маршруты календаря не регистрируются. Ссылку выдают пользователю из
+// заголовка UserHeader, который должен выставлять доверенный прокси после
+// аутентификации. BaseURL — внешний адрес сервиса для ссылок; по умолчанию
+// он берётся из запроса.
+type CalendarConfig struct {
+	Secret     string
+	UserHeader string
+	BaseURL    string
+	Name       string
+}
+
+// WebhookConfig задаёт рассылку вебхуков: как часто воркер разбирает очередь,
+// сколько ждёт ответа и сколько доставок ведёт одновременно. Неудачная попытка
+// повторяется через BackoffMin, затем через вдвое большее время, но не больше
+// BackoffMax; после MaxAttempts попыток доставка считается неудавшейся.
+// AllowPrivateNetworks разрешает отправку на адреса во внутренней сети — только
+// для локальной разработки и тестов.
+type WebhookConfig struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffMin   time.Duration
+	BackoffMax   time.Duration
+	Concurrency  int
+
+	AllowPrivateNetworks bool
+}
+
+// EventsConfig задаёт поток изменений задач. BufferSize — сколько последних
+// событий хранится для клиентов, переподключившихся с Last-Event-ID.
+// UserHeader — заголовок с пользователем, который выполняет изменение; его
+// выставляет доверенный прокси.
+type EventsConfig struct {
+	BufferSize int
+	Heartbeat  time.Duration
+	UserHeader string
+}
+
+// RealtimeConfig задаёт WebSocket для совместной работы. Сервер пингует
+// клиента раз в PingInterval и разрывает соединение, если ответа нет дольше
+// двух интервалов. SendBuffer — сколько сообщений ждёт отправки клиенту:
+// клиент, который не успевает их читать, отключается. AllowedOrigins —
+// сайты, с которых браузер может открыть соединение; по умолчанию только
+// тот же адрес, что у сервиса, "*" разрешает любые.
+type RealtimeConfig struct {
+	PingInterval   time.Duration
+	WriteTimeout   time.Duration
+	SendBuffer     int
+	MaxMessageSize int
+	AllowedOrigins []string
+}
+
+// SyncConfig задаёт синхронизацию офлайн-клиентов. Записи об удалённых
+// задачах хранятся TombstoneTTL: клиент, не синхронизировавшийся дольше,
+// загружает задачи заново. Старые записи удаляются раз в PurgeInterval.
+type SyncConfig struct {
+	TombstoneTTL  time.Duration
+	PurgeInterval time.Duration
+}
+
+// GraphQLConfig ограничивает запросы к /graphql: MaxDepth — вложенность
+// полей, MaxComplexity — оценку числа полей в ответе, в которой поле со
+// списком считается столько раз, сколько элементов в нём может прийти.
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
 }
 
 func Load() *Config {
@@ -39,9 +187,75 @@ func Load() *Config {
 	dbName := getEnv("DB_NAME", "todoapi")
 	testDbName := getEnv("DB_NAME_TEST", "todoapi_test")
 	sslMode := getEnv("DB_SSLMODE", "disable")
+	queryTimeout := getEnvDuration("DB_QUERY_TIMEOUT", 5*time.Second)
 
 	serverPort := getEnv("SERVER_PORT", "8080")
+	grpcPort := getEnv("GRPC_PORT", "9090")
 	serverMode := getEnv("SERVER_MODE", "debug")
+	trustedProxies := getEnvList("SERVER_TRUSTED_PROXIES")
+	readTimeout := getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second)
+	readHeaderTimeout := getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second)
+	writeTimeout := getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second)
+	idleTimeout := getEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second)
+	maxHeaderBytes := getEnvInt("SERVER_MAX_HEADER_BYTES", 1<<20)
+	shutdownDelay := getEnvDuration("SERVER_SHUTDOWN_DELAY", 0)
+	shutdownTimeout := getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 20*time.Second)
+	healthCheckTimeout := getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second)
+
+	storageBackend := getEnv("STORAGE_BACKEND", "postgres")
+	sqlitePath := getEnv("SQLITE_PATH", "todoapi.db")
+	fileDir := getEnv("STORAGE_FILE_DIR", "data")
+	compactInterval := getEnvDuration("STORAGE_COMPACT_INTERVAL", 5*time.Minute)
+
+	tracingExporter := getEnv("TRACING_EXPORTER", "none")
+	tracingEndpoint := getEnv("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	tracingInsecure := getEnvBool("TRACING_OTLP_INSECURE", true)
+	tracingFilePath := getEnv("TRACING_FILE_PATH", "traces.json")
+	tracingSampleRatio := getEnvFloat("TRACING_SAMPLE_RATIO", 1)
+	serviceName := getEnv("TRACING_SERVICE_NAME", "todo-api")
+
+	logLevel := getEnv("LOG_LEVEL", "info")
+	logFormat := getEnv("LOG_FORMAT", "json")
+
+	rateLimitEnabled := getEnvBool("RATE_LIMIT_ENABLED", true)
+	rateLimitRequests := getEnvInt("RATE_LIMIT_REQUESTS", 100)
+	rateLimitPeriod := getEnvDuration("RATE_LIMIT_PERIOD", time.Minute)
+	rateLimitBurst := getEnvInt("RATE_LIMIT_BURST", 0)
+	rateLimitIPRequests := getEnvInt("RATE_LIMIT_IP_REQUESTS", 0)
+	rateLimitKeyBy := getEnv("RATE_LIMIT_KEY", "ip")
+	rateLimitStore := getEnv("RATE_LIMIT_STORE", "memory")
+	rateLimitUserHeader := getEnv("RATE_LIMIT_USER_HEADER", "X-User-ID")
+	rateLimitAPIKeyHeader := getEnv("RATE_LIMIT_API_KEY_HEADER", "X-API-Key")
+	rateLimitPurgeInterval := getEnvDuration("RATE_LIMIT_PURGE_INTERVAL", 5*time.Minute)
+
+	calendarSecret := getEnv("CALENDAR_SECRET", "")
+	calendarUserHeader := getEnv("CALENDAR_USER_HEADER", "X-User-ID")
+	calendarBaseURL := getEnv("CALENDAR_BASE_URL", "")
+	calendarName := getEnv("CALENDAR_NAME", "Задачи")
+
+	webhookPollInterval := getEnvDuration("WEBHOOK_POLL_INTERVAL", time.Second)
+	webhookTimeout := getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	webhookMaxAttempts := getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	webhookBackoffMin := getEnvDuration("WEBHOOK_BACKOFF_MIN", 10*time.Second)
+	webhookBackoffMax := getEnvDuration("WEBHOOK_BACKOFF_MAX", time.Hour)
+	webhookConcurrency := getEnvInt("WEBHOOK_CONCURRENCY", 4)
+	webhookAllowPrivateNetworks := getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+
+	eventsBufferSize := getEnvInt("EVENTS_BUFFER_SIZE", 1000)
+	eventsHeartbeat := getEnvPositiveDuration("EVENTS_HEARTBEAT", 15*time.Second)
+	eventsUserHeader := getEnv("EVENTS_USER_HEADER", "X-User-ID")
+
+	wsPingInterval := getEnvPositiveDuration("WS_PING_INTERVAL", 30*time.Second)
+	wsWriteTimeout := getEnvDuration("WS_WRITE_TIMEOUT", 10*time.Second)
+	wsSendBuffer := getEnvInt("WS_SEND_BUFFER", 256)
+	wsMaxMessageSize := getEnvInt("WS_MAX_MESSAGE_SIZE", 64*1024)
+	wsAllowedOrigins := getEnvList("WS_ALLOWED_ORIGINS")
+
+	syncTombstoneTTL := getEnvDuration("SYNC_TOMBSTONE_TTL", 30*24*time.Hour)
+	syncPurgeInterval := getEnvDuration("SYNC_PURGE_INTERVAL", time.Hour)
+
+	graphqlMaxDepth := getEnvInt("GRAPHQL_MAX_DEPTH", 10)
+	graphqlMaxComplexity := getEnvInt("GRAPHQL_MAX_COMPLEXITY", 1000)
 
 	config := &Config{
 		Database: DatabaseConfig{
@@ -53,10 +267,93 @@ func Load() *Config {
 			Name:     dbName,
 			TestName: testDbName,
 			SSLMode:  sslMode,
+
+			QueryTimeout: queryTimeout,
+		},
+		GRPC: GRPCConfig{
+			Port: grpcPort,
 		},
 		Server: ServerConfig{
 			Port: serverPort,
 			Mode: serverMode,
+
+			TrustedProxies: trustedProxies,
+
+			ReadTimeout:       readTimeout,
+			ReadHeaderTimeout: readHeaderTimeout,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       idleTimeout,
+			MaxHeaderBytes:    maxHeaderBytes,
+			ShutdownDelay:     shutdownDelay,
+			ShutdownTimeout:   shutdownTimeout,
+
+			HealthCheckTimeout: healthCheckTimeout,
+		},
+		Storage: StorageConfig{
+			Backend:         storageBackend,
+			SQLitePath:      sqlitePath,
+			FileDir:         fileDir,
+			CompactInterval: compactInterval,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingExporter,
+			Endpoint:    tracingEndpoint,
+			Insecure:    tracingInsecure,
+			FilePath:    tracingFilePath,
+			SampleRatio: tracingSampleRatio,
+			ServiceName: serviceName,
+		},
+		Log: LogConfig{
+			Level:  logLevel,
+			Format: logFormat,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:       rateLimitEnabled,
+			Requests:      rateLimitRequests,
+			Period:        rateLimitPeriod,
+			Burst:         rateLimitBurst,
+			IPRequests:    rateLimitIPRequests,
+			KeyBy:         rateLimitKeyBy,
+			Store:         rateLimitStore,
+			UserHeader:    rateLimitUserHeader,
+			APIKeyHeader:  rateLimitAPIKeyHeader,
+			PurgeInterval: rateLimitPurgeInterval,
+		},
+		Calendar: CalendarConfig{
+			Secret:     calendarSecret,
+			UserHeader: calendarUserHeader,
+			BaseURL:    calendarBaseURL,
+			Name:       calendarName,
+		},
+		Webhook: WebhookConfig{
+			PollInterval: webhookPollInterval,
+			Timeout:      webhookTimeout,
+			MaxAttempts:  webhookMaxAttempts,
+			BackoffMin:   webhookBackoffMin,
+			BackoffMax:   webhookBackoffMax,
+			Concurrency:  webhookConcurrency,
+
+			AllowPrivateNetworks: webhookAllowPrivateNetworks,
+		},
+		Events: EventsConfig{
+			BufferSize: eventsBufferSize,
+			Heartbeat:  eventsHeartbeat,
+			UserHeader: eventsUserHeader,
+		},
+		Realtime: RealtimeConfig{
+			PingInterval:   wsPingInterval,
+			WriteTimeout:   wsWriteTimeout,
+			SendBuffer:     wsSendBuffer,
+			MaxMessageSize: wsMaxMessageSize,
+			AllowedOrigins: wsAllowedOrigins,
+		},
+		Sync: SyncConfig{
+			TombstoneTTL:  syncTombstoneTTL,
+			PurgeInterval: syncPurgeInterval,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      graphqlMaxDepth,
+			MaxComplexity: graphqlMaxComplexity,
 		},
 	}
 
@@ -70,6 +367,89 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		slog.Warn("некорректное значение переменной окружения", "key", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+
+	return duration
+}
+
+// getEnvPositiveDuration читает интервал тикера: нулевой или отрицательный
+// заменяется значением по умолчанию, иначе time.NewTicker упадёт с паникой.
+func getEnvPositiveDuration(key string, defaultVal time.Duration) time.Duration {
+	duration := getEnvDuration(key, defaultVal)
+	if duration <= 0 {
+		slog.Warn("значение переменной окружения должно быть положительным", "key", key, "value", duration, "default", defaultVal)
+		return defaultVal
+	}
+
+	return duration
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	number, err := strconv.Atoi(val)
+	if err != nil {
+		slog.Warn("некорректное значение переменной окружения", "key", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+
+	return number
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	flag, err := strconv.ParseBool(val)
+	if err != nil {
+		slog.Warn("некорректное значение переменной окружения", "key", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+
+	return flag
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	number, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		slog.Warn("некорректное значение переменной окружения", "key", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+
+	return number
+}
+
+// getEnvList читает список через запятую, пропуская пустые элементы.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func (d *DatabaseConfig) DSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEnvPositiveDuration(t *testing.T) {
+	for _, value := range []string{"0", "-1s", "abc"} {
+		t.Setenv("TEST_INTERVAL", value)
+		assert.Equal(t, time.Minute, getEnvPositiveDuration("TEST_INTERVAL", time.Minute), value)
+	}
+
+	t.Setenv("TEST_INTERVAL", "5s")
+	assert.Equal(t, 5*time.Second, getEnvPositiveDuration("TEST_INTERVAL", time.Minute))
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 	"todo-api/internal/config"
 
@@ -25,8 +26,7 @@ func Connect(cfg config.DatabaseConfig) (*sql.DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	fmt.Printf("Успешное подключение к базе данных %s:%s\n",
-		cfg.Host, cfg.Port)
+	slog.Info("успешное подключение к базе данных", "host", cfg.Host, "port", cfg.Port)
 
 	return db, nil
 }
@@ -47,8 +47,7 @@ func ConnectTestDB(cfg config.DatabaseConfig) (*sql.DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	fmt.Printf("Успешное подключение к базе данных %s:%s\n",
-		cfg.Host, cfg.Port)
+	slog.Info("успешное подключение к базе данных", "host", "localhost", "port", cfg.TestPort)
 
 	return db, nil
 }
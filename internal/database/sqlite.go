@@ -0,0 +1,51 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+func ConnectSQLite(path string) (*sql.DB, error) {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "journal_mode(WAL)")
+	pragmas.Add("_pragma", "busy_timeout(5000)")
+	pragmas.Add("_pragma", "synchronous(NORMAL)")
+	pragmas.Add("_pragma", "foreign_keys(ON)")
+	// Транзакции сразу берут блокировку на запись, иначе параллельные
+	// транзакции получают SQLITE_BUSY при попытке перейти от чтения к записи.
+	pragmas.Add("_txlock", "immediate")
+
+	dsn := "file:" + path + "?" + pragmas.Encode()
+	db, err := sql.Open("sqlite", dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл SQLite: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось подключиться к SQLite: %w", err)
+	}
+
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось прочитать режим журнала SQLite: %w", err)
+	}
+
+	if journalMode != "wal" {
+		db.Close()
+		return nil, fmt.Errorf("SQLite не перешёл в режим WAL: %s", journalMode)
+	}
+
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(4)
+
+	slog.Info("успешное подключение к SQLite", "path", path)
+
+	return db, nil
+}
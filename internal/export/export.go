@@ -0,0 +1,209 @@
+// Package export записывает задачи в CSV, JSON и NDJSON по одной, не собирая
+// весь список в памяти.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+var ErrUnknownFormat = errors.New("формат должен быть csv, json или ndjson")
+
+// Columns — порядок колонок CSV. Клиенты разбирают файл по позициям, поэтому
+// новые колонки добавляются только в конец.
+var Columns = []string{
+	"id", "taskName", "description", "completed", "createdAt", "dueAt", "priority", "tags", "project", "completedAt",
+}
+
+// TagSeparator разделяет теги внутри колонки tags.
+const TagSeparator = ";"
+
+// bom помогает Excel распознать UTF-8 в CSV.
+const bom = "\ufeff"
+
+// Encoder пишет задачи в w. До первого Encode или Close в w ничего не
+// пишется, поэтому до этого момента ещё можно ответить ошибкой.
+type Encoder interface {
+	Encode(task *models.Todo) error
+	// Close дописывает окончание документа и сбрасывает буфер.
+	Close() error
+}
+
+// NewEncoder создаёт кодировщик формата format. withBOM добавляет BOM в начало
+// CSV; JSON по RFC 8259 не должен начинаться с BOM, поэтому для него флаг
+// игнорируется.
+func NewEncoder(format string, w io.Writer, withBOM bool) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w), bom: withBOM, out: w}, nil
+	case FormatJSON:
+		buf := bufio.NewWriter(w)
+		return &jsonEncoder{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case FormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonEncoder{buf: buf, enc: json.NewEncoder(buf)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	out     io.Writer
+	bom     bool
+	started bool
+}
+
+func (e *csvEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	if e.bom {
+		if _, err := io.WriteString(e.out, bom); err != nil {
+			return err
+		}
+	}
+
+	return e.w.Write(Columns)
+}
+
+func (e *csvEncoder) Encode(task *models.Todo) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	var description, dueAt, completedAt string
+	if task.Description != nil {
+		description = *task.Description
+	}
+	if task.DueAt != nil {
+		dueAt = formatTime(*task.DueAt)
+	}
+	if task.CompletedAt != nil {
+		completedAt = formatTime(*task.CompletedAt)
+	}
+
+	return e.w.Write(escapeCells([]string{
+		task.ID,
+		task.TaskName,
+		description,
+		strconv.FormatBool(task.Completed),
+		formatTime(task.CreatedAt),
+		dueAt,
+		task.Priority,
+		strings.Join(task.Tags, TagSeparator),
+		task.Project,
+		completedAt,
+	}))
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// formulaPrefixes — символы, с которых Excel и Google Таблицы начинают формулу.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeCells экранирует ячейки, которые табличный редактор выполнил бы как
+// формулу: название вида =HYPERLINK(...) получает префикс '.
+func escapeCells(record []string) []string {
+	for i, value := range record {
+		if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+			record[i] = "'" + value
+		}
+	}
+	return record
+}
+
+// UnescapeCell снимает префикс, добавленный при выгрузке, чтобы импорт
+// выгруженного файла вернул исходные значения.
+func UnescapeCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// jsonEncoder пишет массив, поэтому оборванная выгрузка не разбирается как
+// корректный JSON.
+type jsonEncoder struct {
+	buf     *bufio.Writer
+	enc     *json.Encoder
+	started bool
+}
+
+func (e *jsonEncoder) Encode(task *models.Todo) error {
+	separator := ","
+	if !e.started {
+		separator = "["
+		e.started = true
+	}
+
+	if _, err := e.buf.WriteString(separator); err != nil {
+		return err
+	}
+
+	return e.enc.Encode(task)
+}
+
+func (e *jsonEncoder) Close() error {
+	closing := "]\n"
+	if !e.started {
+		closing = "[]\n"
+	}
+
+	if _, err := e.buf.WriteString(closing); err != nil {
+		return err
+	}
+
+	return e.buf.Flush()
+}
+
+type ndjsonEncoder struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(task *models.Todo) error {
+	return e.enc.Encode(task)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return e.buf.Flush()
+}
@@ -0,0 +1,59 @@
+package graphqlapi
+
+import (
+	"math"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// complexity оценивает, сколько полей вернёт запрос. Каждое поле стоит 1,
+// а поля с аргументом first умножают стоимость вложенных полей на first:
+// todos(first: 50) { items { id } } стоит 1 + 50 * (1 + 1). Так запрос,
+// который вкладывает списки в списки, отклоняется до обращения к
+// хранилищу. Поля интроспекции не учитываются.
+func complexity(set ast.SelectionSet, vars map[string]any) int {
+	total := 0
+
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name, "__") {
+				continue
+			}
+			total += 1 + complexity(selection.SelectionSet, vars)*multiplier(selection, vars)
+		case *ast.InlineFragment:
+			total += complexity(selection.SelectionSet, vars)
+		case *ast.FragmentSpread:
+			if selection.Definition != nil {
+				total += complexity(selection.Definition.SelectionSet, vars)
+			}
+		}
+		// Вложенные списки быстро переполнили бы int.
+		total = min(total, math.MaxInt32)
+	}
+
+	return total
+}
+
+func multiplier(field *ast.Field, vars map[string]any) int {
+	if field.Definition == nil || field.Definition.Arguments.ForName("first") == nil {
+		return 1
+	}
+
+	// Значение по умолчанию берётся из схемы; неверное значение резолвер
+	// отклонит сам, а стоимость считается по наибольшему допустимому.
+	switch first := field.ArgumentMap(vars)["first"].(type) {
+	case int64:
+		return clamp(int(first))
+	case float64:
+		return clamp(int(first))
+	case int:
+		return clamp(first)
+	}
+	return maxPageSize
+}
+
+func clamp(first int) int {
+	return min(max(first, 1), maxPageSize)
+}
@@ -0,0 +1,98 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+)
+
+// Коды ошибок в extensions.code.
+const (
+	codeParseFailed      = "GRAPHQL_PARSE_FAILED"
+	codeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	codeTooDeep          = "QUERY_TOO_DEEP"
+	codeTooComplex       = "QUERY_TOO_COMPLEX"
+	codeBadInput         = "BAD_USER_INPUT"
+	codeNotFound         = "NOT_FOUND"
+	codeConflict         = "CONFLICT"
+	codeTimeout          = "TIMEOUT"
+	codeInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+const internalMessage = "внутренняя ошибка сервера"
+
+// inputError — ошибка в аргументах запроса, которую находит сам резолвер.
+type inputError string
+
+func (e inputError) Error() string {
+	return string(e)
+}
+
+func newError(code, message string) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{Message: message, Extensions: map[string]any{"code": code}}
+}
+
+// maskErrors проставляет ошибкам коды. Как и в REST API, внутренние ошибки
+// не раскрываются клиенту, а пишутся в лог.
+func maskErrors(ctx context.Context, errs []*gqlerrors.QueryError) {
+	for _, err := range errs {
+		if err.Extensions != nil {
+			continue
+		}
+
+		if err.ResolverError == nil {
+			switch {
+			case err.Rule == "MaxDepthExceeded":
+				err.Extensions = map[string]any{"code": codeTooDeep}
+			case err.Rule != "":
+				err.Extensions = map[string]any{"code": codeValidationFailed}
+			}
+			continue
+		}
+
+		code := codeBadInput
+		var input inputError
+
+		switch cause := err.ResolverError; {
+		case errors.As(cause, &input),
+			errors.Is(cause, repository.ErrEmptyID),
+			errors.Is(cause, repository.ErrEmptyData),
+			errors.Is(cause, repository.ErrEmptyTask),
+			errors.Is(cause, repository.ErrEmptyName),
+			errors.Is(cause, repository.ErrInvalidPriority),
+			errors.Is(cause, repository.ErrDueAtConflict),
+			errors.Is(cause, services.ErrInvalidPageToken):
+		case errors.Is(cause, repository.ErrInvalidID):
+			code = codeNotFound
+		case errors.Is(cause, repository.ErrAlreadyExist), errors.Is(cause, repository.ErrVersionConflict):
+			code = codeConflict
+		case errors.Is(cause, context.DeadlineExceeded):
+			code, err.Message = codeTimeout, "превышено время ожидания ответа от хранилища"
+		default:
+			slog.ErrorContext(ctx, "ошибка при выполнении запроса GraphQL", "error", cause, "path", err.Path)
+			code, err.Message = codeInternal, internalMessage
+		}
+
+		err.Extensions = map[string]any{"code": code}
+	}
+}
+
+func logPanic(ctx context.Context, value any) {
+	slog.ErrorContext(ctx, "паника при выполнении запроса GraphQL",
+		slog.Any("panic", value),
+		slog.String("stack", string(debug.Stack())),
+	)
+}
+
+// panicHandler отдаёт клиенту общую ошибку вместо текста паники.
+type panicHandler struct{}
+
+func (panicHandler) MakePanicError(ctx context.Context, value any) *gqlerrors.QueryError {
+	return newError(codeInternal, internalMessage)
+}
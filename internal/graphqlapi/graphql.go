@@ -0,0 +1,145 @@
+// Package graphqlapi отдаёт задачи по GraphQL на /graphql. Запросы и мутации
+// выполняет тот же TodoService, что и у REST API, подписки получают события
+// из потока изменений. Задачи проектов и тегов загружаются пачками на весь
+// список (см. loader), а глубина и сложность запроса ограничены, чтобы один
+// запрос не мог выгрузить всё хранилище.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/log"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+
+	"todo-api/internal/config"
+	"todo-api/internal/models"
+	"todo-api/internal/realtime"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+var ErrReadOnly = errors.New("через GET выполняются только запросы, мутации отправляются POST")
+
+type Server struct {
+	schema *graphql.Schema
+	// validation — та же схема для gqlparser: по его разбору запроса
+	// считается сложность до выполнения.
+	validation    *ast.Schema
+	maxComplexity int
+
+	ws       config.RealtimeConfig
+	upgrader websocket.Upgrader
+	done     chan struct{}
+	stop     sync.Once
+}
+
+// New собирает сервер GraphQL. Подписки через WebSocket используют настройки
+// соединений ws: интервал пингов, размер сообщений и разрешённые сайты.
+func New(service services.TodoService, broker *stream.Broker, cfg config.GraphQLConfig, ws config.RealtimeConfig) *Server {
+	return &Server{
+		schema: graphql.MustParseSchema(schemaSource, &resolver{service: service, broker: broker},
+			graphql.MaxDepth(cfg.MaxDepth),
+			graphql.Logger(log.LoggerFunc(logPanic)),
+			graphql.PanicHandler(panicHandler{}),
+		),
+		validation:    gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphql", Input: schemaSource}),
+		maxComplexity: cfg.MaxComplexity,
+		ws:            ws,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{subprotocol},
+			CheckOrigin:  realtime.CheckOrigin(ws.AllowedOrigins),
+			Error:        realtime.UpgradeError,
+		},
+		done: make(chan struct{}),
+	}
+}
+
+// Close закрывает открытые WebSocket с кодом 1001. Вызывается при остановке
+// сервиса: HTTP-сервер сам такие соединения не закрывает.
+func (s *Server) Close() {
+	s.stop.Do(func() { close(s.done) })
+}
+
+// Exec выполняет запрос или мутацию. readOnly запрещает мутации: так
+// выполняются GET-запросы, которые могут повторить прокси и браузер.
+// Единственная ошибка — ErrReadOnly; остальные ошибки приходят в ответе.
+func (s *Server) Exec(ctx context.Context, request *models.GraphQLRequest, readOnly bool) (*graphql.Response, error) {
+	operation, errs := s.prepare(request)
+	if len(errs) > 0 {
+		return &graphql.Response{Errors: errs}, nil
+	}
+
+	switch operation.Operation {
+	case ast.Subscription:
+		return &graphql.Response{Errors: []*gqlerrors.QueryError{
+			newError(codeBadInput, "подписки доступны только через WebSocket"),
+		}}, nil
+	case ast.Mutation:
+		if readOnly {
+			return nil, ErrReadOnly
+		}
+	}
+
+	response := s.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+	maskErrors(ctx, response.Errors)
+
+	return response, nil
+}
+
+// prepare проверяет запрос по схеме и отклоняет слишком сложные запросы.
+func (s *Server) prepare(request *models.GraphQLRequest) (*ast.OperationDefinition, []*gqlerrors.QueryError) {
+	document, list := gqlparser.LoadQuery(s.validation, request.Query)
+	if len(list) > 0 {
+		return nil, fromGQLErrors(list)
+	}
+
+	operation := document.Operations.ForName(request.OperationName)
+	if operation == nil {
+		message := fmt.Sprintf("операция %q не найдена", request.OperationName)
+		if request.OperationName == "" {
+			message = "в запросе несколько операций: укажите operationName"
+		}
+		return nil, []*gqlerrors.QueryError{newError(codeValidationFailed, message)}
+	}
+
+	vars, err := validator.VariableValues(s.validation, operation, request.Variables)
+	if err != nil {
+		return nil, []*gqlerrors.QueryError{newError(codeBadInput, err.Error())}
+	}
+
+	if cost := complexity(operation.SelectionSet, vars); cost > s.maxComplexity {
+		return nil, []*gqlerrors.QueryError{newError(codeTooComplex,
+			fmt.Sprintf("сложность запроса %d превышает допустимую %d", cost, s.maxComplexity))}
+	}
+
+	return operation, nil
+}
+
+func fromGQLErrors(list gqlerror.List) []*gqlerrors.QueryError {
+	errs := make([]*gqlerrors.QueryError, len(list))
+	for i, err := range list {
+		code := codeValidationFailed
+		if err.Rule == "" {
+			code = codeParseFailed
+		}
+
+		errs[i] = newError(code, err.Message)
+		for _, location := range err.Locations {
+			errs[i].Locations = append(errs[i].Locations, gqlerrors.Location{Line: location.Line, Column: location.Column})
+		}
+	}
+	return errs
+}
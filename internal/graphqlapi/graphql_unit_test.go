@@ -0,0 +1,418 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingService считает запросы списков задач и прочитанные задачи, чтобы
+// проверить загрузку пачками.
+type countingService struct {
+	services.TodoService
+	lists atomic.Int32
+	rows  atomic.Int32
+	err   error
+}
+
+func (s *countingService) GetAllTodos(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	s.lists.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	todos, err := s.TodoService.GetAllTodos(ctx, filter)
+	s.rows.Add(int32(len(todos)))
+	return todos, err
+}
+
+var testConfig = config.GraphQLConfig{MaxDepth: 7, MaxComplexity: 1000}
+
+var testWS = config.RealtimeConfig{PingInterval: time.Second, WriteTimeout: time.Second, MaxMessageSize: 64 * 1024}
+
+func newTestServer(t *testing.T) (*Server, *countingService, *stream.Broker) {
+	t.Helper()
+
+	broker := stream.NewBroker(100)
+	service := &countingService{
+		TodoService: services.NewTodoService(stream.Repository(repository.Constructor(), stream.Local(broker))),
+	}
+	server := New(service, broker, testConfig, testWS)
+
+	t.Cleanup(func() {
+		server.Close()
+		broker.Close()
+	})
+
+	return server, service, broker
+}
+
+func exec(t *testing.T, server *Server, query string, variables map[string]any) (map[string]any, []map[string]any) {
+	t.Helper()
+
+	response, err := server.Exec(t.Context(), &models.GraphQLRequest{Query: query, Variables: variables}, false)
+	require.NoError(t, err)
+
+	return decode(t, response)
+}
+
+func decode(t *testing.T, response *graphql.Response) (map[string]any, []map[string]any) {
+	t.Helper()
+
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Data   map[string]any   `json:"data"`
+		Errors []map[string]any `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	return decoded.Data, decoded.Errors
+}
+
+func code(err map[string]any) any {
+	extensions, _ := err["extensions"].(map[string]any)
+	return extensions["code"]
+}
+
+func TestServer_Mutations(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	data, errs := exec(t, server, `mutation($input: CreateTodoInput!) {
+		createTodo(input: $input) { id taskName priority dueAt tags { name } project { name } }
+	}`, map[string]any{"input": map[string]any{
+		"taskName": " Pay invoice ",
+		"priority": "HIGH",
+		"dueAt":    "2026-10-20T17:00:00Z",
+		"tags":     []any{"#Finance"},
+		"project":  "@acme",
+	}})
+	require.Empty(t, errs)
+
+	created := data["createTodo"].(map[string]any)
+	id := created["id"].(string)
+	assert.Equal(t, "Pay invoice", created["taskName"])
+	assert.Equal(t, "HIGH", created["priority"])
+	assert.Equal(t, "2026-10-20T17:00:00Z", created["dueAt"])
+	assert.Equal(t, []any{map[string]any{"name": "finance"}}, created["tags"])
+	assert.Equal(t, map[string]any{"name": "acme"}, created["project"])
+
+	data, errs = exec(t, server, `mutation($id: ID!) {
+		updateTodo(id: $id, input: {completed: true, dueAt: null}) { completed completedAt dueAt taskName }
+	}`, map[string]any{"id": id})
+	require.Empty(t, errs)
+
+	updated := data["updateTodo"].(map[string]any)
+	assert.Equal(t, true, updated["completed"])
+	assert.NotNil(t, updated["completedAt"])
+	assert.Nil(t, updated["dueAt"], "dueAt: null снимает срок")
+	assert.Equal(t, "Pay invoice", updated["taskName"], "поля не из входных данных не меняются")
+
+	data, errs = exec(t, server, `mutation($id: ID!) { deleteTodo(id: $id) }`, map[string]any{"id": id})
+	require.Empty(t, errs)
+	assert.Equal(t, id, data["deleteTodo"])
+
+	data, errs = exec(t, server, `query($id: ID!) { todo(id: $id) { id } }`, map[string]any{"id": id})
+	require.Empty(t, errs)
+	assert.Nil(t, data["todo"])
+
+	_, errs = exec(t, server, `mutation($id: ID!) { deleteTodo(id: $id) }`, map[string]any{"id": id})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "NOT_FOUND", code(errs[0]))
+}
+
+func TestServer_TodosPaging(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	for _, name := range []string{"one", "two", "three"} {
+		_, errs := exec(t, server, `mutation($name: String!) { createTodo(input: {taskName: $name}) { id } }`,
+			map[string]any{"name": name})
+		require.Empty(t, errs)
+	}
+
+	var names []any
+	variables := map[string]any{"after": nil}
+	for pages := 1; ; pages++ {
+		data, errs := exec(t, server, `query($after: String) {
+			todos(first: 2, after: $after) { items { taskName } nextCursor }
+		}`, variables)
+		require.Empty(t, errs)
+
+		page := data["todos"].(map[string]any)
+		for _, item := range page["items"].([]any) {
+			names = append(names, item.(map[string]any)["taskName"])
+		}
+		if page["nextCursor"] == nil {
+			assert.Equal(t, 2, pages)
+			break
+		}
+		variables["after"] = page["nextCursor"]
+	}
+	assert.Equal(t, []any{"one", "two", "three"}, names)
+
+	_, errs := exec(t, server, `{ todos(after: "???") { nextCursor } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "BAD_USER_INPUT", code(errs[0]))
+}
+
+func TestServer_BatchLoading(t *testing.T) {
+	server, service, _ := newTestServer(t)
+
+	for i, project := range []string{"home", "work", "home", "garden", "work"} {
+		tag := "all"
+		if i%2 == 1 {
+			tag = "other"
+		}
+		_, errs := exec(t, server, `mutation($input: CreateTodoInput!) { createTodo(input: $input) { id } }`,
+			map[string]any{"input": map[string]any{"taskName": project, "project": project, "tags": []any{tag}}})
+		require.Empty(t, errs)
+	}
+
+	service.lists.Store(0)
+	data, errs := exec(t, server, `{
+		todos(first: 10) {
+			items {
+				project { name todos(first: 5) { taskName project { todos(first: 5) { id } } } }
+				tags { name todos(completed: false, first: 5) { id } }
+			}
+		}
+	}`, nil)
+	require.Empty(t, errs)
+
+	// Проекты списка, теги списка и проекты задач этих проектов — по одному запросу.
+	assert.Equal(t, int32(3), service.lists.Load())
+
+	items := data["todos"].(map[string]any)["items"].([]any)
+	require.Len(t, items, 5)
+	project := items[0].(map[string]any)["project"].(map[string]any)
+	assert.Equal(t, "home", project["name"])
+	assert.Len(t, project["todos"], 2)
+
+	data, errs = exec(t, server, `{ tag(name: "#All") { name todos { taskName } } project(name: "@work") { todos { id } } }`, nil)
+	require.Empty(t, errs)
+	assert.Equal(t, "all", data["tag"].(map[string]any)["name"])
+	assert.Len(t, data["tag"].(map[string]any)["todos"], 3)
+	assert.Len(t, data["project"].(map[string]any)["todos"], 2)
+}
+
+func TestServer_BatchLoadingFirst(t *testing.T) {
+	server, service, _ := newTestServer(t)
+
+	for _, project := range []string{"home", "work", "home", "home", "work"} {
+		_, errs := exec(t, server, `mutation($input: CreateTodoInput!) { createTodo(input: $input) { id } }`,
+			map[string]any{"input": map[string]any{"taskName": project, "project": project}})
+		require.Empty(t, errs)
+	}
+
+	service.rows.Store(0)
+	data, errs := exec(t, server, `{ project(name: "home") { todos(first: 1) { id } } }`, nil)
+	require.Empty(t, errs)
+	assert.Len(t, data["project"].(map[string]any)["todos"], 1)
+	assert.Equal(t, int32(1), service.rows.Load(), "из хранилища читается не больше first задач проекта")
+
+	service.lists.Store(0)
+	service.rows.Store(0)
+	data, errs = exec(t, server, `{ todos(first: 2) { items { project { todos(first: 2) { id } } } } }`, nil)
+	require.Empty(t, errs)
+	assert.Equal(t, int32(1), service.lists.Load())
+	assert.Equal(t, int32(4), service.rows.Load(), "по две задачи на каждый из двух проектов")
+
+	items := data["todos"].(map[string]any)["items"].([]any)
+	for _, item := range items {
+		assert.Len(t, item.(map[string]any)["project"].(map[string]any)["todos"], 2)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	server, service, _ := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"Parse", `{ todos {`, "GRAPHQL_PARSE_FAILED"},
+		{"Validation", `{ todos { missing } }`, "GRAPHQL_VALIDATION_FAILED"},
+		{"Depth", `{ todos(first: 1) { items { project { todos(first: 1) { project { todos(first: 1) { project { name } } } } } } } }`, "QUERY_TOO_DEEP"},
+		{"Complexity", `{ todos(first: 100) { items { project { todos(first: 100) { id } } } } }`, "QUERY_TOO_COMPLEX"},
+		{"First", `{ todos(first: 0) { nextCursor } }`, "BAD_USER_INPUT"},
+		{"Subscription", `subscription { todoChanged { type } }`, "BAD_USER_INPUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.lists.Store(0)
+
+			_, errs := exec(t, server, tt.query, nil)
+			require.NotEmpty(t, errs)
+			assert.Equal(t, tt.code, code(errs[0]), errs[0]["message"])
+		})
+	}
+
+	_, err := server.Exec(t.Context(), &models.GraphQLRequest{Query: `mutation { deleteTodo(id: "1") }`}, true)
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func TestServer_InternalError(t *testing.T) {
+	server, service, _ := newTestServer(t)
+	service.err = errors.New("pq: connection refused")
+
+	_, errs := exec(t, server, `{ project(name: "home") { todos { id } } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", code(errs[0]))
+	assert.Equal(t, "внутренняя ошибка сервера", errs[0]["message"], "детали не уходят клиенту")
+}
+
+func TestComplexity(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	tests := []struct {
+		query string
+		vars  map[string]any
+		cost  int
+	}{
+		{`{ todo(id: "1") { id taskName } }`, nil, 3},
+		{`{ todos(first: 50) { items { id } } }`, nil, 101},
+		{`{ todos { items { id } } }`, nil, 201},
+		{`query($n: Int) { todos(first: $n) { items { id } } }`, map[string]any{"n": 10}, 21},
+		{`{ todos(first: 2) { ...page } } fragment page on TodoPage { items { id } nextCursor }`, nil, 7},
+		{`{ __schema { types { name } } todo(id: "1") { id } }`, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			server.maxComplexity = tt.cost
+			_, errs := server.prepare(&models.GraphQLRequest{Query: tt.query, Variables: tt.vars})
+			assert.Empty(t, errs)
+
+			server.maxComplexity = tt.cost - 1
+			_, errs = server.prepare(&models.GraphQLRequest{Query: tt.query, Variables: tt.vars})
+			require.Len(t, errs, 1)
+			assert.Equal(t, "QUERY_TOO_COMPLEX", errs[0].Extensions["code"])
+		})
+	}
+}
+
+func dial(t *testing.T, server *Server) *websocket.Conn {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(server.ServeWebSocket))
+	t.Cleanup(srv.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg message
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	conn := dial(t, server)
+
+	require.NoError(t, conn.WriteJSON(message{Type: typeConnectionInit}))
+	assert.Equal(t, typeConnectionAck, read(t, conn).Type)
+
+	require.NoError(t, conn.WriteJSON(message{Type: typePing}))
+	assert.Equal(t, typePong, read(t, conn).Type)
+
+	subscribe := func(id, query string) {
+		payload, err := json.Marshal(models.GraphQLRequest{Query: query})
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteJSON(message{ID: id, Type: typeSubscribe, Payload: payload}))
+	}
+
+	// RESET на неизвестный lastEventId означает, что подписка оформлена.
+	subscribe("1", `subscription { todoChanged(projects: ["@home"], lastEventId: "unknown") { type actor todo { taskName project { name } } } }`)
+	next := read(t, conn)
+	assert.Equal(t, "1", next.ID)
+	assert.Equal(t, typeNext, next.Type)
+	assert.JSONEq(t, `{"data":{"todoChanged":{"type":"RESET","actor":null,"todo":null}}}`, string(next.Payload))
+
+	subscribe("2", `mutation { a: createTodo(input: {taskName: "work", project: "work"}) { id } b: createTodo(input: {taskName: "home", project: "home"}) { id } }`)
+
+	var event, result, complete *message
+	for event == nil || result == nil || complete == nil {
+		msg := read(t, conn)
+		switch {
+		case msg.ID == "1":
+			event = &msg
+		case msg.Type == typeNext:
+			result = &msg
+		case msg.Type == typeComplete:
+			complete = &msg
+		}
+	}
+	assert.Equal(t, "2", complete.ID)
+	assert.Contains(t, string(result.Payload), `"a":{"id":`)
+	assert.JSONEq(t, `{"data":{"todoChanged":{"type":"CREATED","actor":null,"todo":{"taskName":"home","project":{"name":"home"}}}}}`,
+		string(event.Payload))
+
+	subscribe("3", `{ todos(first: 0) { nextCursor } }`)
+	next = read(t, conn)
+	assert.Equal(t, typeNext, next.Type)
+	assert.Contains(t, string(next.Payload), "BAD_USER_INPUT")
+	assert.Equal(t, typeComplete, read(t, conn).Type)
+
+	subscribe("4", `{ todos { missing } }`)
+	failed := read(t, conn)
+	assert.Equal(t, typeError, failed.Type)
+	assert.Contains(t, string(failed.Payload), "GRAPHQL_VALIDATION_FAILED")
+
+	subscribe("1", `subscription { todoChanged { type } }`)
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, closeSubscriberExists, closeErr.Code)
+}
+
+func TestServer_WebSocketProtocol(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	closeCode := func(conn *websocket.Conn) int {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err := conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		require.ErrorAs(t, err, &closeErr)
+		return closeErr.Code
+	}
+
+	conn := dial(t, server)
+	require.NoError(t, conn.WriteJSON(message{ID: "1", Type: typeSubscribe, Payload: json.RawMessage(`{"query":"{ todos { nextCursor } }"}`)}))
+	assert.Equal(t, closeUnauthorized, closeCode(conn))
+
+	conn = dial(t, server)
+	assert.Equal(t, closeInitTimeout, closeCode(conn), "без connection_init соединение закрывается")
+
+	conn = dial(t, server)
+	require.NoError(t, conn.WriteJSON(message{Type: typeConnectionInit}))
+	assert.Equal(t, typeConnectionAck, read(t, conn).Type)
+	server.Close()
+	assert.Equal(t, websocket.CloseGoingAway, closeCode(conn))
+}
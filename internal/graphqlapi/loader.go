@@ -0,0 +1,145 @@
+package graphqlapi
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+)
+
+// loader загружает задачи проектов и тегов, которые встретились в одном
+// списке задач. Первое обращение к todos любого проекта списка загружает
+// задачи всех его проектов одним запросом к хранилищу, остальные берут их
+// из того же результата. Так запрос
+//
+//	todos { items { project { todos { id } } } }
+//
+// обходится двумя запросами к хранилищу, а не одним на каждую задачу.
+type loader struct {
+	service  services.TodoService
+	projects []string
+	tags     []string
+
+	mu      sync.Mutex
+	batches map[batchKey]*batch
+}
+
+// batchKey различает загрузки по проектам и по тегам с разными значениями
+// аргументов completed и first.
+type batchKey struct {
+	tags      bool
+	completed string
+	first     int32
+}
+
+type batch struct {
+	once   sync.Once
+	groups map[string][]*todoResolver
+	err    error
+}
+
+// wrap оборачивает задачи списка в резолверы с общим загрузчиком.
+func wrap(service services.TodoService, tasks []*models.Todo) []*todoResolver {
+	l := &loader{service: service, batches: make(map[batchKey]*batch)}
+
+	resolvers := make([]*todoResolver, len(tasks))
+	for i, task := range tasks {
+		if task.Project != "" && !slices.Contains(l.projects, task.Project) {
+			l.projects = append(l.projects, task.Project)
+		}
+		for _, tag := range task.Tags {
+			if !slices.Contains(l.tags, tag) {
+				l.tags = append(l.tags, tag)
+			}
+		}
+		resolvers[i] = &todoResolver{task: task, loader: l}
+	}
+
+	return resolvers
+}
+
+// projectLoader и tagLoader нужны для проекта и тега, запрошенных по имени.
+func projectLoader(service services.TodoService, name string) *loader {
+	return &loader{service: service, projects: []string{name}, batches: make(map[batchKey]*batch)}
+}
+
+func tagLoader(service services.TodoService, name string) *loader {
+	return &loader{service: service, tags: []string{name}, batches: make(map[batchKey]*batch)}
+}
+
+// todos возвращает первые first задач проекта name или, если tags, задачи с
+// тегом name.
+func (l *loader) todos(ctx context.Context, tags bool, name string, completed *bool, first int32) ([]*todoResolver, error) {
+	key := batchKey{tags: tags, first: first}
+	if completed != nil {
+		key.completed = strconv.FormatBool(*completed)
+	}
+
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = &batch{}
+		l.batches[key] = b
+	}
+	l.mu.Unlock()
+
+	b.once.Do(func() {
+		b.groups, b.err = l.load(ctx, tags, completed, first)
+	})
+
+	return b.groups[name], b.err
+}
+
+// list отдаёт первые args.First задач проекта или тега.
+func (l *loader) list(ctx context.Context, tags bool, name string, args listArgs) ([]*todoResolver, error) {
+	if args.First < 1 || args.First > maxPageSize {
+		return nil, errInvalidFirst
+	}
+
+	todos, err := l.todos(ctx, tags, name, args.Completed, args.First)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(todos) > int(args.First) {
+		todos = todos[:args.First]
+	}
+	if todos == nil {
+		todos = []*todoResolver{}
+	}
+	return todos, nil
+}
+
+// load читает из хранилища не больше first задач на каждый проект или тег.
+func (l *loader) load(ctx context.Context, tags bool, completed *bool, first int32) (map[string][]*todoResolver, error) {
+	filter := &models.TodoFilter{Completed: completed, PerGroup: int(first)}
+	if tags {
+		filter.Tags = l.tags
+	} else {
+		filter.Projects = l.projects
+	}
+
+	tasks, err := l.service.GetAllTodos(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Загруженные задачи — тоже список: их проекты и теги загружаются вместе.
+	groups := make(map[string][]*todoResolver)
+	for _, resolver := range wrap(l.service, tasks) {
+		if !tags {
+			groups[resolver.task.Project] = append(groups[resolver.task.Project], resolver)
+			continue
+		}
+		for _, tag := range resolver.task.Tags {
+			if slices.Contains(l.tags, tag) {
+				groups[tag] = append(groups[tag], resolver)
+			}
+		}
+	}
+
+	return groups, nil
+}
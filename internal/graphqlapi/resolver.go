@@ -0,0 +1,445 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+)
+
+const maxPageSize = 1000
+
+var errInvalidFirst = inputError("first должен быть от 1 до 1000")
+
+var eventTypes = map[string]string{
+	models.EventTodoCreated:   "CREATED",
+	models.EventTodoUpdated:   "UPDATED",
+	models.EventTodoCompleted: "COMPLETED",
+	models.EventTodoDeleted:   "DELETED",
+	stream.TypeReset:          "RESET",
+}
+
+type resolver struct {
+	service services.TodoService
+	broker  *stream.Broker
+}
+
+func (r *resolver) Todo(ctx context.Context, args struct{ ID graphql.ID }) (*todoResolver, error) {
+	task, err := r.service.GetById(ctx, string(args.ID))
+	if errors.Is(err, repository.ErrInvalidID) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return wrap(r.service, []*models.Todo{task})[0], nil
+}
+
+type filterInput struct {
+	Completed *bool
+	Priority  *string
+	Project   *string
+	Tag       *string
+	Projects  *[]string
+	Tags      *[]string
+	DueBefore *graphql.Time
+	DueAfter  *graphql.Time
+}
+
+func (f *filterInput) model() *models.TodoFilter {
+	if f == nil {
+		return nil
+	}
+
+	filter := &models.TodoFilter{
+		Completed: f.Completed,
+		Priority:  fromPriority(f.Priority),
+		Project:   deref(f.Project),
+		Tag:       deref(f.Tag),
+		DueBefore: fromTime(f.DueBefore),
+		DueAfter:  fromTime(f.DueAfter),
+	}
+	if f.Projects != nil {
+		filter.Projects = *f.Projects
+	}
+	if f.Tags != nil {
+		filter.Tags = *f.Tags
+	}
+
+	return filter
+}
+
+func (r *resolver) Todos(ctx context.Context, args struct {
+	Filter *filterInput
+	First  int32
+	After  *string
+}) (*pageResolver, error) {
+	if args.First < 1 || args.First > maxPageSize {
+		return nil, errInvalidFirst
+	}
+
+	page, err := r.service.ListTodos(ctx, args.Filter.model(), int(args.First), deref(args.After))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pageResolver{items: wrap(r.service, page.Todos), next: page.NextPageToken}, nil
+}
+
+func (r *resolver) Project(args struct{ Name string }) *projectResolver {
+	name := services.NormalizeProject(args.Name)
+	return &projectResolver{name: name, loader: projectLoader(r.service, name)}
+}
+
+func (r *resolver) Tag(args struct{ Name string }) *tagResolver {
+	name := services.NormalizeTag(args.Name)
+	return &tagResolver{name: name, loader: tagLoader(r.service, name)}
+}
+
+type createInput struct {
+	TaskName    string
+	Description *string
+	DueAt       *graphql.Time
+	Priority    *string
+	Tags        *[]string
+	Project     *string
+}
+
+func (r *resolver) CreateTodo(ctx context.Context, args struct{ Input createInput }) (*todoResolver, error) {
+	request := &models.CreateTodoRequest{
+		TaskName:    args.Input.TaskName,
+		Description: args.Input.Description,
+		DueAt:       fromTime(args.Input.DueAt),
+		Priority:    fromPriority(args.Input.Priority),
+		Project:     deref(args.Input.Project),
+	}
+	if args.Input.Tags != nil {
+		request.Tags = *args.Input.Tags
+	}
+
+	task, err := r.service.CreateTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrap(r.service, []*models.Todo{task})[0], nil
+}
+
+type updateInput struct {
+	TaskName    *string
+	Description *string
+	Completed   *bool
+	DueAt       graphql.NullTime
+	Priority    *string
+	Tags        *[]string
+	Project     *string
+}
+
+func (r *resolver) UpdateTodo(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateInput
+}) (*todoResolver, error) {
+	input := args.Input
+	request := &models.UpdateTodoRequest{
+		TaskName:    input.TaskName,
+		Description: input.Description,
+		Completed:   input.Completed,
+		Project:     input.Project,
+	}
+	if input.DueAt.Set {
+		request.DueAt = fromTime(input.DueAt.Value)
+		request.ClearDueAt = input.DueAt.Value == nil
+	}
+	if input.Priority != nil {
+		priority := fromPriority(input.Priority)
+		request.Priority = &priority
+	}
+	if input.Tags != nil {
+		request.Tags = *input.Tags
+		if request.Tags == nil {
+			request.Tags = []string{}
+		}
+	}
+
+	task, err := r.service.UpdateTodo(ctx, string(args.ID), request)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrap(r.service, []*models.Todo{task})[0], nil
+}
+
+func (r *resolver) DeleteTodo(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if err := r.service.DeleteTodo(ctx, string(args.ID)); err != nil {
+		return "", err
+	}
+	return args.ID, nil
+}
+
+// TodoChanged передаёт события так же, как поток /todos/events: после
+// переподключения с lastEventId клиент получает пропущенные события из
+// буфера брокера, а если их там уже нет — событие RESET.
+func (r *resolver) TodoChanged(ctx context.Context, args struct {
+	Actors      *[]string
+	Projects    *[]string
+	LastEventID *string
+}) (<-chan *eventResolver, error) {
+	var actors, projects []string
+	if args.Actors != nil {
+		actors = *args.Actors
+	}
+	if args.Projects != nil {
+		for _, project := range *args.Projects {
+			projects = append(projects, services.NormalizeProject(project))
+		}
+	}
+
+	match := func(event stream.Event) bool {
+		if len(actors) > 0 && !slices.Contains(actors, event.Actor) {
+			return false
+		}
+		return len(projects) == 0 || slices.Contains(projects, event.Project) ||
+			event.PreviousProject != "" && slices.Contains(projects, event.PreviousProject)
+	}
+
+	sub, replay, resumed := r.broker.Subscribe(deref(args.LastEventID), match)
+	if !resumed {
+		replay = append([]stream.Event{{Type: stream.TypeReset}}, replay...)
+	}
+
+	events := make(chan *eventResolver)
+	go func() {
+		defer close(events)
+		defer r.broker.Unsubscribe(sub)
+
+		send := func(event stream.Event) bool {
+			select {
+			case events <- r.event(event):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, event := range replay {
+			if !send(event) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.C:
+				if !ok || !send(event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (r *resolver) event(event stream.Event) *eventResolver {
+	resolver := &eventResolver{typ: eventTypes[event.Type]}
+	if event.Type == stream.TypeReset {
+		return resolver
+	}
+
+	resolver.id = &event.ID
+
+	var payload models.EventPayload
+	if err := json.Unmarshal(event.Data, &payload); err != nil {
+		return resolver
+	}
+
+	resolver.actor = optional(payload.Actor)
+	resolver.occurredAt = &graphql.Time{Time: payload.OccurredAt}
+	resolver.previousProject = optional(payload.PreviousProject)
+	if payload.Data != nil {
+		resolver.todo = wrap(r.service, []*models.Todo{payload.Data})[0]
+	}
+
+	return resolver
+}
+
+type todoResolver struct {
+	task   *models.Todo
+	loader *loader
+}
+
+func (r *todoResolver) ID() graphql.ID {
+	return graphql.ID(r.task.ID)
+}
+
+func (r *todoResolver) TaskName() string {
+	return r.task.TaskName
+}
+
+func (r *todoResolver) Description() *string {
+	return r.task.Description
+}
+
+func (r *todoResolver) Completed() bool {
+	return r.task.Completed
+}
+
+func (r *todoResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.task.CreatedAt}
+}
+
+func (r *todoResolver) CompletedAt() *graphql.Time {
+	return toTime(r.task.CompletedAt)
+}
+
+func (r *todoResolver) DueAt() *graphql.Time {
+	return toTime(r.task.DueAt)
+}
+
+func (r *todoResolver) Priority() *string {
+	if r.task.Priority == "" {
+		return nil
+	}
+	priority := strings.ToUpper(r.task.Priority)
+	return &priority
+}
+
+func (r *todoResolver) Tags() []*tagResolver {
+	tags := make([]*tagResolver, len(r.task.Tags))
+	for i, tag := range r.task.Tags {
+		tags[i] = &tagResolver{name: tag, loader: r.loader}
+	}
+	return tags
+}
+
+func (r *todoResolver) Project() *projectResolver {
+	if r.task.Project == "" {
+		return nil
+	}
+	return &projectResolver{name: r.task.Project, loader: r.loader}
+}
+
+func (r *todoResolver) Version() int32 {
+	return int32(r.task.Version)
+}
+
+type listArgs struct {
+	Completed *bool
+	First     int32
+}
+
+type tagResolver struct {
+	name   string
+	loader *loader
+}
+
+func (r *tagResolver) Name() string {
+	return r.name
+}
+
+func (r *tagResolver) Todos(ctx context.Context, args listArgs) ([]*todoResolver, error) {
+	return r.loader.list(ctx, true, r.name, args)
+}
+
+type projectResolver struct {
+	name   string
+	loader *loader
+}
+
+func (r *projectResolver) Name() string {
+	return r.name
+}
+
+func (r *projectResolver) Todos(ctx context.Context, args listArgs) ([]*todoResolver, error) {
+	return r.loader.list(ctx, false, r.name, args)
+}
+
+type pageResolver struct {
+	items []*todoResolver
+	next  string
+}
+
+func (r *pageResolver) Items() []*todoResolver {
+	return r.items
+}
+
+func (r *pageResolver) NextCursor() *string {
+	return optional(r.next)
+}
+
+type eventResolver struct {
+	id              *string
+	typ             string
+	actor           *string
+	occurredAt      *graphql.Time
+	todo            *todoResolver
+	previousProject *string
+}
+
+func (r *eventResolver) ID() *string {
+	return r.id
+}
+
+func (r *eventResolver) Type() string {
+	return r.typ
+}
+
+func (r *eventResolver) Actor() *string {
+	return r.actor
+}
+
+func (r *eventResolver) OccurredAt() *graphql.Time {
+	return r.occurredAt
+}
+
+func (r *eventResolver) Todo() *todoResolver {
+	return r.todo
+}
+
+func (r *eventResolver) PreviousProject() *string {
+	return r.previousProject
+}
+
+// fromPriority переводит значение перечисления Priority в приоритет модели.
+func fromPriority(priority *string) string {
+	return strings.ToLower(deref(priority))
+}
+
+func toTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+func fromTime(t *graphql.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,283 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"todo-api/internal/models"
+)
+
+// subprotocol — протокол graphql-transport-ws из библиотеки graphql-ws:
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const subprotocol = "graphql-transport-ws"
+
+const (
+	typeConnectionInit = "connection_init"
+	typeConnectionAck  = "connection_ack"
+	typePing           = "ping"
+	typePong           = "pong"
+	typeSubscribe      = "subscribe"
+	typeNext           = "next"
+	typeError          = "error"
+	typeComplete       = "complete"
+)
+
+// Коды закрытия соединения из протокола.
+const (
+	closeBadRequest       = 4400
+	closeUnauthorized     = 4401
+	closeBadSubprotocol   = 4406
+	closeInitTimeout      = 4408
+	closeSubscriberExists = 4409
+	closeTooManyInit      = 4429
+)
+
+// maxOperations ограничивает число одновременных операций в одном
+// соединении.
+const maxOperations = 100
+
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ServeWebSocket обслуживает подписки, запросы и мутации по WebSocket, пока
+// соединение открыто. Пользователь берётся из контекста запроса.
+func (s *Server) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &connection{server: s, conn: conn, operations: make(map[string]context.CancelFunc)}
+	if conn.Subprotocol() != subprotocol {
+		c.close(closeBadSubprotocol, "ожидается протокол "+subprotocol)
+		return
+	}
+
+	c.serve(r.Context())
+}
+
+type connection struct {
+	server *Server
+	conn   *websocket.Conn
+
+	// writeMu упорядочивает запись: ответы операций пишутся из разных горутин.
+	writeMu      sync.Mutex
+	acknowledged atomic.Bool
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+	running    sync.WaitGroup
+}
+
+func (c *connection) serve(ctx context.Context) {
+	defer c.conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer c.running.Wait()
+	defer cancel()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go c.keepAlive(stopped)
+
+	// Клиент, который не ответил на два пинга подряд, считается отключившимся.
+	timeout := 2 * c.server.ws.PingInterval
+	c.conn.SetReadLimit(int64(c.server.ws.MaxMessageSize))
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(closeBadRequest, "неверное сообщение")
+			return
+		}
+		if !c.handle(ctx, &msg) {
+			return
+		}
+	}
+}
+
+// keepAlive пингует клиента, требует connection_init в течение интервала
+// пингов и закрывает соединение при остановке сервиса.
+func (c *connection) keepAlive(stopped <-chan struct{}) {
+	ping := time.NewTicker(c.server.ws.PingInterval)
+	defer ping.Stop()
+
+	initTimeout := time.NewTimer(c.server.ws.PingInterval)
+	defer initTimeout.Stop()
+
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-c.server.done:
+			c.close(websocket.CloseGoingAway, "сервер останавливается")
+			return
+		case <-initTimeout.C:
+			if !c.acknowledged.Load() {
+				c.close(closeInitTimeout, "не получен connection_init")
+				return
+			}
+		case <-ping.C:
+			deadline := time.Now().Add(c.server.ws.WriteTimeout)
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle обрабатывает сообщение клиента; false означает, что соединение
+// закрыто.
+func (c *connection) handle(ctx context.Context, msg *message) bool {
+	switch msg.Type {
+	case typeConnectionInit:
+		if c.acknowledged.Swap(true) {
+			c.close(closeTooManyInit, "повторный connection_init")
+			return false
+		}
+		c.send(message{Type: typeConnectionAck})
+	case typePing:
+		c.send(message{Type: typePong})
+	case typePong:
+	case typeSubscribe:
+		if !c.acknowledged.Load() {
+			c.close(closeUnauthorized, "не получен connection_init")
+			return false
+		}
+
+		var request models.GraphQLRequest
+		if msg.ID == "" || json.Unmarshal(msg.Payload, &request) != nil {
+			c.close(closeBadRequest, "неверное сообщение subscribe")
+			return false
+		}
+
+		return c.start(ctx, msg.ID, &request)
+	case typeComplete:
+		c.stop(msg.ID)
+	default:
+		c.close(closeBadRequest, "неизвестный тип сообщения")
+		return false
+	}
+
+	return true
+}
+
+func (c *connection) start(ctx context.Context, id string, request *models.GraphQLRequest) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.operations[id]; ok {
+		c.close(closeSubscriberExists, "операция с таким id уже выполняется")
+		return false
+	}
+	if len(c.operations) >= maxOperations {
+		c.sendErrors(id, []*gqlerrors.QueryError{newError(codeBadInput, "слишком много операций в соединении")})
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		defer c.stop(id)
+		c.execute(ctx, id, request)
+	}()
+
+	return true
+}
+
+// stop отменяет операцию: после complete от клиента сервер больше ничего по
+// ней не отправляет.
+func (c *connection) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.operations[id]; ok {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+func (c *connection) execute(ctx context.Context, id string, request *models.GraphQLRequest) {
+	if _, errs := c.server.prepare(request); len(errs) > 0 {
+		c.sendErrors(id, errs)
+		return
+	}
+
+	responses, err := c.server.schema.Subscribe(ctx, request.Query, request.OperationName, request.Variables)
+	if err != nil {
+		c.sendErrors(id, []*gqlerrors.QueryError{newError(codeInternal, internalMessage)})
+		return
+	}
+
+	for response := range responses {
+		response := response.(*graphql.Response)
+		maskErrors(ctx, response.Errors)
+
+		if ctx.Err() != nil {
+			return
+		}
+		c.sendPayload(id, typeNext, response)
+	}
+
+	if ctx.Err() == nil {
+		c.send(message{ID: id, Type: typeComplete})
+	}
+}
+
+func (c *connection) sendErrors(id string, errs []*gqlerrors.QueryError) {
+	c.sendPayload(id, typeError, errs)
+}
+
+func (c *connection) sendPayload(id, typ string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		c.close(websocket.CloseInternalServerErr, internalMessage)
+		return
+	}
+	c.send(message{ID: id, Type: typ, Payload: data})
+}
+
+func (c *connection) send(msg message) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.server.ws.WriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		// Чтение завершится с ошибкой, и соединение закроется.
+		c.conn.Close()
+	}
+}
+
+// close отправляет клиенту код закрытия и закрывает соединение.
+func (c *connection) close(code int, text string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	deadline := time.Now().Add(c.server.ws.WriteTimeout)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline)
+	c.conn.Close()
+}
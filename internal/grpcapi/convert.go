@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	todov1 "todo-api/api/todo/v1"
+	"todo-api/internal/models"
+)
+
+var priorities = map[string]todov1.Priority{
+	models.PriorityLow:    todov1.Priority_PRIORITY_LOW,
+	models.PriorityMedium: todov1.Priority_PRIORITY_MEDIUM,
+	models.PriorityHigh:   todov1.Priority_PRIORITY_HIGH,
+}
+
+var eventTypes = map[string]todov1.TodoEvent_Type{
+	models.EventTodoCreated:   todov1.TodoEvent_TYPE_CREATED,
+	models.EventTodoUpdated:   todov1.TodoEvent_TYPE_UPDATED,
+	models.EventTodoCompleted: todov1.TodoEvent_TYPE_COMPLETED,
+	models.EventTodoDeleted:   todov1.TodoEvent_TYPE_DELETED,
+}
+
+func toProto(task *models.Todo) *todov1.Todo {
+	return &todov1.Todo{
+		Id:           task.ID,
+		TaskName:     task.TaskName,
+		Description:  task.Description,
+		Completed:    task.Completed,
+		CreateTime:   timestamppb.New(task.CreatedAt),
+		CompleteTime: toTimestamp(task.CompletedAt),
+		DueTime:      toTimestamp(task.DueAt),
+		Priority:     priorities[task.Priority],
+		Tags:         task.Tags,
+		Project:      task.Project,
+		Version:      task.Version,
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromTimestamp(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.AsTime()
+	return &value
+}
+
+// fromPriority возвращает приоритет модели; PRIORITY_UNSPECIFIED — пустая
+// строка, то есть приоритет не задан.
+func fromPriority(priority todov1.Priority) string {
+	for name, value := range priorities {
+		if value == priority {
+			return name
+		}
+	}
+	if priority == todov1.Priority_PRIORITY_UNSPECIFIED {
+		return ""
+	}
+	// Неизвестное значение сервис отклонит как неверный приоритет.
+	return priority.String()
+}
+
+// updateRequest переводит поля из update_mask в частичное обновление.
+func updateRequest(request *todov1.UpdateTodoRequest) (*models.UpdateTodoRequest, error) {
+	task := request.GetTodo()
+	if task == nil {
+		return nil, errors.New("не передана задача")
+	}
+
+	paths := request.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		return nil, errors.New("update_mask не задан")
+	}
+
+	update := &models.UpdateTodoRequest{}
+	for _, path := range paths {
+		switch path {
+		case "task_name":
+			update.TaskName = &task.TaskName
+		case "description":
+			description := task.GetDescription()
+			update.Description = &description
+		case "completed":
+			update.Completed = &task.Completed
+		case "due_time":
+			update.DueAt = fromTimestamp(task.DueTime)
+			update.ClearDueAt = task.DueTime == nil
+		case "priority":
+			priority := fromPriority(task.Priority)
+			update.Priority = &priority
+		case "tags":
+			update.Tags = task.Tags
+			if update.Tags == nil {
+				update.Tags = []string{}
+			}
+		case "project":
+			update.Project = &task.Project
+		default:
+			return nil, fmt.Errorf("поле %q нельзя изменить", path)
+		}
+	}
+
+	return update, nil
+}
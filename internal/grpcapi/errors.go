@@ -0,0 +1,65 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+)
+
+// statusError переводит ошибку сервиса в статус gRPC. Как и в REST API,
+// внутренние ошибки не раскрываются клиенту, а пишутся в лог вызова.
+func statusError(err error) error {
+	var code codes.Code
+
+	switch {
+	case errors.Is(err, repository.ErrEmptyID),
+		errors.Is(err, repository.ErrEmptyData),
+		errors.Is(err, repository.ErrEmptyTask),
+		errors.Is(err, repository.ErrEmptyName),
+		errors.Is(err, repository.ErrInvalidPriority),
+		errors.Is(err, repository.ErrDueAtConflict),
+		errors.Is(err, services.ErrInvalidPageToken):
+		code = codes.InvalidArgument
+	case errors.Is(err, repository.ErrInvalidID):
+		code = codes.NotFound
+	case errors.Is(err, repository.ErrAlreadyExist):
+		code = codes.AlreadyExists
+	case errors.Is(err, repository.ErrVersionConflict):
+		code = codes.Aborted
+	case errors.Is(err, repository.ErrStorageClosed):
+		return &internalError{code: codes.Unavailable, message: "хранилище недоступно", err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &internalError{code: codes.DeadlineExceeded, message: "превышено время ожидания ответа от хранилища", err: err}
+	case errors.Is(err, context.Canceled):
+		return &internalError{code: codes.Canceled, message: "запрос отменён клиентом", err: err}
+	default:
+		return &internalError{code: codes.Internal, message: "внутренняя ошибка сервера", err: err}
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// internalError отдаёт клиенту общий статус, а исходную ошибку сохраняет
+// для лога вызова.
+type internalError struct {
+	code    codes.Code
+	message string
+	err     error
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+func (e *internalError) GRPCStatus() *status.Status {
+	return status.New(e.code, e.message)
+}
@@ -0,0 +1,170 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	todov1 "todo-api/api/todo/v1"
+	"todo-api/internal/identity"
+)
+
+// New собирает gRPC-сервер с TodoService. Пользователь, выполняющий
+// изменение, берётся из метаданных userHeader, как в REST API из заголовка.
+func New(server *Server, logger *slog.Logger, userHeader string) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			logUnary(logger),
+			recoverUnary,
+			identityUnary(userHeader),
+		),
+		grpc.ChainStreamInterceptor(
+			logStream(logger),
+			recoverStream,
+			identityStream(userHeader),
+		),
+	)
+
+	todov1.RegisterTodoServiceServer(srv, server)
+
+	return srv
+}
+
+// Serve обслуживает вызовы, пока не будет отменён ctx, затем ждёт завершения
+// текущих вызовов не дольше shutdownTimeout. Потоки WatchTodos сами не
+// завершаются, поэтому по истечении времени сервер закрывает их.
+func Serve(ctx context.Context, srv *grpc.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		srv.Stop()
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	return nil
+}
+
+func identityUnary(header string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withUser(ctx, header), req)
+	}
+}
+
+func identityStream(header string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withUser(ss.Context(), header)})
+	}
+}
+
+func withUser(ctx context.Context, header string) context.Context {
+	if values := metadata.ValueFromIncomingContext(ctx, header); len(values) > 0 {
+		return identity.FromHeader(ctx, values[0])
+	}
+	return ctx
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer recoverPanic(ctx, &err)
+	return handler(ctx, req)
+}
+
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(ss.Context(), &err)
+	return handler(srv, ss)
+}
+
+func recoverPanic(ctx context.Context, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	slog.ErrorContext(ctx, "паника при обработке вызова gRPC",
+		slog.Any("panic", recovered),
+		slog.String("stack", string(debug.Stack())),
+	)
+
+	*err = status.Error(codes.Internal, "внутренняя ошибка сервера")
+}
+
+func logUnary(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func logStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logCall пишет строку на вызов, как access-лог HTTP: ошибки сервера — с
+// уровнем error, ошибки клиента — warn.
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DeadlineExceeded, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	logger.LogAttrs(ctx, level, "вызов gRPC", attrs...)
+}
@@ -0,0 +1,175 @@
+// Package grpcapi отдаёт задачи по gRPC (todo.v1.TodoService) для внутренних
+// сервисов. Операции выполняет тот же TodoService, что и у REST API, поэтому
+// изменения попадают в поток событий, вебхуки и метрики одинаково.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	todov1 "todo-api/api/todo/v1"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+type Server struct {
+	todov1.UnimplementedTodoServiceServer
+
+	service services.TodoService
+	broker  *stream.Broker
+}
+
+func NewServer(service services.TodoService, broker *stream.Broker) *Server {
+	return &Server{service: service, broker: broker}
+}
+
+func (s *Server) CreateTodo(ctx context.Context, request *todov1.CreateTodoRequest) (*todov1.Todo, error) {
+	task, err := s.service.CreateTodo(ctx, &models.CreateTodoRequest{
+		TaskName:    request.GetTaskName(),
+		Description: request.Description,
+		DueAt:       fromTimestamp(request.GetDueTime()),
+		Priority:    fromPriority(request.GetPriority()),
+		Tags:        request.GetTags(),
+		Project:     request.GetProject(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toProto(task), nil
+}
+
+func (s *Server) GetTodo(ctx context.Context, request *todov1.GetTodoRequest) (*todov1.Todo, error) {
+	task, err := s.service.GetById(ctx, request.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toProto(task), nil
+}
+
+func (s *Server) ListTodos(ctx context.Context, request *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	size := int(request.GetPageSize())
+	switch {
+	case size == 0:
+		size = defaultPageSize
+	case size < 0 || size > maxPageSize:
+		return nil, status.Error(codes.InvalidArgument, "page_size должен быть от 1 до 1000")
+	}
+
+	filter := &models.TodoFilter{
+		Completed: request.Completed,
+		Priority:  fromPriority(request.GetPriority()),
+		Project:   request.GetProject(),
+		Tag:       request.GetTag(),
+	}
+
+	page, err := s.service.ListTodos(ctx, filter, size, request.GetPageToken())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &todov1.ListTodosResponse{NextPageToken: page.NextPageToken}
+	for _, task := range page.Todos {
+		response.Todos = append(response.Todos, toProto(task))
+	}
+
+	return response, nil
+}
+
+func (s *Server) UpdateTodo(ctx context.Context, request *todov1.UpdateTodoRequest) (*todov1.Todo, error) {
+	update, err := updateRequest(request)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	task, err := s.service.UpdateTodo(ctx, request.GetTodo().GetId(), update)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toProto(task), nil
+}
+
+func (s *Server) DeleteTodo(ctx context.Context, request *todov1.DeleteTodoRequest) (*emptypb.Empty, error) {
+	if err := s.service.DeleteTodo(ctx, request.GetId()); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// WatchTodos передаёт события так же, как поток /todos/events: после
+// переподключения с last_event_id клиент получает пропущенные события из
+// буфера брокера.
+func (s *Server) WatchTodos(request *todov1.WatchTodosRequest, srv grpc.ServerStreamingServer[todov1.TodoEvent]) error {
+	actors := request.GetActors()
+	match := func(event stream.Event) bool {
+		return len(actors) == 0 || slices.Contains(actors, event.Actor)
+	}
+
+	sub, replay, resumed := s.broker.Subscribe(request.GetLastEventId(), match)
+	defer s.broker.Unsubscribe(sub)
+
+	if !resumed {
+		if err := srv.Send(&todov1.TodoEvent{Type: todov1.TodoEvent_TYPE_RESET}); err != nil {
+			return err
+		}
+	}
+	for _, event := range replay {
+		if err := sendEvent(srv, event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "поток изменений закрыт, переподключитесь с last_event_id")
+			}
+			if err := sendEvent(srv, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func sendEvent(srv grpc.ServerStreamingServer[todov1.TodoEvent], event stream.Event) error {
+	if event.Type == stream.TypeReset {
+		return srv.Send(&todov1.TodoEvent{Type: todov1.TodoEvent_TYPE_RESET})
+	}
+
+	var payload models.EventPayload
+	if err := json.Unmarshal(event.Data, &payload); err != nil {
+		return status.Error(codes.Internal, "не удалось прочитать событие")
+	}
+
+	message := &todov1.TodoEvent{
+		Id:              event.ID,
+		Type:            eventTypes[event.Type],
+		Actor:           payload.Actor,
+		OccurTime:       timestamppb.New(payload.OccurredAt),
+		PreviousProject: payload.PreviousProject,
+	}
+	if payload.Data != nil {
+		message.Todo = toProto(payload.Data)
+	}
+
+	return srv.Send(message)
+}
@@ -0,0 +1,266 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	todov1 "todo-api/api/todo/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newClient(t *testing.T) todov1.TodoServiceClient {
+	t.Helper()
+
+	broker := stream.NewBroker(100)
+	service := services.NewTodoService(stream.Repository(repository.Constructor(), stream.Local(broker)))
+	srv := New(NewServer(service, broker), slog.New(slog.DiscardHandler), "X-User-ID")
+
+	listener := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, srv, listener, time.Second) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		broker.Close()
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	return todov1.NewTodoServiceClient(conn)
+}
+
+func assertCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	assert.Equal(t, code, status.Code(err), err)
+}
+
+func TestServer_CRUD(t *testing.T) {
+	client := newClient(t)
+	ctx := t.Context()
+	due := time.Date(2026, 10, 20, 17, 0, 0, 0, time.UTC)
+
+	created, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{
+		TaskName: " Pay invoice ",
+		DueTime:  timestamppb.New(due),
+		Priority: todov1.Priority_PRIORITY_HIGH,
+		Tags:     []string{"#Finance"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.Id)
+	assert.Equal(t, "Pay invoice", created.TaskName)
+	assert.Equal(t, todov1.Priority_PRIORITY_HIGH, created.Priority)
+	assert.Equal(t, []string{"finance"}, created.Tags)
+	assert.True(t, created.DueTime.AsTime().Equal(due))
+	assert.Nil(t, created.Description)
+
+	got, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(created, got))
+
+	updated, err := client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{
+		Todo:       &todov1.Todo{Id: created.Id, TaskName: "ignored", Completed: true, Description: proto.String("paid")},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"completed", "description", "due_time", "priority"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Pay invoice", updated.TaskName, "поля не из маски не меняются")
+	assert.True(t, updated.Completed)
+	assert.NotNil(t, updated.CompleteTime)
+	assert.Equal(t, "paid", updated.GetDescription())
+	assert.Nil(t, updated.DueTime, "поле из маски без значения очищается")
+	assert.Equal(t, todov1.Priority_PRIORITY_UNSPECIFIED, updated.Priority)
+	assert.Greater(t, updated.Version, created.Version)
+
+	_, err = client.DeleteTodo(ctx, &todov1.DeleteTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+
+	_, err = client.GetTodo(ctx, &todov1.GetTodoRequest{Id: created.Id})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestServer_Errors(t *testing.T) {
+	client := newClient(t)
+	ctx := t.Context()
+
+	created, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"EmptyName", func() error {
+			_, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{TaskName: " "})
+			return err
+		}, codes.InvalidArgument},
+		{"UnknownPriority", func() error {
+			_, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{TaskName: "test", Priority: 7})
+			return err
+		}, codes.InvalidArgument},
+		{"EmptyID", func() error {
+			_, err := client.GetTodo(ctx, &todov1.GetTodoRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"NotFound", func() error {
+			_, err := client.DeleteTodo(ctx, &todov1.DeleteTodoRequest{Id: "missing"})
+			return err
+		}, codes.NotFound},
+		{"NoMask", func() error {
+			_, err := client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{Todo: &todov1.Todo{Id: created.Id}})
+			return err
+		}, codes.InvalidArgument},
+		{"UnknownMaskPath", func() error {
+			_, err := client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{
+				Todo:       &todov1.Todo{Id: created.Id},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"version"}},
+			})
+			return err
+		}, codes.InvalidArgument},
+		{"UpdateNotFound", func() error {
+			_, err := client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{
+				Todo:       &todov1.Todo{Id: "missing", TaskName: "test"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"task_name"}},
+			})
+			return err
+		}, codes.NotFound},
+		{"PageSize", func() error {
+			_, err := client.ListTodos(ctx, &todov1.ListTodosRequest{PageSize: 1001})
+			return err
+		}, codes.InvalidArgument},
+		{"PageToken", func() error {
+			_, err := client.ListTodos(ctx, &todov1.ListTodosRequest{PageToken: "???"})
+			return err
+		}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCode(t, tt.call(), tt.code)
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	err := statusError(errors.New("pq: connection refused"))
+	assertCode(t, err, codes.Internal)
+	assert.Equal(t, "внутренняя ошибка сервера", status.Convert(err).Message(), "детали не уходят клиенту")
+
+	assertCode(t, statusError(context.DeadlineExceeded), codes.DeadlineExceeded)
+	assertCode(t, statusError(repository.ErrVersionConflict), codes.Aborted)
+	assertCode(t, statusError(repository.ErrAlreadyExist), codes.AlreadyExists)
+}
+
+func TestServer_ListTodos(t *testing.T) {
+	client := newClient(t)
+	ctx := t.Context()
+
+	var ids []string
+	for i, name := range []string{"one", "two", "three", "four", "five"} {
+		task, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{TaskName: name, Project: []string{"home", "work"}[i%2]})
+		require.NoError(t, err)
+		ids = append(ids, task.Id)
+	}
+
+	var listed []string
+	request := &todov1.ListTodosRequest{PageSize: 2}
+	for pages := 1; ; pages++ {
+		response, err := client.ListTodos(ctx, request)
+		require.NoError(t, err)
+		for _, task := range response.Todos {
+			listed = append(listed, task.Id)
+		}
+
+		if response.NextPageToken == "" {
+			assert.Equal(t, 3, pages)
+			break
+		}
+		request.PageToken = response.NextPageToken
+	}
+	assert.Equal(t, ids, listed)
+
+	response, err := client.ListTodos(ctx, &todov1.ListTodosRequest{Project: "home"})
+	require.NoError(t, err)
+	require.Len(t, response.Todos, 3)
+	assert.Empty(t, response.NextPageToken)
+	assert.Equal(t, ids[0], response.Todos[0].Id)
+}
+
+func TestServer_WatchTodos(t *testing.T) {
+	client := newClient(t)
+	ctx := t.Context()
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watch, err := client.WatchTodos(watchCtx, &todov1.WatchTodosRequest{Actors: []string{"alice"}})
+	require.NoError(t, err)
+
+	// Подписка оформляется на сервере после первого сообщения клиента, поэтому
+	// задачи создаются, пока сервер не передаст первое событие.
+	received := make(chan *todov1.TodoEvent)
+	go func() {
+		defer close(received)
+		for {
+			event, err := watch.Recv()
+			if err != nil {
+				return
+			}
+			received <- event
+		}
+	}()
+
+	alice := metadata.AppendToOutgoingContext(ctx, "x-user-id", "alice")
+
+	var event *todov1.TodoEvent
+	require.Eventually(t, func() bool {
+		_, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{TaskName: "bob's"})
+		require.NoError(t, err)
+		_, err = client.CreateTodo(alice, &todov1.CreateTodoRequest{TaskName: "alice's"})
+		require.NoError(t, err)
+
+		select {
+		case event = <-received:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, time.Millisecond)
+
+	assert.Equal(t, todov1.TodoEvent_TYPE_CREATED, event.Type)
+	assert.Equal(t, "alice", event.Actor)
+	assert.Equal(t, "alice's", event.Todo.TaskName)
+	assert.NotEmpty(t, event.Id)
+
+	resumed, err := client.WatchTodos(ctx, &todov1.WatchTodosRequest{LastEventId: "unknown"})
+	require.NoError(t, err)
+	first, err := resumed.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, todov1.TodoEvent_TYPE_RESET, first.Type)
+}
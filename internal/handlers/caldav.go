@@ -0,0 +1,577 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/caldav"
+	"todo-api/internal/ical"
+	"todo-api/internal/identity"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CalDAVMethods — методы, на которые отвечает /caldav.
+var CalDAVMethods = []string{"OPTIONS", "GET", "HEAD", "PUT", "DELETE", "PROPFIND", "REPORT"}
+
+const (
+	caldavUserKey = "caldavUser"
+	// caldavCollection — единственный календарь пользователя со всеми задачами.
+	caldavCollection = "/todos/"
+	// maxCalDAVBody ограничивает тело PUT и REPORT: задача занимает
+	// единицы килобайт, multiget на тысячи ссылок — сотни.
+	maxCalDAVBody     = 1 << 20
+	objectContentType = "text/calendar; charset=utf-8; component=VTODO"
+)
+
+// CalDAVAuth проверяет Basic-авторизацию CalDAV: логин — пользователь,
+// пароль — токен из ссылки подписки. OPTIONS клиенты отправляют до
+// авторизации, чтобы узнать возможности сервера.
+func (h *CalendarHandler) CalDAVAuth(c *gin.Context) {
+	if c.Request.Method == http.MethodOptions {
+		return
+	}
+
+	user, password, ok := c.Request.BasicAuth()
+	if ok {
+		owner, err := h.tokens.Verify(password)
+		ok = err == nil && owner == user
+	}
+	if !ok {
+		c.Header("WWW-Authenticate", `Basic realm="todo-api", charset="UTF-8"`)
+		respondError(c, 401, "неверный логин или пароль CalDAV")
+		return
+	}
+
+	c.Set(caldavUserKey, user)
+	c.Request = c.Request.WithContext(identity.WithUser(c.Request.Context(), user))
+}
+
+// @Summary Обнаружение CalDAV
+// @Description Перенаправляет календарные клиенты на корень CalDAV по RFC 6764.
+// @Tags calendar
+// @Success 301 {string} string "Перенаправление на /caldav/"
+// @Router /.well-known/caldav [get]
+func (h *CalendarHandler) WellKnown(c *gin.Context) {
+	c.Redirect(301, h.davHref("/"))
+}
+
+// @Summary CalDAV
+// @Description Календарь задач по протоколу CalDAV (RFC 4791) для Apple Reminders, Thunderbird, DAVx5 и других клиентов. Логин — пользователь, пароль — токен из ссылки подписки (GET /calendar/subscription). /caldav/ — принципал и домашний каталог, /caldav/todos/ — календарь со всеми задачами, /caldav/todos/{id}.ics — задача в формате VTODO, id — UUID. Поддерживаются OPTIONS, PROPFIND, REPORT (calendar-query по компоненту VTODO и calendar-multiget), GET, PUT и DELETE. ETag задачи — её версия; PUT и DELETE учитывают If-Match и If-None-Match, при несовпадении отвечают 412. Для синхронизации клиент сравнивает cs:getctag календаря и ETag задач и запрашивает только изменившиеся. Свойства VTODO, которых нет у задачи (напоминания, повторения), не сохраняются.
+// @Tags calendar
+// @Param path path string true "Путь внутри /caldav"
+// @Success 200 {string} string "Ответ на OPTIONS, GET"
+// @Success 207 {string} string "Ответ multistatus на PROPFIND и REPORT"
+// @Failure 401 {object} map[string]string "Неверный логин или пароль"
+// @Failure 404 {object} map[string]string "Ресурс не найден"
+// @Failure 412 {object} map[string]string "Задача изменилась"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Router /caldav/{path} [get]
+func (h *CalendarHandler) CalDAV(c *gin.Context) {
+	path := c.Param("path")
+
+	switch {
+	case path == "/":
+		h.caldavHome(c)
+	case path == caldavCollection || path+"/" == caldavCollection:
+		h.caldavCalendar(c)
+	case strings.HasPrefix(path, caldavCollection):
+		h.caldavObject(c, strings.TrimPrefix(path, caldavCollection))
+	default:
+		respondError(c, 404, "ресурс не найден")
+	}
+}
+
+func (h *CalendarHandler) caldavHome(c *gin.Context) {
+	switch c.Request.Method {
+	case http.MethodOptions:
+		caldavOptions(c, "OPTIONS, PROPFIND")
+	case "PROPFIND":
+		names, all, ok := h.readPropfind(c)
+		if !ok {
+			return
+		}
+
+		home := h.davHref("/")
+		responses := []caldav.Response{h.response(home, h.homeProps(c), names, all)}
+
+		if c.GetHeader("Depth") != "0" {
+			ctag, _, err := h.collect(c, nil)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			responses = append(responses, h.response(h.davHref(caldavCollection), h.calendarProps(ctag), names, all))
+		}
+
+		writeMultistatus(c, responses)
+	default:
+		methodNotAllowed(c, "OPTIONS, PROPFIND")
+	}
+}
+
+func (h *CalendarHandler) caldavCalendar(c *gin.Context) {
+	const allow = "OPTIONS, PROPFIND, REPORT"
+
+	switch c.Request.Method {
+	case http.MethodOptions:
+		caldavOptions(c, allow)
+	case "PROPFIND":
+		names, all, ok := h.readPropfind(c)
+		if !ok {
+			return
+		}
+
+		// Глубина 0 нужна клиентам, чтобы сверить ctag; 1 и infinity
+		// возвращают ещё и задачи.
+		var each func(task *models.Todo) caldav.Response
+		if c.GetHeader("Depth") != "0" {
+			each = func(task *models.Todo) caldav.Response {
+				return h.response(h.objectHref(task.ID), h.objectProps(task, names, all), names, all)
+			}
+		}
+
+		ctag, objects, err := h.collect(c, each)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+
+		calendar := h.response(h.davHref(caldavCollection), h.calendarProps(ctag), names, all)
+		writeMultistatus(c, append([]caldav.Response{calendar}, objects...))
+	case "REPORT":
+		h.caldavReport(c)
+	default:
+		methodNotAllowed(c, allow)
+	}
+}
+
+func (h *CalendarHandler) caldavReport(c *gin.Context) {
+	body, ok := readCalDAVBody(c)
+	if !ok {
+		return
+	}
+
+	report, err := caldav.ParseReport(body)
+	switch {
+	case errors.Is(err, caldav.ErrUnsupportedReport):
+		davError(c, 403, caldav.PreconditionSupportedReport)
+		return
+	case err != nil:
+		respondError(c, 400, err.Error())
+		return
+	}
+
+	if report.Multiget {
+		responses := make([]caldav.Response, 0, len(report.Hrefs))
+		for _, href := range report.Hrefs {
+			response, err := h.multigetResponse(c, href, report)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			responses = append(responses, response)
+		}
+
+		writeMultistatus(c, responses)
+		return
+	}
+
+	if report.Component != "" && report.Component != "VTODO" {
+		writeMultistatus(c, nil)
+		return
+	}
+
+	_, responses, err := h.collect(c, func(task *models.Todo) caldav.Response {
+		return h.response(h.objectHref(task.ID), h.objectProps(task, report.Props, report.AllProp), report.Props, report.AllProp)
+	})
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	writeMultistatus(c, responses)
+}
+
+func (h *CalendarHandler) multigetResponse(c *gin.Context, href string, report *caldav.Report) (caldav.Response, error) {
+	notFound := caldav.Response{Href: href, Status: 404}
+
+	// Клиенты присылают путь, иногда полный адрес, с экранированными символами.
+	u, err := url.Parse(href)
+	if err != nil {
+		return notFound, nil
+	}
+	name, ok := strings.CutPrefix(u.Path, h.davHref(caldavCollection))
+	if !ok {
+		return notFound, nil
+	}
+	id, ok := resourceID(name)
+	if !ok {
+		return notFound, nil
+	}
+
+	task, err := h.service.GetById(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrInvalidID) {
+		return notFound, nil
+	}
+	if err != nil {
+		return caldav.Response{}, err
+	}
+
+	return h.response(href, h.objectProps(task, report.Props, report.AllProp), report.Props, report.AllProp), nil
+}
+
+func (h *CalendarHandler) caldavObject(c *gin.Context, name string) {
+	const allow = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND"
+
+	if c.Request.Method == http.MethodOptions {
+		caldavOptions(c, allow)
+		return
+	}
+
+	id, ok := resourceID(name)
+	if !ok {
+		if c.Request.Method == http.MethodPut {
+			respondError(c, 403, "имя задачи должно иметь вид <uuid>.ics")
+			return
+		}
+		respondError(c, 404, "ресурс не найден")
+		return
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		task, ok := h.getObject(c, id)
+		if !ok {
+			return
+		}
+
+		data, err := h.calendarData(task)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+
+		c.Header("ETag", caldav.ETag(task.Version))
+		c.Data(200, ical.ContentType, data)
+	case "PROPFIND":
+		names, all, ok := h.readPropfind(c)
+		if !ok {
+			return
+		}
+
+		task, ok := h.getObject(c, id)
+		if !ok {
+			return
+		}
+
+		writeMultistatus(c, []caldav.Response{h.response(h.objectHref(task.ID), h.objectProps(task, names, all), names, all)})
+	case http.MethodPut:
+		h.putObject(c, id)
+	case http.MethodDelete:
+		h.deleteObject(c, id)
+	default:
+		methodNotAllowed(c, allow)
+	}
+}
+
+// putObject создаёт или целиком заменяет задачу. ETag в ответе не
+// возвращается: задача хранит не все свойства VTODO, и по RFC 4791 клиент
+// должен перечитать её, а не считать свою копию актуальной.
+func (h *CalendarHandler) putObject(c *gin.Context, id string) {
+	body, ok := readCalDAVBody(c)
+	if !ok {
+		return
+	}
+
+	lines, err := ical.Parse(body, time.Local)
+	switch {
+	case err != nil:
+		davError(c, 403, caldav.PreconditionValidData)
+		return
+	case len(lines) == 0:
+		davError(c, 403, caldav.PreconditionComponent)
+		return
+	case len(lines) > 1:
+		davError(c, 403, caldav.PreconditionValidObject)
+		return
+	case lines[0].Error != "":
+		davError(c, 403, caldav.PreconditionValidData)
+		return
+	}
+
+	request := &models.ReplaceTodoRequest{CreateTodoRequest: *lines[0].Todo, Completed: lines[0].Completed}
+
+	if c.GetHeader("If-None-Match") == "*" {
+		request.IfVersion = new(int64)
+	}
+	if match := c.GetHeader("If-Match"); match != "" {
+		version, ok := h.matchVersion(c, id, match)
+		if !ok {
+			return
+		}
+		request.IfVersion = &version
+	}
+
+	_, created, err := h.service.ReplaceTodo(c.Request.Context(), id, request)
+	if err != nil {
+		switch err {
+		case repository.ErrVersionConflict, repository.ErrAlreadyExist:
+			respondError(c, 412, repository.ErrVersionConflict.Error())
+			return
+		case repository.ErrEmptyName, repository.ErrInvalidPriority:
+			davError(c, 403, caldav.PreconditionValidObject)
+			return
+		default:
+			respondServerError(c, err)
+			return
+		}
+	}
+
+	if created {
+		c.Status(201)
+		return
+	}
+	c.Status(204)
+}
+
+// matchVersion возвращает версию, которую требует If-Match. Для «*»
+// подходит любая существующая задача, поэтому берётся её текущая версия.
+func (h *CalendarHandler) matchVersion(c *gin.Context, id, match string) (int64, bool) {
+	if match != "*" {
+		version, ok := caldav.ParseETag(match)
+		if !ok {
+			respondError(c, 412, repository.ErrVersionConflict.Error())
+		}
+		return version, ok
+	}
+
+	task, err := h.service.GetById(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrInvalidID):
+		respondError(c, 412, repository.ErrVersionConflict.Error())
+		return 0, false
+	case err != nil:
+		respondServerError(c, err)
+		return 0, false
+	}
+
+	return task.Version, true
+}
+
+func (h *CalendarHandler) deleteObject(c *gin.Context, id string) {
+	task, ok := h.getObject(c, id)
+	if !ok {
+		return
+	}
+
+	if match := c.GetHeader("If-Match"); match != "" && match != "*" {
+		if version, ok := caldav.ParseETag(match); !ok || version != task.Version {
+			respondError(c, 412, repository.ErrVersionConflict.Error())
+			return
+		}
+	}
+
+	if err := h.service.DeleteTodo(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrInvalidID) {
+			respondError(c, 404, err.Error())
+			return
+		}
+		respondServerError(c, err)
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *CalendarHandler) getObject(c *gin.Context, id string) (*models.Todo, bool) {
+	task, err := h.service.GetById(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrInvalidID):
+		respondError(c, 404, err.Error())
+		return nil, false
+	case err != nil:
+		respondServerError(c, err)
+		return nil, false
+	}
+	return task, true
+}
+
+// collect проходит по всем задачам, считая ctag календаря, и собирает ответы
+// each о каждой задаче, если each задан. ctag меняется при любом изменении,
+// создании или удалении задачи.
+func (h *CalendarHandler) collect(c *gin.Context, each func(task *models.Todo) caldav.Response) (string, []caldav.Response, error) {
+	hash := sha256.New()
+	var responses []caldav.Response
+
+	err := h.service.ExportTodos(c.Request.Context(), &models.TodoFilter{}, func(task *models.Todo) error {
+		hash.Write([]byte(task.ID + ":" + strconv.FormatInt(task.Version, 10) + ":" + strconv.FormatInt(task.CreatedAt.UnixNano(), 10) + "\n"))
+		if each != nil {
+			responses = append(responses, each(task))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)), responses, nil
+}
+
+func (h *CalendarHandler) response(href string, props []caldav.Prop, names []xml.Name, all bool) caldav.Response {
+	found, notFound := caldav.Select(props, names, all)
+	return caldav.Response{Href: href, Found: found, NotFound: notFound}
+}
+
+func (h *CalendarHandler) homeProps(c *gin.Context) []caldav.Prop {
+	home := caldav.Href(h.davHref("/"))
+	return []caldav.Prop{
+		{Name: caldav.PropResourceType, Value: "<d:collection/><d:principal/>"},
+		caldav.Text(caldav.PropDisplayName, c.GetString(caldavUserKey)),
+		{Name: caldav.PropCurrentUserPrincipal, Value: home},
+		{Name: caldav.PropPrincipalURL, Value: home},
+		{Name: caldav.PropCalendarHomeSet, Value: home},
+		{Name: caldav.PropCurrentUserPrivileges, Value: "<d:privilege><d:read/></d:privilege>"},
+	}
+}
+
+func (h *CalendarHandler) calendarProps(ctag string) []caldav.Prop {
+	return []caldav.Prop{
+		{Name: caldav.PropResourceType, Value: "<d:collection/><c:calendar/>"},
+		caldav.Text(caldav.PropDisplayName, h.name),
+		{Name: caldav.PropSupportedComponents, Value: `<c:comp name="VTODO"/>`},
+		caldav.Text(caldav.PropGetCTag, ctag),
+		{Name: caldav.PropCurrentUserPrincipal, Value: caldav.Href(h.davHref("/"))},
+		{Name: caldav.PropCurrentUserPrivileges, Value: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>"},
+		{Name: caldav.PropSupportedReportSet, Value: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"},
+	}
+}
+
+// objectProps возвращает свойства задачи. calendar-data тяжёлое, поэтому
+// отдаётся, только если его запросили явно.
+func (h *CalendarHandler) objectProps(task *models.Todo, names []xml.Name, all bool) []caldav.Prop {
+	props := []caldav.Prop{
+		{Name: caldav.PropResourceType},
+		caldav.Text(caldav.PropGetETag, caldav.ETag(task.Version)),
+		caldav.Text(caldav.PropGetContentType, objectContentType),
+	}
+
+	if !all && containsName(names, caldav.PropCalendarData) {
+		// Кодировщик пишет в память и ошибиться не может.
+		data, _ := h.calendarData(task)
+		props = append(props, caldav.Text(caldav.PropCalendarData, string(data)))
+	}
+
+	return props
+}
+
+func (h *CalendarHandler) calendarData(task *models.Todo) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := ical.NewEncoder(&buf, "", h.now())
+	if err := encoder.Encode(task); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *CalendarHandler) readPropfind(c *gin.Context) ([]xml.Name, bool, bool) {
+	body, ok := readCalDAVBody(c)
+	if !ok {
+		return nil, false, false
+	}
+
+	names, all, err := caldav.ParsePropfind(body)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return nil, false, false
+	}
+	return names, all, true
+}
+
+// davHref строит ссылку на ресурс CalDAV с учётом пути из CALENDAR_BASE_URL,
+// если сервис опубликован за прокси не в корне.
+func (h *CalendarHandler) davHref(path string) string {
+	return h.basePath + "/caldav" + path
+}
+
+func (h *CalendarHandler) objectHref(id string) string {
+	return h.davHref(caldavCollection + id + ".ics")
+}
+
+// resourceID достаёт айди задачи из имени ресурса <uuid>.ics. Айди задач —
+// UUID, а клиенты могут прислать его в верхнем регистре, поэтому он
+// приводится к каноническому виду.
+func resourceID(name string) (string, bool) {
+	name, ok := strings.CutSuffix(name, ".ics")
+	if !ok {
+		return "", false
+	}
+
+	id, err := uuid.Parse(name)
+	if err != nil {
+		return "", false
+	}
+	return id.String(), true
+}
+
+func readCalDAVBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalDAVBody+1))
+	if err != nil {
+		respondServerError(c, err)
+		return nil, false
+	}
+	if len(body) > maxCalDAVBody {
+		respondError(c, 413, "тело запроса больше 1 МБ")
+		return nil, false
+	}
+	return body, true
+}
+
+func writeMultistatus(c *gin.Context, responses []caldav.Response) {
+	var buf bytes.Buffer
+	// Запись в память не возвращает ошибок.
+	_ = caldav.WriteMultistatus(&buf, responses)
+	c.Data(207, caldav.ContentType, buf.Bytes())
+}
+
+func davError(c *gin.Context, status int, precondition xml.Name) {
+	var buf bytes.Buffer
+	_ = caldav.WriteError(&buf, precondition)
+	c.Data(status, caldav.ContentType, buf.Bytes())
+	c.Abort()
+}
+
+func caldavOptions(c *gin.Context, allow string) {
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", allow)
+	c.Status(200)
+}
+
+func methodNotAllowed(c *gin.Context, allow string) {
+	c.Header("Allow", allow)
+	respondError(c, 405, "метод не поддерживается")
+}
+
+func containsName(names []xml.Name, name xml.Name) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const caldavTodo = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:client-uid\r\n" +
+	"SUMMARY:Позвонить маме\r\nDUE:20261105T140000Z\r\nPRIORITY:1\r\nBEGIN:VALARM\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\n" +
+	"END:VTODO\r\nEND:VCALENDAR\r\n"
+
+const caldavID = "5f0c1d2e-1111-4222-8333-444455556666"
+
+// caldavServer поднимает CalDAV поверх настоящего сервиса в памяти: протокол
+// проверяется последовательностями запросов, как их шлют клиенты.
+func caldavServer(t *testing.T, baseURL string) http.Handler {
+	t.Helper()
+
+	handler := newCalendarHandler(&MockService{}, baseURL)
+	handler.service = services.NewTodoService(repository.Constructor())
+
+	router := gin.New()
+	router.Handle("PROPFIND", "/.well-known/caldav", handler.WellKnown)
+	group := router.Group("/caldav", handler.CalDAVAuth)
+	for _, method := range CalDAVMethods {
+		group.Handle(method, "/*path", handler.CalDAV)
+	}
+	return router
+}
+
+func caldavRequest(t *testing.T, server http.Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.SetBasicAuth("alice", calendarTokens.Issue("alice"))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Status   string `xml:"DAV: status"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag         string `xml:"DAV: getetag"`
+				CTag         string `xml:"http://calendarserver.org/ns/ getctag"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+				HomeSet      struct {
+					Href string `xml:"DAV: href"`
+				} `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+func parseMultistatus(t *testing.T, w *httptest.ResponseRecorder) multistatus {
+	t.Helper()
+	require.Equal(t, 207, w.Code, w.Body.String())
+
+	var result multistatus
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &result))
+	return result
+}
+
+func TestCalDAV_Auth(t *testing.T) {
+	server := caldavServer(t, "")
+
+	for name, auth := range map[string][2]string{
+		"NoAuth":        {"", ""},
+		"WrongPassword": {"alice", "пароль"},
+		"OtherUser":     {"bob", calendarTokens.Issue("alice")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("PROPFIND", "/caldav/", nil)
+			if auth[0] != "" {
+				req.SetBasicAuth(auth[0], auth[1])
+			}
+
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			assert.Equal(t, 401, w.Code)
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+		})
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/caldav/todos/", nil))
+	assert.Equal(t, 200, w.Code, "OPTIONS без авторизации")
+	assert.Equal(t, "1, 3, calendar-access", w.Header().Get("DAV"))
+}
+
+func TestCalDAV_Discovery(t *testing.T) {
+	server := caldavServer(t, "https://api.example.com/todo/")
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("PROPFIND", "/.well-known/caldav", nil))
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/todo/caldav/", w.Header().Get("Location"))
+
+	result := parseMultistatus(t, caldavRequest(t, server, "PROPFIND", "/caldav/", "", "Depth", "1"))
+	require.Len(t, result.Responses, 2)
+	assert.Equal(t, "/todo/caldav/", result.Responses[0].Href)
+	assert.Equal(t, "/todo/caldav/", result.Responses[0].Propstat[0].Prop.HomeSet.Href)
+	assert.Equal(t, "/todo/caldav/todos/", result.Responses[1].Href)
+	assert.NotEmpty(t, result.Responses[1].Propstat[0].Prop.CTag)
+}
+
+func TestCalDAV_Sync(t *testing.T) {
+	server := caldavServer(t, "")
+	object := "/caldav/todos/" + strings.ToUpper(caldavID) + ".ics"
+	propfind := `<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/"><d:prop><d:getetag/><cs:getctag/></d:prop></d:propfind>`
+
+	ctag := func() string {
+		result := parseMultistatus(t, caldavRequest(t, server, "PROPFIND", "/caldav/todos/", propfind, "Depth", "0"))
+		require.Len(t, result.Responses, 1)
+		return result.Responses[0].Propstat[0].Prop.CTag
+	}
+	empty := ctag()
+
+	w := caldavRequest(t, server, "PUT", object, caldavTodo, "If-None-Match", "*")
+	require.Equal(t, 201, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("ETag"), "задача сохранена не целиком, клиент должен её перечитать")
+
+	assert.Equal(t, 412, caldavRequest(t, server, "PUT", object, caldavTodo, "If-None-Match", "*").Code)
+
+	created := ctag()
+	assert.NotEqual(t, empty, created)
+
+	result := parseMultistatus(t, caldavRequest(t, server, "PROPFIND", "/caldav/todos/", propfind, "Depth", "1"))
+	require.Len(t, result.Responses, 2)
+	assert.Equal(t, "/caldav/todos/"+caldavID+".ics", result.Responses[1].Href)
+	assert.Equal(t, `"1"`, result.Responses[1].Propstat[0].Prop.ETag)
+
+	w = caldavRequest(t, server, "GET", object, "")
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "UID:"+caldavID+"\r\n")
+	assert.Contains(t, w.Body.String(), "SUMMARY:Позвонить маме\r\n")
+	assert.NotContains(t, w.Body.String(), "VALARM")
+
+	completed := strings.Replace(caldavTodo, "PRIORITY:1", "STATUS:COMPLETED", 1)
+	assert.Equal(t, 412, caldavRequest(t, server, "PUT", object, completed, "If-Match", `"2"`).Code)
+	assert.Equal(t, 204, caldavRequest(t, server, "PUT", object, completed, "If-Match", `"1"`).Code)
+	assert.NotEqual(t, created, ctag())
+
+	multiget := `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+		`<d:prop><d:getetag/><c:calendar-data/></d:prop>` +
+		`<d:href>` + object + `</d:href><d:href>/caldav/todos/missing.ics</d:href></c:calendar-multiget>`
+	result = parseMultistatus(t, caldavRequest(t, server, "REPORT", "/caldav/todos/", multiget))
+	require.Len(t, result.Responses, 2)
+	assert.Equal(t, `"2"`, result.Responses[0].Propstat[0].Prop.ETag)
+	data := result.Responses[0].Propstat[0].Prop.CalendarData
+	assert.Contains(t, data, "STATUS:COMPLETED\r\n")
+	assert.NotContains(t, data, "PRIORITY", "PUT заменяет задачу целиком")
+	assert.Contains(t, result.Responses[1].Status, "404")
+
+	assert.Equal(t, 412, caldavRequest(t, server, "DELETE", object, "", "If-Match", `"1"`).Code)
+	assert.Equal(t, 204, caldavRequest(t, server, "DELETE", object, "", "If-Match", `"2"`).Code)
+	assert.Equal(t, 404, caldavRequest(t, server, "GET", object, "").Code)
+	assert.Equal(t, empty, ctag())
+}
+
+func TestCalDAV_CalendarQuery(t *testing.T) {
+	server := caldavServer(t, "")
+	require.Equal(t, 201, caldavRequest(t, server, "PUT", "/caldav/todos/"+caldavID+".ics", caldavTodo).Code)
+
+	query := func(component string) multistatus {
+		return parseMultistatus(t, caldavRequest(t, server, "REPORT", "/caldav/todos/",
+			`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/></d:prop>`+
+				`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="`+component+`"/></c:comp-filter></c:filter></c:calendar-query>`,
+			"Depth", "1"))
+	}
+
+	assert.Len(t, query("VTODO").Responses, 1)
+	assert.Empty(t, query("VEVENT").Responses, "событий в календаре задач нет")
+}
+
+func TestCalDAV_Errors(t *testing.T) {
+	server := caldavServer(t, "")
+	object := "/caldav/todos/" + caldavID + ".ics"
+	event := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Встреча\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	twoTodos := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:a\r\nEND:VTODO\r\nBEGIN:VTODO\r\nSUMMARY:b\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		headers      []string
+		status       int
+		precondition string
+	}{
+		{"NotCalendar", "PUT", object, "не календарь", nil, 403, "valid-calendar-data"},
+		{"Event", "PUT", object, event, nil, 403, "supported-calendar-component"},
+		{"TwoTodos", "PUT", object, twoTodos, nil, 403, "valid-calendar-object-resource"},
+		{"NoSummary", "PUT", object, strings.Replace(caldavTodo, "SUMMARY:Позвонить маме\r\n", "", 1), nil, 403, "valid-calendar-object-resource"},
+		{"NotUUID", "PUT", "/caldav/todos/client-uid.ics", caldavTodo, nil, 403, ""},
+		{"IfMatchMissing", "PUT", object, caldavTodo, []string{"If-Match", "*"}, 412, ""},
+		{"GetMissing", "GET", object, "", nil, 404, ""},
+		{"DeleteMissing", "DELETE", object, "", nil, 404, ""},
+		{"UnknownPath", "PROPFIND", "/caldav/other/", "", nil, 404, ""},
+		{"BadPropfind", "PROPFIND", "/caldav/todos/", "<oops", nil, 400, ""},
+		{"SyncCollection", "REPORT", "/caldav/todos/", `<d:sync-collection xmlns:d="DAV:"/>`, nil, 403, "supported-report"},
+		{"MethodNotAllowed", "PUT", "/caldav/todos/", caldavTodo, nil, 405, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := caldavRequest(t, server, tt.method, tt.path, tt.body, tt.headers...)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.precondition != "" {
+				assert.Contains(t, w.Body.String(), ":"+tt.precondition+"/>")
+			}
+		})
+	}
+}
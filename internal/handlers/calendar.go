@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"todo-api/internal/calendar"
+	"todo-api/internal/ical"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	service    services.TodoService
+	tokens     *calendar.Tokens
+	userHeader string
+	baseURL    string
+	// basePath — путь из baseURL, с которого начинаются ссылки CalDAV.
+	basePath string
+	name     string
+	now      func() time.Time
+}
+
+// NewCalendarHandler создаёт обработчики календаря. Пустой baseURL означает,
+// что адрес для ссылок берётся из запроса.
+func NewCalendarHandler(service services.TodoService, tokens *calendar.Tokens, userHeader, baseURL, name string) *CalendarHandler {
+	handler := &CalendarHandler{
+		service:    service,
+		tokens:     tokens,
+		userHeader: userHeader,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		name:       name,
+		now:        time.Now,
+	}
+	if u, err := url.Parse(handler.baseURL); err == nil {
+		handler.basePath = u.Path
+	}
+	return handler
+}
+
+// @Summary Ссылка на календарь
+// @Description Возвращает личную ссылку для подписки на задачи из календарного приложения. Пользователь берётся из заголовка, который выставляет прокси после аутентификации (по умолчанию X-User-ID). Там же логин и пароль для подключения по CalDAV. Ссылка не меняется при повторных запросах; отозвать ссылки можно только все сразу, сменив CALENDAR_SECRET.
+// @Tags calendar
+// @Produce json
+// @Param X-User-ID header string true "Пользователь"
+// @Success 200 {object} models.CalendarSubscription
+// @Failure 401 {object} map[string]string "Пользователь не передан"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Router /calendar/subscription [get]
+func (h *CalendarHandler) Subscription(c *gin.Context) {
+	user := c.GetHeader(h.userHeader)
+	if user == "" {
+		respondError(c, 401, "необходимо передать заголовок "+h.userHeader)
+		return
+	}
+
+	base := h.baseURL
+	if base == "" {
+		base = requestScheme(c) + "://" + c.Request.Host
+	}
+
+	token := h.tokens.Issue(user)
+	feed := base + "/calendar.ics?token=" + url.QueryEscape(token)
+	_, rest, _ := strings.Cut(feed, "://")
+
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(200, models.CalendarSubscription{
+		URL:       feed,
+		WebcalURL: "webcal://" + rest,
+		CalDAV:    models.CalDAVAccount{URL: base + "/caldav/", Username: user, Password: token},
+	})
+}
+
+// requestScheme учитывает X-Forwarded-Proto: за прокси с TLS сервис сам
+// принимает обычный HTTP.
+func requestScheme(c *gin.Context) string {
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		return proto
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// @Summary Календарь задач
+// @Description Отдаёт задачи в формате iCalendar (RFC 5545) компонентами VTODO: SUMMARY, DESCRIPTION, DUE, STATUS, COMPLETED, PRIORITY (1 — high, 5 — medium, 9 — low), CATEGORIES с тегами и X-TODO-PROJECT с проектом. Доступ по токену из ссылки подписки. Принимает те же фильтры, что и список, например только невыполненные задачи проекта. Чтобы создать задачи из файла .ics, загрузите его в POST /todos/import.
+// @Tags calendar
+// @Produce text/calendar
+// @Param token query string true "Токен из ссылки подписки"
+// @Param completed query bool false "Только выполненные или только невыполненные"
+// @Param priority query string false "Приоритет" Enums(low, medium, high)
+// @Param project query string false "Проект"
+// @Param tag query string false "Тег"
+// @Param dueBefore query string false "Срок раньше этого момента, RFC 3339"
+// @Param dueAfter query string false "Срок не раньше этого момента, RFC 3339"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string "Неверный фильтр"
+// @Failure 401 {object} map[string]string "Неверная ссылка на календарь"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /calendar.ics [get]
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	user, err := h.tokens.Verify(c.Query("token"))
+	if err != nil {
+		respondError(c, 401, err.Error())
+		return
+	}
+
+	filter, err := parseTodoFilter(c)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return
+	}
+
+	encoder := ical.NewEncoder(c.Writer, h.name, h.now())
+
+	// Как и в выгрузке, заголовки отправляются с первой задачей.
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+
+		c.Header("Content-Type", ical.ContentType)
+		c.Header("Content-Disposition", `inline; filename="todos.ics"`)
+		c.Header("Cache-Control", "private, no-cache")
+		c.Status(200)
+	}
+
+	err = h.service.ExportTodos(c.Request.Context(), filter, func(task *models.Todo) error {
+		start()
+		return encoder.Encode(task)
+	})
+
+	if err != nil && !started {
+		switch err {
+		case repository.ErrInvalidPriority:
+			respondError(c, 400, err.Error())
+			return
+		default:
+			respondServerError(c, err)
+			return
+		}
+	}
+
+	if err == nil {
+		start()
+		err = encoder.Close()
+	}
+
+	if err != nil {
+		c.Error(err)
+		slog.ErrorContext(c.Request.Context(), "выгрузка календаря прервана", slog.String("user", user), slog.Any("error", err))
+		panic(http.ErrAbortHandler)
+	}
+}
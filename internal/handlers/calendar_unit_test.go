@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/calendar"
+	"todo-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var calendarTokens = calendar.NewTokens("секрет")
+
+func newCalendarHandler(mock *MockService, baseURL string) *CalendarHandler {
+	handler := NewCalendarHandler(mock, calendarTokens, "X-User-ID", baseURL, "Задачи")
+	handler.now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	return handler
+}
+
+func TestCalendarHandler_Subscription(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		headers map[string]string
+		tls     bool
+		url     string
+	}{
+		{"FromRequest", "", nil, false, "http://todo.example.com/calendar.ics"},
+		{"TLS", "", nil, true, "https://todo.example.com/calendar.ics"},
+		{"ForwardedProto", "", map[string]string{"X-Forwarded-Proto": "https"}, false, "https://todo.example.com/calendar.ics"},
+		{"BaseURL", "https://api.example.com/todo/", nil, false, "https://api.example.com/todo/calendar.ics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newCalendarHandler(&MockService{}, tt.baseURL)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "http://todo.example.com/calendar/subscription", nil)
+			c.Request.Header.Set("X-User-ID", "alice")
+			for name, value := range tt.headers {
+				c.Request.Header.Set(name, value)
+			}
+			if tt.tls {
+				c.Request.TLS = &tls.ConnectionState{}
+			}
+
+			handler.Subscription(c)
+
+			require.Equal(t, 200, w.Code)
+			assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+
+			var subscription models.CalendarSubscription
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &subscription))
+
+			feed, err := url.Parse(subscription.URL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.url, strings.TrimSuffix(subscription.URL, "?"+feed.RawQuery))
+			assert.Equal(t, "webcal://"+strings.SplitN(subscription.URL, "://", 2)[1], subscription.WebcalURL)
+
+			user, err := calendarTokens.Verify(feed.Query().Get("token"))
+			require.NoError(t, err)
+			assert.Equal(t, "alice", user)
+
+			assert.Equal(t, strings.TrimSuffix(tt.url, "calendar.ics")+"caldav/", subscription.CalDAV.URL)
+			assert.Equal(t, "alice", subscription.CalDAV.Username)
+			assert.Equal(t, feed.Query().Get("token"), subscription.CalDAV.Password)
+		})
+	}
+}
+
+func TestCalendarHandler_Subscription_NoUser(t *testing.T) {
+	handler := newCalendarHandler(&MockService{}, "")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/calendar/subscription", nil)
+
+	handler.Subscription(c)
+
+	assert.Equal(t, 401, w.Code)
+	assert.JSONEq(t, `{"error":"необходимо передать заголовок X-User-ID"}`, w.Body.String())
+}
+
+func TestCalendarHandler_Feed(t *testing.T) {
+	dueAt := time.Date(2026, 11, 5, 14, 0, 0, 0, time.UTC)
+	completedAt := time.Date(2026, 10, 3, 18, 30, 0, 0, time.UTC)
+	todos := []*models.Todo{
+		{ID: "1", TaskName: "Оплатить счёт", DueAt: &dueAt, Priority: "high", Project: "acme"},
+		{ID: "2", TaskName: "Купить молоко", Completed: true, CompletedAt: &completedAt},
+	}
+
+	var gotFilter *models.TodoFilter
+	mock := exportMock(todos, -1)
+	export := mock.exportTodosFunc
+	mock.exportTodosFunc = func(filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+		gotFilter = filter
+		return export(filter, fn)
+	}
+
+	handler := newCalendarHandler(mock, "")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/calendar.ics?project=acme&token="+url.QueryEscape(calendarTokens.Issue("alice")), nil)
+
+	handler.Feed(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, &models.TodoFilter{Project: "acme"}, gotFilter, "токен не попадает в фильтр")
+
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 2, strings.Count(body, "BEGIN:VTODO\r\n"))
+	for _, line := range []string{
+		"X-WR-CALNAME:Задачи", "DTSTAMP:20261019T120000Z",
+		"DUE:20261105T140000Z", "STATUS:NEEDS-ACTION", "PRIORITY:1",
+		"STATUS:COMPLETED", "COMPLETED:20261003T183000Z",
+	} {
+		assert.Contains(t, body, line+"\r\n")
+	}
+}
+
+func TestCalendarHandler_Feed_Empty(t *testing.T) {
+	handler := newCalendarHandler(exportMock(nil, -1), "")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/calendar.ics?token="+calendarTokens.Issue("alice"), nil)
+
+	handler.Feed(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "END:VCALENDAR")
+}
+
+func TestCalendarHandler_Feed_Errors(t *testing.T) {
+	valid := calendarTokens.Issue("alice")
+
+	tests := []struct {
+		name   string
+		query  string
+		mock   *MockService
+		status int
+	}{
+		{"NoToken", "", &MockService{}, 401},
+		{"InvalidToken", "token=" + calendar.NewTokens("другой").Issue("alice"), &MockService{}, 401},
+		{"InvalidFilter", "completed=maybe&token=" + valid, &MockService{}, 400},
+		{"Storage", "token=" + valid, exportMock(nil, 0), 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newCalendarHandler(tt.mock, "")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/calendar.ics?"+tt.query, nil)
+
+			handler.Feed(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestCalendarHandler_Feed_ErrorMidStream(t *testing.T) {
+	handler := newCalendarHandler(exportMock([]*models.Todo{{ID: "1"}, {ID: "2"}}, 1), "")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/calendar.ics?token="+calendarTokens.Issue("alice"), nil)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { handler.Feed(c) })
+}
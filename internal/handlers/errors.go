@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"todo-api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest — нестандартный код nginx для запросов, которые
+// клиент отменил до получения ответа.
+const statusClientClosedRequest = 499
+
+// respondError отвечает ошибкой в едином формате. requestId позволяет найти
+// запрос в логах по ответу, который прислал клиент.
+func respondError(c *gin.Context, status int, message string) {
+	body := gin.H{"error": message}
+	if id := logging.RequestID(c.Request.Context()); id != "" {
+		body["requestId"] = id
+	}
+
+	c.AbortWithStatusJSON(status, body)
+}
+
+// respondServerError скрывает от клиента детали ошибки, но прикрепляет её
+// к запросу, чтобы она попала в access-лог.
+func respondServerError(c *gin.Context, err error) {
+	c.Error(err)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		respondError(c, 504, "превышено время ожидания ответа от хранилища")
+	case errors.Is(err, context.Canceled):
+		respondError(c, statusClientClosedRequest, "запрос отменён клиентом")
+	default:
+		respondError(c, 500, "внутренняя ошибка сервера")
+	}
+}
+
+// Recovery перехватывает панику в обработчике, пишет её в лог со стеком
+// и отвечает 500 в общем формате ошибок.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// Клиент разорвал соединение: отвечать уже некому.
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			slog.ErrorContext(c.Request.Context(), "паника при обработке запроса",
+				slog.Any("panic", recovered),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			respondError(c, 500, "внутренняя ошибка сервера")
+		}()
+
+		c.Next()
+	}
+}
+
+// NoRoute отвечает на запросы к несуществующим маршрутам в общем формате ошибок.
+func NoRoute(c *gin.Context) {
+	respondError(c, 404, "маршрут не найден")
+}
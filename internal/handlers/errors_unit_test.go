@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"todo-api/internal/logging"
+	"todo-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponse_ContainsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mock := &MockService{
+		getByIdFunc: func(id string) (*models.Todo, error) {
+			return nil, errors.New("соединение потеряно")
+		},
+	}
+	handler := NewTodoHandler(mock)
+
+	router := gin.New()
+	router.Use(logging.RequestIDMiddleware(), Recovery())
+	router.NoRoute(NoRoute)
+	router.GET("/todos/:id", handler.GetById)
+	router.GET("/panic", func(c *gin.Context) { panic("сбой") })
+
+	tests := []struct {
+		path   string
+		status int
+		error  string
+	}{
+		{"/todos/1", 500, "внутренняя ошибка сервера"},
+		{"/panic", 500, "внутренняя ошибка сервера"},
+		{"/unknown", 404, "маршрут не найден"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set(logging.RequestIDHeader, "req-7")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.error, body["error"])
+			assert.Equal(t, "req-7", body["requestId"])
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/stream"
+)
+
+// retryInterval — через сколько миллисекунд клиент переподключается после
+// обрыва потока.
+const retryInterval = 3000
+
+type EventsHandler struct {
+	broker    *stream.Broker
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(broker *stream.Broker, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{broker: broker, heartbeat: heartbeat}
+}
+
+// @Summary Поток изменений задач
+// @Description Server-Sent Events: todo.created, todo.updated, todo.completed и todo.deleted с телом как у вебхуков.
+// @Description Id события передаётся в поле id: после обрыва EventSource переподключается с Last-Event-ID и получает
+// @Description пропущенные события из буфера последних событий. Если такого события в буфере уже нет, первым приходит
+// @Description событие reset: задачи нужно загрузить заново. Сервер может закрыть поток, если клиент не успевает читать;
+// @Description клиент переподключается и дочитывает пропущенное. Раз в интервал приходит комментарий для поддержания соединения.
+// @Description Задачи общие, поэтому поток получает изменения всех пользователей; actor только отбирает изменения, сделанные
+// @Description указанными пользователями (поле actor события), и не ограничивает доступ.
+// @Tags todos
+// @Produce text/event-stream
+// @Param actor query []string false "Только изменения, сделанные этими пользователями" collectionFormat(multi)
+// @Param Last-Event-ID header string false "Id последнего полученного события"
+// @Param lastEventId query string false "То же, что Last-Event-ID, для клиентов, которые не могут задать заголовок"
+// @Success 200 {string} string "Поток событий"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Router /todos/events [get]
+func (h *EventsHandler) Stream(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	actors := c.QueryArray("actor")
+	match := func(event stream.Event) bool {
+		return len(actors) == 0 || slices.Contains(actors, event.Actor)
+	}
+
+	sub, replay, resumed := h.broker.Subscribe(lastEventID, match)
+	defer h.broker.Unsubscribe(sub)
+
+	// Поток живёт дольше WriteTimeout сервера.
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", retryInterval)
+	if !resumed {
+		writeEvent(c.Writer, stream.Event{Type: stream.TypeReset})
+	}
+	for _, event := range replay {
+		writeEvent(c.Writer, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			err = writeEvent(c.Writer, event)
+		case <-heartbeat.C:
+			_, err = io.WriteString(c.Writer, ": ping\n\n")
+		}
+
+		if err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent пишет событие в формате SSE. Тело события — JSON в одну строку.
+func writeEvent(w io.Writer, event stream.Event) error {
+	data := event.Data
+	if data == nil {
+		data = []byte("{}")
+	}
+
+	var err error
+	if event.ID != "" {
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	} else {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	}
+	return err
+}
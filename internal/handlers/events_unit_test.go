@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/identity"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// sseReader разбирает поток SSE, пропуская комментарии и retry.
+type sseReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *sseReader) next(t *testing.T) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		switch {
+		case line == "":
+			if event.event != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	t.Fatalf("поток закрыт: %v", r.scanner.Err())
+	return event
+}
+
+func eventsServer(t *testing.T, heartbeat time.Duration) (*httptest.Server, services.TodoService, *stream.Broker) {
+	t.Helper()
+
+	broker := stream.NewBroker(10)
+	service := services.NewTodoService(stream.Repository(repository.Constructor(), stream.Local(broker)))
+
+	router := gin.New()
+	router.GET("/todos/events", NewEventsHandler(broker, heartbeat).Stream)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		broker.Close()
+		server.Close()
+	})
+	return server, service, broker
+}
+
+func subscribe(t *testing.T, server *httptest.Server, path, lastEventID string) *sseReader {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return &sseReader{scanner: bufio.NewScanner(resp.Body)}
+}
+
+func createAs(t *testing.T, service services.TodoService, user, name string) *models.Todo {
+	t.Helper()
+
+	todo, err := service.CreateTodo(identity.WithUser(context.Background(), user), &models.CreateTodoRequest{TaskName: name})
+	require.NoError(t, err)
+	return todo
+}
+
+func TestEventsHandler_Stream(t *testing.T) {
+	server, service, _ := eventsServer(t, time.Minute)
+	events := subscribe(t, server, "/todos/events", "")
+
+	todo := createAs(t, service, "alice", "Купить молоко")
+	require.NoError(t, service.DeleteTodo(context.Background(), todo.ID))
+
+	created := events.next(t)
+	assert.Equal(t, models.EventTodoCreated, created.event)
+	assert.NotEmpty(t, created.id)
+	assert.Contains(t, created.data, `"actor":"alice"`)
+	assert.Contains(t, created.data, todo.ID)
+
+	deleted := events.next(t)
+	assert.Equal(t, models.EventTodoDeleted, deleted.event)
+	assert.NotContains(t, deleted.data, "actor")
+}
+
+func TestEventsHandler_Resume(t *testing.T) {
+	server, service, _ := eventsServer(t, time.Minute)
+
+	createAs(t, service, "alice", "Первая")
+
+	all := subscribe(t, server, "/todos/events", "missing")
+	reset := all.next(t)
+	assert.Equal(t, stream.TypeReset, reset.event)
+	assert.Empty(t, reset.id)
+
+	live := subscribe(t, server, "/todos/events", "")
+	createAs(t, service, "alice", "Вторая")
+	second := live.next(t)
+
+	assert.Equal(t, second.id, all.next(t).id, "после reset поток продолжается")
+
+	resumed := subscribe(t, server, "/todos/events?lastEventId="+second.id, "")
+	createAs(t, service, "alice", "Третья")
+	assert.Contains(t, resumed.next(t).data, "Третья", "reset не отправляется, если событие найдено")
+}
+
+func TestEventsHandler_Replay(t *testing.T) {
+	server, service, _ := eventsServer(t, time.Minute)
+
+	live := subscribe(t, server, "/todos/events", "")
+	createAs(t, service, "alice", "Первая")
+	first := live.next(t)
+	createAs(t, service, "bob", "Вторая")
+	createAs(t, service, "alice", "Третья")
+
+	replay := subscribe(t, server, "/todos/events?actor=alice", first.id)
+	assert.Contains(t, replay.next(t).data, "Третья")
+
+	createAs(t, service, "bob", "Четвёртая")
+	createAs(t, service, "carol", "Пятая")
+	createAs(t, service, "alice", "Шестая")
+	assert.Contains(t, replay.next(t).data, "Шестая")
+
+	several := subscribe(t, server, "/todos/events?actor=bob&actor=carol", first.id)
+	assert.Contains(t, several.next(t).data, "Вторая")
+	assert.Contains(t, several.next(t).data, "Четвёртая")
+	assert.Contains(t, several.next(t).data, "Пятая")
+}
+
+func TestEventsHandler_Heartbeat(t *testing.T) {
+	server, _, _ := eventsServer(t, 10*time.Millisecond)
+	events := subscribe(t, server, "/todos/events", "")
+
+	for events.scanner.Scan() {
+		if events.scanner.Text() == ": ping" {
+			return
+		}
+	}
+	t.Fatal("нет комментария-пинга")
+}
+
+func TestEventsHandler_Closed(t *testing.T) {
+	server, _, broker := eventsServer(t, time.Minute)
+	events := subscribe(t, server, "/todos/events", "")
+
+	broker.Close()
+
+	for events.scanner.Scan() {
+	}
+	assert.NoError(t, events.scanner.Err(), "сервер завершает поток")
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-api/internal/export"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Выгрузить задачи
+// @Description Выгружает задачи в порядке создания с теми же фильтрами, что и список. Ответ пишется по мере чтения из хранилища. Колонки CSV: id, taskName, description, completed, createdAt, dueAt, priority, tags, project, completedAt; теги разделены точкой с запятой, время в UTC в формате RFC 3339. Если хранилище откажет посреди выгрузки, соединение разрывается, чтобы клиент не принял неполный файл за целый.
+// @Tags todos
+// @Produce text/csv
+// @Produce json
+// @Produce application/x-ndjson
+// @Param format query string false "Формат файла" Enums(csv, json, ndjson) default(csv)
+// @Param bom query bool false "Добавить UTF-8 BOM в начало CSV, чтобы Excel распознал кодировку"
+// @Param completed query bool false "Только выполненные или только невыполненные"
+// @Param priority query string false "Приоритет" Enums(low, medium, high)
+// @Param project query string false "Проект"
+// @Param tag query string false "Тег"
+// @Param dueBefore query string false "Срок раньше этого момента, RFC 3339"
+// @Param dueAfter query string false "Срок не раньше этого момента, RFC 3339"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string "Неизвестный формат или неверный фильтр"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /todos/export [get]
+func (h *TodoHandler) Export(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", export.FormatCSV))
+
+	withBOM := false
+	if value := c.Query("bom"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(c, 400, "bom должен быть true или false")
+			return
+		}
+		withBOM = parsed
+	}
+
+	filter, err := parseTodoFilter(c)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return
+	}
+
+	encoder, err := export.NewEncoder(format, c.Writer, withBOM)
+	if err != nil {
+		respondError(c, 400, export.ErrUnknownFormat.Error())
+		return
+	}
+
+	// Заголовки отправляются с первой задачей: пока ничего не записано,
+	// об ошибке хранилища ещё можно сообщить обычным ответом.
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+
+		filename := "todos-" + h.now().Format("2006-01-02") + "." + format
+		c.Header("Content-Type", export.ContentType(format))
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		c.Status(200)
+	}
+
+	err = h.service.ExportTodos(c.Request.Context(), filter, func(task *models.Todo) error {
+		start()
+		return encoder.Encode(task)
+	})
+
+	if err != nil && !started {
+		switch err {
+		case repository.ErrInvalidPriority:
+			respondError(c, 400, err.Error())
+			return
+		default:
+			respondServerError(c, err)
+			return
+		}
+	}
+
+	if err == nil {
+		start()
+		err = encoder.Close()
+	}
+
+	if err != nil {
+		c.Error(err)
+		slog.ErrorContext(c.Request.Context(), "выгрузка прервана", slog.Any("error", err))
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// exportMock отдаёт todos и падает перед задачей с номером failAt; -1 — без ошибки.
+func exportMock(todos []*models.Todo, failAt int) *MockService {
+	errLost := errors.New("соединение с базой потеряно")
+
+	return &MockService{
+		exportTodosFunc: func(filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+			for i, task := range todos {
+				if i == failAt {
+					return errLost
+				}
+				if err := fn(task); err != nil {
+					return err
+				}
+			}
+			if failAt == len(todos) {
+				return errLost
+			}
+			return nil
+		},
+	}
+}
+
+func newExportHandler(mock *MockService) *TodoHandler {
+	handler := NewTodoHandler(mock)
+	handler.now = func() time.Time { return time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) }
+	return handler
+}
+
+func TestTodoHandler_Export_CSV(t *testing.T) {
+	description := "в \"кавычках\", с запятой"
+	completedAt := time.Date(2026, 10, 3, 18, 30, 0, 0, time.UTC)
+	todos := []*models.Todo{
+		{ID: "1", TaskName: "Оплатить счёт", Description: &description, CreatedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC), Priority: "high", Tags: []string{"finance", "work"}, Project: "acme"},
+		{ID: "2", TaskName: "Купить молоко", Completed: true, CreatedAt: time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC), CompletedAt: &completedAt},
+	}
+
+	handler := newExportHandler(exportMock(todos, -1))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos/export?format=csv&bom=true", nil)
+
+	handler.Export(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=todos-2026-10-14.csv`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "\ufeff"+
+		"id,taskName,description,completed,createdAt,dueAt,priority,tags,project,completedAt\n"+
+		"1,Оплатить счёт,\"в \"\"кавычках\"\", с запятой\",false,2026-10-01T09:00:00Z,,high,finance;work,acme,\n"+
+		"2,Купить молоко,,true,2026-10-02T09:00:00Z,,,,,2026-10-03T18:30:00Z\n",
+		w.Body.String())
+}
+
+func TestTodoHandler_Export_JSON(t *testing.T) {
+	todos := []*models.Todo{{ID: "1", TaskName: "a"}, {ID: "2", TaskName: "b"}}
+
+	for _, tt := range []struct {
+		format      string
+		todos       []*models.Todo
+		contentType string
+		body        string
+	}{
+		{"json", nil, "application/json; charset=utf-8", "[]"},
+		{"json", todos, "application/json; charset=utf-8", `[{"id":"1","taskName":"a","description":null,"completed":false,"createdAt":"0001-01-01T00:00:00Z"},{"id":"2","taskName":"b","description":null,"completed":false,"createdAt":"0001-01-01T00:00:00Z"}]`},
+	} {
+		handler := newExportHandler(exportMock(tt.todos, -1))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/todos/export?format="+tt.format+"&bom=true", nil)
+
+		handler.Export(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+		assert.JSONEq(t, tt.body, w.Body.String(), "BOM в JSON не пишется")
+	}
+}
+
+func TestTodoHandler_Export_NDJSON(t *testing.T) {
+	handler := newExportHandler(exportMock([]*models.Todo{{ID: "1"}, {ID: "2"}}, -1))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos/export?format=ndjson", nil)
+
+	handler.Export(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"id":"1","taskName":"","description":null,"completed":false,"createdAt":"0001-01-01T00:00:00Z"}`+"\n"+
+		`{"id":"2","taskName":"","description":null,"completed":false,"createdAt":"0001-01-01T00:00:00Z"}`+"\n",
+		w.Body.String())
+}
+
+func TestTodoHandler_Export_BadRequest(t *testing.T) {
+	handler := newExportHandler(&MockService{})
+
+	for _, query := range []string{"format=xlsx", "bom=yes-please", "completed=maybe"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/todos/export?"+query, nil)
+
+		handler.Export(c)
+
+		assert.Equal(t, 400, w.Code, query)
+	}
+}
+
+func TestTodoHandler_Export_ErrorBeforeFirstRow(t *testing.T) {
+	handler := newExportHandler(exportMock(nil, 0))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos/export", nil)
+
+	handler.Export(c)
+
+	assert.Equal(t, 500, w.Code)
+	assert.JSONEq(t, `{"error":"внутренняя ошибка сервера"}`, w.Body.String())
+}
+
+func TestTodoHandler_Export_ErrorMidStream(t *testing.T) {
+	handler := newExportHandler(exportMock([]*models.Todo{{ID: "1"}, {ID: "2"}}, 1))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos/export?format=json", nil)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { handler.Export(c) },
+		"оборванная выгрузка должна разорвать соединение")
+}
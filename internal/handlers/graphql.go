@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"todo-api/internal/graphqlapi"
+	"todo-api/internal/models"
+)
+
+type GraphQLHandler struct {
+	server *graphqlapi.Server
+}
+
+func NewGraphQLHandler(server *graphqlapi.Server) *GraphQLHandler {
+	return &GraphQLHandler{server: server}
+}
+
+// @Summary Запрос GraphQL
+// @Description Схема: задачи (Todo) с тегами и проектами, запросы todo, todos с курсором, project и tag, мутации createTodo,
+// @Description updateTodo и deleteTodo. Ошибки выполнения приходят в errors с кодом в extensions.code: BAD_USER_INPUT,
+// @Description NOT_FOUND, CONFLICT, TIMEOUT, INTERNAL_SERVER_ERROR; запрос глубже GRAPHQL_MAX_DEPTH — QUERY_TOO_DEEP,
+// @Description сложнее GRAPHQL_MAX_COMPLEXITY — QUERY_TOO_COMPLEX. Сложность — оценка числа полей ответа: поле с аргументом
+// @Description first считается first раз. Мутации выполняются от имени пользователя из заголовка, заданного EVENTS_USER_HEADER.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body models.GraphQLRequest true "Запрос"
+// @Success 200 {object} map[string]any "Ответ GraphQL: data и errors"
+// @Failure 400 {object} map[string]string "Неверное тело запроса"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Router /graphql [post]
+func (h *GraphQLHandler) Post(c *gin.Context) {
+	var request models.GraphQLRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, "неверный JSON")
+		return
+	}
+
+	h.exec(c, &request, false)
+}
+
+// @Summary Запрос GraphQL через GET или подписка по WebSocket
+// @Description Без заголовков WebSocket выполняет запрос из параметров, мутации через GET не выполняются.
+// @Description С заголовками открывает WebSocket по протоколу graphql-transport-ws (подпротокол graphql-transport-ws):
+// @Description через него доступны подписка todoChanged, запросы и мутации. todoChanged принимает actors, projects и lastEventId,
+// @Description как поток /todos/events, и присылает RESET, если события могли быть пропущены.
+// @Tags graphql
+// @Produce json
+// @Param query query string true "Запрос"
+// @Param operationName query string false "Имя операции"
+// @Param variables query string false "Переменные в JSON"
+// @Success 200 {object} map[string]any "Ответ GraphQL: data и errors"
+// @Success 101 "Соединение открыто"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 403 {object} map[string]string "Сайт не может открывать WebSocket"
+// @Failure 405 {object} map[string]string "Мутация в GET-запросе"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Router /graphql [get]
+func (h *GraphQLHandler) Get(c *gin.Context) {
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.server.ServeWebSocket(c.Writer, c.Request)
+		return
+	}
+
+	request := models.GraphQLRequest{
+		Query:         c.Query("query"),
+		OperationName: c.Query("operationName"),
+	}
+	if variables := c.Query("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+			respondError(c, 400, "variables должен быть JSON-объектом")
+			return
+		}
+	}
+
+	h.exec(c, &request, true)
+}
+
+func (h *GraphQLHandler) exec(c *gin.Context, request *models.GraphQLRequest, readOnly bool) {
+	if request.Query == "" {
+		respondError(c, 400, "не передан query")
+		return
+	}
+
+	response, err := h.server.Exec(c.Request.Context(), request, readOnly)
+	if err != nil {
+		c.Header("Allow", "POST")
+		respondError(c, 405, err.Error())
+		return
+	}
+
+	c.JSON(200, response)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/graphqlapi"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	broker := stream.NewBroker(10)
+	service := services.NewTodoService(repository.Constructor())
+	server := graphqlapi.New(service, broker, config.GraphQLConfig{MaxDepth: 10, MaxComplexity: 1000},
+		config.RealtimeConfig{PingInterval: time.Minute, WriteTimeout: time.Second, MaxMessageSize: 1024})
+	defer server.Close()
+
+	handler := NewGraphQLHandler(server)
+	router := gin.New()
+	router.GET("/graphql", handler.Get)
+	router.POST("/graphql", handler.Post)
+
+	w := serve(router, "POST", "/graphql", `{"query":"mutation($name: String!) { createTodo(input: {taskName: $name}) { taskName } }","variables":{"name":"test"}}`)
+	require.Equal(t, 200, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"data":{"createTodo":{"taskName":"test"}}}`, w.Body.String())
+
+	w = serve(router, "GET", "/graphql?query="+url.QueryEscape(`query($first: Int) { todos(first: $first) { items { taskName } } }`)+
+		"&variables="+url.QueryEscape(`{"first":1}`), "")
+	require.Equal(t, 200, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"data":{"todos":{"items":[{"taskName":"test"}]}}}`, w.Body.String())
+
+	w = serve(router, "GET", "/graphql?query="+url.QueryEscape(`mutation { deleteTodo(id: "1") }`), "")
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "POST", w.Header().Get("Allow"))
+
+	w = serve(router, "POST", "/graphql", `{"query":"{ todos { missing } }"}`)
+	assert.Equal(t, 200, w.Code, "ошибки запроса приходят в теле ответа")
+	assert.Contains(t, w.Body.String(), "GRAPHQL_VALIDATION_FAILED")
+
+	assert.Equal(t, 400, serve(router, "POST", "/graphql", `{`).Code)
+	assert.Equal(t, 400, serve(router, "POST", "/graphql", `{}`).Code)
+	assert.Equal(t, 400, serve(router, "GET", "/graphql?query=x&variables=%5B", "").Code)
+}
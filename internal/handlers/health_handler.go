@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"todo-api/internal/health"
+	"todo-api/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// @Summary Проверка жизнеспособности
+// @Description Процесс запущен и обрабатывает запросы
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// @Summary Проверка готовности
+// @Description Доступность хранилища, применённые миграции и работа фоновых задач
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report "Сервис не готов принимать трафик"
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Ready(c.Request.Context())
+
+	if !report.Ready {
+		c.JSON(503, report)
+		return
+	}
+
+	c.JSON(200, report)
+}
+
+// @Summary Версия сборки
+// @Description Версия, коммит и время сборки приложения
+// @Tags health
+// @Produce json
+// @Success 200 {object} version.Info
+// @Router /version [get]
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(200, version.Get())
+}
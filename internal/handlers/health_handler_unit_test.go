@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-api/internal/health"
+	"todo-api/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler_Liveness(t *testing.T) {
+	handler := NewHealthHandler(health.NewChecker(time.Second))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/healthz", nil)
+
+	handler.Liveness(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestHealthHandler_Readiness(t *testing.T) {
+	checker := health.NewChecker(time.Second, health.Check{
+		Name: "database",
+		Func: func(ctx context.Context) error { return nil },
+	})
+	handler := NewHealthHandler(checker)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/readyz", nil)
+
+	handler.Readiness(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"ready":true,"checks":[{"name":"database"}]}`, w.Body.String())
+}
+
+func TestHealthHandler_Readiness_NotReady(t *testing.T) {
+	checker := health.NewChecker(time.Second, health.Check{
+		Name: "database",
+		Func: func(ctx context.Context) error { return errors.New("нет соединения") },
+	})
+	handler := NewHealthHandler(checker)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/readyz", nil)
+
+	handler.Readiness(c)
+
+	assert.Equal(t, 503, w.Code)
+	assert.JSONEq(t, `{"ready":false,"checks":[{"name":"database","error":"нет соединения"}]}`, w.Body.String())
+}
+
+func TestHealthHandler_Readiness_ShuttingDown(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.MarkShuttingDown()
+	handler := NewHealthHandler(checker)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/readyz", nil)
+
+	handler.Readiness(c)
+
+	assert.Equal(t, 503, w.Code)
+}
+
+func TestHealthHandler_Version(t *testing.T) {
+	handler := NewHealthHandler(health.NewChecker(time.Second))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/version", nil)
+
+	handler.Version(c)
+
+	assert.Equal(t, 200, w.Code)
+
+	var response version.Info
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, version.Version, response.Version)
+	assert.NotEmpty(t, response.GoVersion)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/importer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize ограничивает размер загружаемого файла.
+const maxImportSize = 10 << 20
+
+// @Summary Импортировать задачи
+// @Description Загружает задачи из файла в поле file. Форматы: csv (колонки по заголовку, как в выгрузке; id и createdAt пропускаются), json (массив или NDJSON), ndjson, todotxt ("x" — выполнена, (A)/(B)/(C) — high/medium/low, +проект, @контекст становится тегом, due:2006-01-02) и ics (компоненты VTODO: SUMMARY, DESCRIPTION, DUE, STATUS или COMPLETED, PRIORITY, CATEGORIES — теги, X-TODO-PROJECT — проект; UID и остальные компоненты пропускаются). Формат берётся из поля format или из расширения файла. Задачи создаются в одной транзакции: если хотя бы одна строка не прошла проверку, не создаётся ничего и возвращается 422 с отчётом по строкам. С dryRun=true только проверяет файл и всегда отвечает 200.
+// @Tags todos
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Файл с задачами, не больше 10 МБ и 10000 задач"
+// @Param format formData string false "Формат файла" Enums(csv, json, ndjson, todotxt, ics)
+// @Param timezone formData string false "Часовой пояс IANA для сроков без времени, по умолчанию пояс сервера"
+// @Param dryRun query bool false "Только проверить файл, не создавая задачи"
+// @Success 200 {object} models.ImportReport "Отчёт о проверке при dryRun=true"
+// @Success 201 {object} models.ImportReport
+// @Failure 400 {object} map[string]string "Нет файла, неизвестный формат или файл не читается"
+// @Failure 413 {object} map[string]string "Файл слишком большой"
+// @Failure 422 {object} models.ImportReport "Есть строки с ошибками, ничего не импортировано"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /todos/import [post]
+func (h *TodoHandler) Import(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(c, 400, "dryRun должен быть true или false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	// Запас на заголовки multipart и остальные поля формы.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+1<<20)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(c, 413, "файл больше 10 МБ")
+			return
+		}
+		respondError(c, 400, "необходимо передать файл в поле file")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxImportSize {
+		respondError(c, 413, "файл больше 10 МБ")
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		format = importer.FormatOf(header.Filename)
+	}
+
+	location := time.Local
+	if name := c.PostForm("timezone"); name != "" {
+		location, err = time.LoadLocation(name)
+		if err != nil {
+			respondError(c, 400, "неизвестный часовой пояс")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	lines, err := importer.Parse(format, data, location)
+	if err != nil {
+		if errors.Is(err, importer.ErrUnknownFormat) {
+			respondError(c, 400, importer.ErrUnknownFormat.Error())
+			return
+		}
+		respondError(c, 400, err.Error())
+		return
+	}
+
+	report, err := h.service.ImportTodos(c.Request.Context(), lines, dryRun)
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+	report.Format = format
+
+	switch {
+	case dryRun:
+		c.JSON(200, report)
+	case report.Invalid > 0:
+		c.JSON(422, report)
+	default:
+		c.JSON(201, report)
+	}
+}
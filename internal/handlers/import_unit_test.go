@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+	"todo-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newImportContext собирает multipart-запрос с файлом в поле file.
+func newImportContext(t *testing.T, url, filename, content string, fields map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		assert.NoError(t, form.WriteField(name, value))
+	}
+	if filename != "" {
+		part, err := form.CreateFormFile("file", filename)
+		assert.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, form.Close())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", url, &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+
+	return c, w
+}
+
+func TestTodoHandler_Import(t *testing.T) {
+	var gotLines []models.ImportLine
+	var gotDryRun bool
+	mock := &MockService{
+		importTodosFunc: func(lines []models.ImportLine, dryRun bool) (*models.ImportReport, error) {
+			gotLines, gotDryRun = lines, dryRun
+			report := &models.ImportReport{DryRun: dryRun, Total: len(lines), Lines: lines}
+			for _, line := range lines {
+				if line.Error != "" {
+					report.Invalid++
+				}
+			}
+			if !dryRun && report.Invalid == 0 {
+				report.Imported = len(lines)
+			}
+			return report, nil
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	tests := []struct {
+		name    string
+		url     string
+		file    string
+		content string
+		fields  map[string]string
+		status  int
+		format  string
+		dryRun  bool
+		lines   int
+	}{
+		{"CSV", "/todos/import", "tasks.csv", "taskName\nпервая\nвторая\n", nil, 201, "csv", false, 2},
+		{"TodoTxtDryRun", "/todos/import?dryRun=true", "todo.txt", "(A) Позвонить +Семья\n", nil, 200, "todotxt", true, 1},
+		{"ExplicitFormat", "/todos/import", "export", `[{"taskName":"a"}]`, map[string]string{"format": "JSON"}, 201, "json", false, 1},
+		{"InvalidLine", "/todos/import", "tasks.ndjson", "{\"taskName\":\"a\"}\n{oops}\n", nil, 422, "ndjson", false, 2},
+		{"InvalidLineDryRun", "/todos/import?dryRun=1", "tasks.ndjson", "{oops}\n", nil, 200, "ndjson", true, 1},
+		{"ICS", "/todos/import", "Reminders.ics", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:a\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", nil, 201, "ics", false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newImportContext(t, tt.url, tt.file, tt.content, tt.fields)
+
+			handler.Import(c)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, tt.dryRun, gotDryRun)
+			assert.Len(t, gotLines, tt.lines)
+
+			var report models.ImportReport
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tt.format, report.Format)
+		})
+	}
+}
+
+func TestTodoHandler_Import_BadRequest(t *testing.T) {
+	handler := NewTodoHandler(&MockService{})
+
+	tests := []struct {
+		name    string
+		url     string
+		file    string
+		content string
+		fields  map[string]string
+		status  int
+		err     string
+	}{
+		{"NoFile", "/todos/import", "", "", nil, 400, "необходимо передать файл в поле file"},
+		{"UnknownFormat", "/todos/import", "tasks.xlsx", "data", nil, 400, "формат должен быть csv, json, ndjson, todotxt или ics"},
+		{"EmptyFile", "/todos/import", "todo.txt", "\n\n", nil, 400, "в файле нет задач"},
+		{"BrokenJSON", "/todos/import", "tasks.json", `[{"taskName":`, nil, 400, ""},
+		{"Timezone", "/todos/import", "todo.txt", "задача", map[string]string{"timezone": "Mars/Olympus"}, 400, "неизвестный часовой пояс"},
+		{"DryRun", "/todos/import?dryRun=maybe", "todo.txt", "задача", nil, 400, "dryRun должен быть true или false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newImportContext(t, tt.url, tt.file, tt.content, tt.fields)
+
+			handler.Import(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.err != "" {
+				assert.JSONEq(t, `{"error":"`+tt.err+`"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTodoHandler_Import_TooLarge(t *testing.T) {
+	handler := NewTodoHandler(&MockService{})
+
+	c, w := newImportContext(t, "/todos/import", "todo.txt", string(bytes.Repeat([]byte("a"), maxImportSize+1)), nil)
+
+	handler.Import(c)
+
+	assert.Equal(t, 413, w.Code)
+}
+
+func TestTodoHandler_Import_InternalServerError(t *testing.T) {
+	mock := &MockService{
+		importTodosFunc: func(lines []models.ImportLine, dryRun bool) (*models.ImportReport, error) {
+			return nil, errors.New("база недоступна")
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	c, w := newImportContext(t, "/todos/import", "todo.txt", "задача", nil)
+
+	handler.Import(c)
+
+	assert.Equal(t, 500, w.Code)
+}
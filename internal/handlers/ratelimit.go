@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+	"todo-api/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit отклоняет запросы сверх лимита с кодом 429. Запрос забирает токен
+// из каждой своей корзины по очереди, а заголовки RateLimit-* описывают самую
+// пустую из них и выставляются на каждый ответ. Если хранилище счётчиков
+// недоступно, запрос пропускается: отказ лимитера не должен останавливать
+// весь API.
+func RateLimit(limiter *ratelimit.Limiter, buckets func(c *gin.Context) []ratelimit.Bucket) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var result ratelimit.Result
+		for i, key := range buckets(c) {
+			bucket, err := limiter.AllowBucket(c.Request.Context(), key)
+			if err != nil {
+				slog.WarnContext(c.Request.Context(), "ограничитель запросов недоступен, запрос пропущен", "error", err)
+				c.Next()
+				return
+			}
+
+			if i == 0 || bucket.Remaining < result.Remaining || !bucket.Allowed {
+				result = bucket
+			}
+			if !bucket.Allowed {
+				break
+			}
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.Reset))
+		c.Header("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+seconds(result.Window))
+
+		if !result.Allowed {
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			respondError(c, 429, "слишком много запросов, повторите позже")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// seconds округляет вверх, чтобы клиент не повторил запрос раньше времени.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (float64, bool, error) {
+	return 0, false, errors.New("база недоступна")
+}
+
+func (failingStore) Purge(ctx context.Context, idle time.Duration) error {
+	return nil
+}
+
+func setUpRateLimitRouter(t *testing.T, store ratelimit.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := ratelimit.NewLimiter(store, ratelimit.Limit{Rate: 0.5, Burst: 1})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(RateLimit(limiter, func(c *gin.Context) []ratelimit.Bucket {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			return []ratelimit.Bucket{{Key: "key:" + key}, {Key: "ip"}}
+		}
+		return []ratelimit.Bucket{{Key: "ip"}}
+	}))
+	router.GET("/todos", func(c *gin.Context) { c.Status(200) })
+
+	return router
+}
+
+func TestRateLimit_TooManyRequests(t *testing.T) {
+	router := setUpRateLimitRouter(t, ratelimit.NewMemoryStore())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/todos", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1;w=2", w.Header().Get("RateLimit-Policy"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/todos", nil))
+
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"слишком много запросов, повторите позже"}`, w.Body.String())
+}
+
+func TestRateLimit_StoreFailureLetsRequestThrough(t *testing.T) {
+	router := setUpRateLimitRouter(t, failingStore{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/todos", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimit_EveryBucketIsCharged(t *testing.T) {
+	router := setUpRateLimitRouter(t, ratelimit.NewMemoryStore())
+
+	request := func(apiKey string) int {
+		r := httptest.NewRequest("GET", "/todos", nil)
+		r.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, 200, request("first"))
+	assert.Equal(t, 429, request("second"), "новый ключ не получает новую корзину адреса")
+}
+
+func TestRateLimit_SpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.5, Burst: 1})
+	require.NoError(t, err)
+	keys, err := ratelimit.KeyFunc(config.RateLimitConfig{KeyBy: ratelimit.KeyByIP})
+	require.NoError(t, err)
+
+	newRouter := func(trusted []string) *gin.Engine {
+		router := gin.New()
+		require.NoError(t, router.SetTrustedProxies(trusted))
+		router.Use(RateLimit(limiter, keys))
+		router.GET("/todos", func(c *gin.Context) { c.Status(200) })
+		return router
+	}
+
+	request := func(router *gin.Engine, forwardedFor string) int {
+		r := httptest.NewRequest("GET", "/todos", nil)
+		r.RemoteAddr = "203.0.113.7:1234"
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	router := newRouter(nil)
+	assert.Equal(t, 200, request(router, "198.51.100.1"))
+	assert.Equal(t, 429, request(router, "198.51.100.2"), "без доверенных прокси заголовок не даёт новую корзину")
+
+	router = newRouter([]string{"203.0.113.7"})
+	assert.Equal(t, 200, request(router, "198.51.100.3"))
+	assert.Equal(t, 200, request(router, "198.51.100.4"), "адрес из заголовка доверенного прокси")
+}
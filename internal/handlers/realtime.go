@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"todo-api/internal/realtime"
+)
+
+type RealtimeHandler struct {
+	hub *realtime.Hub
+}
+
+func NewRealtimeHandler(hub *realtime.Hub) *RealtimeHandler {
+	return &RealtimeHandler{hub: hub}
+}
+
+// @Summary WebSocket для совместной работы
+// @Description Сообщения — JSON-объекты с полем type. Клиент отправляет subscribe и unsubscribe с project, create с data
+// @Description как в POST /todos, update с todoId, data как в PATCH /todos/{id} и необязательным ifVersion, delete с todoId
+// @Description и ping. На каждое сообщение приходит result или error (pong на ping) с тем же id; status у error — код,
+// @Description которым ответил бы REST API. Ответ на subscribe содержит задачи проекта и его зрителей: data.todos, data.users.
+// @Description По подпискам приходят event с project подписки и data — телом события, как у вебхуков (задачу, перенесённую
+// @Description в другой проект, получает и прежний проект: data.previousProject), и presence с data.users, когда меняется,
+// @Description кто смотрит проект. reset означает, что события могли быть пропущены и задачи нужно загрузить заново.
+// @Description Изменения выполняются от имени пользователя из заголовка, заданного EVENTS_USER_HEADER.
+// @Description Сервер пингует клиента и закрывает соединение, если тот не отвечает. Клиент, который не успевает читать
+// @Description сообщения, отключается с кодом 1013; при остановке сервера соединение закрывается с кодом 1001.
+// @Tags todos
+// @Success 101 "Соединение открыто"
+// @Failure 400 {object} map[string]string "Не запрос на открытие WebSocket"
+// @Failure 403 {object} map[string]string "Сайт не может открывать WebSocket"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Router /todos/ws [get]
+func (h *RealtimeHandler) Connect(c *gin.Context) {
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		respondError(c, 400, "ожидается запрос на открытие WebSocket")
+		return
+	}
+
+	h.hub.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/realtime"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealtimeHandler_Connect(t *testing.T) {
+	broker := stream.NewBroker(10)
+	service := services.NewTodoService(repository.Constructor())
+	hub := realtime.NewHub(service, broker, config.RealtimeConfig{
+		PingInterval: time.Minute, WriteTimeout: time.Second, SendBuffer: 8, MaxMessageSize: 1024,
+	})
+
+	router := gin.New()
+	router.GET("/todos/ws", NewRealtimeHandler(hub).Connect)
+
+	w := serve(router, "GET", "/todos/ws", "")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "WebSocket")
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/todos/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(realtime.Request{Type: realtime.TypePing, ID: "1"}))
+	var message realtime.Message
+	require.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, realtime.TypePong, message.Type)
+	assert.Equal(t, "1", message.ID)
+}
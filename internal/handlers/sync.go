@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+)
+
+type SyncHandler struct {
+	service services.SyncService
+}
+
+func NewSyncHandler(service services.SyncService) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+// @Summary Получить изменения
+// @Description Задачи, созданные или изменённые после токена since, и удалённые задачи (deleted) в порядке изменений.
+// @Description Без since приходят все задачи без удалённых. Токен из ответа передаётся в следующий запрос; пока hasMore,
+// @Description изменения нужно дочитать сразу. Токен непрозрачен. Записи об удалениях хранятся ограниченное время:
+// @Description на слишком старый токен сервер отвечает 410, и задачи нужно загрузить заново без since.
+// @Tags sync
+// @Produce json
+// @Param since query string false "Токен из предыдущего ответа"
+// @Param limit query int false "Количество изменений, от 1 до 1000" default(500)
+// @Success 200 {object} models.SyncPullResponse
+// @Failure 400 {object} map[string]string "Неверный токен или limit"
+// @Failure 410 {object} map[string]string "Токен устарел"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /sync [get]
+func (h *SyncHandler) Pull(c *gin.Context) {
+	limit := defaultSyncLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSyncLimit {
+			respondError(c, 400, "limit должен быть числом от 1 до 1000")
+			return
+		}
+		limit = parsed
+	}
+
+	response, err := h.service.Pull(c.Request.Context(), c.Query("since"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSyncToken):
+			respondError(c, 400, err.Error())
+		case errors.Is(err, repository.ErrSyncTokenExpired):
+			respondError(c, 410, err.Error())
+		default:
+			respondServerError(c, err)
+		}
+		return
+	}
+
+	c.JSON(200, response)
+}
+
+// @Summary Отправить изменения
+// @Description Изменения, накопленные клиентом без связи, применяются по порядку, каждое отдельно. baseVersion — версия задачи,
+// @Description на которой клиент сделал изменение. Поле, которое на сервере после неё тоже менялось, — конфликт: при lastWriterWins
+// @Description остаётся значение, изменённое позже (по changedAt клиента), при report — значение сервера. Остальные поля применяются.
+// @Description Удаление задачи, изменённой на сервере после baseVersion, при report не применяется, при lastWriterWins — если changedAt позже.
+// @Description Изменение задачи, удалённой на сервере, не применяется. Повторная отправка тех же изменений безопасна.
+// @Description Каждое изменение получает результат: applied, conflict с полями конфликта или rejected с причиной.
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Param changes body models.SyncPushRequest true "Изменения клиента, не больше 500"
+// @Success 200 {object} models.SyncPushResponse
+// @Failure 400 {object} map[string]string "Неверный JSON, стратегия или слишком много изменений"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /sync [post]
+func (h *SyncHandler) Push(c *gin.Context) {
+	var request models.SyncPushRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, "неверный JSON")
+		return
+	}
+
+	response, err := h.service.Push(c.Request.Context(), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownStrategy), errors.Is(err, services.ErrTooManyChanges):
+			respondError(c, 400, err.Error())
+		default:
+			respondServerError(c, err)
+		}
+		return
+	}
+
+	c.JSON(200, response)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func syncServer(t *testing.T) (http.Handler, *repository.MemorySyncRepository) {
+	t.Helper()
+
+	todos := repository.Constructor()
+	changes := repository.NewMemorySyncRepository(todos)
+	handler := NewSyncHandler(services.NewSyncService(todos, changes))
+
+	router := gin.New()
+	router.GET("/sync", handler.Pull)
+	router.POST("/sync", handler.Push)
+
+	return router, changes
+}
+
+func TestSyncHandler_PushAndPull(t *testing.T) {
+	server, _ := syncServer(t)
+	id := uuid.NewString()
+
+	w := serve(server, "POST", "/sync", `{"changes":[
+		{"op":"create","id":"`+id+`","fields":{"taskName":"offline","dueAt":"2026-10-20T10:00:00Z"}},
+		{"op":"update","id":"`+id+`","baseVersion":1,"fields":{"completed":true,"dueAt":null}},
+		{"op":"update","id":"`+id+`","fields":{"title":"x"}}
+	]}`)
+	require.Equal(t, 200, w.Code, w.Body.String())
+
+	var pushed models.SyncPushResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pushed))
+	require.Len(t, pushed.Results, 3)
+	assert.Equal(t, models.SyncApplied, pushed.Results[0].Status)
+	assert.Equal(t, models.SyncApplied, pushed.Results[1].Status)
+	assert.True(t, pushed.Results[1].Todo.Completed)
+	assert.Nil(t, pushed.Results[1].Todo.DueAt)
+	assert.Equal(t, models.SyncRejected, pushed.Results[2].Status)
+
+	w = serve(server, "GET", "/sync", "")
+	require.Equal(t, 200, w.Code, w.Body.String())
+
+	var pulled models.SyncPullResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pulled))
+	require.Len(t, pulled.Changes, 1)
+	assert.Equal(t, id, pulled.Changes[0].Todo.ID)
+	assert.Equal(t, int64(2), pulled.Changes[0].Todo.Version)
+	assert.NotEmpty(t, pulled.Token)
+	assert.False(t, pulled.HasMore)
+
+	w = serve(server, "POST", "/sync", `{"changes":[{"op":"delete","id":"`+id+`","baseVersion":2}]}`)
+	require.Equal(t, 200, w.Code, w.Body.String())
+
+	w = serve(server, "GET", "/sync?since="+pulled.Token, "")
+	require.Equal(t, 200, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"deleted":{"id":"`+id+`"`)
+}
+
+func TestSyncHandler_Errors(t *testing.T) {
+	server, changes := syncServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"BadToken", "GET", "/sync?since=abc", "", 400},
+		{"BadLimit", "GET", "/sync?limit=0", "", 400},
+		{"LimitTooLarge", "GET", "/sync?limit=1001", "", 400},
+		{"BadJSON", "POST", "/sync", `{"changes":`, 400},
+		{"UnknownStrategy", "POST", "/sync", `{"strategy":"merge","changes":[]}`, 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("Expired", func(t *testing.T) {
+		id := uuid.NewString()
+		w := serve(server, "POST", "/sync", `{"changes":[
+			{"op":"create","id":"`+id+`","fields":{"taskName":"test"}},
+			{"op":"delete","id":"`+id+`","baseVersion":1}
+		]}`)
+		require.Equal(t, 200, w.Code, w.Body.String())
+
+		_, err := changes.PurgeTombstones(t.Context(), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		w = serve(server, "GET", "/sync?since=1", "")
+		assert.Equal(t, 410, w.Code, w.Body.String())
+	})
+}
@@ -1,20 +1,32 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"todo-api/internal/models"
+	"todo-api/internal/quickadd"
 	"todo-api/internal/repository"
 	"todo-api/internal/services"
 )
 
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
 type TodoHandler struct {
 	service services.TodoService
+	now     func() time.Time
 }
 
 func NewTodoHandler(service services.TodoService) *TodoHandler {
 	return &TodoHandler{
 		service: service,
+		now:     time.Now,
 	}
 }
 
@@ -27,27 +39,29 @@ func NewTodoHandler(service services.TodoService) *TodoHandler {
 // @Success 201 {object} models.Todo
 // @Failure 400 {object} map[string]string "Неверный формат ID"
 // @Failure 409 {object} map[string]string "Задача с таким айди уже существует"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
 // @Router /todos [post]
 func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	var request models.CreateTodoRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(400, gin.H{"error": "неверный JSON"})
+		respondError(c, 400, "неверный JSON")
 		return
 	}
 
-	task, err := h.service.CreateTodo(&request)
+	task, err := h.service.CreateTodo(c.Request.Context(), &request)
 	if err != nil {
 		switch err {
-		case repository.ErrEmptyID, repository.ErrEmptyData, repository.ErrEmptyTask, repository.ErrEmptyName:
-			c.JSON(400, gin.H{"error": err.Error()})
+		case repository.ErrEmptyID, repository.ErrEmptyData, repository.ErrEmptyTask, repository.ErrEmptyName, repository.ErrInvalidPriority:
+			respondError(c, 400, err.Error())
 			return
 		case repository.ErrAlreadyExist:
-			c.JSON(409, gin.H{"error": err.Error()})
+			respondError(c, 409, err.Error())
 			return
 		default:
-			c.JSON(500, gin.H{"error": "внутренняя ошибка сервера"})
+			respondServerError(c, err)
 			return
 		}
 	}
@@ -63,23 +77,25 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 // @Success 200 {object} models.Todo
 // @Failure 400 {object} map[string]string "Неверный формат ID"
 // @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
 // @Router /todos/{id} [get]
 func (h *TodoHandler) GetById(c *gin.Context) {
 	id := c.Param("id")
 
-	task, err := h.service.GetById(id)
+	task, err := h.service.GetById(c.Request.Context(), id)
 
 	if err != nil {
 		switch err {
 		case repository.ErrEmptyID:
-			c.JSON(400, gin.H{"error": err.Error()})
+			respondError(c, 400, err.Error())
 			return
 		case repository.ErrInvalidID:
-			c.JSON(404, gin.H{"error": err.Error()})
+			respondError(c, 404, err.Error())
 			return
 		default:
-			c.JSON(500, gin.H{"error": "внутренняя ошибка сервера"})
+			respondServerError(c, err)
 			return
 		}
 	}
@@ -97,29 +113,31 @@ func (h *TodoHandler) GetById(c *gin.Context) {
 // @Success 200 {object} models.Todo
 // @Failure 400 {object} map[string]string "Неверный формат ID или данных для обновления"
 // @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
 // @Router /todos/{id} [patch]
 func (h *TodoHandler) Update(c *gin.Context) {
 	id := c.Param("id")
 	var updateData models.UpdateTodoRequest
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(400, gin.H{"error": "неверный JSON"})
+		respondError(c, 400, "неверный JSON")
 		return
 	}
 
-	task, err := h.service.UpdateTodo(id, &updateData)
+	task, err := h.service.UpdateTodo(c.Request.Context(), id, &updateData)
 
 	if err != nil {
 		switch err {
-		case repository.ErrEmptyID, repository.ErrEmptyData, repository.ErrEmptyName:
-			c.JSON(400, gin.H{"error": err.Error()})
+		case repository.ErrEmptyID, repository.ErrEmptyData, repository.ErrEmptyName, repository.ErrInvalidPriority, repository.ErrDueAtConflict:
+			respondError(c, 400, err.Error())
 			return
 		case repository.ErrInvalidID:
-			c.JSON(404, gin.H{"error": err.Error()})
+			respondError(c, 404, err.Error())
 			return
 		default:
-			c.JSON(500, gin.H{"error": "внутренняя ошибка сервера"})
+			respondServerError(c, err)
 			return
 		}
 	}
@@ -135,23 +153,25 @@ func (h *TodoHandler) Update(c *gin.Context) {
 // @Success 204 "Задача успешно удалена"
 // @Failure 400 {object} map[string]string "Неверный формат ID"
 // @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
 // @Router /todos/{id} [delete]
 func (h *TodoHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	err := h.service.DeleteTodo(id)
+	err := h.service.DeleteTodo(c.Request.Context(), id)
 
 	if err != nil {
 		switch err {
 		case repository.ErrEmptyID:
-			c.JSON(400, gin.H{"error": err.Error()})
+			respondError(c, 400, err.Error())
 			return
 		case repository.ErrInvalidID:
-			c.JSON(404, gin.H{"error": err.Error()})
+			respondError(c, 404, err.Error())
 			return
 		default:
-			c.JSON(500, gin.H{"error": "внутренняя ошибка сервера"})
+			respondServerError(c, err)
 			return
 		}
 	}
@@ -160,19 +180,210 @@ func (h *TodoHandler) Delete(c *gin.Context) {
 }
 
 // @Summary Получить все задачи
-// @Description Получения списка всех задач
+// @Description Получения списка задач в порядке создания. Фильтры объединяются по «И».
 // @Tags todos
 // @Produce json
+// @Param completed query bool false "Только выполненные или только невыполненные"
+// @Param priority query string false "Приоритет" Enums(low, medium, high)
+// @Param project query string false "Проект"
+// @Param tag query string false "Тег"
+// @Param dueBefore query string false "Срок раньше этого момента, RFC 3339"
+// @Param dueAfter query string false "Срок не раньше этого момента, RFC 3339"
 // @Success 200 {array} models.Todo
+// @Failure 400 {object} map[string]string "Неверный фильтр"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
 // @Router /todos [get]
 func (h *TodoHandler) GetAllTask(c *gin.Context) {
-	tasks, err := h.service.GetAllTodos()
-
+	filter, err := parseTodoFilter(c)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "внутренняя ошибка сервера"})
+		respondError(c, 400, err.Error())
 		return
 	}
 
+	tasks, err := h.service.GetAllTodos(c.Request.Context(), filter)
+
+	if err != nil {
+		switch err {
+		case repository.ErrInvalidPriority:
+			respondError(c, 400, err.Error())
+			return
+		default:
+			respondServerError(c, err)
+			return
+		}
+	}
+
 	c.JSON(200, tasks)
 }
+
+var (
+	errInvalidCompleted = errors.New("completed должен быть true или false")
+	errInvalidDueBefore = errors.New("dueBefore должен быть датой в формате RFC 3339")
+	errInvalidDueAfter  = errors.New("dueAfter должен быть датой в формате RFC 3339")
+)
+
+// parseTodoFilter читает фильтр списка из query. Без параметров возвращает nil.
+func parseTodoFilter(c *gin.Context) (*models.TodoFilter, error) {
+	var filter models.TodoFilter
+	empty := true
+
+	if value := c.Query("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errInvalidCompleted
+		}
+		filter.Completed = &completed
+		empty = false
+	}
+
+	for param, target := range map[string]*string{
+		"priority": &filter.Priority,
+		"project":  &filter.Project,
+		"tag":      &filter.Tag,
+	} {
+		if value := c.Query(param); value != "" {
+			*target = value
+			empty = false
+		}
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+		err    error
+	}{
+		{"dueBefore", &filter.DueBefore, errInvalidDueBefore},
+		{"dueAfter", &filter.DueAfter, errInvalidDueAfter},
+	} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, param.err
+		}
+		*param.target = &parsed
+		empty = false
+	}
+
+	if empty {
+		return nil, nil
+	}
+
+	return &filter, nil
+}
+
+// @Summary Поиск задач
+// @Description Полнотекстовый поиск по названию и описанию. Слова через пробел должны встретиться все, "текст в кавычках" ищется как фраза, слово* — по префиксу. Совпадения в highlight обрамлены тегом <mark>.
+// @Tags todos
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param limit query int false "Количество результатов, от 1 до 100" default(20)
+// @Success 200 {array} models.SearchResult
+// @Failure 400 {object} map[string]string "Пустой запрос или неверный limit"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /todos/search [get]
+func (h *TodoHandler) Search(c *gin.Context) {
+	limit := defaultSearchLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			respondError(c, 400, "limit должен быть числом от 1 до 100")
+			return
+		}
+		limit = parsed
+	}
+
+	results, err := h.service.SearchTodos(c.Request.Context(), c.Query("q"), limit)
+
+	if err != nil {
+		switch err {
+		case repository.ErrEmptyQuery:
+			respondError(c, 400, err.Error())
+			return
+		default:
+			respondServerError(c, err)
+			return
+		}
+	}
+
+	c.JSON(200, results)
+}
+
+// @Summary Быстро создать задачу
+// @Description Разбирает строку вроде "Pay invoice tomorrow 5pm !high #finance @acme": !high, !medium, !low — приоритет, #тег, @проект, относительные даты и время на английском и русском ("next monday", "in 3 days", "через неделю", "в 5 вечера"). Дата без времени означает 23:59. С dryRun=true задача не создаётся, а возвращаются только разобранные поля для подтверждения.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param request body models.QuickAddRequest true "Строка быстрого ввода"
+// @Param dryRun query bool false "Только разобрать строку, не создавая задачу"
+// @Success 200 {object} models.QuickAddResponse "Результат разбора при dryRun=true"
+// @Success 201 {object} models.QuickAddResponse
+// @Failure 400 {object} map[string]string "Пустой текст, нет названия или неизвестный часовой пояс"
+// @Failure 409 {object} map[string]string "Задача с таким айди уже существует"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /todos/quick [post]
+func (h *TodoHandler) QuickAdd(c *gin.Context) {
+	var request models.QuickAddRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, "неверный JSON")
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(c, 400, "dryRun должен быть true или false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	now := h.now()
+	if request.Timezone != "" {
+		location, err := time.LoadLocation(request.Timezone)
+		if err != nil {
+			respondError(c, 400, "неизвестный часовой пояс")
+			return
+		}
+		now = now.In(location)
+	}
+
+	parsed, err := quickadd.Parse(request.Text, now)
+	if err != nil {
+		respondError(c, 400, err.Error())
+		return
+	}
+
+	if dryRun {
+		c.JSON(200, models.QuickAddResponse{Parsed: *parsed})
+		return
+	}
+
+	task, err := h.service.CreateTodo(c.Request.Context(), parsed)
+	if err != nil {
+		switch err {
+		case repository.ErrEmptyID, repository.ErrEmptyData, repository.ErrEmptyTask, repository.ErrEmptyName, repository.ErrInvalidPriority:
+			respondError(c, 400, err.Error())
+			return
+		case repository.ErrAlreadyExist:
+			respondError(c, 409, err.Error())
+			return
+		default:
+			respondServerError(c, err)
+			return
+		}
+	}
+
+	c.JSON(201, models.QuickAddResponse{Parsed: *parsed, Todo: task})
+}
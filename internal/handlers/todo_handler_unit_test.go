@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 	"todo-api/internal/models"
 	"todo-api/internal/repository"
 
@@ -16,31 +19,55 @@ import (
 type MockService struct {
 	createTodoFunc  func(req *models.CreateTodoRequest) (*models.Todo, error)
 	getByIdFunc     func(id string) (*models.Todo, error)
-	getAllTodosFunc func() ([]*models.Todo, error)
+	getAllTodosFunc func(filter *models.TodoFilter) ([]*models.Todo, error)
+	exportTodosFunc func(filter *models.TodoFilter, fn func(task *models.Todo) error) error
 	updateTodoFunc  func(id string, req *models.UpdateTodoRequest) (*models.Todo, error)
+	replaceTodoFunc func(id string, req *models.ReplaceTodoRequest) (*models.Todo, bool, error)
 	deleteTodoFunc  func(id string) error
+	searchTodosFunc func(query string, limit int) ([]*models.SearchResult, error)
+	importTodosFunc func(lines []models.ImportLine, dryRun bool) (*models.ImportReport, error)
 }
 
-func (m *MockService) CreateTodo(req *models.CreateTodoRequest) (*models.Todo, error) {
+func (m *MockService) CreateTodo(ctx context.Context, req *models.CreateTodoRequest) (*models.Todo, error) {
 	return m.createTodoFunc(req)
 }
 
-func (m *MockService) GetById(id string) (*models.Todo, error) {
+func (m *MockService) GetById(ctx context.Context, id string) (*models.Todo, error) {
 	return m.getByIdFunc(id)
 }
 
-func (m *MockService) GetAllTodos() ([]*models.Todo, error) {
-	return m.getAllTodosFunc()
+func (m *MockService) GetAllTodos(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	return m.getAllTodosFunc(filter)
 }
 
-func (m *MockService) UpdateTodo(id string, req *models.UpdateTodoRequest) (*models.Todo, error) {
+func (m *MockService) ExportTodos(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	return m.exportTodosFunc(filter, fn)
+}
+
+func (m *MockService) ListTodos(ctx context.Context, filter *models.TodoFilter, pageSize int, pageToken string) (*models.TodoPage, error) {
+	return nil, errors.New("не реализовано")
+}
+
+func (m *MockService) UpdateTodo(ctx context.Context, id string, req *models.UpdateTodoRequest) (*models.Todo, error) {
 	return m.updateTodoFunc(id, req)
 }
 
-func (m *MockService) DeleteTodo(id string) error {
+func (m *MockService) ReplaceTodo(ctx context.Context, id string, req *models.ReplaceTodoRequest) (*models.Todo, bool, error) {
+	return m.replaceTodoFunc(id, req)
+}
+
+func (m *MockService) DeleteTodo(ctx context.Context, id string) error {
 	return m.deleteTodoFunc(id)
 }
 
+func (m *MockService) SearchTodos(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return m.searchTodosFunc(query, limit)
+}
+
+func (m *MockService) ImportTodos(ctx context.Context, lines []models.ImportLine, dryRun bool) (*models.ImportReport, error) {
+	return m.importTodosFunc(lines, dryRun)
+}
+
 func TestTodoHadler_Create(t *testing.T) {
 	mock := &MockService{
 		createTodoFunc: func(req *models.CreateTodoRequest) (*models.Todo, error) {
@@ -525,7 +552,7 @@ func TestTodoHandler_GetAllTask(t *testing.T) {
 	todos := []*models.Todo{expectedTodo1, expectedTodo2}
 
 	mock := &MockService{
-		getAllTodosFunc: func() ([]*models.Todo, error) {
+		getAllTodosFunc: func(filter *models.TodoFilter) ([]*models.Todo, error) {
 			return todos, nil
 		},
 	}
@@ -556,10 +583,73 @@ func TestTodoHandler_GetAllTask(t *testing.T) {
 	assert.Equal(t, "test description 2", *response[1].Description)
 }
 
+func TestTodoHandler_GetAllTask_Filter(t *testing.T) {
+	var got *models.TodoFilter
+	mock := &MockService{
+		getAllTodosFunc: func(filter *models.TodoFilter) ([]*models.Todo, error) {
+			got = filter
+			return nil, nil
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos?completed=false&priority=high&project=acme&tag=finance&dueBefore=2026-11-01T00:00:00%2B03:00", nil)
+
+	handler.GetAllTask(c)
+
+	assert.Equal(t, 200, w.Code)
+	if assert.NotNil(t, got) {
+		assert.False(t, *got.Completed)
+		assert.Equal(t, "high", got.Priority)
+		assert.Equal(t, "acme", got.Project)
+		assert.Equal(t, "finance", got.Tag)
+		assert.Equal(t, "2026-10-31T21:00:00Z", got.DueBefore.UTC().Format(time.RFC3339))
+		assert.Nil(t, got.DueAfter)
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/todos", nil)
+	handler.GetAllTask(c)
+	assert.Nil(t, got, "без параметров фильтр не передаётся")
+}
+
+func TestTodoHandler_GetAllTask_InvalidFilter(t *testing.T) {
+	mock := &MockService{
+		getAllTodosFunc: func(filter *models.TodoFilter) ([]*models.Todo, error) {
+			return nil, repository.ErrInvalidPriority
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"completed=maybe", "completed должен быть true или false"},
+		{"dueAfter=tomorrow", "dueAfter должен быть датой в формате RFC 3339"},
+		{"priority=urgent", "приоритет должен быть low, medium или high"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/todos?"+tt.query, nil)
+
+		handler.GetAllTask(c)
+
+		assert.Equal(t, 400, w.Code, tt.query)
+		assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.err), w.Body.String())
+	}
+}
+
 func TestTodoHandler_GetAllTask_InternalServerError(t *testing.T) {
 
 	mock := &MockService{
-		getAllTodosFunc: func() ([]*models.Todo, error) {
+		getAllTodosFunc: func(filter *models.TodoFilter) ([]*models.Todo, error) {
 			return nil, errors.New("внутренняя ошибка сервера")
 		},
 	}
@@ -579,7 +669,7 @@ func TestTodoHandler_GetAllTask_InternalServerError(t *testing.T) {
 
 func TestTodoHandler_GetAllTask_EmptyList(t *testing.T) {
 	mock := &MockService{
-		getAllTodosFunc: func() ([]*models.Todo, error) {
+		getAllTodosFunc: func(filter *models.TodoFilter) ([]*models.Todo, error) {
 			return []*models.Todo{}, nil
 		},
 	}
@@ -600,3 +690,186 @@ func TestTodoHandler_GetAllTask_EmptyList(t *testing.T) {
 	assert.Empty(t, response)
 	assert.Len(t, response, 0)
 }
+
+func TestTodoHandler_GetById_DeadlineExceeded(t *testing.T) {
+	mock := &MockService{
+		getByIdFunc: func(id string) (*models.Todo, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	c.Request = httptest.NewRequest("GET", "/todos/1", nil)
+
+	handler.GetById(c)
+
+	assert.Equal(t, 504, w.Code)
+}
+
+func TestTodoHandler_GetAllTask_Canceled(t *testing.T) {
+	mock := &MockService{
+		getAllTodosFunc: func(filter *models.TodoFilter) ([]*models.Todo, error) {
+			return nil, fmt.Errorf("запрос прерван: %w", context.Canceled)
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos", nil)
+
+	handler.GetAllTask(c)
+
+	assert.Equal(t, 499, w.Code)
+}
+
+func TestTodoHandler_Search(t *testing.T) {
+	var gotQuery string
+	var gotLimit int
+
+	mock := &MockService{
+		searchTodosFunc: func(query string, limit int) ([]*models.SearchResult, error) {
+			gotQuery, gotLimit = query, limit
+			return []*models.SearchResult{{
+				Todo:      models.Todo{ID: "1", TaskName: "Купить молоко"},
+				Rank:      0.5,
+				Highlight: models.SearchHighlight{TaskName: "Купить <mark>молоко</mark>"},
+			}}, nil
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos/search?q=%D0%BC%D0%BE%D0%BB*", nil)
+
+	handler.Search(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "мол*", gotQuery)
+	assert.Equal(t, defaultSearchLimit, gotLimit)
+
+	var response []*models.SearchResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, "1", response[0].ID)
+	assert.Equal(t, "Купить <mark>молоко</mark>", response[0].Highlight.TaskName)
+}
+
+func TestTodoHandler_Search_InvalidLimit(t *testing.T) {
+	handler := NewTodoHandler(&MockService{})
+
+	for _, limit := range []string{"0", "101", "abc"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/todos/search?q=test&limit="+limit, nil)
+
+		handler.Search(c)
+
+		assert.Equal(t, 400, w.Code, "limit=%s", limit)
+	}
+}
+
+func TestTodoHandler_Search_ErrEmptyQuery(t *testing.T) {
+	mock := &MockService{
+		searchTodosFunc: func(query string, limit int) ([]*models.SearchResult, error) {
+			return nil, repository.ErrEmptyQuery
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/todos/search", nil)
+
+	handler.Search(c)
+
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"error":"передан пустой поисковый запрос"}`, w.Body.String())
+}
+
+func TestTodoHandler_QuickAdd(t *testing.T) {
+	var created *models.CreateTodoRequest
+	mock := &MockService{
+		createTodoFunc: func(req *models.CreateTodoRequest) (*models.Todo, error) {
+			created = req
+			return &models.Todo{ID: "1", TaskName: req.TaskName, DueAt: req.DueAt, Priority: req.Priority, Tags: req.Tags, Project: req.Project}, nil
+		},
+	}
+
+	handler := NewTodoHandler(mock)
+	handler.now = func() time.Time { return time.Date(2026, 10, 14, 7, 30, 0, 0, time.UTC) }
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	reqBody := `{"text":"Pay invoice tomorrow 5pm !high #finance @acme","timezone":"Europe/Moscow"}`
+	c.Request = httptest.NewRequest("POST", "/todos/quick", strings.NewReader(reqBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.QuickAdd(c)
+
+	assert.Equal(t, 201, w.Code)
+	assert.NotNil(t, created)
+
+	var response models.QuickAddResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Pay invoice", response.Parsed.TaskName)
+	assert.Equal(t, "2026-10-15T17:00:00+03:00", response.Parsed.DueAt.Format(time.RFC3339))
+	assert.Equal(t, models.PriorityHigh, response.Parsed.Priority)
+	assert.Equal(t, []string{"finance"}, response.Parsed.Tags)
+	assert.Equal(t, "acme", response.Parsed.Project)
+	assert.Equal(t, "1", response.Todo.ID)
+}
+
+func TestTodoHandler_QuickAdd_DryRun(t *testing.T) {
+	handler := NewTodoHandler(&MockService{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	reqBody := `{"text":"Созвон через неделю"}`
+	c.Request = httptest.NewRequest("POST", "/todos/quick?dryRun=true", strings.NewReader(reqBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.QuickAdd(c)
+
+	assert.Equal(t, 200, w.Code)
+
+	var response models.QuickAddResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Созвон", response.Parsed.TaskName)
+	assert.NotNil(t, response.Parsed.DueAt)
+	assert.Nil(t, response.Todo)
+}
+
+func TestTodoHandler_QuickAdd_BadRequest(t *testing.T) {
+	handler := NewTodoHandler(&MockService{})
+
+	tests := []struct {
+		url  string
+		body string
+		err  string
+	}{
+		{"/todos/quick", `{"text":"tomorrow 5pm !high"}`, "в тексте нет названия задачи"},
+		{"/todos/quick", `{"text":"Созвон","timezone":"Mars/Olympus"}`, "неизвестный часовой пояс"},
+		{"/todos/quick?dryRun=maybe", `{"text":"Созвон"}`, "dryRun должен быть true или false"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.QuickAdd(c)
+
+		assert.Equal(t, 400, w.Code, tt.body)
+		assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.err), w.Body.String())
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+)
+
+const maxDeliveryLimit = 100
+
+type WebhookHandler struct {
+	service services.WebhookService
+}
+
+func NewWebhookHandler(service services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// @Summary Создать вебхук
+// @Description Подписка на события задач. Пустой events — все события. Каждый запрос к вебхуку подписан:
+// @Description X-Webhook-Signature: sha256=HMAC-SHA256(secret, X-Webhook-Timestamp + "." + тело) в hex.
+// @Description Секрет возвращается только в этом ответе; без secret в запросе он генерируется сервером.
+// @Description Доставки идут параллельно и повторяются при ошибках, поэтому порядок событий не гарантирован: сравнивайте occurredAt.
+// @Description Адреса во внутренней сети (localhost, частные и link-local) не принимаются, перенаправления не выполняются:
+// @Description ответ 3xx считается неудачной попыткой.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.CreateWebhookRequest true "Адрес и события"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} map[string]string "Неверный адрес, адрес во внутренней сети или неизвестное событие"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /webhooks [post]
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var request models.CreateWebhookRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, "неверный JSON")
+		return
+	}
+
+	hook, err := h.service.CreateWebhook(c.Request.Context(), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidWebhookURL), errors.Is(err, repository.ErrPrivateWebhookURL),
+			errors.Is(err, repository.ErrUnknownEvent):
+			respondError(c, 400, err.Error())
+		default:
+			respondServerError(c, err)
+		}
+		return
+	}
+
+	c.JSON(201, hook)
+}
+
+// @Summary Получить вебхуки
+// @Description Список вебхуков в порядке создания, без секретов
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /webhooks [get]
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	c.JSON(200, hooks)
+}
+
+// @Summary Получить вебхук
+// @Description Вебхук по айди, без секрета
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Success 200 {object} models.Webhook
+// @Failure 404 {object} map[string]string "Вебхук не найден"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) Get(c *gin.Context) {
+	hook, err := h.service.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondNotFound(c, err)
+		return
+	}
+
+	c.JSON(200, hook)
+}
+
+// @Summary Удалить вебхук
+// @Description Удаление вебхука вместе с журналом доставок. Неотправленные события ему больше не отправляются.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Success 204 "Вебхук удалён"
+// @Failure 404 {object} map[string]string "Вебхук не найден"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	if err := h.service.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondNotFound(c, err)
+		return
+	}
+
+	c.Status(204)
+}
+
+// @Summary Журнал доставок
+// @Description Доставки событий вебхуку, новые первыми: число попыток, код ответа и ошибка последней попытки, срок следующей.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Param status query string false "Статус доставки" Enums(pending, succeeded, failed)
+// @Param limit query int false "Количество доставок, от 1 до 100" default(50)
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} map[string]string "Неверный статус или limit"
+// @Failure 404 {object} map[string]string "Вебхук не найден"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	filter := &models.DeliveryFilter{Status: c.Query("status")}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			respondError(c, 400, "limit должен быть числом от 1 до 100")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), c.Param("id"), filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidDeliveryStatus) {
+			respondError(c, 400, err.Error())
+			return
+		}
+		h.respondNotFound(c, err)
+		return
+	}
+
+	c.JSON(200, deliveries)
+}
+
+// @Summary Отправить доставку повторно
+// @Description Ставит доставку в очередь на немедленную отправку с полным запасом попыток. Отправляется то же событие
+// @Description с тем же X-Webhook-Delivery, так что получатель может отбросить повтор.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Param deliveryId path string true "ID доставки"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 404 {object} map[string]string "Вебхук или доставка не найдены"
+// @Failure 429 {object} map[string]string "Превышен лимит запросов"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 504 {object} map[string]string "Превышено время ожидания ответа от хранилища"
+// @Router /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	delivery, err := h.service.Redeliver(c.Request.Context(), c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		h.respondNotFound(c, err)
+		return
+	}
+
+	c.JSON(202, delivery)
+}
+
+func (h *WebhookHandler) respondNotFound(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrWebhookNotFound), errors.Is(err, repository.ErrDeliveryNotFound):
+		respondError(c, 404, err.Error())
+	default:
+		respondServerError(c, err)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func webhookServer(t *testing.T) (http.Handler, *repository.StorageRepository, *repository.MemoryWebhookRepository) {
+	t.Helper()
+
+	todos := repository.Constructor()
+	hooks := repository.NewMemoryWebhookRepository(todos)
+	handler := NewWebhookHandler(services.NewWebhookService(hooks))
+
+	router := gin.New()
+	router.POST("/webhooks", handler.Create)
+	router.GET("/webhooks", handler.List)
+	router.GET("/webhooks/:id", handler.Get)
+	router.DELETE("/webhooks/:id", handler.Delete)
+	router.GET("/webhooks/:id/deliveries", handler.Deliveries)
+	router.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", handler.Redeliver)
+
+	return router, todos, hooks
+}
+
+func serve(server http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebhookHandler_CRUD(t *testing.T) {
+	server, _, _ := webhookServer(t)
+
+	w := serve(server, "POST", "/webhooks", `{"url":"https://example.com/hooks","events":["todo.completed"]}`)
+	require.Equal(t, 201, w.Code, w.Body.String())
+
+	var created models.Webhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, []string{models.EventTodoCompleted}, created.Events)
+
+	w = serve(server, "GET", "/webhooks/"+created.ID, "")
+	require.Equal(t, 200, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	w = serve(server, "GET", "/webhooks", "")
+	require.Equal(t, 200, w.Code)
+	var list []models.Webhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Empty(t, list[0].Secret)
+
+	assert.Equal(t, 204, serve(server, "DELETE", "/webhooks/"+created.ID, "").Code)
+	assert.Equal(t, 404, serve(server, "DELETE", "/webhooks/"+created.ID, "").Code)
+	assert.Equal(t, 404, serve(server, "GET", "/webhooks/"+created.ID, "").Code)
+	assert.Equal(t, "[]", serve(server, "GET", "/webhooks", "").Body.String())
+}
+
+func TestWebhookHandler_CreateErrors(t *testing.T) {
+	server, _, _ := webhookServer(t)
+
+	for name, body := range map[string]string{
+		"BadJSON":      `{"url":`,
+		"RelativeURL":  `{"url":"/hooks"}`,
+		"UnknownEvent": `{"url":"https://example.com","events":["todo.moved"]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, 400, serve(server, "POST", "/webhooks", body).Code)
+		})
+	}
+}
+
+func TestWebhookHandler_Deliveries(t *testing.T) {
+	server, todos, hooks := webhookServer(t)
+
+	w := serve(server, "POST", "/webhooks", `{"url":"https://example.com/hooks"}`)
+	require.Equal(t, 201, w.Code)
+	var hook models.Webhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hook))
+
+	_, err := services.NewTodoService(todos).CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+	_, err = hooks.DispatchEvents(t.Context(), 10, time.Now())
+	require.NoError(t, err)
+
+	w = serve(server, "GET", "/webhooks/"+hook.ID+"/deliveries?status=pending&limit=10", "")
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.EventTodoCreated, deliveries[0].EventType)
+	assert.Contains(t, string(deliveries[0].Payload), `"taskName":"test"`)
+
+	assert.Equal(t, "[]", serve(server, "GET", "/webhooks/"+hook.ID+"/deliveries?status=failed", "").Body.String())
+	assert.Equal(t, 400, serve(server, "GET", "/webhooks/"+hook.ID+"/deliveries?status=lost", "").Code)
+	assert.Equal(t, 400, serve(server, "GET", "/webhooks/"+hook.ID+"/deliveries?limit=0", "").Code)
+	assert.Equal(t, 404, serve(server, "GET", "/webhooks/missing/deliveries", "").Code)
+
+	redeliver := "/webhooks/" + hook.ID + "/deliveries/" + deliveries[0].ID + "/redeliver"
+	w = serve(server, "POST", redeliver, "")
+	require.Equal(t, 202, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+
+	assert.Equal(t, 404, serve(server, "POST", "/webhooks/"+hook.ID+"/deliveries/missing/redeliver", "").Code)
+	assert.Equal(t, 404, serve(server, "POST", "/webhooks/missing/deliveries/"+deliveries[0].ID+"/redeliver", "").Code)
+}
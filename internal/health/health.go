@@ -0,0 +1,90 @@
+// Package health собирает проверки готовности сервиса к приёму трафика.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrShuttingDown = errors.New("сервис останавливается")
+
+// Check — одна проверка готовности, например доступность базы данных.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+type Result struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Ready  bool     `json:"ready"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker выполняет проверки параллельно, ограничивая каждую таймаутом.
+// После MarkShuttingDown сервис всегда считается неготовым.
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{
+			Ready:  false,
+			Checks: []Result{{Name: "shutdown", Error: ErrShuttingDown.Error()}},
+		}
+	}
+
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = Result{Name: check.Name}
+			if err := c.run(ctx, check); err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Checks: results}
+	for _, result := range results {
+		if result.Error != "" {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	return check.Func(ctx)
+}
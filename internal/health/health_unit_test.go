@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	checker := NewChecker(time.Second,
+		Check{Name: "database", Func: func(ctx context.Context) error { return nil }},
+		Check{Name: "workers", Func: func(ctx context.Context) error { return nil }},
+	)
+
+	report := checker.Ready(context.Background())
+
+	assert.True(t, report.Ready)
+	assert.Equal(t, []Result{{Name: "database"}, {Name: "workers"}}, report.Checks)
+}
+
+func TestChecker_Ready_Failed(t *testing.T) {
+	checker := NewChecker(time.Second,
+		Check{Name: "database", Func: func(ctx context.Context) error { return errors.New("нет соединения") }},
+		Check{Name: "workers", Func: func(ctx context.Context) error { return nil }},
+	)
+
+	report := checker.Ready(context.Background())
+
+	assert.False(t, report.Ready)
+	assert.Equal(t, "нет соединения", report.Checks[0].Error)
+	assert.Empty(t, report.Checks[1].Error)
+}
+
+func TestChecker_Ready_Timeout(t *testing.T) {
+	checker := NewChecker(10*time.Millisecond,
+		Check{Name: "database", Func: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	report := checker.Ready(context.Background())
+
+	assert.False(t, report.Ready)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestChecker_MarkShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	require.True(t, checker.Ready(context.Background()).Ready)
+
+	checker.MarkShuttingDown()
+
+	report := checker.Ready(context.Background())
+	assert.False(t, report.Ready)
+	assert.Equal(t, ErrShuttingDown.Error(), report.Checks[0].Error)
+}
@@ -0,0 +1,216 @@
+// Package ical записывает задачи в iCalendar (RFC 5545) компонентами VTODO и
+// читает VTODO из загруженных файлов.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+)
+
+const ContentType = "text/calendar; charset=utf-8"
+
+// ProductID попадает в PRODID календаря.
+const ProductID = "-//todo-api//todo-api//RU"
+
+// PropertyProject хранит проект задачи. Стандартного свойства для него нет,
+// а в CATEGORIES он смешался бы с тегами.
+const PropertyProject = "X-TODO-PROJECT"
+
+var ErrInvalidCalendar = errors.New("файл не является календарём iCalendar")
+
+// maxLineOctets — длина строки без CRLF, после которой RFC 5545 требует перенос.
+const maxLineOctets = 75
+
+const dateTimeLayout = "20060102T150405Z"
+
+// Encoder пишет календарь с задачами. Как и кодировщики выгрузки, до первого
+// Encode или Close он ничего не пишет в w.
+type Encoder struct {
+	buf     *bufio.Writer
+	name    string
+	stamp   time.Time
+	started bool
+	// err — первая ошибка записи, например после обрыва соединения. Encode
+	// возвращает её, чтобы выгрузка не продолжалась впустую.
+	err error
+}
+
+// NewEncoder создаёт календарь с названием name. stamp — время выгрузки,
+// оно записывается в DTSTAMP каждой задачи.
+func NewEncoder(w io.Writer, name string, stamp time.Time) *Encoder {
+	return &Encoder{buf: bufio.NewWriter(w), name: name, stamp: stamp}
+}
+
+func (e *Encoder) start() {
+	if e.started {
+		return
+	}
+	e.started = true
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", ProductID)
+	e.line("CALSCALE", "GREGORIAN")
+	if e.name != "" {
+		e.line("X-WR-CALNAME", escape(e.name))
+	}
+}
+
+func (e *Encoder) Encode(task *models.Todo) error {
+	e.start()
+
+	e.line("BEGIN", "VTODO")
+	e.line("UID", escape(task.ID))
+	e.line("DTSTAMP", formatTime(e.stamp))
+	if !task.CreatedAt.IsZero() {
+		e.line("CREATED", formatTime(task.CreatedAt))
+	}
+	e.line("SUMMARY", escape(task.TaskName))
+	if task.Description != nil && *task.Description != "" {
+		e.line("DESCRIPTION", escape(*task.Description))
+	}
+	if task.DueAt != nil {
+		e.line("DUE", formatTime(*task.DueAt))
+	}
+	if task.Completed {
+		e.line("STATUS", "COMPLETED")
+		e.line("PERCENT-COMPLETE", "100")
+	} else {
+		e.line("STATUS", "NEEDS-ACTION")
+	}
+	if task.CompletedAt != nil {
+		e.line("COMPLETED", formatTime(*task.CompletedAt))
+	}
+	if priority := priorityValue(task.Priority); priority != 0 {
+		e.line("PRIORITY", strconv.Itoa(priority))
+	}
+	if len(task.Tags) > 0 {
+		categories := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			categories[i] = escape(tag)
+		}
+		e.line("CATEGORIES", strings.Join(categories, ","))
+	}
+	if task.Project != "" {
+		e.line(PropertyProject, escape(task.Project))
+	}
+	e.line("END", "VTODO")
+
+	return e.err
+}
+
+// Close дописывает конец календаря и сбрасывает буфер. Календарь без задач
+// тоже корректен.
+func (e *Encoder) Close() error {
+	e.start()
+	e.line("END", "VCALENDAR")
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.buf.Flush()
+}
+
+// line пишет свойство, перенося строку по RFC 5545: не длиннее 75 октетов,
+// продолжение начинается с пробела. Многобайтовые символы не разрываются.
+func (e *Encoder) line(name, value string) {
+	text := name + ":" + value
+	limit := maxLineOctets
+
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(text[cut]) {
+			cut--
+		}
+		e.write(text[:cut] + "\r\n ")
+		text = text[cut:]
+		// Пробел в начале строки продолжения тоже занимает октет.
+		limit = maxLineOctets - 1
+	}
+
+	e.write(text + "\r\n")
+}
+
+func (e *Encoder) write(s string) {
+	if _, err := e.buf.WriteString(s); err != nil && e.err == nil {
+		e.err = err
+	}
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape экранирует значение типа TEXT.
+func escape(value string) string {
+	return escaper.Replace(value)
+}
+
+func unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+
+	return b.String()
+}
+
+// priorityValue переводит приоритет в шкалу RFC 5545: 1 — высший, 9 — низший,
+// 0 — не задан.
+func priorityValue(priority string) int {
+	switch priority {
+	case models.PriorityHigh:
+		return 1
+	case models.PriorityMedium:
+		return 5
+	case models.PriorityLow:
+		return 9
+	default:
+		return 0
+	}
+}
+
+// priorityOf выполняет обратный перевод: 1–4 — high, 5 — medium, 6–9 — low.
+func priorityOf(value string) (string, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 || number > 9 {
+		return "", fmt.Errorf("PRIORITY %q должен быть числом от 0 до 9", value)
+	}
+
+	switch {
+	case number == 0:
+		return "", nil
+	case number < 5:
+		return models.PriorityHigh, nil
+	case number == 5:
+		return models.PriorityMedium, nil
+	default:
+		return models.PriorityLow, nil
+	}
+}
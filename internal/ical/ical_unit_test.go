@@ -0,0 +1,210 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var msk = time.FixedZone("MSK", 3*60*60)
+
+func TestEncoder(t *testing.T) {
+	description := "строка с ; запятой, и\nпереносом"
+	createdAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	dueAt := time.Date(2026, 11, 5, 17, 0, 0, 0, msk)
+	completedAt := time.Date(2026, 10, 3, 18, 30, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf, "Задачи", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, encoder.Encode(&models.Todo{
+		ID: "1", TaskName: "Оплатить счёт", Description: &description, CreatedAt: createdAt,
+		DueAt: &dueAt, Priority: "high", Tags: []string{"finance", "a,b"}, Project: "acme",
+	}))
+	require.NoError(t, encoder.Encode(&models.Todo{
+		ID: "2", TaskName: "Купить молоко", Completed: true, CreatedAt: createdAt, CompletedAt: &completedAt,
+	}))
+	require.NoError(t, encoder.Close())
+
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//todo-api//todo-api//RU",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:Задачи",
+		"BEGIN:VTODO",
+		"UID:1",
+		"DTSTAMP:20261019T120000Z",
+		"CREATED:20261001T090000Z",
+		"SUMMARY:Оплатить счёт",
+		`DESCRIPTION:строка с \; запятой\, и\nпереносом`,
+		"DUE:20261105T140000Z",
+		"STATUS:NEEDS-ACTION",
+		"PRIORITY:1",
+		`CATEGORIES:finance,a\,b`,
+		"X-TODO-PROJECT:acme",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:2",
+		"DTSTAMP:20261019T120000Z",
+		"CREATED:20261001T090000Z",
+		"SUMMARY:Купить молоко",
+		"STATUS:COMPLETED",
+		"PERCENT-COMPLETE:100",
+		"COMPLETED:20261003T183000Z",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), buf.String())
+}
+
+func TestEncoder_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf, "", time.Now())
+	assert.Zero(t, buf.Len(), "до Close ничего не пишется")
+
+	require.NoError(t, encoder.Close())
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:"+ProductID+"\r\nCALSCALE:GREGORIAN\r\nEND:VCALENDAR\r\n", buf.String())
+}
+
+func TestEncoder_Folding(t *testing.T) {
+	name := strings.Repeat("Очень длинное название задачи ", 10)
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf, "", time.Now())
+	require.NoError(t, encoder.Encode(&models.Todo{ID: "1", TaskName: name}))
+	require.NoError(t, encoder.Close())
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+		assert.True(t, utf8.ValidString(line), "перенос не разрывает символы: %q", line)
+	}
+
+	lines, err := Parse(buf.Bytes(), time.UTC)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, strings.TrimSpace(name), lines[0].Todo.TaskName)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("соединение закрыто")
+}
+
+func TestEncoder_WriteError(t *testing.T) {
+	encoder := NewEncoder(failingWriter{}, "", time.Now())
+	todo := &models.Todo{ID: "1", TaskName: strings.Repeat("a", 70)}
+
+	var err error
+	for i := 0; i < 1000 && err == nil; i++ {
+		err = encoder.Encode(todo)
+	}
+	assert.Error(t, err)
+	assert.Error(t, encoder.Close())
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	description := "строка с ; запятой, и\nпереносом"
+	dueAt := time.Date(2026, 11, 5, 14, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf, "Задачи", time.Now())
+	require.NoError(t, encoder.Encode(&models.Todo{
+		ID: "1", TaskName: "Оплатить счёт", Description: &description, Completed: true,
+		DueAt: &dueAt, Priority: "medium", Tags: []string{"finance", "a,b"}, Project: "acme",
+	}))
+	require.NoError(t, encoder.Close())
+
+	lines, err := Parse(buf.Bytes(), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+
+	assert.Equal(t, models.ImportLine{Line: 6, Completed: true, Todo: &models.CreateTodoRequest{
+		TaskName: "Оплатить счёт", Description: &description, DueAt: lines[0].Todo.DueAt,
+		Priority: "medium", Tags: []string{"finance", "a,b"}, Project: "acme",
+	}}, lines[0])
+	assert.True(t, dueAt.Equal(*lines[0].Todo.DueAt))
+}
+
+func TestParse_Clients(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Apple Inc.//iOS 18//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Moscow",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"SUMMARY:Встреча, не задача",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:abc",
+		"SUMMARY:Позвонить ",
+		" маме",
+		"DUE;TZID=Europe/Moscow:20261020T190000",
+		"PRIORITY:3",
+		"CATEGORIES:Семья",
+		"CATEGORIES:phone",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Напоминание",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:Оплатить счёт",
+		"DUE;VALUE=DATE:20261105",
+		"STATUS:COMPLETED",
+		"PRIORITY:7",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		`X-UNKNOWN;X-PARAM="a:b;c":значение`,
+		"SUMMARY:Сломанный срок",
+		"DUE;TZID=Russian Standard Time:20261105T1400",
+		"PRIORITY:срочно",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	lines, err := Parse([]byte(data), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, 9, lines[0].Line)
+	assert.Empty(t, lines[0].Error)
+	assert.Equal(t, "Позвонить маме", lines[0].Todo.TaskName)
+	assert.Nil(t, lines[0].Todo.Description, "описание напоминания не относится к задаче")
+	assert.Equal(t, "2026-10-20T16:00:00Z", lines[0].Todo.DueAt.UTC().Format(time.RFC3339))
+	assert.Equal(t, "high", lines[0].Todo.Priority)
+	assert.Equal(t, []string{"Семья", "phone"}, lines[0].Todo.Tags)
+	assert.False(t, lines[0].Completed)
+
+	assert.True(t, lines[1].Completed)
+	assert.Equal(t, "2026-11-05T23:59:00+03:00", lines[1].Todo.DueAt.Format(time.RFC3339))
+	assert.Equal(t, "low", lines[1].Todo.Priority)
+
+	assert.Equal(t, "Сломанный срок", lines[2].Todo.TaskName)
+	assert.Contains(t, lines[2].Error, "20261105T1400", "в отчёт попадает первая ошибка")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"taskName\nзадача\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:без конца\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nстрока без двоеточия\r\nEND:VCALENDAR\r\n",
+	} {
+		_, err := Parse([]byte(data), msk)
+		assert.ErrorIs(t, err, ErrInvalidCalendar, data)
+	}
+
+	lines, err := Parse([]byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"), msk)
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
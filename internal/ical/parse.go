@@ -0,0 +1,245 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+)
+
+// contentLine — свойство после склейки перенесённых строк. line — номер
+// физической строки, с которой оно начинается.
+type contentLine struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse читает задачи из компонентов VTODO. Остальные компоненты, в том числе
+// VEVENT и вложенные в задачу VALARM, пропускаются. UID не переносится: импорт
+// всегда создаёт новые задачи. Даты без времени означают конец дня в loc, время
+// без часового пояса тоже считается в loc.
+func Parse(data []byte, loc *time.Location) ([]models.ImportLine, error) {
+	lines, err := unfold(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || lines[0].name != "BEGIN" || !strings.EqualFold(lines[0].value, "VCALENDAR") {
+		return nil, ErrInvalidCalendar
+	}
+
+	var result []models.ImportLine
+	var current *models.ImportLine
+	// nested — глубина вложенных в задачу компонентов, их свойства пропускаются.
+	nested := 0
+
+	for _, cl := range lines {
+		component := strings.ToUpper(cl.value)
+
+		switch {
+		case cl.name == "BEGIN" && current == nil && component == "VTODO":
+			current = &models.ImportLine{Line: cl.line, Todo: &models.CreateTodoRequest{}}
+		case cl.name == "BEGIN" && current != nil:
+			nested++
+		case cl.name == "END" && current != nil && nested > 0:
+			nested--
+		case cl.name == "END" && current != nil:
+			if component != "VTODO" {
+				return nil, fmt.Errorf("%w: задача со строки %d не закрыта END:VTODO", ErrInvalidCalendar, current.Line)
+			}
+			result = append(result, *current)
+			current = nil
+		case current != nil && nested == 0:
+			if err := applyProperty(current, cl, loc); err != nil && current.Error == "" {
+				current.Error = err.Error()
+			}
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("%w: задача со строки %d не закрыта END:VTODO", ErrInvalidCalendar, current.Line)
+	}
+
+	return result, nil
+}
+
+func applyProperty(result *models.ImportLine, cl contentLine, loc *time.Location) error {
+	request := result.Todo
+
+	switch cl.name {
+	case "SUMMARY":
+		request.TaskName = strings.TrimSpace(unescape(cl.value))
+	case "DESCRIPTION":
+		if description := unescape(cl.value); description != "" {
+			request.Description = &description
+		}
+	case "DUE":
+		dueAt, err := parseTime(cl, loc)
+		if err != nil {
+			return err
+		}
+		request.DueAt = &dueAt
+	case "STATUS":
+		result.Completed = strings.EqualFold(cl.value, "COMPLETED")
+	case "COMPLETED":
+		result.Completed = true
+	case "PRIORITY":
+		priority, err := priorityOf(strings.TrimSpace(cl.value))
+		if err != nil {
+			return err
+		}
+		request.Priority = priority
+	case "CATEGORIES":
+		for _, tag := range splitList(cl.value) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				request.Tags = append(request.Tags, tag)
+			}
+		}
+	case PropertyProject:
+		request.Project = strings.TrimSpace(unescape(cl.value))
+	}
+
+	return nil
+}
+
+// parseTime понимает время в UTC, время с TZID и «плавающее» время, а также
+// даты с VALUE=DATE. Неизвестный TZID, например имя пояса из Outlook,
+// заменяется на loc.
+func parseTime(cl contentLine, loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(cl.value)
+
+	if len(value) == len("20060102") {
+		date, err := time.ParseInLocation("20060102", value, loc)
+		if err == nil {
+			return date.Add(23*time.Hour + 59*time.Minute), nil
+		}
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		if parsed, err := time.Parse(dateTimeLayout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	if tzid := cl.params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = zone
+		}
+	}
+
+	if parsed, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return parsed, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%s %q должен быть датой iCalendar, например 20261105T140000Z", cl.name, value)
+}
+
+// unfold склеивает перенесённые строки и разбирает их на свойства.
+func unfold(text string) ([]contentLine, error) {
+	var raw []string
+	var starts []int
+
+	for i, line := range strings.Split(strings.TrimPrefix(text, "\ufeff"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(raw) > 0 {
+			raw[len(raw)-1] += line[1:]
+			continue
+		}
+		raw = append(raw, line)
+		starts = append(starts, i+1)
+	}
+
+	lines := make([]contentLine, 0, len(raw))
+
+	for i, line := range raw {
+		cl, ok := splitLine(line)
+		if !ok {
+			return nil, fmt.Errorf("%w: строка %d", ErrInvalidCalendar, starts[i])
+		}
+		cl.line = starts[i]
+		lines = append(lines, cl)
+	}
+
+	return lines, nil
+}
+
+// splitLine разбирает «ИМЯ;ПАРАМЕТР=значение:ЗНАЧЕНИЕ». Двоеточие и точка
+// с запятой внутри кавычек в параметрах не считаются разделителями.
+func splitLine(line string) (contentLine, bool) {
+	quoted := false
+	colon := -1
+
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+
+	if colon <= 0 {
+		return contentLine{}, false
+	}
+
+	parts := splitParams(line[:colon])
+	cl := contentLine{name: strings.ToUpper(parts[0]), value: line[colon+1:]}
+
+	for _, param := range parts[1:] {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		if cl.params == nil {
+			cl.params = make(map[string]string)
+		}
+		cl.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+
+	return cl, cl.name != ""
+}
+
+func splitParams(text string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				parts = append(parts, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, text[start:])
+}
+
+// splitList делит значение-список по запятым, пропуская экранированные.
+func splitList(value string) []string {
+	var items []string
+	start := 0
+
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, unescape(value[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(items, unescape(value[start:]))
+}
@@ -0,0 +1,47 @@
+// Package identity передаёт пользователя, от имени которого выполняется
+// запрос, из HTTP-слоя в сервисы. Пользователя определяет доверенный прокси
+// после аутентификации и передаёт в заголовке; сам сервис пользователей не
+// проверяет.
+package identity
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUserLength ограничивает длину принятого из заголовка пользователя.
+const maxUserLength = 256
+
+type userKey struct{}
+
+// WithUser сохраняет пользователя в контексте.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// User возвращает пользователя из контекста или пустую строку.
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// FromHeader сохраняет в контексте пользователя из значения заголовка.
+// Пустое или слишком длинное значение игнорируется.
+func FromHeader(ctx context.Context, value string) context.Context {
+	user := strings.TrimSpace(value)
+	if user == "" || len(user) > maxUserLength {
+		return ctx
+	}
+	return WithUser(ctx, user)
+}
+
+// Middleware берёт пользователя из заголовка header и кладёт его в контекст
+// запроса.
+func Middleware(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(FromHeader(c.Request.Context(), c.GetHeader(header)))
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package identity
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware("X-User-ID"))
+	router.GET("/", func(c *gin.Context) { c.String(200, User(c.Request.Context())) })
+
+	for header, want := range map[string]string{
+		"":                       "",
+		" alice ":                "alice",
+		strings.Repeat("a", 300): "",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-User-ID", header)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Body.String(), header)
+	}
+}
@@ -0,0 +1,403 @@
+// Package importer разбирает файлы импорта: CSV, JSON-массив, NDJSON, todo.txt
+// и iCalendar.
+// Ошибка в отдельной задаче не прерывает разбор, а попадает в её строку отчёта.
+// Весь файл отвергается, только если его нельзя прочитать целиком.
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/export"
+	"todo-api/internal/ical"
+	"todo-api/internal/models"
+)
+
+const (
+	FormatTodoTxt = "todotxt"
+	FormatICS     = "ics"
+)
+
+// MaxLines ограничивает число задач в одном файле: импорт идёт одной
+// транзакцией и не должен держать её слишком долго.
+const MaxLines = 10000
+
+var ErrUnknownFormat = errors.New("формат должен быть csv, json, ndjson, todotxt или ics")
+var ErrEmptyFile = errors.New("в файле нет задач")
+var ErrTooManyLines = errors.New("в файле больше 10000 задач")
+var ErrNoNameColumn = errors.New("в заголовке CSV нет колонки taskName")
+var ErrInvalidFile = errors.New("файл повреждён")
+
+// FormatOf определяет формат по расширению файла. Пустая строка означает,
+// что формат нужно передать явно.
+func FormatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return export.FormatCSV
+	case ".json":
+		return export.FormatJSON
+	case ".ndjson", ".jsonl":
+		return export.FormatNDJSON
+	case ".txt":
+		return FormatTodoTxt
+	case ".ics", ".ical":
+		return FormatICS
+	}
+
+	return ""
+}
+
+// Parse разбирает файл формата format. Даты без времени означают конец дня
+// в часовом поясе loc.
+func Parse(format string, data []byte, loc *time.Location) ([]models.ImportLine, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	var lines []models.ImportLine
+	var err error
+
+	switch format {
+	case export.FormatCSV:
+		lines, err = parseCSV(data, loc)
+	case export.FormatJSON:
+		lines, err = parseJSON(data)
+	case export.FormatNDJSON:
+		lines = parseNDJSON(data)
+	case FormatTodoTxt:
+		lines = parseTodoTxt(data, loc)
+	case FormatICS:
+		lines, err = ical.Parse(data, loc)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if len(lines) > MaxLines {
+		return nil, ErrTooManyLines
+	}
+
+	return lines, nil
+}
+
+func invalid(line int, err error) models.ImportLine {
+	return models.ImportLine{Line: line, Error: err.Error()}
+}
+
+// csvColumns сопоставляет заголовки в нижнем регистре колонкам выгрузки.
+// Остальные колонки, в том числе id, createdAt и completedAt, пропускаются:
+// импорт всегда создаёт новые задачи.
+var csvColumns = map[string]string{
+	"taskname": "taskName", "title": "taskName", "task": "taskName",
+	"completed": "completed", "done": "completed",
+	"dueat": "dueAt", "due": "dueAt",
+
+	"description": "description",
+	"priority":    "priority",
+	"tags":        "tags",
+	"project":     "project",
+}
+
+func parseCSV(data []byte, loc *time.Location) ([]models.ImportLine, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		column, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]
+		if _, seen := columns[column]; ok && !seen {
+			columns[column] = i
+		}
+	}
+
+	if _, ok := columns["taskName"]; !ok {
+		return nil, ErrNoNameColumn
+	}
+
+	var lines []models.ImportLine
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			lines = append(lines, invalid(parseErr.StartLine, err))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		lines = append(lines, csvLine(line, record, columns, loc))
+	}
+
+	return lines, nil
+}
+
+func csvLine(line int, record []string, columns map[string]int, loc *time.Location) models.ImportLine {
+	field := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return export.UnescapeCell(strings.TrimSpace(record[i]))
+	}
+
+	request := &models.CreateTodoRequest{
+		TaskName: field("taskName"),
+		Priority: field("priority"),
+		Project:  field("project"),
+		Tags:     splitTags(field("tags")),
+	}
+
+	if description := field("description"); description != "" {
+		request.Description = &description
+	}
+
+	result := models.ImportLine{Line: line, Todo: request}
+
+	if value := field("completed"); value != "" {
+		completed, err := parseBool(value)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Completed = completed
+	}
+
+	if value := field("dueAt"); value != "" {
+		dueAt, err := parseDate(value, loc)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		request.DueAt = &dueAt
+	}
+
+	return result
+}
+
+// splitTags понимает и разделитель выгрузки, и запятую, которую обычно
+// ставят в таблицах.
+func splitTags(value string) []string {
+	var tags []string
+
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "x", "yes", "да":
+		return true, nil
+	case "no", "нет":
+		return false, nil
+	}
+
+	completed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("completed должен быть true или false")
+	}
+
+	return completed, nil
+}
+
+// parseDate принимает RFC 3339, «2006-01-02 15:04» и даты без времени.
+func parseDate(value string, loc *time.Location) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	if parsed, err := time.ParseInLocation("2006-01-02 15:04", value, loc); err == nil {
+		return parsed, nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if parsed, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return parsed.Add(23*time.Hour + 59*time.Minute), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("срок %q должен быть в формате 2006-01-02, 2006-01-02 15:04 или RFC 3339", value)
+}
+
+// jsonTodo принимает и тело запроса на создание, и задачу из выгрузки.
+type jsonTodo struct {
+	models.CreateTodoRequest
+	Completed bool `json:"completed"`
+}
+
+func jsonLine(line int, data []byte) models.ImportLine {
+	var item jsonTodo
+	if err := json.Unmarshal(data, &item); err != nil {
+		return invalid(line, fmt.Errorf("некорректная задача: %v", err))
+	}
+
+	return models.ImportLine{Line: line, Todo: &item.CreateTodoRequest, Completed: item.Completed}
+}
+
+// parseJSON читает массив задач. Файл, который начинается не с «[», читается
+// как NDJSON.
+func parseJSON(data []byte) ([]models.ImportLine, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return parseNDJSON(data), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	var lines []models.ImportLine
+
+	for decoder.More() {
+		start := decoder.InputOffset()
+
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("%w: строка %d: %v", ErrInvalidFile, lineAt(data, start), err)
+		}
+
+		lines = append(lines, jsonLine(lineAt(data, start), item))
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	return lines, nil
+}
+
+// lineAt возвращает номер строки, на которой после offset начинается значение.
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+func parseNDJSON(data []byte) []models.ImportLine {
+	var lines []models.ImportLine
+
+	for i, text := range bytes.Split(data, []byte("\n")) {
+		if text = bytes.TrimSpace(text); len(text) > 0 {
+			lines = append(lines, jsonLine(i+1, text))
+		}
+	}
+
+	return lines
+}
+
+var (
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+)
+
+// parseTodoTxt читает формат todo.txt: «x» в начале отмечает выполненную
+// задачу, (A) — приоритет, +проект, @контекст и due:2006-01-02. Контексты
+// становятся тегами, а из нескольких проектов берётся первый. Даты создания
+// и выполнения пропускаются.
+func parseTodoTxt(data []byte, loc *time.Location) []models.ImportLine {
+	var lines []models.ImportLine
+
+	for i, text := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(text); len(fields) > 0 {
+			lines = append(lines, todoTxtLine(i+1, fields, loc))
+		}
+	}
+
+	return lines
+}
+
+func todoTxtLine(line int, fields []string, loc *time.Location) models.ImportLine {
+	request := &models.CreateTodoRequest{}
+	result := models.ImportLine{Line: line, Todo: request}
+
+	i := 0
+	if fields[0] == "x" {
+		result.Completed = true
+		i++
+		for dates := 0; dates < 2 && i < len(fields) && todoTxtDate.MatchString(fields[i]); dates++ {
+			i++
+		}
+	}
+
+	if i < len(fields) {
+		if m := todoTxtPriority.FindStringSubmatch(fields[i]); m != nil {
+			request.Priority = todoTxtPriorityOf(m[1])
+			i++
+		}
+	}
+
+	if i < len(fields) && todoTxtDate.MatchString(fields[i]) {
+		i++
+	}
+
+	var name []string
+	for _, field := range fields[i:] {
+		switch {
+		case len(field) > 1 && field[0] == '+' && request.Project == "":
+			request.Project = field[1:]
+		case len(field) > 1 && field[0] == '@':
+			request.Tags = append(request.Tags, field[1:])
+		case strings.HasPrefix(field, "due:") && len(field) > len("due:"):
+			dueAt, err := parseDate(field[len("due:"):], loc)
+			if err != nil {
+				result.Error = err.Error()
+				continue
+			}
+			request.DueAt = &dueAt
+		case strings.HasPrefix(field, "pri:") && len(field) == len("pri:")+1 && request.Priority == "":
+			// Так todo.txt сохраняет приоритет выполненных задач.
+			request.Priority = todoTxtPriorityOf(field[len("pri:"):])
+		default:
+			name = append(name, field)
+		}
+	}
+
+	request.TaskName = strings.Join(name, " ")
+
+	return result
+}
+
+// todoTxtPriorityOf переводит буквенный приоритет: A — high, B — medium,
+// остальные — low.
+func todoTxtPriorityOf(letter string) string {
+	switch strings.ToUpper(letter) {
+	case "A":
+		return models.PriorityHigh
+	case "B":
+		return models.PriorityMedium
+	default:
+		return models.PriorityLow
+	}
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/export"
+	"todo-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var msk = time.FixedZone("MSK", 3*60*60)
+
+func TestParse_CSV(t *testing.T) {
+	data := "\ufeffTitle,Done,Due,Tags,Priority,Notes\n" +
+		"Оплатить счёт,yes,2026-11-05,\"finance, work\",high,не используется\n" +
+		"\n" +
+		"Купить молоко,,,,,\n" +
+		"Сломанная дата,false,завтра,,,\n"
+
+	lines, err := Parse(export.FormatCSV, []byte(data), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, 2, lines[0].Line)
+	assert.Empty(t, lines[0].Error)
+	assert.True(t, lines[0].Completed)
+	assert.Equal(t, "Оплатить счёт", lines[0].Todo.TaskName)
+	assert.Equal(t, "2026-11-05T23:59:00+03:00", lines[0].Todo.DueAt.Format(time.RFC3339))
+	assert.Equal(t, []string{"finance", "work"}, lines[0].Todo.Tags)
+	assert.Equal(t, "high", lines[0].Todo.Priority)
+	assert.Nil(t, lines[0].Todo.Description)
+
+	assert.Equal(t, 4, lines[1].Line)
+	assert.Equal(t, "Купить молоко", lines[1].Todo.TaskName)
+	assert.False(t, lines[1].Completed)
+
+	assert.Equal(t, 5, lines[2].Line)
+	assert.Contains(t, lines[2].Error, "завтра")
+}
+
+func TestParse_CSV_RoundTrip(t *testing.T) {
+	description := "многострочное\nописание"
+	dueAt := time.Date(2026, 11, 5, 14, 0, 0, 0, time.UTC)
+	todo := &models.Todo{
+		ID: "1", TaskName: "Оплатить счёт", Description: &description, Completed: true,
+		CreatedAt: time.Now(), DueAt: &dueAt, Priority: "high", Tags: []string{"finance", "work"}, Project: "acme",
+	}
+
+	var buf bytes.Buffer
+	encoder, err := export.NewEncoder(export.FormatCSV, &buf, true)
+	require.NoError(t, err)
+	require.NoError(t, encoder.Encode(todo))
+	require.NoError(t, encoder.Encode(&models.Todo{ID: "2", TaskName: `=HYPERLINK("http://example.com")`, Project: "-home"}))
+	require.NoError(t, encoder.Close())
+	assert.Contains(t, buf.String(), `"'=HYPERLINK(""http://example.com"")",`, "формулы экранируются при выгрузке")
+
+	lines, err := Parse(export.FormatCSV, buf.Bytes(), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+
+	assert.Empty(t, lines[0].Error)
+	assert.True(t, lines[0].Completed)
+	assert.Equal(t, &models.CreateTodoRequest{
+		TaskName: "Оплатить счёт", Description: &description, DueAt: &dueAt,
+		Priority: "high", Tags: []string{"finance", "work"}, Project: "acme",
+	}, lines[0].Todo)
+	assert.Equal(t, 4, lines[1].Line, "номер строки учитывает перенос внутри описания")
+	assert.Equal(t, `=HYPERLINK("http://example.com")`, lines[1].Todo.TaskName)
+	assert.Equal(t, "-home", lines[1].Todo.Project)
+}
+
+func TestParse_CSV_Errors(t *testing.T) {
+	_, err := Parse(export.FormatCSV, []byte("id,description\n1,текст\n"), msk)
+	assert.ErrorIs(t, err, ErrNoNameColumn)
+
+	_, err = Parse(export.FormatCSV, []byte("taskName\n"), msk)
+	assert.ErrorIs(t, err, ErrEmptyFile)
+
+	lines, err := Parse(export.FormatCSV, []byte("taskName,description\nпервая,\"ok\"\nвторая,bad\"quote\nтретья,\n"), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Empty(t, lines[0].Error)
+	assert.Equal(t, 3, lines[1].Line)
+	assert.NotEmpty(t, lines[1].Error)
+	assert.Equal(t, "третья", lines[2].Todo.TaskName)
+}
+
+func TestParse_JSON(t *testing.T) {
+	data := `[
+  {"taskName": "Оплатить счёт", "completed": true, "dueAt": "2026-11-05T17:00:00+03:00", "tags": ["finance"]},
+  {"id": "из выгрузки", "taskName": "Купить молоко", "createdAt": "2026-10-01T00:00:00Z"},
+  {"taskName": "Неверный тип", "completed": "да"},
+  42
+]`
+
+	lines, err := Parse(export.FormatJSON, []byte(data), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+
+	assert.Equal(t, 2, lines[0].Line)
+	assert.True(t, lines[0].Completed)
+	assert.Equal(t, []string{"finance"}, lines[0].Todo.Tags)
+
+	assert.Equal(t, 3, lines[1].Line)
+	assert.Equal(t, "Купить молоко", lines[1].Todo.TaskName)
+	assert.Empty(t, lines[1].Error)
+
+	assert.Equal(t, 4, lines[2].Line)
+	assert.NotEmpty(t, lines[2].Error)
+	assert.Equal(t, 5, lines[3].Line)
+	assert.NotEmpty(t, lines[3].Error)
+
+	_, err = Parse(export.FormatJSON, []byte(`[{"taskName": "a"}, {"taskName": `), msk)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+
+	_, err = Parse(export.FormatJSON, []byte(`[]`), msk)
+	assert.ErrorIs(t, err, ErrEmptyFile)
+}
+
+func TestParse_NDJSON(t *testing.T) {
+	data := "{\"taskName\": \"первая\"}\n\n{\"taskName\": \"вторая\", \"priority\": \"low\"}\n{не json}\n"
+
+	for _, format := range []string{export.FormatNDJSON, export.FormatJSON} {
+		lines, err := Parse(format, []byte(data), msk)
+		require.NoError(t, err)
+		require.Len(t, lines, 3)
+
+		assert.Equal(t, 1, lines[0].Line)
+		assert.Equal(t, 3, lines[1].Line)
+		assert.Equal(t, "low", lines[1].Todo.Priority)
+		assert.Equal(t, 4, lines[2].Line)
+		assert.NotEmpty(t, lines[2].Error)
+	}
+}
+
+func TestParse_TodoTxt(t *testing.T) {
+	data := strings.Join([]string{
+		"(A) 2026-10-01 Позвонить маме +Семья @phone @home due:2026-10-20",
+		"x 2026-10-02 2026-10-01 Оплатить счёт +Финансы +Работа pri:B",
+		"",
+		"Купить молоко",
+		"(C) Починить кран due:когда-нибудь",
+	}, "\n")
+
+	lines, err := Parse(FormatTodoTxt, []byte(data), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+
+	assert.Equal(t, models.ImportLine{Line: 1, Todo: &models.CreateTodoRequest{
+		TaskName: "Позвонить маме", Priority: "high", Project: "Семья",
+		Tags: []string{"phone", "home"}, DueAt: lines[0].Todo.DueAt,
+	}}, lines[0])
+	assert.Equal(t, "2026-10-20T23:59:00+03:00", lines[0].Todo.DueAt.Format(time.RFC3339))
+
+	assert.True(t, lines[1].Completed)
+	assert.Equal(t, "Оплатить счёт +Работа", lines[1].Todo.TaskName)
+	assert.Equal(t, "Финансы", lines[1].Todo.Project)
+	assert.Equal(t, "medium", lines[1].Todo.Priority)
+
+	assert.Equal(t, 4, lines[2].Line)
+	assert.Equal(t, "Купить молоко", lines[2].Todo.TaskName)
+
+	assert.Equal(t, "low", lines[3].Todo.Priority)
+	assert.Contains(t, lines[3].Error, "когда-нибудь")
+}
+
+func TestParse_ICS(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:Позвонить маме\r\nDUE;VALUE=DATE:20261020\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+	lines, err := Parse(FormatICS, []byte(data), msk)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, 2, lines[0].Line)
+	assert.Equal(t, "2026-10-20T23:59:00+03:00", lines[0].Todo.DueAt.Format(time.RFC3339))
+
+	_, err = Parse(FormatICS, []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"), msk)
+	assert.ErrorIs(t, err, ErrEmptyFile)
+}
+
+func TestParse_Limits(t *testing.T) {
+	_, err := Parse("xlsx", []byte("a"), msk)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = Parse(FormatTodoTxt, []byte(strings.Repeat("задача\n", MaxLines+1)), msk)
+	assert.ErrorIs(t, err, ErrTooManyLines)
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, export.FormatCSV, FormatOf("tasks.CSV"))
+	assert.Equal(t, export.FormatNDJSON, FormatOf("tasks.jsonl"))
+	assert.Equal(t, FormatTodoTxt, FormatOf("todo.txt"))
+	assert.Equal(t, FormatICS, FormatOf("Reminders.ics"))
+	assert.Empty(t, FormatOf("tasks"))
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(config.LogConfig{Level: "verbose", Format: "json"}, &bytes.Buffer{})
+	assert.Error(t, err)
+
+	_, err = New(config.LogConfig{Level: "info", Format: "xml"}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestNew_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(config.LogConfig{Level: "info", Format: "json"}, &buf)
+	require.NoError(t, err)
+
+	ctx := WithRequestID(t.Context(), "req-1")
+	logger.InfoContext(ctx, "проверка")
+	logger.DebugContext(ctx, "не попадёт в лог")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "проверка", record["msg"])
+	assert.Equal(t, "req-1", record["request_id"])
+}
+
+func setUpRouter(buf *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger, _ := New(config.LogConfig{Level: "info", Format: "json"}, buf)
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), AccessLogMiddleware(logger, "/healthz"))
+	router.GET("/todos/:id", func(c *gin.Context) {
+		c.String(404, RequestID(c.Request.Context()))
+	})
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(200)
+	})
+
+	return router
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := setUpRouter(&bytes.Buffer{})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"принимает идентификатор клиента", "abc-123", true},
+		{"генерирует при отсутствии", "", false},
+		{"заменяет недопустимый", "bad id\r\n", false},
+		{"заменяет слишком длинный", strings.Repeat("a", 200), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/todos/1", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			assert.NotEmpty(t, id)
+			assert.Equal(t, id, w.Body.String())
+			if tt.keep {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.NotEqual(t, tt.incoming, id)
+			}
+		})
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	router := setUpRouter(&buf)
+
+	req := httptest.NewRequest("GET", "/todos/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1, "запросы к тихим маршрутам пишутся с уровнем debug")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "/todos/1", record["path"])
+	assert.Equal(t, "/todos/:id", record["route"])
+	assert.Equal(t, float64(404), record["status"])
+	assert.Equal(t, "req-42", record["request_id"])
+	assert.Contains(t, record, "latency")
+}
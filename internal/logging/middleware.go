@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader — заголовок, в котором идентификатор запроса приходит от
+// клиента или балансировщика и возвращается в ответе.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину принятого от клиента идентификатора.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware берёт идентификатор из X-Request-ID или генерирует новый,
+// кладёт его в контекст запроса и возвращает в заголовке ответа.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+// validRequestID пропускает только печатные ASCII-символы, чтобы чужой
+// идентификатор не мог испортить заголовки ответа и строки логов.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AccessLogMiddleware пишет по строке на каждый запрос. Ответы 5xx логируются
+// с уровнем error, 4xx — warn. Запросы к quietRoutes (пробы, /metrics)
+// опрашиваются постоянно, поэтому пишутся с уровнем debug.
+func AccessLogMiddleware(logger *slog.Logger, quietRoutes ...string) gin.HandlerFunc {
+	quiet := make(map[string]bool, len(quietRoutes))
+	for _, route := range quietRoutes {
+		quiet[route] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		size := max(c.Writer.Size(), 0)
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case quiet[route]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", size),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate); len(errs) > 0 {
+			attrs = append(attrs, slog.String("error", errs.String()))
+		}
+
+		logger.LogAttrs(c.Request.Context(), level, "HTTP-запрос", attrs...)
+	}
+}
@@ -0,0 +1,129 @@
+// Package metrics собирает метрики Prometheus: HTTP-запросы, пул соединений
+// с базой, количество задач и ошибки репозитория.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+	"todo-api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "todoapi"
+
+// statsTimeout ограничивает подсчёт задач при каждом опросе /metrics.
+const statsTimeout = 2 * time.Second
+
+type Metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	repositoryErrors *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Количество обработанных HTTP-запросов.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки HTTP-запросов.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_errors_total",
+			Help:      "Количество ошибок репозитория по операциям и типам.",
+		}, []string{"operation", "error"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.repositoryErrors,
+	)
+
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware считает запросы с меткой шаблона маршрута, а не фактического пути,
+// чтобы идентификаторы задач не раздували число временных рядов.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := strconv.Itoa(c.Writer.Status())
+
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDB публикует статистику пула соединений: открытые, занятые,
+// ожидания соединения и их длительность.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterTodoStats публикует количество задач, запрашивая его у репозитория
+// при каждом опросе.
+func (m *Metrics) RegisterTodoStats(repo repository.TodoRepository) {
+	m.registry.MustRegister(&todoStatsCollector{
+		repo: repo,
+		total: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "todos"),
+			"Количество задач.", nil, nil),
+		completed: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "todos_completed"),
+			"Количество выполненных задач.", nil, nil),
+	})
+}
+
+type todoStatsCollector struct {
+	repo      repository.TodoRepository
+	total     *prometheus.Desc
+	completed *prometheus.Desc
+}
+
+func (c *todoStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.completed
+}
+
+func (c *todoStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := c.repo.Count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.total, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.Total))
+	ch <- prometheus.MustNewConstMetric(c.completed, prometheus.GaugeValue, float64(stats.Completed))
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/todos/:id", func(c *gin.Context) { c.Status(404) })
+
+	for _, path := range []string{"/todos/1", "/todos/2", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/todos/:id", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "unmatched", "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.requestDuration))
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	m := New()
+	repo := m.InstrumentRepository(repository.Constructor())
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &models.Todo{ID: "1", TaskName: "test"}))
+	assert.ErrorIs(t, repo.Create(ctx, &models.Todo{ID: "1", TaskName: "test"}), repository.ErrAlreadyExist)
+	_, err := repo.GetById(ctx, "2")
+	assert.ErrorIs(t, err, repository.ErrInvalidID)
+
+	errRollback := errors.New("rollback")
+	err = repo.WithinTx(ctx, func(tx repository.TodoRepository) error {
+		if err := tx.Delete(ctx, "2"); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, repository.ErrInvalidID)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repositoryErrors.WithLabelValues("create", "already_exists")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repositoryErrors.WithLabelValues("get_by_id", "not_found")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repositoryErrors.WithLabelValues("delete", "not_found")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.repositoryErrors))
+}
+
+func TestErrorLabel(t *testing.T) {
+	assert.Equal(t, "timeout", errorLabel(context.DeadlineExceeded))
+	assert.Equal(t, "empty_name", errorLabel(repository.ErrEmptyName))
+	assert.Equal(t, "internal", errorLabel(errors.New("boom")))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	repo := repository.Constructor()
+	require.NoError(t, repo.Create(context.Background(), &models.Todo{ID: "1", TaskName: "first"}))
+	require.NoError(t, repo.Create(context.Background(), &models.Todo{ID: "2", TaskName: "second", Completed: true}))
+	m.RegisterTodoStats(repo)
+
+	db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	m.RegisterDB(db, "sqlite")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, w.Code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "todoapi_todos 2")
+	assert.Contains(t, body, "todoapi_todos_completed 1")
+	assert.Contains(t, body, `go_sql_open_connections{db_name="sqlite"}`)
+	assert.Contains(t, body, `go_sql_wait_duration_seconds_total{db_name="sqlite"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+)
+
+// errorLabels сопоставляет ошибки репозитория значениям метки error.
+var errorLabels = []struct {
+	err   error
+	label string
+}{
+	{repository.ErrEmptyID, "empty_id"},
+	{repository.ErrInvalidID, "not_found"},
+	{repository.ErrEmptyTask, "empty_task"},
+	{repository.ErrEmptyData, "empty_data"},
+	{repository.ErrAlreadyExist, "already_exists"},
+	{repository.ErrEmptyName, "empty_name"},
+	{repository.ErrEmptyQuery, "empty_query"},
+	{repository.ErrInvalidPriority, "invalid_priority"},
+	{repository.ErrDueAtConflict, "due_at_conflict"},
+	{repository.ErrVersionConflict, "version_conflict"},
+	{repository.ErrInvalidEvent, "invalid_event"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "timeout"},
+}
+
+func errorLabel(err error) string {
+	for _, known := range errorLabels {
+		if errors.Is(err, known.err) {
+			return known.label
+		}
+	}
+	return "internal"
+}
+
+// InstrumentRepository оборачивает репозиторий и считает его ошибки.
+func (m *Metrics) InstrumentRepository(repo repository.TodoRepository) repository.TodoRepository {
+	return &instrumentedRepository{repo: repo, metrics: m}
+}
+
+type instrumentedRepository struct {
+	repo    repository.TodoRepository
+	metrics *Metrics
+}
+
+func (r *instrumentedRepository) observe(operation string, err error) error {
+	if err != nil {
+		r.metrics.repositoryErrors.WithLabelValues(operation, errorLabel(err)).Inc()
+	}
+	return err
+}
+
+func (r *instrumentedRepository) Create(ctx context.Context, task *models.Todo) error {
+	return r.observe("create", r.repo.Create(ctx, task))
+}
+
+func (r *instrumentedRepository) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	task, err := r.repo.GetById(ctx, id)
+	return task, r.observe("get_by_id", err)
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	return r.observe("update", r.repo.Update(ctx, id, updateData))
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id string) error {
+	return r.observe("delete", r.repo.Delete(ctx, id))
+}
+
+func (r *instrumentedRepository) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	tasks, err := r.repo.GetAllTask(ctx, filter)
+	return tasks, r.observe("get_all", err)
+}
+
+func (r *instrumentedRepository) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	return r.observe("each", r.repo.Each(ctx, filter, fn))
+}
+
+func (r *instrumentedRepository) Count(ctx context.Context) (*models.TodoStats, error) {
+	stats, err := r.repo.Count(ctx)
+	return stats, r.observe("count", err)
+}
+
+func (r *instrumentedRepository) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	results, err := r.repo.Search(ctx, query, limit)
+	return results, r.observe("search", err)
+}
+
+func (r *instrumentedRepository) AddEvents(ctx context.Context, events ...*models.Event) error {
+	return r.observe("add_events", r.repo.AddEvents(ctx, events...))
+}
+
+// WithinTx оборачивает и репозиторий транзакции, чтобы ошибки внутри неё
+// тоже попадали в метрики. Ошибка самой транзакции считается отдельно только
+// если её не вернула одна из операций.
+func (r *instrumentedRepository) WithinTx(ctx context.Context, fn func(repo repository.TodoRepository) error) error {
+	var fnErr error
+
+	err := r.repo.WithinTx(ctx, func(tx repository.TodoRepository) error {
+		fnErr = fn(&instrumentedRepository{repo: tx, metrics: r.metrics})
+		return fnErr
+	})
+
+	if err != nil && !errors.Is(err, fnErr) {
+		r.metrics.repositoryErrors.WithLabelValues("transaction", errorLabel(err)).Inc()
+	}
+
+	return err
+}
@@ -1,22 +1,398 @@
 package models
 
-import "time"
+import (
+	"cmp"
+	"encoding/json"
+	"time"
+)
+
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
 
 type Todo struct {
-	ID          string    `json:"id" db:"id"`
-	TaskName    string    `json:"taskName" db:"taskName"`
-	Description *string   `json:"description" db:"description"`
-	Completed   bool      `json:"completed" db:"completed"`
-	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+	ID          string     `json:"id" db:"id"`
+	TaskName    string     `json:"taskName" db:"taskName"`
+	Description *string    `json:"description" db:"description"`
+	Completed   bool       `json:"completed" db:"completed"`
+	CreatedAt   time.Time  `json:"createdAt" db:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" db:"completedAt"`
+	DueAt       *time.Time `json:"dueAt,omitempty" db:"dueAt"`
+	Priority    string     `json:"priority,omitempty" db:"priority" enums:"low,medium,high"`
+	Tags        []string   `json:"tags,omitempty" db:"tags"`
+	Project     string     `json:"project,omitempty" db:"project"`
+	// Version растёт с каждым изменением задачи. По нему строятся ETag в CalDAV.
+	Version int64 `json:"version,omitempty" db:"version"`
+	// FieldChanges — последние изменения полей по их именам в JSON. Поле,
+	// которого здесь нет, не менялось с создания задачи. Клиентам не отдаётся:
+	// по нему синхронизация находит конфликты.
+	FieldChanges map[string]FieldChange `json:"-" db:"field_changes"`
+}
+
+// FieldChange — версия задачи, в которой поле получило текущее значение, и
+// время изменения.
+type FieldChange struct {
+	Version int64     `json:"version"`
+	At      time.Time `json:"at"`
+}
+
+// Поля задачи, которые меняет синхронизация и для которых хранится
+// FieldChanges.
+const (
+	FieldTaskName    = "taskName"
+	FieldDescription = "description"
+	FieldCompleted   = "completed"
+	FieldDueAt       = "dueAt"
+	FieldPriority    = "priority"
+	FieldTags        = "tags"
+	FieldProject     = "project"
+)
+
+type TodoStats struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
 }
 
 type CreateTodoRequest struct {
-	TaskName    string  `json:"taskName,omitempty"`
-	Description *string `json:"description,omitempty"`
+	TaskName    string     `json:"taskName,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	Priority    string     `json:"priority,omitempty" enums:"low,medium,high"`
+	Tags        []string   `json:"tags,omitempty"`
+	Project     string     `json:"project,omitempty"`
 }
 
+// UpdateTodoRequest меняет только переданные поля. Tags без omitempty: пустой
+// список очищает теги и должен пережить сериализацию в журнал.
 type UpdateTodoRequest struct {
-	TaskName    *string `json:"taskName,omitempty"`
+	TaskName    *string    `json:"taskName,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Completed   *bool      `json:"completed,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	Priority    *string    `json:"priority,omitempty" enums:"low,medium,high"`
+	Tags        []string   `json:"tags"`
+	Project     *string    `json:"project,omitempty"`
+	// ClearDueAt снимает срок. Передать его вместе с dueAt нельзя.
+	ClearDueAt bool `json:"clearDueAt,omitempty"`
+	// IfVersion применяет изменение, только если версия задачи совпадает.
+	// В JSON не читается: его задают условные запросы CalDAV.
+	IfVersion *int64 `json:"-"`
+}
+
+// ReplaceTodoRequest заменяет задачу целиком, как PUT в CalDAV: поля, которых
+// нет в запросе, очищаются. Если задачи нет, она создаётся с переданным айди.
+type ReplaceTodoRequest struct {
+	CreateTodoRequest
+	Completed bool
+	// IfVersion — ожидаемая версия задачи: 0 — задачи ещё не должно быть,
+	// nil — без проверки.
+	IfVersion *int64
+}
+
+// TodoFilter отбирает задачи для списка и экспорта. Пустые поля в отборе не
+// участвуют, остальные условия объединяются по «И».
+type TodoFilter struct {
+	Completed *bool
+	Priority  string
+	Project   string
+	Tag       string
+	// DueBefore и DueAfter ограничивают срок: DueAfter <= dueAt < DueBefore.
+	// Задачи без срока под такой фильтр не попадают.
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	// Projects и Tags отбирают задачи из любого проекта списка и с любым
+	// тегом списка: так задачи нескольких проектов загружаются одним запросом.
+	Projects []string
+	Tags     []string
+	// After и Limit выбирают страницу: задачи после After в порядке создания,
+	// не больше Limit. Нулевой Limit не ограничивает число задач.
+	After *TodoCursor
+	Limit int
+	// PerGroup оставляет первые PerGroup задач каждого тега из Tags, а если
+	// Tags не задан — каждого проекта. Нулевой PerGroup не ограничивает.
+	PerGroup int
+}
+
+// TodoCursor — место задачи в порядке создания: время создания, а при
+// одинаковом времени — айди.
+type TodoCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// TodoPage — страница списка задач. NextPageToken пуст на последней странице.
+type TodoPage struct {
+	Todos         []*Todo
+	NextPageToken string
+}
+
+// GraphQLRequest — запрос к /graphql. В GET-запросе поля передаются
+// параметрами query, operationName и variables (JSON).
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// QuickAddRequest — строка быстрого ввода. Timezone — имя из базы IANA, в нём
+// считаются «завтра» и «в 5 вечера»; по умолчанию берётся пояс сервера.
+type QuickAddRequest struct {
+	Text     string `json:"text" example:"Pay invoice tomorrow 5pm !high #finance @acme"`
+	Timezone string `json:"timezone,omitempty" example:"Europe/Moscow"`
+}
+
+// QuickAddResponse возвращает разобранные поля, чтобы клиент мог показать их
+// пользователю. Todo пуст, если задача не создавалась.
+type QuickAddResponse struct {
+	Parsed CreateTodoRequest `json:"parsed"`
+	Todo   *Todo             `json:"todo,omitempty"`
+}
+
+// ImportLine — задача из файла импорта и результат её проверки. Line — номер
+// строки в файле, с которой начинается задача.
+type ImportLine struct {
+	Line      int                `json:"line"`
+	Todo      *CreateTodoRequest `json:"todo,omitempty"`
+	Completed bool               `json:"completed,omitempty"`
+	// ID заполняется после успешного импорта.
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ImportReport описывает импорт целиком. Если хотя бы одна строка не прошла
+// проверку, не импортируется ничего и Imported равен нулю.
+type ImportReport struct {
+	Format   string       `json:"format"`
+	DryRun   bool         `json:"dryRun"`
+	Total    int          `json:"total"`
+	Invalid  int          `json:"invalid"`
+	Imported int          `json:"imported"`
+	Lines    []ImportLine `json:"lines"`
+}
+
+// CalendarSubscription — ссылка на календарь задач. WebcalURL открывает
+// подписку в календарном приложении по клику.
+type CalendarSubscription struct {
+	URL       string        `json:"url"`
+	WebcalURL string        `json:"webcalUrl"`
+	CalDAV    CalDAVAccount `json:"caldav"`
+}
+
+// CalDAVAccount — данные для подключения календарного приложения по CalDAV,
+// в отличие от подписки оно может и изменять задачи.
+type CalDAVAccount struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SearchResult — задача, найденная полнотекстовым поиском. Rank сравним только
+// внутри одного ответа: его шкала зависит от хранилища.
+type SearchResult struct {
+	Todo
+	Rank      float64         `json:"rank"`
+	Highlight SearchHighlight `json:"highlight"`
+}
+
+// SearchHighlight содержит текст с совпадениями, обрамлёнными тегом <mark>.
+// Текст задачи не экранируется: перед выводом в HTML его нужно экранировать.
+type SearchHighlight struct {
+	TaskName    string  `json:"taskName"`
 	Description *string `json:"description,omitempty"`
-	Completed   *bool   `json:"completed,omitempty"`
+}
+
+// События об изменениях задач, на которые подписываются вебхуки. Если
+// изменение выполняет задачу, отправляется todo.completed вместо todo.updated.
+const (
+	EventTodoCreated   = "todo.created"
+	EventTodoUpdated   = "todo.updated"
+	EventTodoCompleted = "todo.completed"
+	EventTodoDeleted   = "todo.deleted"
+)
+
+// EventTypes — все события в порядке из документации.
+var EventTypes = []string{EventTodoCreated, EventTodoUpdated, EventTodoCompleted, EventTodoDeleted}
+
+// Event — событие в outbox. Payload — готовое тело запроса к вебхуку, оно
+// подписывается и отправляется без изменений.
+type Event struct {
+	ID        string
+	Type      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// EventPayload — тело запроса к вебхуку и данные события в потоке изменений.
+// Data — задача после изменения, для todo.deleted — перед удалением. Actor —
+// пользователь, выполнивший изменение, если он известен. PreviousProject —
+// проект, из которого перенесена задача; пуст, если проект не менялся или
+// задача была без проекта.
+type EventPayload struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Actor           string    `json:"actor,omitempty"`
+	OccurredAt      time.Time `json:"occurredAt"`
+	Data            *Todo     `json:"data"`
+	PreviousProject string    `json:"previousProject,omitempty"`
+}
+
+// Webhook — подписка на события. Пустой Events означает все события. Secret
+// возвращается только при создании.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие eventType.
+func (w *Webhook) Subscribed(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookRequest — новая подписка. Без Secret секрет для подписи
+// генерируется сервером.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty" enums:"todo.created,todo.updated,todo.completed,todo.deleted"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery — доставка события одному вебхуку и журнал её попыток:
+// число попыток и результат последней. NextAttemptAt задан, пока доставка
+// ждёт отправки.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Status         string          `json:"status" enums:"pending,succeeded,failed"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// DeliveryFilter отбирает доставки для журнала, новые первыми.
+type DeliveryFilter struct {
+	Status string
+	Limit  int
+}
+
+// SyncPosition — место изменения в ленте синхронизации: айди транзакции,
+// записавшей изменение, и номер изменения. Лента упорядочена по Tx, затем по
+// Seq; Tx заполняет только Postgres.
+type SyncPosition struct {
+	Tx  int64
+	Seq int64
+}
+
+func (p SyncPosition) Compare(other SyncPosition) int {
+	return cmp.Or(cmp.Compare(p.Tx, other.Tx), cmp.Compare(p.Seq, other.Seq))
+}
+
+// SyncChange — изменение в ответе синхронизации: задача целиком или, если она
+// удалена, запись об удалении. Из Position строится токен.
+type SyncChange struct {
+	Position SyncPosition `json:"-"`
+	Todo     *Todo        `json:"todo,omitempty"`
+	Deleted  *Tombstone   `json:"deleted,omitempty"`
+}
+
+// Tombstone — запись об удалённой задаче.
+type Tombstone struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// SyncPullResponse — изменения после токена since. Token передаётся в
+// следующий запрос; пока HasMore, изменения нужно дочитать сразу.
+type SyncPullResponse struct {
+	Changes []*SyncChange `json:"changes"`
+	Token   string        `json:"token"`
+	HasMore bool          `json:"hasMore"`
+}
+
+// Стратегии разрешения конфликтов при отправке изменений.
+const (
+	SyncLastWriterWins = "lastWriterWins"
+	SyncReport         = "report"
+)
+
+// Операции над задачей в SyncPushRequest.
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// Результаты применения изменения из SyncPushRequest.
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncRejected = "rejected"
+)
+
+// SyncPushRequest — изменения, накопленные клиентом без связи. Изменения
+// применяются по порядку, каждое в своей транзакции.
+type SyncPushRequest struct {
+	Strategy string           `json:"strategy,omitempty" enums:"lastWriterWins,report"`
+	Changes  []SyncPushChange `json:"changes"`
+}
+
+// SyncPushChange — изменение одной задачи. BaseVersion — версия задачи, на
+// которой клиент сделал изменение; ChangedAt — когда он его сделал, по нему
+// выбирается последняя запись. Fields — новые значения полей с именами как в
+// Todo; null в description и dueAt очищает поле.
+type SyncPushChange struct {
+	Op          string                     `json:"op" enums:"create,update,delete"`
+	ID          string                     `json:"id"`
+	BaseVersion int64                      `json:"baseVersion,omitempty"`
+	ChangedAt   time.Time                  `json:"changedAt"`
+	Fields      map[string]json.RawMessage `json:"fields,omitempty" swaggertype:"object"`
+}
+
+// SyncPushResult — чем закончилось изменение. applied — все значения клиента
+// записаны, conflict — часть значений или удаление не применены, rejected —
+// изменение некорректно и не применено совсем. Todo — задача после изменения,
+// если она существует.
+type SyncPushResult struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status" enums:"applied,conflict,rejected"`
+	Todo      *Todo           `json:"todo,omitempty"`
+	Conflicts []FieldConflict `json:"conflicts,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// FieldConflict — поле, которое клиент и сервер изменили независимо. Winner —
+// чьё значение осталось в задаче: client или server.
+type FieldConflict struct {
+	Field  string          `json:"field"`
+	Client json.RawMessage `json:"client" swaggertype:"object"`
+	Server any             `json:"server" swaggertype:"object"`
+	Winner string          `json:"winner" enums:"client,server"`
+}
+
+type SyncPushResponse struct {
+	Results []SyncPushResult `json:"results"`
 }
@@ -0,0 +1,97 @@
+// Package netguard не даёт исходящим запросам сервиса уходить во внутреннюю
+// сеть: адрес вебхука задаёт пользователь, и без проверки через него можно
+// обращаться к localhost, метаданным облака и соседним сервисам.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("адрес во внутренней сети запрещён")
+
+// forbiddenPrefixes — диапазоны специального назначения из реестров IANA,
+// которые не ведут в публичный интернет.
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // «эта сеть»
+	netip.MustParsePrefix("10.0.0.0/8"),      // частная сеть
+	netip.MustParsePrefix("100.64.0.0/10"),   // CGNAT, в некоторых облаках — метаданные
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, метаданные облака
+	netip.MustParsePrefix("172.16.0.0/12"),   // частная сеть
+	netip.MustParsePrefix("192.0.0.0/24"),    // протоколы IETF
+	netip.MustParsePrefix("192.0.2.0/24"),    // документация
+	netip.MustParsePrefix("192.88.99.0/24"),  // релеи 6to4
+	netip.MustParsePrefix("192.168.0.0/16"),  // частная сеть
+	netip.MustParsePrefix("198.18.0.0/15"),   // тестирование производительности
+	netip.MustParsePrefix("198.51.100.0/24"), // документация
+	netip.MustParsePrefix("203.0.113.0/24"),  // документация
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // зарезервировано, широковещание
+
+	netip.MustParsePrefix("::/96"),          // неуказанный, loopback и IPv4-совместимые
+	netip.MustParsePrefix("64:ff9b:1::/48"), // локальный NAT64
+	netip.MustParsePrefix("100::/64"),       // сброс трафика
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("2001:2::/48"),    // тестирование производительности
+	netip.MustParsePrefix("2001:10::/28"),   // ORCHID
+	netip.MustParsePrefix("2001:20::/28"),   // ORCHIDv2
+	netip.MustParsePrefix("2001:db8::/32"),  // документация
+	netip.MustParsePrefix("3fff::/20"),      // документация
+	netip.MustParsePrefix("5f00::/16"),      // SRv6
+	netip.MustParsePrefix("fc00::/7"),       // уникальные локальные
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// Префиксы, в которые вложен IPv4-адрес: NAT64 (последние 32 бита) и 6to4
+// (биты 16–48). Такой адрес проверяется по вложенному IPv4.
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour   = netip.MustParsePrefix("2002::/16")
+)
+
+// Forbidden сообщает, относится ли адрес к диапазонам специального
+// назначения: loopback, частным, link-local, CGNAT, документации, multicast и
+// другим. IPv4 внутри IPv6 (отображённый, NAT64, 6to4) проверяется как IPv4.
+func Forbidden(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if embedded, ok := embeddedIPv4(addr); ok {
+		addr = embedded
+	}
+
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	bytes := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[12:16])), true
+	case sixToFour.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[2:6])), true
+	default:
+		return netip.Addr{}, false
+	}
+}
+
+// Control подходит для net.Dialer.Control: он вызывается после разрешения
+// имени для каждого адреса, к которому идёт подключение, поэтому имя, которое
+// указывает во внутреннюю сеть, тоже отклоняется.
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if Forbidden(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
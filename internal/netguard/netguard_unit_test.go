@@ -0,0 +1,71 @@
+package netguard
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForbidden(t *testing.T) {
+	tests := []struct {
+		addr      string
+		forbidden bool
+	}{
+		{"0.1.2.3", true},
+		{"10.1.2.3", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"127.0.0.1", true},
+		{"169.254.169.254", true},
+		{"172.16.0.1", true},
+		{"192.0.0.8", true},
+		{"192.0.2.1", true},
+		{"192.88.99.1", true},
+		{"192.168.1.1", true},
+		{"198.18.0.1", true},
+		{"198.19.255.254", true},
+		{"198.51.100.1", true},
+		{"203.0.113.1", true},
+		{"224.0.0.1", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"::127.0.0.1", true},
+		{"::ffff:127.0.0.1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"64:ff9b:1::1", true},
+		{"100::1", true},
+		{"2001::1", true},
+		{"2001:2::1", true},
+		{"2001:10::1", true},
+		{"2001:20::1", true},
+		{"2001:db8::1", true},
+		{"2002:a00:1::1", true},
+		{"2002:7f00:1::1", true},
+		{"3fff::1", true},
+		{"5f00::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		{"2606:4700::1111", false},
+		{"64:ff9b::808:808", false},
+		{"2002:808:808::1", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.forbidden, Forbidden(netip.MustParseAddr(tt.addr)), tt.addr)
+	}
+}
+
+func TestControl(t *testing.T) {
+	assert.ErrorIs(t, Control("tcp4", "169.254.169.254:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, Control("tcp6", "[::1]:443", nil), ErrForbiddenAddress)
+	assert.NoError(t, Control("tcp4", "93.184.216.34:443", nil))
+}
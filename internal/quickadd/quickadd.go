@@ -0,0 +1,547 @@
+// Package quickadd разбирает строку быстрого ввода вроде
+// «Pay invoice tomorrow 5pm !high #finance @acme» в запрос на создание задачи.
+//
+// Разбор детерминирован: результат зависит только от текста и переданного
+// момента now, а относительные даты считаются в часовом поясе now.
+// Понимаются английские и русские выражения:
+//
+//   - !high, !medium, !low (!h, !m, !l, !!!, !!, !высокий, !средний, !низкий) — приоритет;
+//   - #тег — тег, @проект — проект;
+//   - today, tomorrow, day after tomorrow, сегодня, завтра, послезавтра;
+//   - in 3 days, in a week, in 2 hours, через 3 дня, через неделю, через час;
+//   - monday, next friday, on sunday, в понедельник, в следующую среду;
+//   - next week, на следующей неделе — ближайший понедельник;
+//   - 2026-11-05, 05.11, 05.11.2026, 5 nov, nov 5, 5 ноября;
+//   - 5pm, 5:30pm, 17:00, at 17, в 17, в 5 вечера, 9 утра.
+//
+// Из нескольких дат, времён или приоритетов учитывается первый, остальные
+// остаются в названии. Дата без времени означает конец дня (23:59), время без
+// даты — ближайший такой момент: сегодня или, если он прошёл, завтра.
+// Число после at или в без уточнения части дня читается как час в 24-часовом
+// формате.
+package quickadd
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+)
+
+var ErrEmptyText = errors.New("передан пустой текст задачи")
+var ErrEmptyName = errors.New("в тексте нет названия задачи")
+
+// Parse разбирает строку быстрого ввода относительно момента now.
+func Parse(text string, now time.Time) (*models.CreateTodoRequest, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, ErrEmptyText
+	}
+
+	p := &parser{now: now, request: &models.CreateTodoRequest{}}
+
+	words := make([]string, len(fields))
+	for i, field := range fields {
+		words[i] = normalizeWord(field)
+	}
+
+	var name []string
+	for i := 0; i < len(fields); {
+		if p.marker(fields[i]) {
+			i++
+			continue
+		}
+
+		if n := p.when(words[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		name = append(name, fields[i])
+		i++
+	}
+
+	p.request.TaskName = strings.Join(name, " ")
+	if p.request.TaskName == "" {
+		return nil, ErrEmptyName
+	}
+
+	p.request.DueAt = p.dueAt()
+
+	return p.request, nil
+}
+
+type clock struct {
+	hour, minute int
+}
+
+type parser struct {
+	now     time.Time
+	request *models.CreateTodoRequest
+
+	date  *time.Time
+	clock *clock
+	// exact задают выражения вроде «через 2 часа»: дата и время уже известны.
+	exact *time.Time
+}
+
+// moment — то, что нашлось в начале оставшихся слов. n — число поглощённых слов.
+type moment struct {
+	n     int
+	date  *time.Time
+	clock *clock
+	exact *time.Time
+}
+
+var priorities = map[string]string{
+	"high": models.PriorityHigh, "h": models.PriorityHigh, "!!": models.PriorityHigh,
+	"высокий": models.PriorityHigh, "важно": models.PriorityHigh, "срочно": models.PriorityHigh,
+
+	"medium": models.PriorityMedium, "med": models.PriorityMedium, "m": models.PriorityMedium,
+	"!": models.PriorityMedium, "средний": models.PriorityMedium,
+
+	"low": models.PriorityLow, "l": models.PriorityLow, "низкий": models.PriorityLow,
+}
+
+// marker разбирает !приоритет, #тег и @проект.
+func (p *parser) marker(field string) bool {
+	if len(field) < 2 {
+		return false
+	}
+
+	value := strings.TrimRight(field[1:], ",.;:?")
+
+	switch field[0] {
+	case '!':
+		priority, ok := priorities[strings.ToLower(value)]
+		if !ok || p.request.Priority != "" {
+			return false
+		}
+		p.request.Priority = priority
+		return true
+	case '#':
+		if value == "" {
+			return false
+		}
+		p.request.Tags = append(p.request.Tags, strings.ToLower(value))
+		return true
+	case '@':
+		if value == "" || p.request.Project != "" {
+			return false
+		}
+		p.request.Project = value
+		return true
+	}
+
+	return false
+}
+
+// prepositions можно опустить перед датой или временем. После тех, что
+// указывают на время, голое число считается часом.
+var prepositions = map[string]bool{
+	"on": false, "by": false, "at": true,
+	"в": true, "во": true, "к": true, "на": false,
+}
+
+// when ищет дату или время в начале words и возвращает число поглощённых
+// слов. Выражение, которое уже встречалось раньше, не поглощается.
+func (p *parser) when(words []string) int {
+	m := p.match(words, false)
+
+	if hourOnly, ok := prepositions[words[0]]; ok && m.n == 0 && len(words) > 1 {
+		m = p.match(words[1:], hourOnly)
+		if m.n > 0 {
+			m.n++
+		}
+	}
+
+	if m.n == 0 {
+		return 0
+	}
+
+	switch {
+	case m.exact != nil:
+		if p.exact != nil || p.date != nil || p.clock != nil {
+			return 0
+		}
+		p.exact = m.exact
+	case m.date != nil:
+		if p.exact != nil || p.date != nil {
+			return 0
+		}
+		p.date = m.date
+	case m.clock != nil:
+		if p.exact != nil || p.clock != nil {
+			return 0
+		}
+		p.clock = m.clock
+	}
+
+	return m.n
+}
+
+func (p *parser) match(words []string, hourOnly bool) moment {
+	matchers := []func([]string) moment{
+		p.relativeDay,
+		p.relativeOffset,
+		p.weekday,
+		p.nextWeek,
+		p.numericDate,
+		p.monthDate,
+		parseClock,
+	}
+
+	for _, match := range matchers {
+		if m := match(words); m.n > 0 {
+			return m
+		}
+	}
+
+	if hourOnly {
+		return parseHour(words)
+	}
+
+	return moment{}
+}
+
+func (p *parser) dueAt() *time.Time {
+	if p.exact != nil {
+		return p.exact
+	}
+
+	if p.date == nil && p.clock == nil {
+		return nil
+	}
+
+	if p.date == nil {
+		due := p.at(p.today(), *p.clock)
+		if due.Before(p.now) {
+			due = p.at(p.today().AddDate(0, 0, 1), *p.clock)
+		}
+		return &due
+	}
+
+	c := clock{hour: 23, minute: 59}
+	if p.clock != nil {
+		c = *p.clock
+	}
+
+	due := p.at(*p.date, c)
+	return &due
+}
+
+func (p *parser) today() time.Time {
+	year, month, day := p.now.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+}
+
+func (p *parser) at(date time.Time, c clock) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), c.hour, c.minute, 0, 0, p.now.Location())
+}
+
+func (p *parser) day(offset int) *time.Time {
+	date := p.today().AddDate(0, 0, offset)
+	return &date
+}
+
+func (p *parser) relativeDay(words []string) moment {
+	if hasWords(words, "day", "after", "tomorrow") {
+		return moment{n: 3, date: p.day(2)}
+	}
+
+	switch words[0] {
+	case "today", "сегодня":
+		return moment{n: 1, date: p.day(0)}
+	case "tomorrow", "завтра":
+		return moment{n: 1, date: p.day(1)}
+	case "послезавтра":
+		return moment{n: 1, date: p.day(2)}
+	}
+
+	return moment{}
+}
+
+var units = map[string]string{
+	"minute": "minute", "minutes": "minute", "min": "minute", "mins": "minute",
+	"минуту": "minute", "минуты": "minute", "минут": "minute",
+	"hour": "hour", "hours": "hour", "hr": "hour", "hrs": "hour",
+	"час": "hour", "часа": "hour", "часов": "hour",
+	"day": "day", "days": "day",
+	"день": "day", "дня": "day", "дней": "day",
+	"week": "week", "weeks": "week",
+	"неделю": "week", "недели": "week", "недель": "week",
+	"month": "month", "months": "month",
+	"месяц": "month", "месяца": "month", "месяцев": "month",
+}
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"один": 1, "одну": 1, "одна": 1, "пару": 2, "два": 2, "две": 2, "три": 3, "четыре": 4, "пять": 5,
+}
+
+// relativeOffset разбирает «in 3 days», «через 2 часа» и «через неделю».
+func (p *parser) relativeOffset(words []string) moment {
+	if len(words) < 2 || (words[0] != "in" && words[0] != "через") {
+		return moment{}
+	}
+
+	count, n := 1, 2
+	if value, ok := parseNumber(words[1]); ok {
+		if len(words) < 3 {
+			return moment{}
+		}
+		count, n = value, 3
+	} else if words[0] == "in" {
+		// «in week» без числа по-английски не говорят, а «in time» — не дата.
+		return moment{}
+	}
+
+	switch units[words[n-1]] {
+	case "minute":
+		exact := p.now.Add(time.Duration(count) * time.Minute).Truncate(time.Minute)
+		return moment{n: n, exact: &exact}
+	case "hour":
+		exact := p.now.Add(time.Duration(count) * time.Hour).Truncate(time.Minute)
+		return moment{n: n, exact: &exact}
+	case "day":
+		return moment{n: n, date: p.day(count)}
+	case "week":
+		return moment{n: n, date: p.day(7 * count)}
+	case "month":
+		date := p.today().AddDate(0, count, 0)
+		return moment{n: n, date: &date}
+	}
+
+	return moment{}
+}
+
+func parseNumber(word string) (int, bool) {
+	if value, ok := numberWords[word]; ok {
+		return value, true
+	}
+
+	value, err := strconv.Atoi(word)
+	if err != nil || value < 0 || value > 1000 {
+		return 0, false
+	}
+
+	return value, true
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday, "sunday": time.Sunday,
+	"понедельник": time.Monday, "вторник": time.Tuesday, "среда": time.Wednesday, "среду": time.Wednesday,
+	"четверг": time.Thursday, "пятница": time.Friday, "пятницу": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "воскресенье": time.Sunday,
+}
+
+var nextWords = map[string]bool{
+	"next": true, "this": true,
+	"следующий": true, "следующую": true, "следующее": true, "эту": true, "этот": true, "это": true,
+}
+
+// weekday разбирает «friday», «next friday» и «следующую пятницу». День недели
+// всегда означает ближайший такой день после сегодняшнего.
+func (p *parser) weekday(words []string) moment {
+	n := 1
+	if nextWords[words[0]] && len(words) > 1 {
+		words, n = words[1:], 2
+	}
+
+	target, ok := weekdays[words[0]]
+	if !ok {
+		return moment{}
+	}
+
+	offset := (int(target) - int(p.now.Weekday()) + 7) % 7
+	if offset == 0 {
+		offset = 7
+	}
+
+	return moment{n: n, date: p.day(offset)}
+}
+
+// nextWeek разбирает «next week» и «следующей неделе»: это ближайший понедельник.
+func (p *parser) nextWeek(words []string) moment {
+	if !hasWords(words, "next", "week") && !hasWords(words, "следующей", "неделе") {
+		return moment{}
+	}
+
+	offset := (int(time.Monday) - int(p.now.Weekday()) + 7) % 7
+	if offset == 0 {
+		offset = 7
+	}
+
+	return moment{n: 2, date: p.day(offset)}
+}
+
+var (
+	isoDate     = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	dottedDate  = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{4}))?$`)
+	clockPeriod = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	clock24     = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+)
+
+func (p *parser) numericDate(words []string) moment {
+	if m := isoDate.FindStringSubmatch(words[0]); m != nil {
+		return p.dateOf(atoi(m[1]), time.Month(atoi(m[2])), atoi(m[3]), 1)
+	}
+
+	if m := dottedDate.FindStringSubmatch(words[0]); m != nil {
+		year := 0
+		if m[3] != "" {
+			year = atoi(m[3])
+		}
+		return p.dateOf(year, time.Month(atoi(m[2])), atoi(m[1]), 1)
+	}
+
+	return moment{}
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January, "february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March, "april": time.April, "apr": time.April,
+	"may": time.May, "june": time.June, "jun": time.June, "july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August, "september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October, "november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+	"января": time.January, "февраля": time.February, "марта": time.March, "апреля": time.April,
+	"мая": time.May, "июня": time.June, "июля": time.July, "августа": time.August,
+	"сентября": time.September, "октября": time.October, "ноября": time.November, "декабря": time.December,
+}
+
+// monthDate разбирает «5 ноября», «5 nov» и «nov 5».
+func (p *parser) monthDate(words []string) moment {
+	if len(words) < 2 {
+		return moment{}
+	}
+
+	if month, ok := months[words[1]]; ok {
+		if day, err := strconv.Atoi(words[0]); err == nil {
+			return p.dateOf(0, month, day, 2)
+		}
+	}
+
+	if month, ok := months[words[0]]; ok {
+		if day, err := strconv.Atoi(words[1]); err == nil {
+			return p.dateOf(0, month, day, 2)
+		}
+	}
+
+	return moment{}
+}
+
+// dateOf проверяет дату. Без года берётся ближайшая ещё не прошедшая.
+func (p *parser) dateOf(year int, month time.Month, day, n int) moment {
+	explicit := year != 0
+	if !explicit {
+		year = p.now.Year()
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+	if date.Month() != month || date.Day() != day {
+		return moment{}
+	}
+
+	if !explicit && date.Before(p.today()) {
+		date = date.AddDate(1, 0, 0)
+	}
+
+	return moment{n: n, date: &date}
+}
+
+// parseClock разбирает «5pm», «5:30 pm», «17:00» и «5 вечера».
+func parseClock(words []string) moment {
+	if m := clockPeriod.FindStringSubmatch(words[0]); m != nil {
+		return withPeriod(atoi(m[1]), atoi(m[2]), m[3], 1)
+	}
+
+	if m := clock24.FindStringSubmatch(words[0]); m != nil {
+		if len(words) > 1 {
+			if c := withPeriod(atoi(m[1]), atoi(m[2]), words[1], 2); c.n > 0 {
+				return c
+			}
+		}
+		return clockOf(atoi(m[1]), atoi(m[2]), 1)
+	}
+
+	if len(words) > 1 {
+		if hour, err := strconv.Atoi(words[0]); err == nil {
+			return withPeriod(hour, 0, words[1], 2)
+		}
+	}
+
+	return moment{}
+}
+
+// parseHour разбирает голое число после at или в.
+func parseHour(words []string) moment {
+	hour, err := strconv.Atoi(words[0])
+	if err != nil {
+		return moment{}
+	}
+
+	return clockOf(hour, 0, 1)
+}
+
+// withPeriod переводит время с частью дня в 24-часовой формат.
+func withPeriod(hour, minute int, period string, n int) moment {
+	if hour < 1 || hour > 12 {
+		return moment{}
+	}
+
+	switch period {
+	case "am", "утра", "ночи":
+		if hour == 12 {
+			hour = 0
+		}
+		// «11 ночи» — это 23:00, а «2 ночи» — 02:00.
+		if period == "ночи" && hour >= 9 {
+			hour += 12
+		}
+	case "pm", "вечера", "дня":
+		if hour != 12 {
+			hour += 12
+		}
+	default:
+		return moment{}
+	}
+
+	return clockOf(hour, minute, n)
+}
+
+func clockOf(hour, minute, n int) moment {
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return moment{}
+	}
+
+	return moment{n: n, clock: &clock{hour: hour, minute: minute}}
+}
+
+func hasWords(words []string, expected ...string) bool {
+	if len(words) < len(expected) {
+		return false
+	}
+
+	for i, word := range expected {
+		if words[i] != word {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeWord приводит слово к нижнему регистру и отрезает знаки препинания
+// в конце, чтобы «tomorrow,» тоже считалось датой.
+func normalizeWord(field string) string {
+	return strings.TrimRight(strings.ToLower(field), ",.;!?")
+}
+
+func atoi(s string) int {
+	value, _ := strconv.Atoi(s)
+	return value
+}
@@ -0,0 +1,104 @@
+package quickadd
+
+import (
+	"testing"
+	"time"
+	"todo-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// now — среда, 14 октября 2026 года, 10:30 по Москве.
+var now = time.Date(2026, 10, 14, 10, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+func TestParse_Example(t *testing.T) {
+	request, err := Parse("Pay invoice tomorrow 5pm !high #finance @acme", now)
+	require.NoError(t, err)
+
+	require.NotNil(t, request.DueAt)
+	assert.Equal(t, "Pay invoice", request.TaskName)
+	assert.Equal(t, "2026-10-15T17:00:00+03:00", request.DueAt.Format(time.RFC3339))
+	assert.Equal(t, models.PriorityHigh, request.Priority)
+	assert.Equal(t, []string{"finance"}, request.Tags)
+	assert.Equal(t, "acme", request.Project)
+}
+
+func TestParse_Dates(t *testing.T) {
+	tests := []struct {
+		text string
+		name string
+		due  string
+	}{
+		{"Купить молоко", "Купить молоко", ""},
+		{"Call mom today", "Call mom", "2026-10-14 23:59"},
+		{"Call mom tomorrow,", "Call mom", "2026-10-15 23:59"},
+		{"Call mom day after tomorrow", "Call mom", "2026-10-16 23:59"},
+		{"Позвонить маме завтра", "Позвонить маме", "2026-10-15 23:59"},
+		{"Позвонить маме послезавтра в 9 утра", "Позвонить маме", "2026-10-16 09:00"},
+		{"Renew passport in 3 days", "Renew passport", "2026-10-17 23:59"},
+		{"Renew passport in a week", "Renew passport", "2026-10-21 23:59"},
+		{"Check oven in 2 hours", "Check oven", "2026-10-14 12:30"},
+		{"Проверить духовку через 15 минут", "Проверить духовку", "2026-10-14 10:45"},
+		{"Сдать отчёт через 2 дня", "Сдать отчёт", "2026-10-16 23:59"},
+		{"Сдать отчёт через неделю", "Сдать отчёт", "2026-10-21 23:59"},
+		{"Standup next monday at 10", "Standup", "2026-10-19 10:00"},
+		{"Standup on wednesday", "Standup", "2026-10-21 23:59"},
+		{"Созвон в следующую среду в 17:30", "Созвон", "2026-10-21 17:30"},
+		{"Созвон во вторник", "Созвон", "2026-10-20 23:59"},
+		{"Plan sprint next week", "Plan sprint", "2026-10-19 23:59"},
+		{"Спланировать спринт на следующей неделе", "Спланировать спринт", "2026-10-19 23:59"},
+		{"Dentist 2026-11-05 9:15am", "Dentist", "2026-11-05 09:15"},
+		{"Стоматолог 05.11", "Стоматолог", "2026-11-05 23:59"},
+		{"Оплатить налог 01.02", "Оплатить налог", "2027-02-01 23:59"},
+		{"Оплатить налог 01.12.2026", "Оплатить налог", "2026-12-01 23:59"},
+		{"Conference nov 5", "Conference", "2026-11-05 23:59"},
+		{"Конференция 5 ноября в 5 вечера", "Конференция", "2026-11-05 17:00"},
+		{"Dinner 7pm", "Dinner", "2026-10-14 19:00"},
+		{"Breakfast 8:00", "Breakfast", "2026-10-15 08:00"},
+		{"Ужин в 11 ночи", "Ужин", "2026-10-14 23:00"},
+		{"Meet in office at home", "Meet in office at home", ""},
+		{"Buy 5 apples", "Buy 5 apples", ""},
+		{"Watch 31.02 tomorrow", "Watch 31.02", "2026-10-15 23:59"},
+		{"Move today tomorrow", "Move tomorrow", "2026-10-14 23:59"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			request, err := Parse(tt.text, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, request.TaskName)
+
+			if tt.due == "" {
+				assert.Nil(t, request.DueAt)
+				return
+			}
+
+			require.NotNil(t, request.DueAt)
+			assert.Equal(t, tt.due, request.DueAt.Format("2006-01-02 15:04"))
+			assert.Equal(t, now.Location(), request.DueAt.Location())
+		})
+	}
+}
+
+func TestParse_Markers(t *testing.T) {
+	request, err := Parse("Отчёт !низкий #Работа #срочное @Офис !high @дом !", now)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Отчёт !high @дом !", request.TaskName)
+	assert.Equal(t, models.PriorityLow, request.Priority)
+	assert.Equal(t, []string{"работа", "срочное"}, request.Tags)
+	assert.Equal(t, "Офис", request.Project)
+
+	request, err = Parse("Deploy !!!", now)
+	require.NoError(t, err)
+	assert.Equal(t, models.PriorityHigh, request.Priority)
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse("   ", now)
+	assert.ErrorIs(t, err, ErrEmptyText)
+
+	_, err = Parse("tomorrow 5pm #finance !high", now)
+	assert.ErrorIs(t, err, ErrEmptyName)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"todo-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	KeyByIP     = "ip"
+	KeyByUser   = "user"
+	KeyByAPIKey = "api_key"
+)
+
+var ErrUnknownKey = errors.New("неизвестный ключ ограничения запросов")
+
+// KeyFunc возвращает функцию, определяющую корзины запроса. Запрос проходит,
+// только если токен есть в каждой из них. Корзина пользователя или API-ключа
+// всегда идёт вместе с общей корзиной адреса: ключи не проверяются, и клиент,
+// который подставляет новый ключ в каждый запрос, всё равно упрётся в лимит
+// адреса. У общей корзины свой, больший лимит, чтобы клиенты за одним NAT или
+// прокси не делили квоту одного пользователя. Заголовок пользователя должен
+// выставлять доверенный прокси после аутентификации. Адрес берётся из
+// X-Forwarded-For, только если соединение пришло от доверенного прокси
+// роутера.
+func KeyFunc(cfg config.RateLimitConfig) (func(c *gin.Context) []Bucket, error) {
+	byIP := func(c *gin.Context) Bucket {
+		return Bucket{Key: "ip:" + c.ClientIP()}
+	}
+	shared := func(c *gin.Context) Bucket {
+		return Bucket{Key: "net:" + c.ClientIP(), Shared: true}
+	}
+
+	switch strings.ToLower(cfg.KeyBy) {
+	case KeyByIP:
+		return func(c *gin.Context) []Bucket {
+			return []Bucket{byIP(c)}
+		}, nil
+	case KeyByUser:
+		return func(c *gin.Context) []Bucket {
+			if user := c.GetHeader(cfg.UserHeader); user != "" {
+				return []Bucket{{Key: "user:" + user}, shared(c)}
+			}
+			return []Bucket{byIP(c)}
+		}, nil
+	case KeyByAPIKey:
+		return func(c *gin.Context) []Bucket {
+			if apiKey := c.GetHeader(cfg.APIKeyHeader); apiKey != "" {
+				return []Bucket{{Key: "key:" + hashKey(apiKey)}, shared(c)}
+			}
+			return []Bucket{byIP(c)}
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, cfg.KeyBy)
+	}
+}
+
+// hashKey не даёт API-ключам попасть в хранилище счётчиков в открытом виде.
+func hashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит корзины в памяти процесса. Подходит для одного экземпляра
+// сервиса: у каждой реплики будут свои счётчики.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := max(now.Sub(b.updated).Seconds(), 0)
+	b.tokens = min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+
+	b.tokens--
+	return b.tokens, true, nil
+}
+
+func (s *MemoryStore) Purge(ctx context.Context, idle time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline := s.now().Add(-idle)
+	for key, b := range s.buckets {
+		if b.updated.Before(deadline) {
+			delete(s.buckets, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PostgresStore держит корзины в таблице rate_limits, поэтому лимит общий для
+// всех реплик. Время берётся из часов базы, чтобы расхождение часов реплик
+// не влияло на пополнение.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// takeQuery пополняет корзину и списывает токен одним запросом. Если токенов
+// не хватает, условие WHERE не даёт обновить строку и запрос не возвращает
+// ничего — это и есть отказ.
+const takeQuery = `
+INSERT INTO rate_limits AS rl (key, tokens, updated_at)
+VALUES ($1, $2::float8 - 1, now())
+ON CONFLICT (key) DO UPDATE SET
+    tokens = LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) - 1,
+    updated_at = now()
+WHERE LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) >= 1
+RETURNING tokens`
+
+const tokensQuery = `
+SELECT LEAST($2::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at)::float8 * $3::float8)
+FROM rate_limits WHERE key = $1`
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	var tokens float64
+
+	err := s.db.QueryRowContext(ctx, takeQuery, key, limit.Burst, limit.Rate).Scan(&tokens)
+	if err == nil {
+		return tokens, true, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("не удалось списать токен: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, tokensQuery, key, limit.Burst, limit.Rate).Scan(&tokens)
+	if err != nil {
+		return 0, false, fmt.Errorf("не удалось прочитать счётчик: %w", err)
+	}
+
+	return tokens, false, nil
+}
+
+func (s *PostgresStore) Purge(ctx context.Context, idle time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM rate_limits WHERE updated_at < now() - make_interval(secs => $1)",
+		idle.Seconds(),
+	)
+	return err
+}
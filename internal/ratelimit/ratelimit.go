@@ -0,0 +1,200 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket:
+// у каждого ключа есть корзина на Burst токенов, которая пополняется со
+// скоростью Rate токенов в секунду, а каждый запрос забирает один токен.
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"todo-api/internal/config"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+	StoreSQLite   = "sqlite"
+)
+
+var ErrUnknownStore = errors.New("неизвестное хранилище счётчиков")
+var ErrNoDatabase = errors.New("общее хранилище счётчиков должно совпадать с бэкендом задач")
+var ErrInvalidLimit = errors.New("некорректный лимит запросов")
+
+// Limit описывает корзину: Rate токенов в секунду, не больше Burst сразу.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Store атомарно забирает токен из корзины ключа. tokens — остаток после
+// списания или, если запрос отклонён, текущее наполнение корзины.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (tokens float64, allowed bool, err error)
+	// Purge удаляет корзины, которые не трогали дольше idle: к этому времени
+	// они заполнены доверху и ничем не отличаются от отсутствующих.
+	Purge(ctx context.Context, idle time.Duration) error
+}
+
+// Result содержит всё, что нужно для заголовков RateLimit-* и Retry-After.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset — время до полного восстановления корзины.
+	Reset time.Duration
+	// RetryAfter — время до появления следующего токена; ноль, если запрос разрешён.
+	RetryAfter time.Duration
+	// Window — время, за которое восстанавливается вся корзина.
+	Window time.Duration
+}
+
+// Bucket — корзина, из которой запрос забирает токен. Shared — корзина
+// адреса, за которым может быть много пользователей или ключей: у неё свой
+// лимит.
+type Bucket struct {
+	Key    string
+	Shared bool
+}
+
+// Limiter ведёт корзины с лимитом limit, а общие корзины адресов — с лимитом
+// shared. NewLimiter делает их одинаковыми.
+type Limiter struct {
+	store  Store
+	limit  Limit
+	shared Limit
+}
+
+func NewLimiter(store Store, limit Limit) (*Limiter, error) {
+	if err := validateLimit(limit); err != nil {
+		return nil, err
+	}
+
+	return &Limiter{store: store, limit: limit, shared: limit}, nil
+}
+
+func validateLimit(limit Limit) error {
+	if limit.Rate <= 0 || limit.Burst < 1 {
+		return fmt.Errorf("%w: rate=%g burst=%d", ErrInvalidLimit, limit.Rate, limit.Burst)
+	}
+	return nil
+}
+
+// New собирает ограничитель по конфигурации. db нужен только для хранилищ
+// postgres и sqlite и должен быть nil, если задачи хранятся в другом бэкенде.
+func New(cfg config.RateLimitConfig, db *sql.DB) (*Limiter, error) {
+	if cfg.Period <= 0 {
+		return nil, fmt.Errorf("%w: period=%s", ErrInvalidLimit, cfg.Period)
+	}
+
+	burst := cfg.Burst
+	if burst == 0 {
+		burst = cfg.Requests
+	}
+
+	limit := Limit{
+		Rate:  float64(cfg.Requests) / cfg.Period.Seconds(),
+		Burst: burst,
+	}
+
+	ipRequests := cfg.IPRequests
+	if ipRequests == 0 {
+		ipRequests = 10 * cfg.Requests
+	}
+	shared := Limit{
+		Rate:  float64(ipRequests) / cfg.Period.Seconds(),
+		Burst: ipRequests,
+	}
+	if err := validateLimit(shared); err != nil {
+		return nil, err
+	}
+
+	var store Store
+	switch strings.ToLower(cfg.Store) {
+	case StoreMemory:
+		store = NewMemoryStore()
+	case StorePostgres:
+		if db == nil {
+			return nil, ErrNoDatabase
+		}
+		store = NewPostgresStore(db)
+	case StoreSQLite:
+		if db == nil {
+			return nil, ErrNoDatabase
+		}
+		store = NewSQLiteStore(db)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStore, cfg.Store)
+	}
+
+	limiter, err := NewLimiter(store, limit)
+	if err != nil {
+		return nil, err
+	}
+	limiter.shared = shared
+
+	return limiter, nil
+}
+
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.allow(ctx, key, l.limit)
+}
+
+// AllowBucket забирает токен из корзины с её лимитом.
+func (l *Limiter) AllowBucket(ctx context.Context, bucket Bucket) (Result, error) {
+	if bucket.Shared {
+		return l.allow(ctx, bucket.Key, l.shared)
+	}
+	return l.allow(ctx, bucket.Key, l.limit)
+}
+
+func (l *Limiter) allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	tokens, allowed, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     duration(float64(limit.Burst)-tokens, limit),
+		Window:    duration(float64(limit.Burst), limit),
+	}
+
+	if !allowed {
+		result.RetryAfter = duration(1-tokens, limit)
+	}
+
+	return result, nil
+}
+
+// duration переводит недостающие токены во время их накопления.
+func duration(tokens float64, limit Limit) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / limit.Rate * float64(time.Second))
+}
+
+// PurgeLoop раз в interval удаляет заполненные корзины, пока не отменён ctx.
+func (l *Limiter) PurgeLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			idle := max(duration(float64(l.limit.Burst), l.limit), duration(float64(l.shared.Burst), l.shared))
+			if err := l.store.Purge(ctx, idle); err != nil && ctx.Err() == nil {
+				slog.Warn("не удалось очистить счётчики ограничения запросов", "error", err)
+			}
+		}
+	}
+}
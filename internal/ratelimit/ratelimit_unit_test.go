@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(t *testing.T, limit Limit) (*Limiter, *MemoryStore, *time.Time) {
+	t.Helper()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limiter, err := NewLimiter(store, limit)
+	require.NoError(t, err)
+
+	return limiter, store, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter, _, now := newTestLimiter(t, Limit{Rate: 1, Burst: 2})
+
+	result, err := limiter.Allow(t.Context(), "a")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Equal(t, time.Second, result.Reset)
+	assert.Equal(t, 2*time.Second, result.Window)
+
+	result, _ = limiter.Allow(t.Context(), "a")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, _ = limiter.Allow(t.Context(), "a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	result, _ = limiter.Allow(t.Context(), "b")
+	assert.True(t, result.Allowed, "у каждого ключа своя корзина")
+
+	*now = now.Add(500 * time.Millisecond)
+	result, _ = limiter.Allow(t.Context(), "a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	*now = now.Add(10 * time.Second)
+	result, _ = limiter.Allow(t.Context(), "a")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining, "корзина не наполняется больше Burst")
+}
+
+func TestLimiter_AllowBucket(t *testing.T) {
+	limiter, _, _ := newTestLimiter(t, Limit{Rate: 1, Burst: 1})
+	limiter.shared = Limit{Rate: 1, Burst: 3}
+
+	for range 3 {
+		result, err := limiter.AllowBucket(t.Context(), Bucket{Key: "net:a", Shared: true})
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+	}
+
+	result, err := limiter.AllowBucket(t.Context(), Bucket{Key: "net:a", Shared: true})
+	require.NoError(t, err)
+	assert.False(t, result.Allowed, "у общей корзины свой лимит, но он тоже кончается")
+
+	result, err = limiter.AllowBucket(t.Context(), Bucket{Key: "user:a"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Limit)
+}
+
+func TestMemoryStore_Purge(t *testing.T) {
+	limiter, store, now := newTestLimiter(t, Limit{Rate: 1, Burst: 1})
+
+	_, _ = limiter.Allow(t.Context(), "old")
+	*now = now.Add(time.Minute)
+	_, _ = limiter.Allow(t.Context(), "fresh")
+
+	require.NoError(t, store.Purge(t.Context(), 30*time.Second))
+
+	assert.NotContains(t, store.buckets, "old")
+	assert.Contains(t, store.buckets, "fresh")
+}
+
+func TestNew_Config(t *testing.T) {
+	cfg := config.RateLimitConfig{Requests: 60, Period: time.Minute, Store: StoreMemory}
+
+	limiter, err := New(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 1, Burst: 60}, limiter.limit)
+	assert.Equal(t, Limit{Rate: 10, Burst: 600}, limiter.shared, "по умолчанию адресу в десять раз больше")
+
+	cfg.IPRequests = 120
+	limiter, err = New(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 2, Burst: 120}, limiter.shared)
+	cfg.IPRequests = 0
+
+	cfg.Store = StorePostgres
+	_, err = New(cfg, nil)
+	assert.ErrorIs(t, err, ErrNoDatabase)
+
+	cfg.Store = StoreSQLite
+	_, err = New(cfg, nil)
+	assert.ErrorIs(t, err, ErrNoDatabase)
+
+	cfg.Store = "redis"
+	_, err = New(cfg, nil)
+	assert.ErrorIs(t, err, ErrUnknownStore)
+
+	_, err = New(config.RateLimitConfig{Requests: 0, Period: time.Minute, Store: StoreMemory}, nil)
+	assert.ErrorIs(t, err, ErrInvalidLimit)
+}
+
+func TestKeyFunc(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(headers map[string]string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/todos", nil)
+		c.Request.RemoteAddr = "10.0.0.1:1234"
+		for name, value := range headers {
+			c.Request.Header.Set(name, value)
+		}
+		return c
+	}
+
+	cfg := config.RateLimitConfig{UserHeader: "X-User-ID", APIKeyHeader: "X-API-Key"}
+
+	cfg.KeyBy = KeyByUser
+	key, err := KeyFunc(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []Bucket{{Key: "user:42"}, {Key: "net:10.0.0.1", Shared: true}}, key(newContext(map[string]string{"X-User-ID": "42"})))
+	assert.Equal(t, []Bucket{{Key: "ip:10.0.0.1"}}, key(newContext(nil)))
+
+	cfg.KeyBy = KeyByAPIKey
+	key, err = KeyFunc(cfg)
+	require.NoError(t, err)
+	keys := key(newContext(map[string]string{"X-API-Key": "secret"}))
+	require.Len(t, keys, 2)
+	assert.Regexp(t, "^key:[0-9a-f]{32}$", keys[0].Key)
+	assert.NotContains(t, keys[0].Key, "secret")
+	assert.Equal(t, Bucket{Key: "net:10.0.0.1", Shared: true}, keys[1], "новый ключ не даёт обойти лимит адреса")
+
+	cfg.KeyBy = "session"
+	_, err = KeyFunc(cfg)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLiteStore держит корзины в таблице rate_limits файла SQLite, поэтому
+// лимит общий для всех процессов, открывших этот файл. Время берётся из
+// SQLite, как и в PostgresStore.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// sqliteNow — текущее время с миллисекундами в формате, который понимает
+// julianday. Внутри одного запроса 'now' не меняется.
+const sqliteNow = `strftime('%Y-%m-%d %H:%M:%f', 'now')`
+
+// sqliteTakeQuery устроен так же, как takeQuery для Postgres: при нехватке
+// токенов WHERE не даёт обновить строку, и запрос ничего не возвращает.
+const sqliteTakeQuery = `
+INSERT INTO rate_limits AS rl (key, tokens, updated_at)
+VALUES ($1, $2 - 1, ` + sqliteNow + `)
+ON CONFLICT (key) DO UPDATE SET
+    tokens = MIN($2, rl.tokens + (julianday('now') - julianday(rl.updated_at)) * 86400 * $3) - 1,
+    updated_at = ` + sqliteNow + `
+WHERE MIN($2, rl.tokens + (julianday('now') - julianday(rl.updated_at)) * 86400 * $3) >= 1
+RETURNING tokens`
+
+const sqliteTokensQuery = `
+SELECT MIN($2, tokens + (julianday('now') - julianday(updated_at)) * 86400 * $3)
+FROM rate_limits WHERE key = $1`
+
+func (s *SQLiteStore) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	var tokens float64
+
+	err := s.db.QueryRowContext(ctx, sqliteTakeQuery, key, float64(limit.Burst), limit.Rate).Scan(&tokens)
+	if err == nil {
+		return tokens, true, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("не удалось списать токен: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, sqliteTokensQuery, key, float64(limit.Burst), limit.Rate).Scan(&tokens)
+	if err != nil {
+		return 0, false, fmt.Errorf("не удалось прочитать счётчик: %w", err)
+	}
+
+	return tokens, false, nil
+}
+
+func (s *SQLiteStore) Purge(ctx context.Context, idle time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM rate_limits WHERE julianday(updated_at) < julianday('now') - $1 / 86400.0",
+		idle.Seconds(),
+	)
+	return err
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"todo-api/internal/database"
+	"todo-api/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore_SharedLimit(t *testing.T) {
+	db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, migrations.RunSQLiteMigrations(db))
+
+	// Два лимитера с общей таблицей ведут себя как два процесса сервиса.
+	limit := Limit{Rate: 0.001, Burst: 3}
+	first, err := NewLimiter(NewSQLiteStore(db), limit)
+	require.NoError(t, err)
+	second, err := NewLimiter(NewSQLiteStore(db), limit)
+	require.NoError(t, err)
+
+	for i, limiter := range []*Limiter{first, second, first} {
+		result, err := limiter.Allow(t.Context(), "ip:10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := second.Allow(t.Context(), "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+
+	result, err = second.Allow(t.Context(), "ip:10.0.0.2")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	require.NoError(t, NewSQLiteStore(db).Purge(t.Context(), time.Hour))
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM rate_limits").Scan(&count))
+	assert.Equal(t, 2, count, "недавно использованные корзины не удаляются")
+
+	require.NoError(t, NewSQLiteStore(db).Purge(t.Context(), -time.Hour))
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM rate_limits").Scan(&count))
+	assert.Zero(t, count)
+}
@@ -0,0 +1,255 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxSubscriptions ограничивает число проектов, на которые подписан один
+// клиент.
+const maxSubscriptions = 100
+
+const (
+	closeShutdown = "сервер останавливается"
+	closeSlow     = "клиент не успевает читать сообщения"
+)
+
+var (
+	errInvalidJSON          = errors.New("неверный JSON")
+	errEmptyProject         = errors.New("не указан проект")
+	errTooManySubscriptions = errors.New("слишком много подписок")
+	errUnknownType          = errors.New("неизвестный тип сообщения")
+)
+
+type client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	user string
+
+	// send — очередь сообщений клиенту. Её разбирает writeLoop; если очередь
+	// заполнена, клиент не успевает читать и отключается.
+	send      chan Message
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+
+	// projects — подписки клиента; защищены мьютексом хаба.
+	projects map[string]struct{}
+}
+
+func newClient(hub *Hub, conn *websocket.Conn, user string) *client {
+	return &client{
+		hub:      hub,
+		conn:     conn,
+		user:     user,
+		send:     make(chan Message, max(hub.cfg.SendBuffer, 1)),
+		done:     make(chan struct{}),
+		projects: make(map[string]struct{}),
+	}
+}
+
+// enqueue ставит сообщение в очередь, не дожидаясь клиента.
+func (c *client) enqueue(message Message) {
+	select {
+	case c.send <- message:
+	default:
+		c.close(websocket.CloseTryAgainLater, closeSlow)
+	}
+}
+
+// close просит writeLoop отправить клиенту code и закрыть соединение.
+// Срабатывает только первый вызов.
+func (c *client) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.closeText = code, text
+		close(c.done)
+	})
+}
+
+// serve читает сообщения клиента, пока соединение открыто. Сообщения
+// обрабатываются по одному: следующее читается после ответа на предыдущее,
+// поэтому клиент, который шлёт быстрее, чем сервер обрабатывает, упирается
+// в буфер TCP.
+func (c *client) serve(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c.writeLoop()
+	}()
+	defer func() {
+		c.close(websocket.CloseNormalClosure, "")
+		<-stopped
+	}()
+
+	// Клиент, который не ответил на два пинга подряд, считается отключившимся.
+	timeout := 2 * c.hub.cfg.PingInterval
+	c.conn.SetReadLimit(int64(c.hub.cfg.MaxMessageSize))
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+
+		c.handle(ctx, data)
+	}
+}
+
+// writeLoop — единственный, кто пишет сообщения в соединение.
+func (c *client) writeLoop() {
+	defer c.conn.Close()
+
+	ping := time.NewTicker(c.hub.cfg.PingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			if err := c.conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.hub.cfg.WriteTimeout)); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeText),
+				time.Now().Add(c.hub.cfg.WriteTimeout))
+			return
+		}
+	}
+}
+
+func (c *client) handle(ctx context.Context, data []byte) {
+	var request Request
+	if err := json.Unmarshal(data, &request); err != nil {
+		c.enqueue(errorMessage(ctx, "", errInvalidJSON))
+		return
+	}
+
+	if request.Type == TypePing {
+		c.enqueue(Message{Type: TypePong, ID: request.ID})
+		return
+	}
+
+	result, err := c.dispatch(ctx, &request)
+	if err != nil {
+		c.enqueue(errorMessage(ctx, request.ID, err))
+		return
+	}
+	c.enqueue(Message{Type: TypeResult, ID: request.ID, Project: request.Project, Data: result})
+}
+
+func (c *client) dispatch(ctx context.Context, request *Request) (any, error) {
+	service := c.hub.service
+
+	switch request.Type {
+	case TypeSubscribe:
+		request.Project = services.NormalizeProject(request.Project)
+		return c.subscribe(ctx, request.Project)
+
+	case TypeUnsubscribe:
+		request.Project = services.NormalizeProject(request.Project)
+		if request.Project == "" {
+			return nil, errEmptyProject
+		}
+		c.hub.leave(c, request.Project)
+		return nil, nil
+
+	case TypeCreate:
+		var body models.CreateTodoRequest
+		if err := decode(request.Data, &body); err != nil {
+			return nil, err
+		}
+		return service.CreateTodo(ctx, &body)
+
+	case TypeUpdate:
+		var body models.UpdateTodoRequest
+		if err := decode(request.Data, &body); err != nil {
+			return nil, err
+		}
+		body.IfVersion = request.IfVersion
+		return service.UpdateTodo(ctx, request.TodoID, &body)
+
+	case TypeDelete:
+		return nil, service.DeleteTodo(ctx, request.TodoID)
+
+	default:
+		return nil, errUnknownType
+	}
+}
+
+// subscribe подписывает на проект до чтения задач, чтобы не пропустить
+// изменения между чтением и подпиской. Событие о задаче, которая уже есть
+// в снимке, клиент узнаёт по версии.
+func (c *client) subscribe(ctx context.Context, project string) (*Snapshot, error) {
+	if project == "" {
+		return nil, errEmptyProject
+	}
+
+	users, err := c.hub.join(c, project)
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := c.hub.service.GetAllTodos(ctx, &models.TodoFilter{Project: project})
+	if err != nil {
+		c.hub.leave(c, project)
+		return nil, err
+	}
+	if todos == nil {
+		todos = []*models.Todo{}
+	}
+
+	return &Snapshot{Users: users, Todos: todos}, nil
+}
+
+func decode(data json.RawMessage, v any) error {
+	if len(data) == 0 {
+		return repository.ErrEmptyData
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errInvalidJSON
+	}
+	return nil
+}
+
+// errorMessage переводит ошибку в ответ с кодом, которым ответил бы REST API.
+// Подробности внутренних ошибок клиенту не передаются.
+func errorMessage(ctx context.Context, id string, err error) Message {
+	message := Message{Type: TypeError, ID: id, Status: 400, Error: err.Error()}
+
+	switch {
+	case errors.Is(err, errInvalidJSON), errors.Is(err, errEmptyProject), errors.Is(err, errTooManySubscriptions),
+		errors.Is(err, errUnknownType), errors.Is(err, repository.ErrEmptyID), errors.Is(err, repository.ErrEmptyData),
+		errors.Is(err, repository.ErrEmptyTask), errors.Is(err, repository.ErrEmptyName),
+		errors.Is(err, repository.ErrInvalidPriority), errors.Is(err, repository.ErrDueAtConflict):
+	case errors.Is(err, repository.ErrInvalidID):
+		message.Status = 404
+	case errors.Is(err, repository.ErrAlreadyExist), errors.Is(err, repository.ErrVersionConflict):
+		message.Status = 409
+	case errors.Is(err, context.DeadlineExceeded):
+		message.Status, message.Error = 504, "превышено время ожидания ответа от хранилища"
+	default:
+		slog.ErrorContext(ctx, "ошибка при обработке сообщения WebSocket", "error", err)
+		message.Status, message.Error = 500, "внутренняя ошибка сервера"
+	}
+
+	return message
+}
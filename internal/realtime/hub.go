@@ -0,0 +1,277 @@
+// Package realtime — WebSocket для совместной работы над задачами. Клиент
+// подписывается на проекты и получает их изменения, видит, кто ещё смотрит
+// проект, и меняет задачи через то же соединение. Изменения приходят из
+// потока изменений, поэтому видны изменения, сделанные на любой реплике и
+// через REST API. Присутствие учитывается в пределах реплики.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"todo-api/internal/config"
+	"todo-api/internal/identity"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	"github.com/gorilla/websocket"
+)
+
+type Hub struct {
+	service  services.TodoService
+	broker   *stream.Broker
+	cfg      config.RealtimeConfig
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	clients  map[*client]struct{}
+	projects map[string]map[*client]struct{}
+	closed   bool
+}
+
+func NewHub(service services.TodoService, broker *stream.Broker, cfg config.RealtimeConfig) *Hub {
+	return &Hub{
+		service: service,
+		broker:  broker,
+		cfg:     cfg,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: CheckOrigin(cfg.AllowedOrigins),
+			Error:       UpgradeError,
+		},
+		clients:  make(map[*client]struct{}),
+		projects: make(map[string]map[*client]struct{}),
+	}
+}
+
+// Run рассылает события потока подписчикам проектов, пока не отменён ctx,
+// затем закрывает все соединения.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.close()
+
+	lastEventID := ""
+	for ctx.Err() == nil {
+		// Брокер отключает подписчика, который не успевает читать; после
+		// переподключения пропущенные события берутся из буфера брокера.
+		sub, replay, resumed := h.broker.Subscribe(lastEventID, func(stream.Event) bool { return true })
+		if !resumed {
+			h.broadcast(Message{Type: TypeReset})
+		}
+		for _, event := range replay {
+			h.dispatch(event)
+			lastEventID = event.ID
+		}
+
+		if !h.consume(ctx, sub, &lastEventID) {
+			h.broker.Unsubscribe(sub)
+			return
+		}
+	}
+}
+
+// consume читает подписку, пока брокер её не закроет. false — отменён ctx.
+func (h *Hub) consume(ctx context.Context, sub *stream.Subscription, lastEventID *string) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			if event.Type == stream.TypeReset {
+				h.broadcast(Message{Type: TypeReset})
+				continue
+			}
+			h.dispatch(event)
+			*lastEventID = event.ID
+		}
+	}
+}
+
+// dispatch отправляет событие подписчикам проекта задачи, а если задачу
+// перенесли — и подписчикам прежнего проекта, чтобы они убрали её у себя.
+func (h *Hub) dispatch(event stream.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data := json.RawMessage(event.Data)
+	for c := range h.projects[event.Project] {
+		c.enqueue(Message{Type: TypeEvent, Project: event.Project, Data: data})
+	}
+
+	if event.PreviousProject == "" || event.PreviousProject == event.Project {
+		return
+	}
+	for c := range h.projects[event.PreviousProject] {
+		if _, both := h.projects[event.Project][c]; !both {
+			c.enqueue(Message{Type: TypeEvent, Project: event.PreviousProject, Data: data})
+		}
+	}
+}
+
+func (h *Hub) broadcast(message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		c.enqueue(message)
+	}
+}
+
+// ServeHTTP открывает WebSocket и обслуживает его до закрытия. Пользователь
+// берётся из контекста запроса.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := newClient(h, conn, identity.User(r.Context()))
+	if !h.add(c) {
+		c.close(websocket.CloseGoingAway, closeShutdown)
+	}
+
+	c.serve(r.Context())
+	h.remove(c)
+}
+
+func (h *Hub) add(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.clients[c] = struct{}{}
+	return true
+}
+
+func (h *Hub) remove(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, c)
+	for project := range c.projects {
+		h.leaveLocked(c, project)
+	}
+}
+
+// join подписывает клиента на проект и возвращает, кто смотрит проект.
+// Остальным подписчикам отправляется новое присутствие, если оно изменилось.
+func (h *Hub) join(c *client, project string) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := c.projects[project]; !ok {
+		if len(c.projects) >= maxSubscriptions {
+			return nil, errTooManySubscriptions
+		}
+
+		before := h.presenceLocked(project)
+		if h.projects[project] == nil {
+			h.projects[project] = make(map[*client]struct{})
+		}
+		h.projects[project][c] = struct{}{}
+		c.projects[project] = struct{}{}
+		h.notifyPresenceLocked(project, before, c)
+	}
+
+	return h.presenceLocked(project), nil
+}
+
+func (h *Hub) leave(c *client, project string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.leaveLocked(c, project)
+}
+
+func (h *Hub) leaveLocked(c *client, project string) {
+	if _, ok := c.projects[project]; !ok {
+		return
+	}
+
+	before := h.presenceLocked(project)
+	delete(c.projects, project)
+	delete(h.projects[project], c)
+	if len(h.projects[project]) == 0 {
+		delete(h.projects, project)
+	}
+	h.notifyPresenceLocked(project, before, c)
+}
+
+// presenceLocked — пользователи подписчиков проекта по алфавиту, без повторов.
+func (h *Hub) presenceLocked(project string) []string {
+	users := []string{}
+	for c := range h.projects[project] {
+		if c.user != "" {
+			users = append(users, c.user)
+		}
+	}
+	slices.Sort(users)
+	return slices.Compact(users)
+}
+
+// notifyPresenceLocked рассылает присутствие всем подписчикам проекта, кроме
+// except, если оно отличается от before.
+func (h *Hub) notifyPresenceLocked(project string, before []string, except *client) {
+	users := h.presenceLocked(project)
+	if slices.Equal(before, users) {
+		return
+	}
+
+	for c := range h.projects[project] {
+		if c != except {
+			c.enqueue(Message{Type: TypePresence, Project: project, Data: Presence{Users: users}})
+		}
+	}
+}
+
+// close отключает клиентов при остановке сервера; новые соединения сразу
+// закрываются.
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		c.close(websocket.CloseGoingAway, closeShutdown)
+	}
+}
+
+// CheckOrigin разрешает браузерам открывать соединение только с сайтов из
+// allowed. Без списка действует проверка gorilla/websocket: адрес сайта
+// должен совпадать с адресом сервиса.
+func CheckOrigin(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, item := range allowed {
+			if item == "*" || strings.EqualFold(item, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// UpgradeError отвечает на неудачное открытие WebSocket в общем формате ошибок.
+func UpgradeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	message := "ожидается запрос на открытие WebSocket"
+	if status == http.StatusForbidden {
+		message = "открывать WebSocket с этого сайта запрещено"
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
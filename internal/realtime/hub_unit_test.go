@@ -0,0 +1,385 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/identity"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+	"todo-api/internal/stream"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	server  *httptest.Server
+	service services.TodoService
+	hub     *Hub
+	cancel  context.CancelFunc
+}
+
+func newFixture(t *testing.T, cfg config.RealtimeConfig) *fixture {
+	t.Helper()
+
+	broker := stream.NewBroker(100)
+	service := services.NewTodoService(stream.Repository(repository.Constructor(), stream.Local(broker)))
+	hub := NewHub(service, broker, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		hub.Run(ctx)
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := r.Header.Get("X-User-ID"); user != "" {
+			r = r.WithContext(identity.WithUser(r.Context(), user))
+		}
+		hub.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+		broker.Close()
+		server.Close()
+	})
+
+	return &fixture{server: server, service: service, hub: hub, cancel: cancel}
+}
+
+func defaultConfig() config.RealtimeConfig {
+	return config.RealtimeConfig{PingInterval: time.Minute, WriteTimeout: time.Second, SendBuffer: 64, MaxMessageSize: 64 * 1024}
+}
+
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func (f *fixture) dial(t *testing.T, user string) *testClient {
+	t.Helper()
+
+	header := http.Header{}
+	if user != "" {
+		header.Set("X-User-ID", user)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(f.server.URL, "http"), header)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &testClient{t: t, conn: conn}
+}
+
+func (c *testClient) send(request Request) {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.WriteJSON(request))
+}
+
+// receivedMessage — Message с Data в виде JSON, чтобы разобрать его в нужный тип.
+type receivedMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Project string          `json:"project"`
+	Status  int             `json:"status"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (c *testClient) next() receivedMessage {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message receivedMessage
+	require.NoError(c.t, c.conn.ReadJSON(&message))
+	return message
+}
+
+// call отправляет запрос и ждёт ответ на него, пропуская события.
+func (c *testClient) call(request Request) receivedMessage {
+	c.t.Helper()
+
+	c.send(request)
+	for {
+		message := c.next()
+		if message.ID == request.ID && message.Type != TypeEvent && message.Type != TypePresence {
+			return message
+		}
+	}
+}
+
+// nextOf пропускает сообщения других типов.
+func (c *testClient) nextOf(messageType string) receivedMessage {
+	c.t.Helper()
+
+	for {
+		if message := c.next(); message.Type == messageType {
+			return message
+		}
+	}
+}
+
+func decodeData[T any](t *testing.T, message receivedMessage) T {
+	t.Helper()
+
+	var value T
+	require.NoError(t, json.Unmarshal(message.Data, &value))
+	return value
+}
+
+func TestHub_Subscribe(t *testing.T) {
+	f := newFixture(t, defaultConfig())
+	_, err := f.service.CreateTodo(context.Background(), &models.CreateTodoRequest{TaskName: "Своя", Project: "work"})
+	require.NoError(t, err)
+	_, err = f.service.CreateTodo(context.Background(), &models.CreateTodoRequest{TaskName: "Чужая", Project: "home"})
+	require.NoError(t, err)
+
+	alice := f.dial(t, "alice")
+	result := alice.call(Request{Type: TypeSubscribe, ID: "1", Project: " @work"})
+	require.Equal(t, TypeResult, result.Type, result.Error)
+	assert.Equal(t, "work", result.Project)
+
+	snapshot := decodeData[Snapshot](t, result)
+	assert.Equal(t, []string{"alice"}, snapshot.Users)
+	require.Len(t, snapshot.Todos, 1)
+	assert.Equal(t, "Своя", snapshot.Todos[0].TaskName)
+
+	result = alice.call(Request{Type: TypeSubscribe, ID: "2", Project: "empty"})
+	assert.Empty(t, decodeData[Snapshot](t, result).Todos)
+
+	result = alice.call(Request{Type: TypeSubscribe, ID: "3"})
+	assert.Equal(t, TypeError, result.Type)
+	assert.Equal(t, 400, result.Status)
+	assert.Equal(t, errEmptyProject.Error(), result.Error)
+}
+
+func TestHub_Events(t *testing.T) {
+	f := newFixture(t, defaultConfig())
+	work := f.dial(t, "alice")
+	home := f.dial(t, "bob")
+	work.call(Request{Type: TypeSubscribe, ID: "s", Project: "work"})
+	home.call(Request{Type: TypeSubscribe, ID: "s", Project: "home"})
+
+	todo, err := f.service.CreateTodo(identity.WithUser(context.Background(), "carol"),
+		&models.CreateTodoRequest{TaskName: "Отчёт", Project: "home"})
+	require.NoError(t, err)
+
+	event := home.nextOf(TypeEvent)
+	assert.Equal(t, "home", event.Project)
+	payload := decodeData[models.EventPayload](t, event)
+	assert.Equal(t, models.EventTodoCreated, payload.Type)
+	assert.Equal(t, "carol", payload.Actor)
+	assert.Equal(t, todo.ID, payload.Data.ID)
+
+	project := "work"
+	_, err = f.service.UpdateTodo(context.Background(), todo.ID, &models.UpdateTodoRequest{Project: &project})
+	require.NoError(t, err)
+
+	moved := home.nextOf(TypeEvent)
+	assert.Equal(t, "home", moved.Project, "прежний проект узнаёт, что задача ушла")
+	assert.Equal(t, "home", decodeData[models.EventPayload](t, moved).PreviousProject)
+
+	arrived := work.nextOf(TypeEvent)
+	assert.Equal(t, "work", arrived.Project, "первое событие проекта work — перенос задачи")
+	assert.Equal(t, todo.ID, decodeData[models.EventPayload](t, arrived).Data.ID)
+
+	result := home.call(Request{Type: TypeUnsubscribe, ID: "u", Project: "home"})
+	assert.Equal(t, TypeResult, result.Type)
+	require.NoError(t, f.service.DeleteTodo(context.Background(), todo.ID))
+	assert.Equal(t, models.EventTodoDeleted, decodeData[models.EventPayload](t, work.nextOf(TypeEvent)).Type)
+
+	pong := home.call(Request{Type: TypePing, ID: "p"})
+	assert.Equal(t, TypePong, pong.Type, "после отписки событий нет")
+}
+
+func TestHub_Mutations(t *testing.T) {
+	f := newFixture(t, defaultConfig())
+	alice := f.dial(t, "alice")
+	bob := f.dial(t, "bob")
+	bob.call(Request{Type: TypeSubscribe, ID: "s", Project: "work"})
+
+	created := alice.call(Request{Type: TypeCreate, ID: "c1", Data: json.RawMessage(`{"taskName":"Созвон","project":"work"}`)})
+	require.Equal(t, TypeResult, created.Type, created.Error)
+	todo := decodeData[models.Todo](t, created)
+	assert.Equal(t, "Созвон", todo.TaskName)
+
+	event := decodeData[models.EventPayload](t, bob.nextOf(TypeEvent))
+	assert.Equal(t, "alice", event.Actor, "изменение через WebSocket выполняется от имени пользователя соединения")
+
+	stale := int64(0)
+	conflict := alice.call(Request{Type: TypeUpdate, ID: "u1", TodoID: todo.ID, IfVersion: &stale, Data: json.RawMessage(`{"completed":true}`)})
+	assert.Equal(t, TypeError, conflict.Type)
+	assert.Equal(t, 409, conflict.Status)
+
+	updated := alice.call(Request{Type: TypeUpdate, ID: "u2", TodoID: todo.ID, IfVersion: &todo.Version, Data: json.RawMessage(`{"completed":true}`)})
+	require.Equal(t, TypeResult, updated.Type, updated.Error)
+	assert.True(t, decodeData[models.Todo](t, updated).Completed)
+	assert.Equal(t, models.EventTodoCompleted, decodeData[models.EventPayload](t, bob.nextOf(TypeEvent)).Type)
+
+	deleted := alice.call(Request{Type: TypeDelete, ID: "d1", TodoID: todo.ID})
+	assert.Equal(t, TypeResult, deleted.Type)
+	assert.Empty(t, deleted.Data)
+
+	missing := alice.call(Request{Type: TypeDelete, ID: "d2", TodoID: todo.ID})
+	assert.Equal(t, 404, missing.Status)
+
+	for _, request := range []Request{
+		{Type: TypeCreate, ID: "e1"},
+		{Type: TypeCreate, ID: "e2", Data: json.RawMessage(`{"taskName":1}`)},
+		{Type: TypeCreate, ID: "e3", Data: json.RawMessage(`{"taskName":""}`)},
+		{Type: "rename", ID: "e4"},
+	} {
+		response := alice.call(request)
+		assert.Equal(t, TypeError, response.Type, request.ID)
+		assert.Equal(t, 400, response.Status, request.ID)
+	}
+
+	require.NoError(t, alice.conn.WriteMessage(websocket.TextMessage, []byte("не json")))
+	invalid := alice.next()
+	assert.Equal(t, TypeError, invalid.Type)
+	assert.Equal(t, errInvalidJSON.Error(), invalid.Error)
+}
+
+func TestHub_Presence(t *testing.T) {
+	f := newFixture(t, defaultConfig())
+	alice := f.dial(t, "alice")
+	alice.call(Request{Type: TypeSubscribe, ID: "s", Project: "work"})
+
+	bob := f.dial(t, "bob")
+	snapshot := decodeData[Snapshot](t, bob.call(Request{Type: TypeSubscribe, ID: "s", Project: "work"}))
+	assert.Equal(t, []string{"alice", "bob"}, snapshot.Users)
+
+	presence := alice.nextOf(TypePresence)
+	assert.Equal(t, "work", presence.Project)
+	assert.Equal(t, []string{"alice", "bob"}, decodeData[Presence](t, presence).Users)
+
+	// Вторая вкладка и анонимный клиент присутствие не меняют.
+	bobAgain := f.dial(t, "bob")
+	bobAgain.call(Request{Type: TypeSubscribe, ID: "s", Project: "work"})
+	anonymous := f.dial(t, "")
+	anonymous.call(Request{Type: TypeSubscribe, ID: "s", Project: "work"})
+
+	bob.conn.Close()
+	bobAgain.call(Request{Type: TypeUnsubscribe, ID: "u", Project: "work"})
+
+	presence = alice.nextOf(TypePresence)
+	assert.Equal(t, []string{"alice"}, decodeData[Presence](t, presence).Users)
+}
+
+func TestHub_Reset(t *testing.T) {
+	f := newFixture(t, defaultConfig())
+	alice := f.dial(t, "alice")
+	alice.call(Request{Type: TypePing, ID: "p"})
+
+	f.hub.broker.Reset()
+
+	assert.Equal(t, TypeReset, alice.next().Type)
+}
+
+func TestHub_Heartbeat(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.PingInterval = 20 * time.Millisecond
+	f := newFixture(t, cfg)
+
+	alice := f.dial(t, "alice")
+	pings := make(chan struct{}, 100)
+	alice.conn.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return alice.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := alice.conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	silent := f.dial(t, "bob")
+
+	for range 5 {
+		select {
+		case <-pings:
+		case <-time.After(time.Second):
+			t.Fatal("сервер не пингует клиента")
+		}
+	}
+
+	// Клиент, который не читает и поэтому не отвечает на пинги, отключается.
+	time.Sleep(10 * cfg.PingInterval)
+	silent.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := silent.conn.ReadMessage(); err != nil {
+			var netErr net.Error
+			assert.False(t, errors.As(err, &netErr) && netErr.Timeout(), "соединение не закрыто сервером")
+			break
+		}
+	}
+}
+
+func TestHub_Shutdown(t *testing.T) {
+	f := newFixture(t, defaultConfig())
+	alice := f.dial(t, "alice")
+	alice.call(Request{Type: TypePing, ID: "p"})
+
+	f.cancel()
+
+	alice.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := alice.conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	late := f.dial(t, "bob")
+	late.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = late.conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "после остановки новые соединения закрываются")
+}
+
+func TestClient_SlowConsumer(t *testing.T) {
+	hub := NewHub(nil, stream.NewBroker(1), config.RealtimeConfig{SendBuffer: 2})
+	c := newClient(hub, nil, "alice")
+
+	c.enqueue(Message{Type: TypeEvent})
+	c.enqueue(Message{Type: TypeEvent})
+	select {
+	case <-c.done:
+		t.Fatal("очередь ещё не заполнена")
+	default:
+	}
+
+	c.enqueue(Message{Type: TypeEvent})
+	<-c.done
+	assert.Equal(t, websocket.CloseTryAgainLater, c.closeCode)
+
+	c.close(websocket.CloseNormalClosure, "")
+	assert.Equal(t, websocket.CloseTryAgainLater, c.closeCode, "срабатывает первое закрытие")
+}
+
+func TestCheckOrigin(t *testing.T) {
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest("GET", "/todos/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+
+	assert.Nil(t, CheckOrigin(nil), "без списка действует проверка по умолчанию")
+
+	check := CheckOrigin([]string{"https://app.example.com"})
+	assert.True(t, check(request("https://APP.example.com")))
+	assert.True(t, check(request("")))
+	assert.False(t, check(request("https://evil.example.com")))
+
+	assert.True(t, CheckOrigin([]string{"*"})(request("https://evil.example.com")))
+}
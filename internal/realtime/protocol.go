@@ -0,0 +1,64 @@
+package realtime
+
+import (
+	"encoding/json"
+	"todo-api/internal/models"
+)
+
+// Сообщения клиента.
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypeCreate      = "create"
+	TypeUpdate      = "update"
+	TypeDelete      = "delete"
+	TypePing        = "ping"
+)
+
+// Сообщения сервера.
+const (
+	TypeResult   = "result"
+	TypeError    = "error"
+	TypePong     = "pong"
+	TypeEvent    = "event"
+	TypePresence = "presence"
+	TypeReset    = "reset"
+)
+
+// Request — сообщение клиента. ID возвращается в ответе на сообщение, чтобы
+// клиент сопоставил ответ с запросом. Project — проект для subscribe и
+// unsubscribe, TodoID — задача для update и delete. Data — тело create и
+// update в том же виде, что в REST API. IfVersion применяет update, только
+// если версия задачи совпадает.
+type Request struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Project   string          `json:"project,omitempty"`
+	TodoID    string          `json:"todoId,omitempty"`
+	IfVersion *int64          `json:"ifVersion,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Message — сообщение сервера. На каждое сообщение клиента приходит result
+// или error с тем же ID; Status у ошибки — код, которым ответил бы REST API.
+// event и presence приходят по подпискам, в Project — проект подписки.
+type Message struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Project string `json:"project,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Presence — пользователи, которые смотрят проект. Подключения без
+// пользователя в список не попадают.
+type Presence struct {
+	Users []string `json:"users"`
+}
+
+// Snapshot — ответ на subscribe: задачи проекта и кто его смотрит.
+type Snapshot struct {
+	Users []string       `json:"users"`
+	Todos []*models.Todo `json:"todos"`
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// lockDir создаёт файл блокировки эксклюзивно. После аварийного завершения
+// процесса файл нужно удалить вручную.
+func lockDir(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrStorageLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать файл блокировки: %w", err)
+	}
+
+	return func() error {
+		file.Close()
+		return os.Remove(path)
+	}, nil
+}
+
+func syncDir(dir string) error {
+	return nil
+}
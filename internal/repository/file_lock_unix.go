@@ -0,0 +1,46 @@
+//go:build unix
+
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockDir берёт эксклюзивную flock-блокировку на файл. Ядро снимает её само,
+// если процесс завершится аварийно.
+func lockDir(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл блокировки: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrStorageLocked
+		}
+		return nil, fmt.Errorf("не удалось заблокировать каталог хранилища: %w", err)
+	}
+
+	return func() error {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		return file.Close()
+	}, nil
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("не удалось сбросить каталог на диск: %w", err)
+	}
+
+	return nil
+}
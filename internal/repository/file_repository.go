@@ -0,0 +1,685 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+	"todo-api/internal/models"
+)
+
+const (
+	snapshotFileName = "snapshot.json"
+	journalFileName  = "journal.jsonl"
+	lockFileName     = "LOCK"
+)
+
+const (
+	journalOpCreate = "create"
+	journalOpUpdate = "update"
+	journalOpDelete = "delete"
+	journalOpBatch  = "batch"
+)
+
+var ErrStorageLocked = errors.New("каталог хранилища уже используется другим процессом")
+var ErrStorageClosed = errors.New("хранилище закрыто")
+
+// FileRepository хранит задачи в памяти и сохраняет каждое изменение на диск.
+// Мутации сначала дописываются в журнал (JSON lines + fsync), а затем применяются
+// к StorageRepository. Журнал периодически сворачивается в снимок.
+type FileRepository struct {
+	mu      sync.Mutex
+	store   *StorageRepository
+	dir     string
+	journal *os.File
+	unlock  func() error
+	seq     uint64
+	pending int
+	closed  bool
+	// failed — ошибка, после которой журнал не удалось вернуть к последней
+	// подтверждённой записи. Изменения запрещены до перезапуска.
+	failed error
+	// compactErr — ошибка последнего фонового сворачивания журнала.
+	compactErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type journalEntry struct {
+	Seq    uint64                    `json:"seq"`
+	Op     string                    `json:"op"`
+	ID     string                    `json:"id"`
+	Todo   *models.Todo              `json:"todo,omitempty"`
+	Update *models.UpdateTodoRequest `json:"update,omitempty"`
+	// CompletedAt — время выполнения задачи после update. Оно пишется в
+	// журнал, чтобы восстановление не подставило время запуска.
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// Batch содержит изменения одной транзакции. Они пишутся одной строкой,
+	// поэтому после сбоя транзакция либо восстанавливается целиком, либо никак.
+	Batch []journalEntry `json:"batch,omitempty"`
+}
+
+type snapshot struct {
+	Seq   uint64         `json:"seq"`
+	Todos []*models.Todo `json:"todos"`
+}
+
+// NewFileRepository открывает (или создаёт) хранилище в каталоге dir, восстанавливает
+// состояние из снимка и журнала и запускает сворачивание журнала раз в compactInterval.
+// Нулевой интервал отключает фоновое сворачивание.
+func NewFileRepository(dir string, compactInterval time.Duration) (*FileRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог хранилища: %w", err)
+	}
+
+	unlock, err := lockDir(filepath.Join(dir, lockFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileRepository{
+		store:  Constructor(),
+		dir:    dir,
+		unlock: unlock,
+	}
+
+	if err := r.recover(); err != nil {
+		unlock()
+		return nil, err
+	}
+
+	journal, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("не удалось открыть журнал: %w", err)
+	}
+	r.journal = journal
+
+	if err := r.compactLocked(); err != nil {
+		journal.Close()
+		unlock()
+		return nil, err
+	}
+
+	if compactInterval > 0 {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.compactLoop(compactInterval)
+	}
+
+	return r, nil
+}
+
+func (r *FileRepository) Create(ctx context.Context, task *models.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateCreate(task); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writableLocked(); err != nil {
+		return err
+	}
+
+	if _, err := r.store.GetById(ctx, task.ID); err == nil {
+		return ErrAlreadyExist
+	} else if !errors.Is(err, ErrInvalidID) {
+		return err
+	}
+
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+
+	// После записи в журнал мутация считается подтверждённой, поэтому она
+	// применяется к памяти без учёта отмены запроса.
+	if err := r.appendLocked(journalEntry{Op: journalOpCreate, ID: task.ID, Todo: task}); err != nil {
+		return err
+	}
+
+	return r.store.Create(context.Background(), task)
+}
+
+func (r *FileRepository) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	return r.store.GetById(ctx, id)
+}
+
+func (r *FileRepository) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := validateUpdate(id, updateData); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writableLocked(); err != nil {
+		return err
+	}
+
+	current, err := r.store.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Условие проверяется до записи в журнал: при восстановлении его не с чем
+	// сравнивать, поэтому IfVersion в журнал не попадает.
+	if updateData.IfVersion != nil && *updateData.IfVersion != current.Version {
+		return ErrVersionConflict
+	}
+
+	entry := journalEntry{Op: journalOpUpdate, ID: id, Update: updateData}
+	if updateData.Completed != nil {
+		entry.CompletedAt = completedAt(current, *updateData.Completed, time.Now())
+	}
+
+	if err := r.appendLocked(entry); err != nil {
+		return err
+	}
+
+	return r.applyUpdate(entry)
+}
+
+func (r *FileRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if id == "" {
+		return ErrEmptyID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writableLocked(); err != nil {
+		return err
+	}
+
+	if _, err := r.store.GetById(ctx, id); err != nil {
+		return err
+	}
+
+	if err := r.appendLocked(journalEntry{Op: journalOpDelete, ID: id}); err != nil {
+		return err
+	}
+
+	return r.store.Delete(context.Background(), id)
+}
+
+func (r *FileRepository) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	return r.store.GetAllTask(ctx, filter)
+}
+
+func (r *FileRepository) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	return r.store.Each(ctx, filter, fn)
+}
+
+func (r *FileRepository) Count(ctx context.Context) (*models.TodoStats, error) {
+	return r.store.Count(ctx)
+}
+
+func (r *FileRepository) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return r.store.Search(ctx, query, limit)
+}
+
+// WithinTx применяет изменения fn к памяти под блокировкой StorageRepository и
+// при успехе записывает их в журнал одной записью. Ошибка fn или записи журнала
+// откатывает изменения в памяти.
+func (r *FileRepository) WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writableLocked(); err != nil {
+		return err
+	}
+
+	return r.store.WithinTx(ctx, func(repo TodoRepository) error {
+		tx := &fileTx{repo: repo}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		if len(tx.entries) == 0 {
+			return nil
+		}
+
+		return r.appendLocked(journalEntry{Op: journalOpBatch, Batch: tx.entries})
+	})
+}
+
+// AddEvents ничего не сохраняет: журнал не хранит outbox, поэтому вебхуки
+// с файловым хранилищем не работают.
+func (r *FileRepository) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return validateEvents(events)
+}
+
+// Compact сворачивает журнал в снимок.
+func (r *FileRepository) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrStorageClosed
+	}
+
+	return r.compactLocked()
+}
+
+// Close останавливает фоновое сворачивание, записывает финальный снимок и
+// освобождает блокировку каталога.
+func (r *FileRepository) Close() error {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+		r.stop = nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.compactLocked()
+
+	if closeErr := r.journal.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	if unlockErr := r.unlock(); unlockErr != nil && err == nil {
+		err = unlockErr
+	}
+
+	return err
+}
+
+// Healthy сообщает, открыто ли хранилище и успешно ли прошло последнее
+// фоновое сворачивание журнала.
+func (r *FileRepository) Healthy() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrStorageClosed
+	}
+
+	if r.failed != nil {
+		return r.failed
+	}
+
+	if r.compactErr != nil {
+		return fmt.Errorf("ошибка сворачивания журнала: %w", r.compactErr)
+	}
+
+	return nil
+}
+
+func (r *FileRepository) compactLoop(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			err := r.Compact()
+			if err != nil {
+				slog.Error("ошибка сворачивания журнала", "error", err)
+			}
+
+			r.mu.Lock()
+			r.compactErr = err
+			r.mu.Unlock()
+		}
+	}
+}
+
+// writableLocked сообщает, можно ли сейчас изменять хранилище.
+func (r *FileRepository) writableLocked() error {
+	if r.closed {
+		return ErrStorageClosed
+	}
+	return r.failed
+}
+
+func (r *FileRepository) appendLocked(entry journalEntry) error {
+	entry.Seq = r.seq + 1
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	info, err := r.journal.Stat()
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать размер журнала: %w", err)
+	}
+
+	if _, err := r.journal.Write(line); err != nil {
+		return r.rollbackLocked(info.Size(), fmt.Errorf("не удалось записать журнал: %w", err))
+	}
+
+	if err := r.journal.Sync(); err != nil {
+		return r.rollbackLocked(info.Size(), fmt.Errorf("не удалось сбросить журнал на диск: %w", err))
+	}
+
+	r.seq = entry.Seq
+	r.pending++
+
+	return nil
+}
+
+// rollbackLocked обрезает журнал до размера перед неудачной записью: иначе
+// строка с тем же seq, что у следующей записи, осталась бы в журнале. Если
+// обрезать не удалось, хранилище переходит в состояние ошибки.
+func (r *FileRepository) rollbackLocked(size int64, cause error) error {
+	if err := r.journal.Truncate(size); err != nil {
+		r.failed = fmt.Errorf("журнал не удалось вернуть к последней записи: %w", errors.Join(cause, err))
+		slog.Error("хранилище переведено в состояние ошибки", "error", r.failed)
+		return r.failed
+	}
+	return cause
+}
+
+// compactLocked записывает снимок во временный файл, атомарно переименовывает его
+// и только после этого очищает журнал. Если процесс упадёт между этими шагами,
+// при восстановлении записи журнала с seq не больше seq снимка будут пропущены.
+func (r *FileRepository) compactLocked() error {
+	if r.pending == 0 {
+		if _, err := os.Stat(filepath.Join(r.dir, snapshotFileName)); err == nil {
+			return nil
+		}
+	}
+
+	todos, err := r.store.GetAllTask(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snapshot{Seq: r.seq, Todos: todos})
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(r.dir, snapshotFileName+".tmp")
+	if err := writeFileSync(tmpPath, data); err != nil {
+		return fmt.Errorf("не удалось записать снимок: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(r.dir, snapshotFileName)); err != nil {
+		return fmt.Errorf("не удалось заменить снимок: %w", err)
+	}
+
+	if err := syncDir(r.dir); err != nil {
+		return err
+	}
+
+	if err := r.journal.Truncate(0); err != nil {
+		return fmt.Errorf("не удалось очистить журнал: %w", err)
+	}
+
+	if err := r.journal.Sync(); err != nil {
+		return fmt.Errorf("не удалось сбросить журнал на диск: %w", err)
+	}
+
+	r.pending = 0
+
+	return nil
+}
+
+func (r *FileRepository) recover() error {
+	data, err := os.ReadFile(filepath.Join(r.dir, snapshotFileName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("не удалось прочитать снимок: %w", err)
+	default:
+		var snap snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return fmt.Errorf("снимок повреждён: %w", err)
+		}
+
+		for _, todo := range snap.Todos {
+			if err := r.store.Create(context.Background(), todo); err != nil {
+				return fmt.Errorf("снимок повреждён: %w", err)
+			}
+		}
+		r.seq = snap.Seq
+	}
+
+	return r.replayJournal()
+}
+
+func (r *FileRepository) replayJournal() error {
+	path := filepath.Join(r.dir, journalFileName)
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось открыть журнал: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Незавершённая последняя строка означает, что процесс упал во время
+			// записи: такая мутация не была подтверждена клиенту и отбрасывается.
+			if len(bytes.TrimSpace(line)) > 0 {
+				if err := file.Truncate(offset); err != nil {
+					return fmt.Errorf("не удалось обрезать журнал: %w", err)
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("не удалось прочитать журнал: %w", err)
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("журнал повреждён на смещении %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+
+		if entry.Seq <= r.seq {
+			continue
+		}
+
+		if err := r.apply(entry); err != nil {
+			return fmt.Errorf("не удалось применить запись журнала %d: %w", entry.Seq, err)
+		}
+		r.seq = entry.Seq
+		r.pending++
+	}
+}
+
+func (r *FileRepository) apply(entry journalEntry) error {
+	switch entry.Op {
+	case journalOpCreate:
+		return r.store.Create(context.Background(), entry.Todo)
+	case journalOpUpdate:
+		return r.applyUpdate(entry)
+	case journalOpDelete:
+		return r.store.Delete(context.Background(), entry.ID)
+	case journalOpBatch:
+		for _, batched := range entry.Batch {
+			if err := r.apply(batched); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестная операция %q", entry.Op)
+	}
+}
+
+func (r *FileRepository) applyUpdate(entry journalEntry) error {
+	now := time.Now()
+	if entry.CompletedAt != nil {
+		now = *entry.CompletedAt
+	}
+
+	return r.store.updateAt(entry.ID, entry.Update, now)
+}
+
+// fileTx копит записи журнала для изменений, сделанных внутри транзакции.
+type fileTx struct {
+	repo    TodoRepository
+	entries []journalEntry
+}
+
+func (tx *fileTx) Create(ctx context.Context, task *models.Todo) error {
+	if err := tx.repo.Create(ctx, task); err != nil {
+		return err
+	}
+
+	tx.entries = append(tx.entries, journalEntry{Op: journalOpCreate, ID: task.ID, Todo: cloneTodo(task)})
+
+	return nil
+}
+
+func (tx *fileTx) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	return tx.repo.GetById(ctx, id)
+}
+
+func (tx *fileTx) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	if err := tx.repo.Update(ctx, id, updateData); err != nil {
+		return err
+	}
+
+	entry := journalEntry{Op: journalOpUpdate, ID: id, Update: cloneUpdate(updateData)}
+	if updateData.Completed != nil {
+		task, err := tx.repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		entry.CompletedAt = task.CompletedAt
+	}
+
+	tx.entries = append(tx.entries, entry)
+
+	return nil
+}
+
+func (tx *fileTx) Delete(ctx context.Context, id string) error {
+	if err := tx.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	tx.entries = append(tx.entries, journalEntry{Op: journalOpDelete, ID: id})
+
+	return nil
+}
+
+func (tx *fileTx) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	return tx.repo.GetAllTask(ctx, filter)
+}
+
+func (tx *fileTx) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	return tx.repo.Each(ctx, filter, fn)
+}
+
+func (tx *fileTx) Count(ctx context.Context) (*models.TodoStats, error) {
+	return tx.repo.Count(ctx)
+}
+
+func (tx *fileTx) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return tx.repo.Search(ctx, query, limit)
+}
+
+func (tx *fileTx) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return validateEvents(events)
+}
+
+func (tx *fileTx) WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error {
+	return tx.repo.WithinTx(ctx, func(TodoRepository) error {
+		return fn(tx)
+	})
+}
+
+func cloneUpdate(updateData *models.UpdateTodoRequest) *models.UpdateTodoRequest {
+	clone := *updateData
+	if updateData.TaskName != nil {
+		name := *updateData.TaskName
+		clone.TaskName = &name
+	}
+	if updateData.Description != nil {
+		description := *updateData.Description
+		clone.Description = &description
+	}
+	if updateData.Completed != nil {
+		completed := *updateData.Completed
+		clone.Completed = &completed
+	}
+	if updateData.DueAt != nil {
+		dueAt := *updateData.DueAt
+		clone.DueAt = &dueAt
+	}
+	if updateData.Priority != nil {
+		priority := *updateData.Priority
+		clone.Priority = &priority
+	}
+	if updateData.Project != nil {
+		project := *updateData.Project
+		clone.Project = &project
+	}
+	clone.Tags = slices.Clone(updateData.Tags)
+	return &clone
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
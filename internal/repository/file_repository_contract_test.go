@@ -0,0 +1,19 @@
+package repository_test
+
+import (
+	"testing"
+	"todo-api/internal/repository"
+	"todo-api/internal/repository/repotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRepository_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.TodoRepository {
+		repo, err := repository.NewFileRepository(t.TempDir(), 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+
+		return repo
+	})
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openFileRepo(t *testing.T, dir string) *FileRepository {
+	t.Helper()
+
+	repo, err := NewFileRepository(dir, 0)
+	require.NoError(t, err)
+
+	return repo
+}
+
+func journalSize(t *testing.T, dir string) int64 {
+	t.Helper()
+
+	info, err := os.Stat(filepath.Join(dir, journalFileName))
+	require.NoError(t, err)
+
+	return info.Size()
+}
+
+func TestFileRepo_RecoverFromJournal(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"}))
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "2", TaskName: "second"}))
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "3", TaskName: "third"}))
+
+	completed := true
+	require.NoError(t, repo.Update(t.Context(), "1", &models.UpdateTodoRequest{Completed: &completed}))
+	require.NoError(t, repo.Delete(t.Context(), "2"))
+	assert.NotZero(t, journalSize(t, dir))
+
+	done, err := repo.GetById(t.Context(), "1")
+	require.NoError(t, err)
+	require.NotNil(t, done.CompletedAt)
+
+	// Имитируем аварийное завершение: блокировку снимаем, но снимок не пишем.
+	require.NoError(t, repo.journal.Close())
+	require.NoError(t, repo.unlock())
+
+	reopened := openFileRepo(t, dir)
+	defer reopened.Close()
+
+	todos, err := reopened.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, todos, 2)
+	assert.Equal(t, "1", todos[0].ID)
+	assert.True(t, todos[0].Completed)
+	require.NotNil(t, todos[0].CompletedAt)
+	assert.True(t, done.CompletedAt.Equal(*todos[0].CompletedAt), "время выполнения берётся из журнала")
+	assert.Equal(t, int64(2), todos[0].Version)
+	assert.Equal(t, "3", todos[1].ID)
+	assert.Zero(t, journalSize(t, dir))
+}
+
+func TestFileRepo_RecoverIgnoresTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"}))
+	require.NoError(t, repo.journal.Close())
+	require.NoError(t, repo.unlock())
+
+	journal, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = journal.WriteString(`{"seq":2,"op":"create","id":"2","todo":{"id":"2","taskN`)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	reopened := openFileRepo(t, dir)
+	defer reopened.Close()
+
+	todos, err := reopened.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "1", todos[0].ID)
+}
+
+func TestFileRepo_RecoverCorruptedJournal(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, journalFileName), []byte("not json\n"), 0o644))
+
+	_, err := NewFileRepository(dir, 0)
+	assert.Error(t, err)
+}
+
+func TestFileRepo_RecoverSkipsCompactedEntries(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"}))
+
+	journal, err := os.ReadFile(filepath.Join(dir, journalFileName))
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// Снимок уже содержит запись, но журнал не успели очистить.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, journalFileName), journal, 0o644))
+
+	reopened := openFileRepo(t, dir)
+	defer reopened.Close()
+
+	todos, err := reopened.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Len(t, todos, 1)
+}
+
+func TestFileRepo_RecoverTransaction(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"}))
+
+	err := repo.WithinTx(t.Context(), func(tx TodoRepository) error {
+		if err := tx.Create(t.Context(), &models.Todo{ID: "2", TaskName: "second"}); err != nil {
+			return err
+		}
+		return tx.Delete(t.Context(), "1")
+	})
+	require.NoError(t, err)
+
+	err = repo.WithinTx(t.Context(), func(tx TodoRepository) error {
+		if err := tx.Create(t.Context(), &models.Todo{ID: "3", TaskName: "third"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	require.NoError(t, repo.journal.Close())
+	require.NoError(t, repo.unlock())
+
+	reopened := openFileRepo(t, dir)
+	defer reopened.Close()
+
+	todos, err := reopened.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "2", todos[0].ID)
+}
+
+func TestFileRepo_Compact(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+
+	description := "text"
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first", Description: &description, CreatedAt: createdAt}))
+	completed := true
+	require.NoError(t, repo.Update(t.Context(), "1", &models.UpdateTodoRequest{Completed: &completed}))
+	assert.NotZero(t, journalSize(t, dir))
+
+	require.NoError(t, repo.Compact())
+	assert.Zero(t, journalSize(t, dir))
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepo(t, dir)
+	defer reopened.Close()
+
+	todo, err := reopened.GetById(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "first", todo.TaskName)
+	assert.Equal(t, "text", *todo.Description)
+	assert.True(t, createdAt.Equal(todo.CreatedAt))
+	assert.Equal(t, int64(2), todo.Version, "версия переживает сворачивание в снимок")
+}
+
+func TestFileRepo_PeriodicCompaction(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := NewFileRepository(dir, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"}))
+
+	assert.Eventually(t, func() bool {
+		return journalSize(t, dir) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileRepo_ErrStorageLocked(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+
+	_, err := NewFileRepository(dir, 0)
+	assert.ErrorIs(t, err, ErrStorageLocked)
+
+	require.NoError(t, repo.Close())
+
+	reopened, err := NewFileRepository(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Close())
+}
+
+func TestFileRepo_ErrStorageClosed(t *testing.T) {
+	repo := openFileRepo(t, t.TempDir())
+	require.NoError(t, repo.Close())
+
+	err := repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"})
+	assert.ErrorIs(t, err, ErrStorageClosed)
+}
+
+func TestFileRepo_FailedJournalWrite(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepo(t, dir)
+	require.NoError(t, repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "first"}))
+
+	// Через дескриптор только для чтения не пройдут ни запись, ни обрезка журнала.
+	writable := repo.journal
+	readOnly, err := os.Open(filepath.Join(dir, journalFileName))
+	require.NoError(t, err)
+	repo.journal = readOnly
+
+	require.Error(t, repo.Create(t.Context(), &models.Todo{ID: "2", TaskName: "second"}))
+	assert.Equal(t, uint64(1), repo.seq)
+	require.Error(t, repo.Healthy())
+
+	_, err = repo.GetById(t.Context(), "2")
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	// Хранилище в состоянии ошибки не принимает изменения, даже если журнал снова доступен.
+	repo.journal = writable
+	require.NoError(t, readOnly.Close())
+	assert.Error(t, repo.Delete(t.Context(), "1"))
+
+	require.NoError(t, repo.Close())
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"todo-api/internal/models"
+)
+
+// matchesFilter проверяет задачу для хранилищ, которые держат задачи в памяти.
+func matchesFilter(task *models.Todo, filter *models.TodoFilter) bool {
+	if filter == nil {
+		return true
+	}
+
+	switch {
+	case filter.Completed != nil && task.Completed != *filter.Completed:
+		return false
+	case filter.Priority != "" && task.Priority != filter.Priority:
+		return false
+	case filter.Project != "" && task.Project != filter.Project:
+		return false
+	case filter.Tag != "" && !slices.Contains(task.Tags, filter.Tag):
+		return false
+	case filter.DueBefore != nil && (task.DueAt == nil || !task.DueAt.Before(*filter.DueBefore)):
+		return false
+	case filter.DueAfter != nil && (task.DueAt == nil || task.DueAt.Before(*filter.DueAfter)):
+		return false
+	case len(filter.Projects) > 0 && !slices.Contains(filter.Projects, task.Project):
+		return false
+	case len(filter.Tags) > 0 && !slices.ContainsFunc(task.Tags, func(tag string) bool { return slices.Contains(filter.Tags, tag) }):
+		return false
+	case filter.After != nil && !after(task, filter.After):
+		return false
+	}
+
+	return true
+}
+
+// after сообщает, что задача идёт после cursor в порядке создания.
+func after(task *models.Todo, cursor *models.TodoCursor) bool {
+	if !task.CreatedAt.Equal(cursor.CreatedAt) {
+		return task.CreatedAt.After(cursor.CreatedAt)
+	}
+	return task.ID > cursor.ID
+}
+
+// compareCreation сравнивает задачи в порядке создания: по времени, а при
+// одинаковом времени по айди.
+func compareCreation(a, b *models.Todo) int {
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+}
+
+// groupSlot решает, остаётся ли задача при ограничении PerGroup: она входит в
+// первые PerGroup задач хотя бы одной своей группы. counts — сколько задач
+// каждой группы уже встретилось.
+func groupSlot(task *models.Todo, filter *models.TodoFilter, counts map[string]int) bool {
+	groups := []string{task.Project}
+	if len(filter.Tags) > 0 {
+		groups = slices.DeleteFunc(slices.Clone(task.Tags), func(tag string) bool { return !slices.Contains(filter.Tags, tag) })
+	}
+
+	if !slices.ContainsFunc(groups, func(group string) bool { return counts[group] < filter.PerGroup }) {
+		return false
+	}
+	for _, group := range groups {
+		counts[group]++
+	}
+	return true
+}
+
+// filterDialect — то, чем условия отбора различаются в Postgres и SQLite.
+// placeholder возвращает обозначение n-го параметра; hasTag, inProjects и
+// hasAnyTag — шаблоны условий с одним %s, list — значение параметра-списка
+// для inProjects и hasAnyTag. tagGroups — шаблон, который раскладывает теги
+// задачи f на строки g.value и оставляет теги из списка %s.
+type filterDialect struct {
+	placeholder func(n int) string
+	hasTag      string
+	inProjects  string
+	hasAnyTag   string
+	list        func(values []string) any
+	tagGroups   string
+}
+
+// filterClause собирает условие WHERE для SQL-хранилищ.
+func filterClause(filter *models.TodoFilter, dialect filterDialect) (string, []any) {
+	if filter == nil {
+		return "", nil
+	}
+
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, dialect.placeholder(len(args))))
+	}
+
+	if filter.Completed != nil {
+		add("completed = %s", *filter.Completed)
+	}
+	if filter.Priority != "" {
+		add("priority = %s", filter.Priority)
+	}
+	if filter.Project != "" {
+		add("project = %s", filter.Project)
+	}
+	if filter.Tag != "" {
+		add(dialect.hasTag, filter.Tag)
+	}
+	if filter.DueBefore != nil {
+		add("due_at < %s", utcTime(filter.DueBefore))
+	}
+	if filter.DueAfter != nil {
+		add("due_at >= %s", utcTime(filter.DueAfter))
+	}
+	if len(filter.Projects) > 0 {
+		add(dialect.inProjects, dialect.list(filter.Projects))
+	}
+	if len(filter.Tags) > 0 {
+		add(dialect.hasAnyTag, dialect.list(filter.Tags))
+	}
+	if filter.After != nil {
+		args = append(args, utcTime(&filter.After.CreatedAt), filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) > (%s, %s)",
+			dialect.placeholder(len(args)-1), dialect.placeholder(len(args))))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if filter.PerGroup <= 0 {
+		return where, args
+	}
+
+	// Задачи нумеруются внутри группы среди подходящих под остальные условия.
+	// Задача с несколькими тегами остаётся, если она среди первых хотя бы в
+	// одном из них.
+	ranked := "SELECT id, row_number() OVER (PARTITION BY project ORDER BY created_at, id) AS group_rank FROM todos" + where
+	if len(filter.Tags) > 0 {
+		args = append(args, dialect.list(filter.Tags))
+		ranked = "SELECT f.id, row_number() OVER (PARTITION BY g.value ORDER BY f.created_at, f.id) AS group_rank" +
+			" FROM (SELECT id, created_at, tags FROM todos" + where + ") AS f, " +
+			fmt.Sprintf(dialect.tagGroups, dialect.placeholder(len(args)))
+	}
+
+	return " WHERE id IN (SELECT id FROM (" + ranked + ") AS ranked WHERE group_rank <= " + strconv.Itoa(filter.PerGroup) + ")", args
+}
+
+// limitClause ограничивает число задач, если фильтр задаёт Limit.
+func limitClause(filter *models.TodoFilter) string {
+	if filter == nil || filter.Limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(filter.Limit)
+}
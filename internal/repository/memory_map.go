@@ -1,99 +1,651 @@
 package repository
 
 import (
+	"context"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 	"todo-api/internal/models"
 )
 
+// StorageRepository хранит задачи в памяти процесса. Безопасен для конкурентного
+// использования: наружу отдаются только копии, внутреннее состояние не утекает.
 type StorageRepository struct {
+	mu    sync.RWMutex
 	todos map[string]*models.Todo
+	// order — айди задач по времени создания, а при одинаковом времени по
+	// айди, как в SQL-хранилищах и в курсоре страниц.
+	order []string
+	// outbox — события для вебхуков, их забирает MemoryWebhookRepository.
+	outbox []*models.Event
+
+	// seq — номер последнего изменения, seqs — номера последних изменений
+	// задач, tombstones — удалённые задачи. По ним отдаёт изменения
+	// MemorySyncRepository; purgedSeq — наибольший номер вычищенного удаления.
+	seq        int64
+	seqs       map[string]int64
+	tombstones map[string]tombstone
+	purgedSeq  int64
+}
+
+type tombstone struct {
+	seq       int64
+	deletedAt time.Time
 }
 
 func Constructor() *StorageRepository {
 	return &StorageRepository{
-		todos: make(map[string]*models.Todo),
+		todos:      make(map[string]*models.Todo),
+		seqs:       make(map[string]int64),
+		tombstones: make(map[string]tombstone),
 	}
 }
 
-func (s *StorageRepository) Create(task *models.Todo) error {
-	if task == nil {
-		return ErrEmptyTask
+func (s *StorageRepository) Create(ctx context.Context, task *models.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	if task.ID == "" {
-		return ErrEmptyID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(task)
+}
+
+func (s *StorageRepository) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.get(id)
+}
+
+func (s *StorageRepository) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.updateAt(id, updateData, time.Now())
+}
+
+// updateAt изменяет задачу так, будто это произошло в момент now. Файловое
+// хранилище передаёт сюда время из журнала.
+func (s *StorageRepository) updateAt(id string, updateData *models.UpdateTodoRequest, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.update(id, updateData, now)
+	return err
+}
+
+func (s *StorageRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, _, err := s.delete(id)
+	return err
+}
+
+func (s *StorageRepository) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list(filter), nil
+}
+
+// Each перебирает снимок, снятый под блокировкой, поэтому медленный fn не
+// задерживает запись.
+func (s *StorageRepository) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	todos := s.list(filter)
+	s.mu.RUnlock()
+
+	return eachTodo(ctx, todos, fn)
+}
+
+func (s *StorageRepository) Count(ctx context.Context) (*models.TodoStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.count(), nil
+}
+
+func (s *StorageRepository) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	terms, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return searchTodos(s.list(nil), terms, limit), nil
+}
+
+// WithinTx держит эксклюзивную блокировку на время fn и записывает обратные
+// операции для каждого изменения. Если fn вернёт ошибку, изменения откатываются.
+// Внутри fn нужно работать только через переданный repo.
+func (s *StorageRepository) WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryTx{store: s}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (s *StorageRepository) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addEvents(events)
+}
+
+func (s *StorageRepository) addEvents(events []*models.Event) error {
+	if err := validateEvents(events); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		event.CreatedAt = time.Now()
+		clone := *event
+		clone.Payload = slices.Clone(event.Payload)
+		s.outbox = append(s.outbox, &clone)
+	}
+
+	return nil
+}
+
+// takeEvents забирает из outbox до limit самых старых событий.
+func (s *StorageRepository) takeEvents(limit int) []*models.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(limit, len(s.outbox))
+	events := slices.Clone(s.outbox[:n])
+	s.outbox = slices.Delete(s.outbox, 0, n)
+
+	return events
+}
+
+func (s *StorageRepository) create(task *models.Todo) error {
+	if err := validateCreate(task); err != nil {
+		return err
 	}
 
 	if _, exists := s.todos[task.ID]; exists {
 		return ErrAlreadyExist
 	}
 
-	s.todos[task.ID] = task
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if task.Completed && task.CompletedAt == nil {
+		completedAt := task.CreatedAt
+		task.CompletedAt = &completedAt
+	}
+	// Задачи из снимка файлового хранилища приходят со своей версией.
+	if task.Version == 0 {
+		task.Version = 1
+	}
+	task.FieldChanges = nil
+
+	position, _ := slices.BinarySearchFunc(s.order, task, func(id string, target *models.Todo) int {
+		return compareCreation(s.todos[id], target)
+	})
+	s.todos[task.ID] = cloneTodo(task)
+	s.order = slices.Insert(s.order, position, task.ID)
+	s.seq++
+	s.seqs[task.ID] = s.seq
+	delete(s.tombstones, task.ID)
 
 	return nil
 }
 
-func (s *StorageRepository) GetById(id string) (*models.Todo, error) {
+func (s *StorageRepository) get(id string) (*models.Todo, error) {
 	if id == "" {
 		return nil, ErrEmptyID
 	}
 
 	if task, exists := s.todos[id]; exists {
-		return task, nil
+		return cloneTodo(task), nil
 	}
 
 	return nil, ErrInvalidID
 }
 
-func (s *StorageRepository) Update(id string, updateData *models.UpdateTodoRequest) error {
+// update возвращает копию задачи до изменения.
+func (s *StorageRepository) update(id string, updateData *models.UpdateTodoRequest, now time.Time) (*models.Todo, error) {
+	name, err := validateUpdate(id, updateData)
+	if err != nil {
+		return nil, err
+	}
+
+	task, exists := s.todos[id]
+	if !exists {
+		return nil, ErrInvalidID
+	}
+
+	if updateData.IfVersion != nil && *updateData.IfVersion != task.Version {
+		return nil, ErrVersionConflict
+	}
+
+	previous := cloneTodo(task)
+	task.FieldChanges = fieldChanges(previous, updateData, task.Version+1, now)
+
+	if updateData.TaskName != nil {
+		task.TaskName = name
+	}
+	if updateData.Description != nil {
+		description := *updateData.Description
+		task.Description = &description
+	}
+	if updateData.Completed != nil {
+		task.CompletedAt = completedAt(task, *updateData.Completed, now)
+		task.Completed = *updateData.Completed
+	}
+	if updateData.DueAt != nil {
+		dueAt := *updateData.DueAt
+		task.DueAt = &dueAt
+	}
+	if updateData.ClearDueAt {
+		task.DueAt = nil
+	}
+	if updateData.Priority != nil {
+		task.Priority = *updateData.Priority
+	}
+	if updateData.Tags != nil {
+		task.Tags = slices.Clone(updateData.Tags)
+	}
+	if updateData.Project != nil {
+		task.Project = *updateData.Project
+	}
+	task.Version++
+	s.seq++
+	s.seqs[id] = s.seq
+
+	return previous, nil
+}
+
+// delete возвращает удалённую задачу и её позицию в порядке создания.
+func (s *StorageRepository) delete(id string) (*models.Todo, int, error) {
 	if id == "" {
-		return ErrEmptyID
+		return nil, 0, ErrEmptyID
 	}
 
-	if updateData == nil {
-		return ErrEmptyTask
+	task, exists := s.todos[id]
+	if !exists {
+		return nil, 0, ErrInvalidID
 	}
 
-	if task, exists := s.todos[id]; exists {
-		if updateData.Completed != nil {
-			task.Completed = *updateData.Completed
+	delete(s.todos, id)
+	delete(s.seqs, id)
+	s.seq++
+	s.tombstones[id] = tombstone{seq: s.seq, deletedAt: time.Now()}
+
+	position := -1
+	for i, orderedID := range s.order {
+		if orderedID == id {
+			position = i
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+
+	return task, position, nil
+}
+
+func (s *StorageRepository) list(filter *models.TodoFilter) []*models.Todo {
+	result := make([]*models.Todo, 0, len(s.order))
+
+	counts := make(map[string]int)
+	for _, id := range s.order {
+		if filter != nil && filter.Limit > 0 && len(result) == filter.Limit {
+			break
 		}
-		if updateData.Description != nil {
-			task.Description = updateData.Description
+		task := s.todos[id]
+		if !matchesFilter(task, filter) || filter != nil && filter.PerGroup > 0 && !groupSlot(task, filter, counts) {
+			continue
 		}
-		if updateData.TaskName != nil {
-			name := strings.TrimSpace(*updateData.TaskName)
-			if name == "" {
-				return ErrEmptyName
-			}
-			task.TaskName = name
+		result = append(result, cloneTodo(task))
+	}
+
+	return result
+}
+
+func (s *StorageRepository) count() *models.TodoStats {
+	stats := &models.TodoStats{Total: len(s.todos)}
+
+	for _, task := range s.todos {
+		if task.Completed {
+			stats.Completed++
 		}
+	}
+
+	return stats
+}
+
+// syncState — номер изменения и запись об удалении задачи, которые
+// откатывает memoryTx. Счётчик s.seq не откатывается: пропуски в номерах
+// синхронизации не мешают.
+type syncState struct {
+	seq       int64
+	tombstone tombstone
+}
+
+func (s *StorageRepository) saveSync(id string) syncState {
+	return syncState{seq: s.seqs[id], tombstone: s.tombstones[id]}
+}
+
+func (s *StorageRepository) restoreSync(id string, state syncState) {
+	if state.seq == 0 {
+		delete(s.seqs, id)
+	} else {
+		s.seqs[id] = state.seq
+	}
+	if state.tombstone.seq == 0 {
+		delete(s.tombstones, id)
 	} else {
-		return ErrInvalidID
+		s.tombstones[id] = state.tombstone
 	}
+}
+
+// memoryTx работает с хранилищем под уже захваченной блокировкой и копит
+// обратные операции для отката.
+type memoryTx struct {
+	store *StorageRepository
+	undo  []func()
+}
+
+func (tx *memoryTx) Create(ctx context.Context, task *models.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	saved := tx.store.saveSync(task.ID)
+	if err := tx.store.create(task); err != nil {
+		return err
+	}
+
+	id := task.ID
+	tx.undo = append(tx.undo, func() {
+		tx.store.delete(id)
+		tx.store.restoreSync(id, saved)
+	})
 
 	return nil
 }
 
-func (s *StorageRepository) Delete(id string) error {
-	if id == "" {
+func (tx *memoryTx) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return tx.store.get(id)
+}
+
+func (tx *memoryTx) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	saved := tx.store.saveSync(id)
+	previous, err := tx.store.update(id, updateData, time.Now())
+	if err != nil {
+		return err
+	}
+
+	tx.undo = append(tx.undo, func() {
+		tx.store.todos[id] = previous
+		tx.store.restoreSync(id, saved)
+	})
+
+	return nil
+}
+
+func (tx *memoryTx) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	saved := tx.store.saveSync(id)
+	task, position, err := tx.store.delete(id)
+	if err != nil {
+		return err
+	}
+
+	tx.undo = append(tx.undo, func() {
+		tx.store.restoreSync(id, saved)
+		tx.store.todos[id] = task
+		tx.store.order = append(tx.store.order, "")
+		copy(tx.store.order[position+1:], tx.store.order[position:])
+		tx.store.order[position] = id
+	})
+
+	return nil
+}
+
+func (tx *memoryTx) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return tx.store.list(filter), nil
+}
+
+func (tx *memoryTx) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return eachTodo(ctx, tx.store.list(filter), fn)
+}
+
+func (tx *memoryTx) Count(ctx context.Context) (*models.TodoStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return tx.store.count(), nil
+}
+
+func (tx *memoryTx) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	terms, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return searchTodos(tx.store.list(nil), terms, limit), nil
+}
+
+func (tx *memoryTx) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n := len(tx.store.outbox)
+	if err := tx.store.addEvents(events); err != nil {
+		return err
+	}
+
+	// Пока идёт транзакция, outbox никто не разбирает: store заблокирован.
+	tx.undo = append(tx.undo, func() {
+		tx.store.outbox = tx.store.outbox[:n]
+	})
+
+	return nil
+}
+
+// WithinTx внутри транзакции присоединяется к ней.
+func (tx *memoryTx) WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return fn(tx)
+}
+
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+// eachTodo передаёт задачи в fn по одной и останавливается на первой ошибке
+// fn или при отмене ctx.
+func eachTodo(ctx context.Context, todos []*models.Todo, fn func(task *models.Todo) error) error {
+	for _, task := range todos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateCreate проверяет задачу перед сохранением.
+func validateCreate(task *models.Todo) error {
+	if task == nil {
+		return ErrEmptyTask
+	}
+
+	if task.ID == "" {
 		return ErrEmptyID
 	}
 
-	if _, exists := s.todos[id]; exists {
-		delete(s.todos, id)
-		return nil
+	if !ValidPriority(task.Priority) {
+		return ErrInvalidPriority
 	}
 
-	return ErrInvalidID
+	return nil
 }
 
-func (s *StorageRepository) GetAllTask() ([]*models.Todo, error) {
-	var result []*models.Todo
+// validateUpdate проверяет запрос на обновление и возвращает очищенное имя задачи.
+func validateUpdate(id string, updateData *models.UpdateTodoRequest) (string, error) {
+	if id == "" {
+		return "", ErrEmptyID
+	}
 
-	for _, task := range s.todos {
-		result = append(result, task)
+	if updateData == nil {
+		return "", ErrEmptyTask
+	}
+
+	if updateData.TaskName == nil && updateData.Description == nil && updateData.Completed == nil &&
+		updateData.DueAt == nil && updateData.Priority == nil && updateData.Tags == nil && updateData.Project == nil &&
+		!updateData.ClearDueAt {
+		return "", ErrEmptyData
+	}
+
+	if updateData.DueAt != nil && updateData.ClearDueAt {
+		return "", ErrDueAtConflict
+	}
+
+	if updateData.Priority != nil && !ValidPriority(*updateData.Priority) {
+		return "", ErrInvalidPriority
+	}
+
+	var name string
+	if updateData.TaskName != nil {
+		name = strings.TrimSpace(*updateData.TaskName)
+		if name == "" {
+			return "", ErrEmptyName
+		}
+	}
+
+	return name, nil
+}
+
+// ValidPriority допускает пустой приоритет и значения models.Priority*.
+func ValidPriority(priority string) bool {
+	switch priority {
+	case "", models.PriorityLow, models.PriorityMedium, models.PriorityHigh:
+		return true
+	}
+	return false
+}
+
+// completedAt возвращает время выполнения задачи после смены отметки на
+// completed. Повторная отметка не сдвигает время, снятие отметки его стирает.
+// Так же считают SQL-хранилища.
+func completedAt(task *models.Todo, completed bool, now time.Time) *time.Time {
+	switch {
+	case !completed:
+		return nil
+	case task.Completed:
+		return task.CompletedAt
+	default:
+		return &now
 	}
+}
 
-	return result, nil
+func cloneTodo(task *models.Todo) *models.Todo {
+	clone := *task
+	if task.Description != nil {
+		description := *task.Description
+		clone.Description = &description
+	}
+	if task.CompletedAt != nil {
+		completedAt := *task.CompletedAt
+		clone.CompletedAt = &completedAt
+	}
+	if task.DueAt != nil {
+		dueAt := *task.DueAt
+		clone.DueAt = &dueAt
+	}
+	clone.Tags = slices.Clone(task.Tags)
+	clone.FieldChanges = maps.Clone(task.FieldChanges)
+	return &clone
 }
@@ -0,0 +1,27 @@
+package repository_test
+
+import (
+	"testing"
+	"todo-api/internal/repository"
+	"todo-api/internal/repository/repotest"
+)
+
+func TestStorageRepo_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.TodoRepository {
+		return repository.Constructor()
+	})
+}
+
+func TestMemoryWebhookRepository_Contract(t *testing.T) {
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.TodoRepository, repository.WebhookRepository) {
+		todos := repository.Constructor()
+		return todos, repository.NewMemoryWebhookRepository(todos)
+	})
+}
+
+func TestMemorySyncRepository_Contract(t *testing.T) {
+	repotest.RunSync(t, func(t *testing.T) (repository.TodoRepository, repository.SyncRepository) {
+		todos := repository.Constructor()
+		return todos, repository.NewMemorySyncRepository(todos)
+	})
+}
@@ -11,28 +11,28 @@ func TestStorageRepo_Create(t *testing.T) {
 	repo := Constructor()
 	todo := &models.Todo{ID: "1", TaskName: "test"}
 
-	err := repo.Create(todo)
+	err := repo.Create(t.Context(), todo)
 	assert.NoError(t, err)
 }
 
 func TestStorageRepo_Create_ErrEmptyTask(t *testing.T) {
 	repo := Constructor()
-	err := repo.Create(nil)
+	err := repo.Create(t.Context(), nil)
 	assert.ErrorIs(t, err, ErrEmptyTask)
 }
 
 func TestStorageRepo_Create_ErrEmptyID(t *testing.T) {
 	repo := Constructor()
 	todo := &models.Todo{ID: "", TaskName: "test"}
-	err := repo.Create(todo)
+	err := repo.Create(t.Context(), todo)
 	assert.ErrorIs(t, err, ErrEmptyID)
 }
 
 func TestStorageRepo_Create_ErrAlreadyExist(t *testing.T) {
 	repo := Constructor()
 	todo := &models.Todo{ID: "1", TaskName: "test"}
-	_ = repo.Create(todo)
-	err := repo.Create(todo)
+	_ = repo.Create(t.Context(), todo)
+	err := repo.Create(t.Context(), todo)
 	assert.ErrorIs(t, err, ErrAlreadyExist)
 }
 
@@ -40,37 +40,37 @@ func TestStorageRepo_GetByID(t *testing.T) {
 	repo := Constructor()
 	todo := &models.Todo{ID: "1", TaskName: "test"}
 
-	_ = repo.Create(todo)
+	_ = repo.Create(t.Context(), todo)
 
-	_, err := repo.GetById(todo.ID)
+	_, err := repo.GetById(t.Context(), todo.ID)
 
 	assert.NoError(t, err)
 }
 
 func TestStorageRepo_GetByID_ErrEmptyID(t *testing.T) {
 	repo := Constructor()
-	_, err := repo.GetById("")
+	_, err := repo.GetById(t.Context(), "")
 	assert.ErrorIs(t, err, ErrEmptyID)
 }
 
 func TestStorageRepo_GetByID_ErrInvalidID(t *testing.T) {
 	repo := Constructor()
-	_, err := repo.GetById("2")
+	_, err := repo.GetById(t.Context(), "2")
 	assert.ErrorIs(t, err, ErrInvalidID)
 }
 
 func TestStorageRepo_Update_Success(t *testing.T) {
 	repo := Constructor()
 	todo := &models.Todo{ID: "1", TaskName: "test"}
-	_ = repo.Create(todo)
+	_ = repo.Create(t.Context(), todo)
 
 	newName := "updated"
 	updateData := &models.UpdateTodoRequest{TaskName: &newName}
 
-	err := repo.Update(todo.ID, updateData)
+	err := repo.Update(t.Context(), todo.ID, updateData)
 	assert.NoError(t, err)
 
-	updated, err := repo.GetById(todo.ID)
+	updated, err := repo.GetById(t.Context(), todo.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, "updated", updated.TaskName)
 }
@@ -86,7 +86,7 @@ func TestStorageRepo_Update_Err_ErrEmptyID(t *testing.T) {
 		Completed: &completed,
 	}
 
-	err := repo.Update("", updateData)
+	err := repo.Update(t.Context(), "", updateData)
 
 	assert.ErrorIs(t, err, ErrEmptyID)
 }
@@ -96,7 +96,7 @@ func TestStorageRepo_Update_Err_ErrEmptyTask(t *testing.T) {
 
 	todo := &models.Todo{ID: "1", TaskName: "test"}
 
-	err := repo.Update(todo.ID, nil)
+	err := repo.Update(t.Context(), todo.ID, nil)
 
 	assert.ErrorIs(t, err, ErrEmptyTask)
 }
@@ -112,7 +112,7 @@ func TestStorageRepo_Update_Err_ErrInvalidID(t *testing.T) {
 		Completed: &completed,
 	}
 
-	err := repo.Update("1", updateData)
+	err := repo.Update(t.Context(), "1", updateData)
 
 	assert.ErrorIs(t, err, ErrInvalidID)
 }
@@ -120,19 +120,19 @@ func TestStorageRepo_Update_Err_ErrInvalidID(t *testing.T) {
 func TestStorageRepo_Delete(t *testing.T) {
 	repo := Constructor()
 	todo := &models.Todo{ID: "1", TaskName: "test"}
-	_ = repo.Create(todo)
+	_ = repo.Create(t.Context(), todo)
 
-	err := repo.Delete(todo.ID)
+	err := repo.Delete(t.Context(), todo.ID)
 	assert.NoError(t, err)
 
-	_, err = repo.GetById(todo.ID)
+	_, err = repo.GetById(t.Context(), todo.ID)
 	assert.ErrorIs(t, err, ErrInvalidID)
 }
 
 func TestStorageRepo_Delete_ErrEmptyID(t *testing.T) {
 	repo := Constructor()
 
-	err := repo.Delete("")
+	err := repo.Delete(t.Context(), "")
 
 	assert.ErrorIs(t, err, ErrEmptyID)
 }
@@ -140,17 +140,17 @@ func TestStorageRepo_Delete_ErrEmptyID(t *testing.T) {
 func TestStorageRepo_Delete_ErrInvalidID(t *testing.T) {
 	repo := Constructor()
 
-	err := repo.Delete("2")
+	err := repo.Delete(t.Context(), "2")
 
 	assert.ErrorIs(t, err, ErrInvalidID)
 }
 
 func TestStorageRepo_GetAllTask(t *testing.T) {
 	repo := Constructor()
-	_ = repo.Create(&models.Todo{ID: "1", TaskName: "test1"})
-	_ = repo.Create(&models.Todo{ID: "2", TaskName: "test2"})
+	_ = repo.Create(t.Context(), &models.Todo{ID: "1", TaskName: "test1"})
+	_ = repo.Create(t.Context(), &models.Todo{ID: "2", TaskName: "test2"})
 
-	todos, err := repo.GetAllTask()
+	todos, err := repo.GetAllTask(t.Context(), nil)
 	assert.NoError(t, err)
 	assert.Len(t, todos, 2)
 }
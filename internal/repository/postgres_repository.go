@@ -1,28 +1,61 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 	"todo-api/internal/models"
+
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type TodoRepository interface {
-	Create(task *models.Todo) error
-	GetById(id string) (*models.Todo, error)
-	Update(id string, updateData *models.UpdateTodoRequest) error
-	Delete(id string) error
-	GetAllTask() ([]*models.Todo, error)
+	Create(ctx context.Context, task *models.Todo) error
+	GetById(ctx context.Context, id string) (*models.Todo, error)
+	Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error
+	Delete(ctx context.Context, id string) error
+	// GetAllTask возвращает задачи, подходящие под filter, в порядке создания.
+	// nil означает все задачи.
+	GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error)
+	// Each передаёт в fn задачи, подходящие под filter, в том же порядке, что
+	// GetAllTask, не загружая их все в память. Ошибка fn прерывает перебор и
+	// возвращается как есть. Пока идёт перебор, SQL-хранилища держат открытый
+	// курсор, поэтому внутри WithinTx fn не должен обращаться к репозиторию.
+	// Таймаут запроса к Each не применяется: перебор длится, пока жив ctx.
+	Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error
+	Count(ctx context.Context) (*models.TodoStats, error)
+	// Search ищет задачи по названию и описанию и возвращает не больше limit
+	// результатов по убыванию релевантности. Синтаксис запроса: слова через
+	// пробел, "фраза в кавычках", префикс*.
+	Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error)
+	// WithinTx выполняет fn в одной транзакции: если fn вернёт ошибку, все
+	// изменения, сделанные через переданный repo, откатываются. Вложенный вызов
+	// присоединяется к текущей транзакции.
+	WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error
+	// AddEvents записывает события об изменениях задач в outbox, откуда их
+	// забирает рассылка вебхуков. Внутри WithinTx события фиксируются и
+	// откатываются вместе с изменениями.
+	AddEvents(ctx context.Context, events ...*models.Event) error
 }
 
 type PostgresRepository struct {
-	db *sql.DB
+	db           *sql.DB
+	q            querier
+	tx           *sql.Tx
+	queryTimeout time.Duration
 }
 
-func NewPostgresRepository(db *sql.DB) TodoRepository {
+// NewPostgresRepository создаёт репозиторий, ограничивающий каждый запрос
+// к базе таймаутом queryTimeout. Нулевой таймаут отключает ограничение.
+func NewPostgresRepository(db *sql.DB, queryTimeout time.Duration) TodoRepository {
 	return &PostgresRepository{
-		db: db,
+		db:           db,
+		q:            traceQuerier(db, semconv.DBSystemPostgreSQL),
+		queryTimeout: queryTimeout,
 	}
 }
 
@@ -32,105 +65,400 @@ var ErrEmptyTask = errors.New("передана пустая задача")
 var ErrEmptyData = errors.New("переданы пустые данные")
 var ErrAlreadyExist = errors.New("задача с таким айди уже существует")
 var ErrEmptyName = errors.New("необходимо передать наименование задачи")
+var ErrInvalidPriority = errors.New("приоритет должен быть low, medium или high")
+var ErrDueAtConflict = errors.New("нельзя одновременно задать и снять срок")
+var ErrVersionConflict = errors.New("задача изменилась с момента чтения")
+
+func (r *PostgresRepository) Create(ctx context.Context, task *models.Todo) error {
+	if err := validateCreate(task); err != nil {
+		return err
+	}
+
+	// Изменение фиксируется вместе со своим номером синхронизации.
+	if r.tx == nil {
+		return r.WithinTx(ctx, func(repo TodoRepository) error { return repo.Create(ctx, task) })
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
 
-func (r *PostgresRepository) Create(task *models.Todo) error {
-	if task == nil {
-		return ErrEmptyTask
+	pos, err := nextPostgresChange(ctx, r.q)
+	if err != nil {
+		return err
 	}
 
-	query := "INSERT INTO todos (task_name, description, completed) VALUES ($1, $2, $3) RETURNING id, created_at"
+	// now() одинаков для всей транзакции, а clock_timestamp() сохраняет порядок
+	// задач, созданных в одной транзакции, например при импорте.
+	query := `INSERT INTO todos (id, task_name, description, completed, created_at, completed_at, due_at, priority, tags, project, change_xid, change_seq)
+VALUES ($1, $2, $3, $4, clock_timestamp(), CASE WHEN $4 THEN clock_timestamp() END, $5, $6, $7, $8, $9, $10)
+RETURNING created_at, completed_at, version`
 
-	err := r.db.QueryRow(query, task.TaskName, task.Description, task.Completed).Scan(&task.ID, &task.CreatedAt)
+	err = r.q.QueryRowContext(ctx, query,
+		task.ID, task.TaskName, task.Description, task.Completed,
+		task.DueAt, task.Priority, pq.Array(nonNilTags(task.Tags)), task.Project, pos.Tx, pos.Seq,
+	).Scan(&task.CreatedAt, &task.CompletedAt, &task.Version)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key") {
 			return ErrAlreadyExist
 		}
-		return err
+		return contextError(ctx, err)
 	}
 
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM todo_tombstones WHERE id = $1", task.ID); err != nil {
+		return contextError(ctx, err)
+	}
+	task.FieldChanges = nil
+
 	return nil
 }
 
-func (r *PostgresRepository) Update(id string, updateData *models.UpdateTodoRequest) error {
+func (r *PostgresRepository) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	name, err := validateUpdate(id, updateData)
+	if err != nil {
+		return err
+	}
+
+	if r.tx == nil {
+		return r.WithinTx(ctx, func(repo TodoRepository) error { return repo.Update(ctx, id, updateData) })
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	pos, err := nextPostgresChange(ctx, r.q)
+	if err != nil {
+		return err
+	}
+
+	// Строка заблокирована до конца транзакции, поэтому прочитанная задача не
+	// изменится до UPDATE.
+	current, err := r.getById(ctx, id, " FOR UPDATE")
+	if err != nil {
+		return err
+	}
+	if updateData.IfVersion != nil && *updateData.IfVersion != current.Version {
+		return ErrVersionConflict
+	}
+
 	args := []any{}
 	setParts := []string{}
-	argIndex := 1
 
-	if updateData.TaskName != nil {
-		setParts = append(setParts, fmt.Sprintf("task_name = $%d", argIndex))
-		args = append(args, *updateData.TaskName)
-		argIndex++
+	set := func(column string, value any) {
+		args = append(args, value)
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", column, len(args)))
 	}
 
+	if updateData.TaskName != nil {
+		set("task_name", name)
+	}
 	if updateData.Description != nil {
-		setParts = append(setParts, fmt.Sprintf("description = $%d", argIndex))
-		args = append(args, *updateData.Description)
-		argIndex++
+		set("description", *updateData.Description)
 	}
-
 	if updateData.Completed != nil {
-		setParts = append(setParts, fmt.Sprintf("completed = $%d", argIndex))
-		args = append(args, *updateData.Completed)
-		argIndex++
+		set("completed", *updateData.Completed)
+		// В правой части SET столбец completed ещё хранит старое значение.
+		setParts = append(setParts, fmt.Sprintf(
+			"completed_at = CASE WHEN NOT $%d THEN NULL WHEN completed THEN completed_at ELSE clock_timestamp() END", len(args)))
 	}
-
-	if len(setParts) == 0 {
-		return ErrEmptyData
+	if updateData.DueAt != nil {
+		set("due_at", *updateData.DueAt)
+	}
+	if updateData.ClearDueAt {
+		setParts = append(setParts, "due_at = NULL")
+	}
+	if updateData.Priority != nil {
+		set("priority", *updateData.Priority)
 	}
+	if updateData.Tags != nil {
+		set("tags", pq.Array(updateData.Tags))
+	}
+	if updateData.Project != nil {
+		set("project", *updateData.Project)
+	}
+	set("field_changes", encodeFieldChanges(fieldChanges(current, updateData, current.Version+1, time.Now().UTC())))
+	set("change_xid", pos.Tx)
+	set("change_seq", pos.Seq)
+
+	setParts = append(setParts, "version = version + 1")
 
-	query := fmt.Sprintf("UPDATE todos SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
 	args = append(args, id)
+	query := fmt.Sprintf("UPDATE todos SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
+
+	result, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return contextError(ctx, err)
+	}
 
-	_, err := r.db.Exec(query, args...)
-	return err
+	return checkAffected(result)
 }
 
-func (r *PostgresRepository) GetById(id string) (*models.Todo, error) {
-	query := "SELECT id, task_name, description, completed, created_at FROM todos WHERE id = $1"
-	row := r.db.QueryRow(query, id)
+func (r *PostgresRepository) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
 
-	var todo models.Todo
-	err := row.Scan(&todo.ID, &todo.TaskName, &todo.Description, &todo.Completed, &todo.CreatedAt)
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.getById(ctx, id, "")
+}
+
+func (r *PostgresRepository) getById(ctx context.Context, id string, lock string) (*models.Todo, error) {
+	query := "SELECT " + postgresTodoColumns + " FROM todos WHERE id = $1" + lock
+	todo, err := scanPostgresTodo(r.q.QueryRowContext(ctx, query, id).Scan)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrInvalidID
 	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return todo, nil
+}
+
+func (r *PostgresRepository) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var result []*models.Todo
+
+	err := r.Each(ctx, filter, func(todo *models.Todo) error {
+		result = append(result, todo)
+		return nil
+	})
+
 	if err != nil {
 		return nil, err
 	}
 
-	return &todo, nil
+	return result, nil
+}
+
+func (r *PostgresRepository) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	where, args := filterClause(filter, postgresFilterDialect)
+
+	query := "SELECT " + postgresTodoColumns + " FROM todos" + where + " ORDER BY created_at, id" + limitClause(filter)
+	rows, err := r.q.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		todo, err := scanPostgresTodo(rows.Scan)
+
+		if err != nil {
+			return contextError(ctx, err)
+		}
+
+		if err := fn(todo); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
 
+func (r *PostgresRepository) Count(ctx context.Context) (*models.TodoStats, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := "SELECT COUNT(*), COUNT(*) FILTER (WHERE completed) FROM todos"
+
+	var stats models.TodoStats
+	if err := r.q.QueryRowContext(ctx, query).Scan(&stats.Total, &stats.Completed); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return &stats, nil
 }
 
-func (r *PostgresRepository) GetAllTask() ([]*models.Todo, error) {
-	query := "SELECT id, task_name, description, completed, created_at FROM todos"
-	rows, err := r.db.Query(query)
+func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrEmptyID
+	}
+
+	if r.tx == nil {
+		return r.WithinTx(ctx, func(repo TodoRepository) error { return repo.Delete(ctx, id) })
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	pos, err := nextPostgresChange(ctx, r.q)
+	if err != nil {
+		return err
+	}
 
+	query := "DELETE FROM todos WHERE id = $1"
+	result, err := r.q.ExecContext(ctx, query, id)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO todo_tombstones (id, change_xid, change_seq, deleted_at) VALUES ($1, $2, $3, clock_timestamp())
+ON CONFLICT (id) DO UPDATE SET change_xid = EXCLUDED.change_xid, change_seq = EXCLUDED.change_seq, deleted_at = EXCLUDED.deleted_at`
+	if _, err := r.q.ExecContext(ctx, query, id, pos.Tx, pos.Seq); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	terms, err := parseSearchQuery(query)
 	if err != nil {
 		return nil, err
 	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	sqlQuery := `
+SELECT ` + postgresTodoColumns + `,
+       ts_rank(search_vector, query) AS rank,
+       ts_headline('simple', task_name, query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+       ts_headline('simple', description, query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15')
+FROM todos, to_tsquery('simple', $1) AS query
+WHERE search_vector @@ query
+ORDER BY rank DESC, created_at, id
+LIMIT $2`
+
+	rows, err := r.q.QueryContext(ctx, sqlQuery, tsQuery(terms), limit)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
 	defer rows.Close()
 
-	var result []*models.Todo
+	return scanSearchResults(ctx, rows, scanPostgresTodo)
+}
+
+func (r *PostgresRepository) WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	return withinSQLTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		return fn(&PostgresRepository{
+			db:           r.db,
+			q:            traceQuerier(tx, semconv.DBSystemPostgreSQL),
+			tx:           tx,
+			queryTimeout: r.queryTimeout,
+		})
+	})
+}
+
+func (r *PostgresRepository) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := validateEvents(events); err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO webhook_outbox (id, event_type, payload, created_at)
+VALUES ($1, $2, $3, clock_timestamp())
+RETURNING created_at`
+
+	for _, event := range events {
+		if err := r.q.QueryRowContext(ctx, query, event.ID, event.Type, string(event.Payload)).Scan(&event.CreatedAt); err != nil {
+			return contextError(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+const postgresTodoColumns = "id, task_name, description, completed, created_at, completed_at, due_at, priority, tags, project, version, field_changes"
+
+// scanPostgresTodo читает столбцы postgresTodoColumns; extra получает
+// следующие за ними столбцы.
+func scanPostgresTodo(scan func(dest ...any) error, extra ...any) (*models.Todo, error) {
+	var todo models.Todo
+
+	dest := []any{
+		&todo.ID, &todo.TaskName, &todo.Description, &todo.Completed, &todo.CreatedAt,
+		&todo.CompletedAt, &todo.DueAt, &todo.Priority, pq.Array(&todo.Tags), &todo.Project, &todo.Version,
+		&fieldChangesColumn{dst: &todo.FieldChanges},
+	}
+
+	if err := scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if len(todo.Tags) == 0 {
+		todo.Tags = nil
+	}
+
+	return &todo, nil
+}
+
+// postgresPlaceholder возвращает n-й параметр запроса Postgres: $1, $2 и т.д.
+func postgresPlaceholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+var postgresFilterDialect = filterDialect{
+	placeholder: postgresPlaceholder,
+	hasTag:      "%s = ANY(tags)",
+	inProjects:  "project = ANY(%s)",
+	hasAnyTag:   "tags && %s",
+	list:        func(values []string) any { return pq.Array(values) },
+	tagGroups:   "unnest(f.tags) AS g(value) WHERE g.value = ANY(%s)",
+}
+
+// nonNilTags нужен, потому что столбец tags не допускает NULL, а nil-срез
+// передаётся в базу как NULL.
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+type scanTodoFunc func(scan func(dest ...any) error, extra ...any) (*models.Todo, error)
+
+func scanSearchResults(ctx context.Context, rows *sql.Rows, scanTodo scanTodoFunc) ([]*models.SearchResult, error) {
+	var result []*models.SearchResult
 
 	for rows.Next() {
-		todo := &models.Todo{}
-		err := rows.Scan(&todo.ID, &todo.TaskName, &todo.Description, &todo.Completed, &todo.CreatedAt)
+		found := &models.SearchResult{}
+		todo, err := scanTodo(rows.Scan, &found.Rank, &found.Highlight.TaskName, &found.Highlight.Description)
 
 		if err != nil {
-			return nil, err
+			return nil, contextError(ctx, err)
 		}
 
-		result = append(result, todo)
+		found.Todo = *todo
+		result = append(result, found)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, contextError(ctx, err)
 	}
 
 	return result, nil
 }
 
-func (r *PostgresRepository) Delete(id string) error {
-	query := "DELETE FROM todos WHERE id = $1"
-	_, err := r.db.Exec(query, id)
-	return err
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrInvalidID
+	}
+
+	return nil
 }
@@ -0,0 +1,954 @@
+// Package repotest содержит общий набор проверок поведения repository.TodoRepository.
+// Каждая реализация хранилища прогоняет один и тот же контракт, чтобы все бэкенды
+// вели себя одинаково.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory возвращает пустой репозиторий для одного теста.
+type Factory func(t *testing.T) repository.TodoRepository
+
+// Run прогоняет весь контракт, включая проверки конкурентного доступа.
+func Run(t *testing.T, newRepo Factory) {
+	RunContract(t, newRepo)
+	RunConcurrency(t, newRepo)
+}
+
+// RunContract проверяет базовое поведение репозитория в одной горутине.
+func RunContract(t *testing.T, newRepo Factory) {
+	t.Run("Create", func(t *testing.T) { testCreate(t, newRepo) })
+	t.Run("GetById", func(t *testing.T) { testGetById(t, newRepo) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo) })
+	t.Run("GetAllTask", func(t *testing.T) { testGetAllTask(t, newRepo) })
+	t.Run("Filter", func(t *testing.T) { testFilter(t, newRepo) })
+	t.Run("PageSameCreatedAt", func(t *testing.T) { testPageSameCreatedAt(t, newRepo) })
+	t.Run("Count", func(t *testing.T) { testCount(t, newRepo) })
+	t.Run("Search", func(t *testing.T) { testSearch(t, newRepo) })
+	t.Run("Planning", func(t *testing.T) { testPlanning(t, newRepo) })
+	t.Run("Isolation", func(t *testing.T) { testIsolation(t, newRepo) })
+	t.Run("Context", func(t *testing.T) { testContext(t, newRepo) })
+	t.Run("WithinTx", func(t *testing.T) { testWithinTx(t, newRepo) })
+}
+
+// RunConcurrency проверяет, что репозиторий выдерживает параллельные запросы.
+func RunConcurrency(t *testing.T, newRepo Factory) {
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newRepo) })
+}
+
+func newTodo(name string) *models.Todo {
+	return &models.Todo{
+		ID:       uuid.New().String(),
+		TaskName: name,
+	}
+}
+
+func mustCreate(t *testing.T, repo repository.TodoRepository, name string) *models.Todo {
+	t.Helper()
+
+	todo := newTodo(name)
+	require.NoError(t, repo.Create(t.Context(), todo))
+
+	return todo
+}
+
+func testCreate(t *testing.T, newRepo Factory) {
+	t.Run("Success", func(t *testing.T) {
+		repo := newRepo(t)
+		description := "test description"
+		todo := newTodo("test")
+		todo.Description = &description
+		id := todo.ID
+
+		require.NoError(t, repo.Create(t.Context(), todo))
+		assert.Equal(t, id, todo.ID)
+		assert.False(t, todo.CreatedAt.IsZero())
+
+		stored, err := repo.GetById(t.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, id, stored.ID)
+		assert.Equal(t, "test", stored.TaskName)
+		require.NotNil(t, stored.Description)
+		assert.Equal(t, "test description", *stored.Description)
+		assert.False(t, stored.Completed)
+		assert.WithinDuration(t, todo.CreatedAt, stored.CreatedAt, time.Second)
+	})
+
+	t.Run("ErrEmptyTask", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.ErrorIs(t, repo.Create(t.Context(), nil), repository.ErrEmptyTask)
+	})
+
+	t.Run("ErrEmptyID", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.ErrorIs(t, repo.Create(t.Context(), &models.Todo{TaskName: "test"}), repository.ErrEmptyID)
+	})
+
+	t.Run("ErrAlreadyExist", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		duplicate := &models.Todo{ID: todo.ID, TaskName: "duplicate"}
+		assert.ErrorIs(t, repo.Create(t.Context(), duplicate), repository.ErrAlreadyExist)
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "test", stored.TaskName)
+	})
+}
+
+func testGetById(t *testing.T, newRepo Factory) {
+	t.Run("ErrEmptyID", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.GetById(t.Context(), "")
+		assert.ErrorIs(t, err, repository.ErrEmptyID)
+	})
+
+	t.Run("ErrInvalidID", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.GetById(t.Context(), uuid.New().String())
+		assert.ErrorIs(t, err, repository.ErrInvalidID)
+	})
+}
+
+func testUpdate(t *testing.T, newRepo Factory) {
+	t.Run("Completed", func(t *testing.T) {
+		repo := newRepo(t)
+		description := "test description"
+		todo := newTodo("test")
+		todo.Description = &description
+		require.NoError(t, repo.Create(t.Context(), todo))
+
+		completed := true
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed}))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Completed)
+		assert.Equal(t, "test", stored.TaskName)
+		require.NotNil(t, stored.Description)
+		assert.Equal(t, "test description", *stored.Description)
+	})
+
+	t.Run("CompletedAt", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+		assert.Nil(t, todo.CompletedAt)
+
+		completed := true
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed}))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.CompletedAt)
+		assert.WithinDuration(t, time.Now(), *stored.CompletedAt, time.Minute)
+		first := *stored.CompletedAt
+
+		// Повторная отметка не сдвигает время выполнения.
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed}))
+		stored, err = repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.CompletedAt)
+		assert.True(t, first.Equal(*stored.CompletedAt))
+
+		completed = false
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed}))
+		stored, err = repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.CompletedAt)
+
+		done := newTodo("done")
+		done.Completed = true
+		require.NoError(t, repo.Create(t.Context(), done))
+		stored, err = repo.GetById(t.Context(), done.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.CompletedAt)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+		assert.Equal(t, int64(1), todo.Version)
+
+		name := "renamed"
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name}))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stored.Version)
+
+		stale := int64(1)
+		name = "stale"
+		err = repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name, IfVersion: &stale})
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+		stored, err = repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "renamed", stored.TaskName, "изменение по устаревшей версии не применяется")
+
+		current := int64(2)
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name, IfVersion: &current}))
+
+		stored, err = repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stored.Version)
+
+		err = repo.Update(t.Context(), uuid.New().String(), &models.UpdateTodoRequest{TaskName: &name, IfVersion: &current})
+		assert.ErrorIs(t, err, repository.ErrInvalidID)
+	})
+
+	t.Run("Description", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		description := "new description"
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Description: &description}))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.Description)
+		assert.Equal(t, "new description", *stored.Description)
+		assert.Equal(t, "test", stored.TaskName)
+		assert.False(t, stored.Completed)
+	})
+
+	t.Run("TaskName", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		name := "  updated  "
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name}))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "updated", stored.TaskName)
+	})
+
+	t.Run("ErrEmptyName", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		name := "   "
+		completed := true
+		err := repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name, Completed: &completed})
+		assert.ErrorIs(t, err, repository.ErrEmptyName)
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "test", stored.TaskName)
+		assert.False(t, stored.Completed)
+	})
+
+	t.Run("ErrEmptyID", func(t *testing.T) {
+		repo := newRepo(t)
+		completed := true
+		err := repo.Update(t.Context(), "", &models.UpdateTodoRequest{Completed: &completed})
+		assert.ErrorIs(t, err, repository.ErrEmptyID)
+	})
+
+	t.Run("ErrEmptyTask", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+		assert.ErrorIs(t, repo.Update(t.Context(), todo.ID, nil), repository.ErrEmptyTask)
+	})
+
+	t.Run("ErrEmptyData", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+		assert.ErrorIs(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{}), repository.ErrEmptyData)
+	})
+
+	t.Run("ErrInvalidID", func(t *testing.T) {
+		repo := newRepo(t)
+		completed := true
+		err := repo.Update(t.Context(), uuid.New().String(), &models.UpdateTodoRequest{Completed: &completed})
+		assert.ErrorIs(t, err, repository.ErrInvalidID)
+	})
+}
+
+func testDelete(t *testing.T, newRepo Factory) {
+	t.Run("Success", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+		other := mustCreate(t, repo, "other")
+
+		require.NoError(t, repo.Delete(t.Context(), todo.ID))
+
+		_, err := repo.GetById(t.Context(), todo.ID)
+		assert.ErrorIs(t, err, repository.ErrInvalidID)
+
+		_, err = repo.GetById(t.Context(), other.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Twice", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		require.NoError(t, repo.Delete(t.Context(), todo.ID))
+		assert.ErrorIs(t, repo.Delete(t.Context(), todo.ID), repository.ErrInvalidID)
+	})
+
+	t.Run("ErrEmptyID", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.ErrorIs(t, repo.Delete(t.Context(), ""), repository.ErrEmptyID)
+	})
+
+	t.Run("ErrInvalidID", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.ErrorIs(t, repo.Delete(t.Context(), uuid.New().String()), repository.ErrInvalidID)
+	})
+}
+
+func testGetAllTask(t *testing.T, newRepo Factory) {
+	t.Run("Empty", func(t *testing.T) {
+		repo := newRepo(t)
+		todos, err := repo.GetAllTask(t.Context(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, todos)
+	})
+
+	t.Run("CreationOrder", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var ids []string
+		for i := 0; i < 5; i++ {
+			ids = append(ids, mustCreate(t, repo, fmt.Sprintf("task %d", i)).ID)
+		}
+		require.NoError(t, repo.Delete(t.Context(), ids[2]))
+		ids = append(ids[:2], ids[3:]...)
+
+		todos, err := repo.GetAllTask(t.Context(), nil)
+		require.NoError(t, err)
+		require.Len(t, todos, len(ids))
+
+		for i, todo := range todos {
+			assert.Equal(t, ids[i], todo.ID)
+		}
+	})
+}
+
+func testPageSameCreatedAt(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ids := []string{
+		"00000000-0000-4000-8000-00000000000c",
+		"00000000-0000-4000-8000-00000000000a",
+		"00000000-0000-4000-8000-00000000000b",
+	}
+	for _, id := range ids {
+		todo := newTodo(id)
+		todo.ID, todo.CreatedAt = id, createdAt
+		require.NoError(t, repo.Create(t.Context(), todo))
+	}
+
+	stored, err := repo.GetById(t.Context(), ids[0])
+	require.NoError(t, err)
+	if !stored.CreatedAt.Equal(createdAt) {
+		t.Skip("хранилище само задаёт время создания")
+	}
+
+	var cursor *models.TodoCursor
+	var paged []string
+	for range ids {
+		page, err := repo.GetAllTask(t.Context(), &models.TodoFilter{After: cursor, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		paged = append(paged, page[0].ID)
+		cursor = &models.TodoCursor{CreatedAt: page[0].CreatedAt, ID: page[0].ID}
+	}
+
+	want := []string{ids[1], ids[2], ids[0]}
+	assert.Equal(t, want, paged, "при одинаковом времени задачи идут по айди и не теряются между страницами")
+
+	all, err := repo.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	var got []string
+	for _, todo := range all {
+		got = append(got, todo.ID)
+	}
+	assert.Equal(t, want, got)
+}
+
+func testFilter(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+	msk := time.FixedZone("MSK", 3*60*60)
+	due := func(day int) *time.Time {
+		dueAt := time.Date(2025, 3, day, 12, 0, 0, 0, msk)
+		return &dueAt
+	}
+
+	create := func(name string, prepare func(todo *models.Todo)) string {
+		todo := newTodo(name)
+		prepare(todo)
+		require.NoError(t, repo.Create(t.Context(), todo))
+		return todo.ID
+	}
+
+	report := create("report", func(todo *models.Todo) {
+		todo.Priority, todo.Project, todo.Tags, todo.DueAt = models.PriorityHigh, "acme", []string{"work", "finance"}, due(10)
+	})
+	invoice := create("invoice", func(todo *models.Todo) {
+		todo.Priority, todo.Project, todo.Tags, todo.DueAt = models.PriorityLow, "acme", []string{"finance"}, due(20)
+	})
+	groceries := create("groceries", func(todo *models.Todo) { todo.Tags = []string{"home"} })
+
+	completed := true
+	require.NoError(t, repo.Update(t.Context(), invoice, &models.UpdateTodoRequest{Completed: &completed}))
+
+	first, err := repo.GetById(t.Context(), report)
+	require.NoError(t, err)
+	afterReport := &models.TodoCursor{CreatedAt: first.CreatedAt, ID: first.ID}
+
+	notCompleted := false
+	tests := []struct {
+		name   string
+		filter *models.TodoFilter
+		want   []string
+	}{
+		{"Nil", nil, []string{report, invoice, groceries}},
+		{"Empty", &models.TodoFilter{}, []string{report, invoice, groceries}},
+		{"Completed", &models.TodoFilter{Completed: &completed}, []string{invoice}},
+		{"NotCompleted", &models.TodoFilter{Completed: &notCompleted}, []string{report, groceries}},
+		{"Priority", &models.TodoFilter{Priority: models.PriorityHigh}, []string{report}},
+		{"Project", &models.TodoFilter{Project: "acme"}, []string{report, invoice}},
+		{"Tag", &models.TodoFilter{Tag: "finance"}, []string{report, invoice}},
+		{"TagMissing", &models.TodoFilter{Tag: "fin"}, nil},
+		{"DueBefore", &models.TodoFilter{DueBefore: due(20)}, []string{report}},
+		{"DueAfter", &models.TodoFilter{DueAfter: due(10)}, []string{report, invoice}},
+		{"Combined", &models.TodoFilter{Project: "acme", Tag: "finance", Completed: &notCompleted}, []string{report}},
+		{"Projects", &models.TodoFilter{Projects: []string{"acme", "other"}}, []string{report, invoice}},
+		{"Tags", &models.TodoFilter{Tags: []string{"work", "home"}}, []string{report, groceries}},
+		{"TagsAndProjects", &models.TodoFilter{Projects: []string{"acme"}, Tags: []string{"home", "finance"}}, []string{report, invoice}},
+		{"Limit", &models.TodoFilter{Limit: 2}, []string{report, invoice}},
+		{"After", &models.TodoFilter{After: afterReport}, []string{invoice, groceries}},
+		{"AfterWithLimit", &models.TodoFilter{After: afterReport, Limit: 1, Tag: "home"}, []string{groceries}},
+		{"PerProject", &models.TodoFilter{Projects: []string{"acme", ""}, PerGroup: 1}, []string{report, groceries}},
+		{"PerProjectCompleted", &models.TodoFilter{Projects: []string{"acme"}, Completed: &completed, PerGroup: 1}, []string{invoice}},
+		{"PerTag", &models.TodoFilter{Tags: []string{"finance", "home"}, PerGroup: 1}, []string{report, groceries}},
+		{"PerTagAnyGroup", &models.TodoFilter{Tags: []string{"work", "finance"}, PerGroup: 1}, []string{report}},
+		{"PerTagCompleted", &models.TodoFilter{Tags: []string{"work", "finance"}, Completed: &completed, PerGroup: 1}, []string{invoice}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos, err := repo.GetAllTask(t.Context(), tt.filter)
+			require.NoError(t, err)
+
+			var ids []string
+			for _, todo := range todos {
+				ids = append(ids, todo.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+
+			ids = nil
+			require.NoError(t, repo.Each(t.Context(), tt.filter, func(todo *models.Todo) error {
+				ids = append(ids, todo.ID)
+				return nil
+			}))
+			assert.Equal(t, tt.want, ids, "Each перебирает то же, что возвращает GetAllTask")
+		})
+	}
+
+	t.Run("EachStops", func(t *testing.T) {
+		errStop := errors.New("stop")
+		calls := 0
+		err := repo.Each(t.Context(), nil, func(*models.Todo) error {
+			calls++
+			return errStop
+		})
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func testCount(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+
+	stats, err := repo.Count(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, models.TodoStats{}, *stats)
+
+	first := mustCreate(t, repo, "first")
+	mustCreate(t, repo, "second")
+	mustCreate(t, repo, "third")
+
+	completed := true
+	require.NoError(t, repo.Update(t.Context(), first.ID, &models.UpdateTodoRequest{Completed: &completed}))
+
+	stats, err = repo.Count(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, models.TodoStats{Total: 3, Completed: 1}, *stats)
+}
+
+func testPlanning(t *testing.T, newRepo Factory) {
+	dueAt := time.Date(2025, 3, 14, 17, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	t.Run("Create", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := newTodo("Оплатить счёт")
+		todo.DueAt = &dueAt
+		todo.Priority = models.PriorityHigh
+		todo.Tags = []string{"finance", "work"}
+		todo.Project = "acme"
+		require.NoError(t, repo.Create(t.Context(), todo))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.DueAt)
+		assert.True(t, dueAt.Equal(*stored.DueAt), "получено %s", stored.DueAt)
+		assert.Equal(t, models.PriorityHigh, stored.Priority)
+		assert.Equal(t, []string{"finance", "work"}, stored.Tags)
+		assert.Equal(t, "acme", stored.Project)
+
+		plain := mustCreate(t, repo, "Без планирования")
+		stored, err = repo.GetById(t.Context(), plain.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.DueAt)
+		assert.Empty(t, stored.Priority)
+		assert.Empty(t, stored.Tags)
+		assert.Empty(t, stored.Project)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := newTodo("Оплатить счёт")
+		todo.Tags = []string{"finance"}
+		require.NoError(t, repo.Create(t.Context(), todo))
+
+		priority := models.PriorityLow
+		project := "home"
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{
+			DueAt:    &dueAt,
+			Priority: &priority,
+			Project:  &project,
+		}))
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.DueAt)
+		assert.True(t, dueAt.Equal(*stored.DueAt))
+		assert.Equal(t, models.PriorityLow, stored.Priority)
+		assert.Equal(t, "home", stored.Project)
+		assert.Equal(t, []string{"finance"}, stored.Tags, "непереданные поля не меняются")
+
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Tags: []string{}}))
+
+		stored, err = repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Empty(t, stored.Tags)
+		assert.Equal(t, "home", stored.Project)
+
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{ClearDueAt: true}))
+
+		stored, err = repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.DueAt)
+
+		err = repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{DueAt: &dueAt, ClearDueAt: true})
+		assert.ErrorIs(t, err, repository.ErrDueAtConflict)
+	})
+
+	t.Run("ErrInvalidPriority", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := newTodo("test")
+		todo.Priority = "urgent"
+		assert.ErrorIs(t, repo.Create(t.Context(), todo), repository.ErrInvalidPriority)
+
+		created := mustCreate(t, repo, "test")
+		priority := "urgent"
+		err := repo.Update(t.Context(), created.ID, &models.UpdateTodoRequest{Priority: &priority})
+		assert.ErrorIs(t, err, repository.ErrInvalidPriority)
+	})
+
+	t.Run("TagsIsolation", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := newTodo("test")
+		todo.Tags = []string{"a", "b"}
+		require.NoError(t, repo.Create(t.Context(), todo))
+		todo.Tags[0] = "mutated"
+
+		got, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		got.Tags[1] = "mutated"
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, stored.Tags)
+	})
+}
+
+func testSearch(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+
+	milk := mustCreate(t, repo, "Купить молоко")
+
+	call := newTodo("Позвонить маме")
+	description := "спросить про молоко и хлеб"
+	call.Description = &description
+	require.NoError(t, repo.Create(t.Context(), call))
+
+	shake := mustCreate(t, repo, "Milk shake recipe")
+
+	search := func(query string, limit int) []*models.SearchResult {
+		t.Helper()
+		results, err := repo.Search(t.Context(), query, limit)
+		require.NoError(t, err)
+		return results
+	}
+
+	ids := func(results []*models.SearchResult) []string {
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		return ids
+	}
+
+	t.Run("Word", func(t *testing.T) {
+		results := search("молоко", 10)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, milk.ID, results[0].ID, "совпадение в названии важнее совпадения в описании")
+		assert.Equal(t, call.ID, results[1].ID)
+		assert.GreaterOrEqual(t, results[0].Rank, results[1].Rank)
+
+		assert.Equal(t, "Купить <mark>молоко</mark>", results[0].Highlight.TaskName)
+		assert.Nil(t, results[0].Highlight.Description)
+
+		assert.Equal(t, "Позвонить маме", results[1].Highlight.TaskName)
+		require.NotNil(t, results[1].Highlight.Description)
+		assert.Contains(t, *results[1].Highlight.Description, "<mark>молоко</mark>")
+	})
+
+	t.Run("AllWords", func(t *testing.T) {
+		assert.Equal(t, []string{call.ID}, ids(search("молоко хлеб", 10)))
+	})
+
+	t.Run("CaseInsensitive", func(t *testing.T) {
+		assert.Equal(t, []string{shake.ID}, ids(search("MILK", 10)))
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{milk.ID, call.ID}, ids(search("мол*", 10)))
+		assert.Empty(t, search("мол", 10))
+	})
+
+	t.Run("Phrase", func(t *testing.T) {
+		assert.Equal(t, []string{call.ID}, ids(search(`"молоко и хлеб"`, 10)))
+		assert.Empty(t, search(`"хлеб и молоко"`, 10))
+		assert.Equal(t, []string{shake.ID}, ids(search(`"milk sh"*`, 10)))
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		assert.Len(t, search("молоко", 1), 1)
+	})
+
+	t.Run("AfterUpdateAndDelete", func(t *testing.T) {
+		name := "Купить кефир"
+		require.NoError(t, repo.Update(t.Context(), milk.ID, &models.UpdateTodoRequest{TaskName: &name}))
+		require.NoError(t, repo.Delete(t.Context(), shake.ID))
+
+		assert.Equal(t, []string{call.ID}, ids(search("молоко", 10)))
+		assert.Equal(t, []string{milk.ID}, ids(search("кефир", 10)))
+		assert.Empty(t, search("milk", 10))
+	})
+
+	t.Run("ErrEmptyQuery", func(t *testing.T) {
+		for _, query := range []string{"", "   ", `"" * !`} {
+			_, err := repo.Search(t.Context(), query, 10)
+			assert.ErrorIs(t, err, repository.ErrEmptyQuery, "запрос %q", query)
+		}
+	})
+}
+
+func testIsolation(t *testing.T, newRepo Factory) {
+	t.Run("CreateInput", func(t *testing.T) {
+		repo := newRepo(t)
+		description := "original"
+		todo := newTodo("test")
+		todo.Description = &description
+		require.NoError(t, repo.Create(t.Context(), todo))
+
+		todo.TaskName = "mutated"
+		todo.Completed = true
+		description = "mutated"
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "test", stored.TaskName)
+		assert.False(t, stored.Completed)
+		require.NotNil(t, stored.Description)
+		assert.Equal(t, "original", *stored.Description)
+	})
+
+	t.Run("ReturnedValues", func(t *testing.T) {
+		repo := newRepo(t)
+		description := "original"
+		todo := newTodo("test")
+		todo.Description = &description
+		require.NoError(t, repo.Create(t.Context(), todo))
+
+		got, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		got.TaskName = "mutated"
+		*got.Description = "mutated"
+
+		all, err := repo.GetAllTask(t.Context(), nil)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		all[0].Completed = true
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "test", stored.TaskName)
+		assert.False(t, stored.Completed)
+		require.NotNil(t, stored.Description)
+		assert.Equal(t, "original", *stored.Description)
+	})
+
+	t.Run("UpdateInput", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		description := "updated"
+		require.NoError(t, repo.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Description: &description}))
+		description = "mutated"
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.Description)
+		assert.Equal(t, "updated", *stored.Description)
+	})
+}
+
+func testContext(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+	todo := mustCreate(t, repo, "test")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	completed := true
+
+	assert.ErrorIs(t, repo.Create(ctx, newTodo("canceled")), context.Canceled)
+	_, err := repo.GetById(ctx, todo.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.Update(ctx, todo.ID, &models.UpdateTodoRequest{Completed: &completed}), context.Canceled)
+	assert.ErrorIs(t, repo.Delete(ctx, todo.ID), context.Canceled)
+	_, err = repo.GetAllTask(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	err = repo.Each(ctx, nil, func(*models.Todo) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.Count(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	stored, err := repo.GetById(t.Context(), todo.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Completed)
+
+	todos, err := repo.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Len(t, todos, 1)
+}
+
+func testWithinTx(t *testing.T, newRepo Factory) {
+	t.Run("Commit", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := mustCreate(t, repo, "existing")
+		removed := mustCreate(t, repo, "removed")
+		created := newTodo("created")
+
+		err := repo.WithinTx(t.Context(), func(tx repository.TodoRepository) error {
+			if err := tx.Create(t.Context(), created); err != nil {
+				return err
+			}
+
+			completed := true
+			if err := tx.Update(t.Context(), existing.ID, &models.UpdateTodoRequest{Completed: &completed}); err != nil {
+				return err
+			}
+
+			if err := tx.Delete(t.Context(), removed.ID); err != nil {
+				return err
+			}
+
+			stored, err := tx.GetById(t.Context(), existing.ID)
+			if err != nil {
+				return err
+			}
+			assert.True(t, stored.Completed)
+
+			todos, err := tx.GetAllTask(t.Context(), nil)
+			if err != nil {
+				return err
+			}
+			assert.Len(t, todos, 2)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		todos, err := repo.GetAllTask(t.Context(), nil)
+		require.NoError(t, err)
+		require.Len(t, todos, 2)
+		assert.Equal(t, existing.ID, todos[0].ID)
+		assert.True(t, todos[0].Completed)
+		assert.Equal(t, created.ID, todos[1].ID)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		repo := newRepo(t)
+		first := mustCreate(t, repo, "first")
+		second := mustCreate(t, repo, "second")
+		third := mustCreate(t, repo, "third")
+		errRollback := errors.New("rollback")
+
+		err := repo.WithinTx(t.Context(), func(tx repository.TodoRepository) error {
+			if err := tx.Create(t.Context(), newTodo("created")); err != nil {
+				return err
+			}
+
+			name := "renamed"
+			if err := tx.Update(t.Context(), first.ID, &models.UpdateTodoRequest{TaskName: &name}); err != nil {
+				return err
+			}
+
+			if err := tx.Delete(t.Context(), second.ID); err != nil {
+				return err
+			}
+
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+
+		todos, err := repo.GetAllTask(t.Context(), nil)
+		require.NoError(t, err)
+		require.Len(t, todos, 3)
+		assert.Equal(t, first.ID, todos[0].ID)
+		assert.Equal(t, "first", todos[0].TaskName)
+		assert.Equal(t, second.ID, todos[1].ID)
+		assert.Equal(t, third.ID, todos[2].ID)
+	})
+
+	t.Run("RollbackOnRepositoryError", func(t *testing.T) {
+		repo := newRepo(t)
+		todo := mustCreate(t, repo, "test")
+
+		err := repo.WithinTx(t.Context(), func(tx repository.TodoRepository) error {
+			completed := true
+			if err := tx.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed}); err != nil {
+				return err
+			}
+
+			return tx.Delete(t.Context(), uuid.New().String())
+		})
+		assert.ErrorIs(t, err, repository.ErrInvalidID)
+
+		stored, err := repo.GetById(t.Context(), todo.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Completed)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		repo := newRepo(t)
+		errRollback := errors.New("rollback")
+
+		err := repo.WithinTx(t.Context(), func(tx repository.TodoRepository) error {
+			err := tx.WithinTx(t.Context(), func(inner repository.TodoRepository) error {
+				return inner.Create(t.Context(), newTodo("inner"))
+			})
+			if err != nil {
+				return err
+			}
+
+			todos, err := tx.GetAllTask(t.Context(), nil)
+			if err != nil {
+				return err
+			}
+			assert.Len(t, todos, 1)
+
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+
+		todos, err := repo.GetAllTask(t.Context(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, todos)
+	})
+}
+
+func testConcurrency(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+
+	const workers = 8
+	const perWorker = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker*3)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < perWorker; i++ {
+				todo := newTodo(fmt.Sprintf("worker %d task %d", w, i))
+				if err := repo.Create(t.Context(), todo); err != nil {
+					errs <- err
+					continue
+				}
+
+				completed := true
+				err := repo.WithinTx(t.Context(), func(tx repository.TodoRepository) error {
+					if err := tx.Update(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed}); err != nil {
+						return err
+					}
+
+					_, err := tx.GetById(t.Context(), todo.ID)
+					return err
+				})
+				if err != nil {
+					errs <- err
+				}
+
+				if _, err := repo.GetById(t.Context(), todo.ID); err != nil {
+					errs <- err
+				}
+
+				if _, err := repo.GetAllTask(t.Context(), nil); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	todos, err := repo.GetAllTask(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Len(t, todos, workers*perWorker)
+
+	for _, todo := range todos {
+		assert.True(t, todo.Completed)
+	}
+}
@@ -0,0 +1,206 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SyncFactory возвращает пустые хранилища задач и изменений, работающие с
+// одними данными.
+type SyncFactory func(t *testing.T) (repository.TodoRepository, repository.SyncRepository)
+
+// RunSync проверяет номера изменений, записи об удалениях и FieldChanges.
+func RunSync(t *testing.T, newRepos SyncFactory) {
+	t.Run("Changes", func(t *testing.T) { testChanges(t, newRepos) })
+	t.Run("FieldChanges", func(t *testing.T) { testFieldChanges(t, newRepos) })
+	t.Run("Rollback", func(t *testing.T) { testSyncRollback(t, newRepos) })
+	t.Run("PurgeTombstones", func(t *testing.T) { testPurgeTombstones(t, newRepos) })
+}
+
+// lastPosition — позиция последнего изменения в хранилище, токен «после
+// всего».
+func lastPosition(t *testing.T, changes repository.SyncRepository) models.SyncPosition {
+	t.Helper()
+
+	list, err := changes.Changes(t.Context(), models.SyncPosition{}, 1000)
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	return list[len(list)-1].Position
+}
+
+// changeIDs описывает изменения как «айди» для задач и «-айди» для удалений.
+func changeIDs(list []*models.SyncChange) []string {
+	ids := []string{}
+	for _, change := range list {
+		if change.Deleted != nil {
+			ids = append(ids, "-"+change.Deleted.ID)
+		} else {
+			ids = append(ids, change.Todo.ID)
+		}
+	}
+	return ids
+}
+
+func testChanges(t *testing.T, newRepos SyncFactory) {
+	repo, sync := newRepos(t)
+	ctx := t.Context()
+
+	first := mustCreate(t, repo, "first")
+	second := mustCreate(t, repo, "second")
+	token := lastPosition(t, sync)
+
+	name := "renamed"
+	require.NoError(t, repo.Update(ctx, first.ID, &models.UpdateTodoRequest{TaskName: &name}))
+	require.NoError(t, repo.Delete(ctx, second.ID))
+	third := mustCreate(t, repo, "third")
+
+	t.Run("Full", func(t *testing.T) {
+		list, err := sync.Changes(ctx, models.SyncPosition{}, 100)
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.ID, third.ID}, changeIDs(list), "без токена удаления не нужны")
+		assert.Equal(t, "renamed", list[0].Todo.TaskName)
+		assert.Equal(t, int64(2), list[0].Todo.Version)
+		assert.Negative(t, list[0].Position.Compare(list[1].Position))
+	})
+
+	t.Run("Since", func(t *testing.T) {
+		list, err := sync.Changes(ctx, token, 100)
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.ID, "-" + second.ID, third.ID}, changeIDs(list))
+		assert.False(t, list[1].Deleted.DeletedAt.IsZero())
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		page, err := sync.Changes(ctx, token, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.ID, "-" + second.ID}, changeIDs(page))
+
+		rest, err := sync.Changes(ctx, page[1].Position, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{third.ID}, changeIDs(rest))
+
+		empty, err := sync.Changes(ctx, rest[0].Position, 2)
+		require.NoError(t, err)
+		assert.Empty(t, empty)
+	})
+
+	t.Run("Recreated", func(t *testing.T) {
+		again := newTodo("second again")
+		again.ID = second.ID
+		require.NoError(t, repo.Create(ctx, again))
+
+		list, err := sync.Changes(ctx, token, 100)
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.ID, third.ID, second.ID}, changeIDs(list),
+			"запись об удалении пропадает, когда задачу создают заново")
+	})
+}
+
+func testFieldChanges(t *testing.T, newRepos SyncFactory) {
+	repo, _ := newRepos(t)
+	ctx := t.Context()
+
+	todo := mustCreate(t, repo, "test")
+	stored, err := repo.GetById(ctx, todo.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.FieldChanges, "поля новой задачи не менялись")
+
+	before := time.Now().Add(-time.Second)
+	name := "renamed"
+	require.NoError(t, repo.Update(ctx, todo.ID, &models.UpdateTodoRequest{TaskName: &name}))
+
+	stored, err = repo.GetById(ctx, todo.ID)
+	require.NoError(t, err)
+	require.Contains(t, stored.FieldChanges, models.FieldTaskName)
+	assert.Equal(t, int64(2), stored.FieldChanges[models.FieldTaskName].Version)
+	assert.True(t, stored.FieldChanges[models.FieldTaskName].At.After(before))
+	assert.NotContains(t, stored.FieldChanges, models.FieldPriority)
+
+	priority := models.PriorityHigh
+	require.NoError(t, repo.Update(ctx, todo.ID, &models.UpdateTodoRequest{TaskName: &name, Priority: &priority}))
+
+	stored, err = repo.GetById(ctx, todo.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stored.Version)
+	assert.Equal(t, int64(2), stored.FieldChanges[models.FieldTaskName].Version, "то же значение не считается изменением")
+	assert.Equal(t, int64(3), stored.FieldChanges[models.FieldPriority].Version)
+}
+
+func testSyncRollback(t *testing.T, newRepos SyncFactory) {
+	repo, sync := newRepos(t)
+	ctx := t.Context()
+
+	todo := mustCreate(t, repo, "test")
+	token := lastPosition(t, sync)
+
+	errRollback := errors.New("откат")
+	err := repo.WithinTx(ctx, func(tx repository.TodoRepository) error {
+		name := "renamed"
+		if err := tx.Update(ctx, todo.ID, &models.UpdateTodoRequest{TaskName: &name}); err != nil {
+			return err
+		}
+		if err := tx.Delete(ctx, todo.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(ctx, newTodo("created")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	list, err := sync.Changes(ctx, token, 100)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	stored, err := repo.GetById(ctx, todo.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.FieldChanges)
+}
+
+func testPurgeTombstones(t *testing.T, newRepos SyncFactory) {
+	repo, sync := newRepos(t)
+	ctx := t.Context()
+
+	todo := mustCreate(t, repo, "test")
+	kept := mustCreate(t, repo, "kept")
+	token := lastPosition(t, sync)
+	require.NoError(t, repo.Delete(ctx, todo.ID))
+
+	list, err := sync.Changes(ctx, token, 100)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	deleted := list[0].Position
+
+	purged, err := sync.PurgeTombstones(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = sync.PurgeTombstones(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = sync.Changes(ctx, token, 100)
+	assert.ErrorIs(t, err, repository.ErrSyncTokenExpired)
+
+	list, err = sync.Changes(ctx, deleted, 100)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	list, err = sync.Changes(ctx, models.SyncPosition{}, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{kept.ID}, changeIDs(list))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = sync.Changes(canceled, models.SyncPosition{}, 100)
+	assert.ErrorIs(t, err, context.Canceled)
+}
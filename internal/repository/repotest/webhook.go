@@ -0,0 +1,233 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WebhookFactory возвращает пустые хранилища задач и вебхуков, связанные
+// общим outbox.
+type WebhookFactory func(t *testing.T) (repository.TodoRepository, repository.WebhookRepository)
+
+// RunWebhooks проверяет хранилище вебхуков и очередь доставок.
+func RunWebhooks(t *testing.T, newRepos WebhookFactory) {
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newRepos) })
+	t.Run("Dispatch", func(t *testing.T) { testDispatch(t, newRepos) })
+	t.Run("Deliveries", func(t *testing.T) { testDeliveries(t, newRepos) })
+}
+
+func newWebhook(events ...string) *models.Webhook {
+	if events == nil {
+		events = []string{}
+	}
+	return &models.Webhook{
+		ID:     uuid.NewString(),
+		URL:    "https://example.com/hooks",
+		Events: events,
+		Secret: "secret",
+	}
+}
+
+func newEvent(eventType string) *models.Event {
+	id := uuid.NewString()
+	return &models.Event{
+		ID:      id,
+		Type:    eventType,
+		Payload: []byte(`{"id":"` + id + `","type":"` + eventType + `"}`),
+	}
+}
+
+func testWebhooks(t *testing.T, newRepos WebhookFactory) {
+	_, hooks := newRepos(t)
+	ctx := t.Context()
+
+	first := newWebhook(models.EventTodoCreated)
+	second := newWebhook()
+	require.NoError(t, hooks.CreateWebhook(ctx, first))
+	require.NoError(t, hooks.CreateWebhook(ctx, second))
+	assert.False(t, first.CreatedAt.IsZero())
+	assert.ErrorIs(t, hooks.CreateWebhook(ctx, first), repository.ErrAlreadyExist)
+
+	stored, err := hooks.GetWebhook(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.URL, stored.URL)
+	assert.Equal(t, []string{models.EventTodoCreated}, stored.Events)
+	assert.Equal(t, "secret", stored.Secret)
+
+	list, err := hooks.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, first.ID, list[0].ID)
+	assert.Equal(t, []string{}, list[1].Events)
+
+	invalid := map[string]*models.Webhook{
+		"URL":     {ID: uuid.NewString(), URL: "ftp://example.com", Secret: "s"},
+		"Private": {ID: uuid.NewString(), URL: "http://169.254.169.254/latest", Secret: "s"},
+		"Event":   {ID: uuid.NewString(), URL: "https://example.com", Events: []string{"todo.moved"}, Secret: "s"},
+		"Secret":  {ID: uuid.NewString(), URL: "https://example.com"},
+	}
+	wantErr := map[string]error{
+		"URL":     repository.ErrInvalidWebhookURL,
+		"Private": repository.ErrPrivateWebhookURL,
+		"Event":   repository.ErrUnknownEvent,
+		"Secret":  repository.ErrEmptySecret,
+	}
+	for name, hook := range invalid {
+		assert.ErrorIs(t, hooks.CreateWebhook(ctx, hook), wantErr[name], name)
+	}
+
+	require.NoError(t, hooks.DeleteWebhook(ctx, first.ID))
+	assert.ErrorIs(t, hooks.DeleteWebhook(ctx, first.ID), repository.ErrWebhookNotFound)
+	_, err = hooks.GetWebhook(ctx, first.ID)
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+}
+
+func testDispatch(t *testing.T, newRepos WebhookFactory) {
+	todos, hooks := newRepos(t)
+	ctx := t.Context()
+	now := time.Now()
+
+	all := newWebhook()
+	created := newWebhook(models.EventTodoCreated)
+	require.NoError(t, hooks.CreateWebhook(ctx, all))
+	require.NoError(t, hooks.CreateWebhook(ctx, created))
+
+	rollback := errors.New("откат")
+	err := todos.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		require.NoError(t, repo.AddEvents(ctx, newEvent(models.EventTodoCreated)))
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+
+	n, err := hooks.DispatchEvents(ctx, 10, now)
+	require.NoError(t, err)
+	assert.Zero(t, n, "откаченные события не рассылаются")
+
+	createdEvent := newEvent(models.EventTodoCreated)
+	require.NoError(t, todos.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		return repo.AddEvents(ctx, createdEvent, newEvent(models.EventTodoDeleted), newEvent(models.EventTodoUpdated))
+	}))
+	assert.ErrorIs(t, todos.AddEvents(ctx, &models.Event{ID: "x"}), repository.ErrInvalidEvent)
+
+	n, err = hooks.DispatchEvents(ctx, 2, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = hooks.DispatchEvents(ctx, 10, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = hooks.DispatchEvents(ctx, 10, now)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	deliveries, err := hooks.ListDeliveries(ctx, all.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+	assert.Equal(t, models.EventTodoUpdated, deliveries[0].EventType, "новые первыми")
+
+	deliveries, err = hooks.ListDeliveries(ctx, created.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, createdEvent.ID, delivery.EventID)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Zero(t, delivery.Attempts)
+	assert.JSONEq(t, string(createdEvent.Payload), string(delivery.Payload))
+}
+
+func testDeliveries(t *testing.T, newRepos WebhookFactory) {
+	todos, hooks := newRepos(t)
+	ctx := t.Context()
+	now := time.Now().Truncate(time.Millisecond)
+
+	hook := newWebhook()
+	require.NoError(t, hooks.CreateWebhook(ctx, hook))
+	require.NoError(t, todos.AddEvents(ctx, newEvent(models.EventTodoCreated), newEvent(models.EventTodoCompleted)))
+	_, err := hooks.DispatchEvents(ctx, 10, now)
+	require.NoError(t, err)
+
+	claim := func(at time.Time) []*models.WebhookDelivery {
+		t.Helper()
+		claimed, err := hooks.ClaimDeliveries(ctx, at, time.Minute, 10)
+		require.NoError(t, err)
+		return claimed
+	}
+
+	claimed := claim(now)
+	require.Len(t, claimed, 2)
+	assert.Empty(t, claim(now), "пока идёт попытка, доставка занята")
+	assert.Len(t, claim(now.Add(2*time.Minute)), 2, "после lease доставка возвращается в очередь")
+
+	succeeded := claimed[0]
+	succeeded.Status = models.DeliverySucceeded
+	succeeded.Attempts = 1
+	succeeded.NextAttemptAt = nil
+	succeeded.LastAttemptAt = &now
+	succeeded.ResponseStatus = 200
+	require.NoError(t, hooks.SaveDelivery(ctx, succeeded))
+
+	failed := claimed[1]
+	failed.Status = models.DeliveryFailed
+	failed.Attempts = 5
+	failed.NextAttemptAt = nil
+	failed.LastAttemptAt = &now
+	failed.ResponseStatus = 500
+	failed.Error = "ответ 500"
+	require.NoError(t, hooks.SaveDelivery(ctx, failed))
+
+	assert.ErrorIs(t, hooks.SaveDelivery(ctx, &models.WebhookDelivery{ID: uuid.NewString()}), repository.ErrDeliveryNotFound)
+	assert.Empty(t, claim(now.Add(time.Hour)), "завершённые доставки не забираются")
+
+	list, err := hooks.ListDeliveries(ctx, hook.ID, &models.DeliveryFilter{Status: models.DeliveryFailed})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, failed.ID, list[0].ID)
+	assert.Equal(t, 500, list[0].ResponseStatus)
+	assert.Equal(t, "ответ 500", list[0].Error)
+	assert.Nil(t, list[0].NextAttemptAt)
+	require.NotNil(t, list[0].LastAttemptAt)
+	assert.True(t, now.Equal(*list[0].LastAttemptAt))
+
+	list, err = hooks.ListDeliveries(ctx, hook.ID, &models.DeliveryFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	_, err = hooks.ListDeliveries(ctx, hook.ID, &models.DeliveryFilter{Status: "lost"})
+	assert.ErrorIs(t, err, repository.ErrInvalidDeliveryStatus)
+	_, err = hooks.ListDeliveries(ctx, uuid.NewString(), nil)
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+
+	redelivered, err := hooks.Redeliver(ctx, hook.ID, failed.ID, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+	assert.Equal(t, "ответ 500", redelivered.Error, "журнал последней попытки сохраняется")
+
+	assert.Empty(t, claim(now.Add(30*time.Minute)))
+	claimed = claim(now.Add(time.Hour))
+	require.Len(t, claimed, 1)
+	assert.Equal(t, failed.ID, claimed[0].ID)
+	assert.JSONEq(t, string(failed.Payload), string(claimed[0].Payload))
+
+	_, err = hooks.Redeliver(ctx, hook.ID, uuid.NewString(), now)
+	assert.ErrorIs(t, err, repository.ErrDeliveryNotFound)
+	_, err = hooks.Redeliver(ctx, uuid.NewString(), failed.ID, now)
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+
+	require.NoError(t, hooks.DeleteWebhook(ctx, hook.ID))
+	assert.Empty(t, claim(now.Add(24*time.Hour)), "доставки удаляются вместе с вебхуком")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = hooks.ClaimDeliveries(canceled, now, time.Minute, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+}
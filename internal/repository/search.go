@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"todo-api/internal/models"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("передан пустой поисковый запрос")
+
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+
+	// snippetWords — длина фрагмента описания в словах, как MaxWords у ts_headline.
+	snippetWords = 35
+
+	// Веса совпадений в названии и описании, как у setweight A и B в Postgres.
+	nameWeight        = 1.0
+	descriptionWeight = 0.4
+)
+
+// searchTerm — слово или фраза из запроса. Для prefix последнее слово
+// сопоставляется по префиксу.
+type searchTerm struct {
+	words  []string
+	prefix bool
+}
+
+// parseSearchQuery разбирает запрос: слова через пробел объединяются по «И»,
+// текст в двойных кавычках ищется как фраза, звёздочка в конце слова или фразы
+// включает поиск по префиксу. Всё, кроме букв и цифр, отбрасывается, поэтому
+// результат можно безопасно подставлять в язык запросов базы.
+func parseSearchQuery(query string) ([]searchTerm, error) {
+	var terms []searchTerm
+
+	addTerm := func(text string, prefix bool) {
+		var words []string
+		for _, tok := range tokenize(text) {
+			words = append(words, tok.text)
+		}
+		if len(words) > 0 {
+			terms = append(terms, searchTerm{words: words, prefix: prefix})
+		}
+	}
+
+	rest := query
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		var chunk string
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				chunk, rest = rest[1:], ""
+			} else {
+				chunk, rest = rest[1:end+1], rest[end+2:]
+			}
+
+			prefix := strings.HasPrefix(rest, "*")
+			if prefix {
+				rest = rest[1:]
+			}
+
+			addTerm(chunk, prefix)
+			continue
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			chunk, rest = rest, ""
+		} else {
+			chunk, rest = rest[:end], rest[end:]
+		}
+
+		addTerm(chunk, strings.HasSuffix(chunk, "*"))
+	}
+
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	return terms, nil
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize делит текст на слова из букв и цифр в нижнем регистре, запоминая
+// их положение в исходной строке.
+func tokenize(text string) []token {
+	var tokens []token
+
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = append(tokens, token{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+
+	return tokens
+}
+
+// match возвращает диапазоны токенов, совпавших с термином.
+func (term searchTerm) match(tokens []token) [][2]int {
+	var spans [][2]int
+
+	last := len(term.words) - 1
+	for i := 0; i+last < len(tokens); i++ {
+		matched := true
+		for j, word := range term.words {
+			text := tokens[i+j].text
+			if j == last && term.prefix {
+				matched = strings.HasPrefix(text, word)
+			} else {
+				matched = text == word
+			}
+			if !matched {
+				break
+			}
+		}
+
+		if matched {
+			spans = append(spans, [2]int{i, i + last})
+		}
+	}
+
+	return spans
+}
+
+// searchTodos — поиск без индекса для хранилищ, которые держат задачи в памяти.
+// Порядок результатов: по убыванию релевантности, затем по порядку создания.
+func searchTodos(todos []*models.Todo, terms []searchTerm, limit int) []*models.SearchResult {
+	var results []*models.SearchResult
+
+	for _, task := range todos {
+		nameTokens := tokenize(task.TaskName)
+
+		var descriptionTokens []token
+		if task.Description != nil {
+			descriptionTokens = tokenize(*task.Description)
+		}
+
+		nameHits := make([]bool, len(nameTokens))
+		descriptionHits := make([]bool, len(descriptionTokens))
+
+		rank := 0.0
+		found := true
+		for _, term := range terms {
+			nameSpans := term.match(nameTokens)
+			descriptionSpans := term.match(descriptionTokens)
+
+			if len(nameSpans) == 0 && len(descriptionSpans) == 0 {
+				found = false
+				break
+			}
+
+			rank += nameWeight*float64(len(nameSpans)) + descriptionWeight*float64(len(descriptionSpans))
+			markHits(nameHits, nameSpans)
+			markHits(descriptionHits, descriptionSpans)
+		}
+
+		if !found {
+			continue
+		}
+
+		result := &models.SearchResult{
+			Todo: *task,
+			// Длинные тексты не должны выигрывать только за счёт длины.
+			Rank: rank / (1 + math.Log(float64(1+len(nameTokens)+len(descriptionTokens)))),
+			Highlight: models.SearchHighlight{
+				TaskName: highlight(task.TaskName, nameTokens, nameHits, 0, len(nameTokens)),
+			},
+		}
+
+		if task.Description != nil {
+			from, to := snippetWindow(descriptionHits)
+			snippet := highlight(*task.Description, descriptionTokens, descriptionHits, from, to)
+			result.Highlight.Description = &snippet
+		}
+
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+func markHits(hits []bool, spans [][2]int) {
+	for _, span := range spans {
+		for i := span[0]; i <= span[1]; i++ {
+			hits[i] = true
+		}
+	}
+}
+
+// snippetWindow выбирает не больше snippetWords слов начиная чуть раньше
+// первого совпадения.
+func snippetWindow(hits []bool) (int, int) {
+	if len(hits) <= snippetWords {
+		return 0, len(hits)
+	}
+
+	first := 0
+	for i, hit := range hits {
+		if hit {
+			first = i
+			break
+		}
+	}
+
+	from := max(first-snippetWords/5, 0)
+	from = min(from, len(hits)-snippetWords)
+
+	return from, from + snippetWords
+}
+
+// highlight возвращает текст между токенами from и to, обрамляя совпавшие слова.
+func highlight(text string, tokens []token, hits []bool, from, to int) string {
+	if len(tokens) == 0 {
+		return text
+	}
+
+	start, end := 0, len(text)
+	if from > 0 {
+		start = tokens[from].start
+	}
+	if to < len(tokens) {
+		end = tokens[to-1].end
+	}
+
+	var b strings.Builder
+	pos := start
+	for i := from; i < to; i++ {
+		if !hits[i] {
+			continue
+		}
+		b.WriteString(text[pos:tokens[i].start])
+		b.WriteString(highlightStart)
+		b.WriteString(text[tokens[i].start:tokens[i].end])
+		b.WriteString(highlightStop)
+		pos = tokens[i].end
+	}
+	b.WriteString(text[pos:end])
+
+	return b.String()
+}
+
+// tsQuery записывает термины на языке to_tsquery: слова фразы соединяются
+// оператором <->, префикс помечается :*. Слова содержат только буквы и цифры.
+func tsQuery(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+
+	for i, term := range terms {
+		words := make([]string, len(term.words))
+		for j, word := range term.words {
+			words[j] = "'" + word + "'"
+		}
+		if term.prefix {
+			words[len(words)-1] += ":*"
+		}
+		parts[i] = strings.Join(words, " <-> ")
+	}
+
+	return strings.Join(parts, " & ")
+}
+
+// ftsQuery записывает термины на языке запросов FTS5 SQLite.
+func ftsQuery(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+
+	for i, term := range terms {
+		parts[i] = `"` + strings.Join(term.words, " ") + `"`
+		if term.prefix {
+			parts[i] += "*"
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
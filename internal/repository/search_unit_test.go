@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		ts    string
+		fts   string
+	}{
+		{"молоко", "'молоко'", `"молоко"`},
+		{"Молоко  ХЛЕБ", "'молоко' & 'хлеб'", `"молоко" "хлеб"`},
+		{"мол*", "'мол':*", `"мол"*`},
+		{`"купить молоко" срочно`, "'купить' <-> 'молоко' & 'срочно'", `"купить молоко" "срочно"`},
+		{`"купить мол"*`, "'купить' <-> 'мол':*", `"купить мол"*`},
+		{"e-mail", "'e' <-> 'mail'", `"e mail"`},
+		{`'; DROP TABLE todos; --`, "'drop' & 'table' & 'todos'", `"drop" "table" "todos"`},
+		{`"незакрытая фраза`, "'незакрытая' <-> 'фраза'", `"незакрытая фраза"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			terms, err := parseSearchQuery(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ts, tsQuery(terms))
+			assert.Equal(t, tt.fts, ftsQuery(terms))
+		})
+	}
+}
+
+func TestHighlight_Snippet(t *testing.T) {
+	text := "начало"
+	for i := 0; i < 50; i++ {
+		text += " слово"
+	}
+	text += " молоко конец"
+
+	tokens := tokenize(text)
+	terms, err := parseSearchQuery("молоко")
+	require.NoError(t, err)
+
+	hits := make([]bool, len(tokens))
+	markHits(hits, terms[0].match(tokens))
+
+	from, to := snippetWindow(hits)
+	assert.Equal(t, snippetWords, to-from)
+
+	snippet := highlight(text, tokens, hits, from, to)
+	assert.Contains(t, snippet, "<mark>молоко</mark> конец")
+	assert.NotContains(t, snippet, "начало")
+}
@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+type SQLiteRepository struct {
+	db           *sql.DB
+	q            querier
+	tx           *sql.Tx
+	queryTimeout time.Duration
+}
+
+func NewSQLiteRepository(db *sql.DB, queryTimeout time.Duration) TodoRepository {
+	return &SQLiteRepository{
+		db:           db,
+		q:            traceQuerier(db, semconv.DBSystemSqlite),
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *SQLiteRepository) Create(ctx context.Context, task *models.Todo) error {
+	if err := validateCreate(task); err != nil {
+		return err
+	}
+
+	// Изменение фиксируется вместе со своим номером синхронизации.
+	if r.tx == nil {
+		return r.WithinTx(ctx, func(repo TodoRepository) error { return repo.Create(ctx, task) })
+	}
+
+	createdAt := task.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	createdAt = createdAt.UTC()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	pos, err := nextSQLiteChange(ctx, r.q)
+	if err != nil {
+		return err
+	}
+
+	var completedAt *time.Time
+	if task.Completed {
+		completedAt = &createdAt
+	}
+
+	query := `INSERT INTO todos (id, task_name, description, completed, created_at, completed_at, due_at, priority, tags, project, change_xid, change_seq)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = r.q.ExecContext(ctx, query,
+		task.ID, task.TaskName, task.Description, task.Completed, createdAt, completedAt,
+		utcTime(task.DueAt), task.Priority, encodeTags(task.Tags), task.Project, pos.Tx, pos.Seq,
+	)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrAlreadyExist
+		}
+		return contextError(ctx, err)
+	}
+
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM todo_tombstones WHERE id = ?", task.ID); err != nil {
+		return contextError(ctx, err)
+	}
+
+	task.CreatedAt = createdAt
+	task.CompletedAt = completedAt
+	task.Version = 1
+	task.FieldChanges = nil
+
+	return nil
+}
+
+func (r *SQLiteRepository) Update(ctx context.Context, id string, updateData *models.UpdateTodoRequest) error {
+	name, err := validateUpdate(id, updateData)
+	if err != nil {
+		return err
+	}
+
+	if r.tx == nil {
+		return r.WithinTx(ctx, func(repo TodoRepository) error { return repo.Update(ctx, id, updateData) })
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	pos, err := nextSQLiteChange(ctx, r.q)
+	if err != nil {
+		return err
+	}
+
+	current, err := r.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if updateData.IfVersion != nil && *updateData.IfVersion != current.Version {
+		return ErrVersionConflict
+	}
+
+	now := time.Now().UTC()
+	args := []any{}
+	setParts := []string{}
+
+	set := func(column string, value any) {
+		args = append(args, value)
+		setParts = append(setParts, column+" = ?")
+	}
+
+	if updateData.TaskName != nil {
+		set("task_name", name)
+	}
+	if updateData.Description != nil {
+		set("description", *updateData.Description)
+	}
+	if updateData.Completed != nil {
+		set("completed", *updateData.Completed)
+		// В правой части SET столбец completed ещё хранит старое значение.
+		args = append(args, *updateData.Completed, now)
+		setParts = append(setParts, "completed_at = CASE WHEN NOT ? THEN NULL WHEN completed THEN completed_at ELSE ? END")
+	}
+	if updateData.DueAt != nil {
+		set("due_at", utcTime(updateData.DueAt))
+	}
+	if updateData.ClearDueAt {
+		setParts = append(setParts, "due_at = NULL")
+	}
+	if updateData.Priority != nil {
+		set("priority", *updateData.Priority)
+	}
+	if updateData.Tags != nil {
+		set("tags", encodeTags(updateData.Tags))
+	}
+	if updateData.Project != nil {
+		set("project", *updateData.Project)
+	}
+	set("field_changes", encodeFieldChanges(fieldChanges(current, updateData, current.Version+1, now)))
+	set("change_xid", pos.Tx)
+	set("change_seq", pos.Seq)
+
+	setParts = append(setParts, "version = version + 1")
+
+	query := "UPDATE todos SET " + strings.Join(setParts, ", ") + " WHERE id = ?"
+	args = append(args, id)
+
+	result, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	return checkAffected(result)
+}
+
+func (r *SQLiteRepository) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := "SELECT " + sqliteTodoColumns + " FROM todos WHERE id = ?"
+	todo, err := scanSQLiteTodo(r.q.QueryRowContext(ctx, query, id).Scan)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidID
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return todo, nil
+}
+
+func (r *SQLiteRepository) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var result []*models.Todo
+
+	err := r.Each(ctx, filter, func(todo *models.Todo) error {
+		result = append(result, todo)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *SQLiteRepository) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	where, args := filterClause(filter, sqliteFilterDialect)
+
+	query := "SELECT " + sqliteTodoColumns + " FROM todos" + where + " ORDER BY created_at, id" + limitClause(filter)
+	rows, err := r.q.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		todo, err := scanSQLiteTodo(rows.Scan)
+
+		if err != nil {
+			return contextError(ctx, err)
+		}
+
+		if err := fn(todo); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) Count(ctx context.Context) (*models.TodoStats, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := "SELECT COUNT(*), COALESCE(SUM(completed), 0) FROM todos"
+
+	var stats models.TodoStats
+	if err := r.q.QueryRowContext(ctx, query).Scan(&stats.Total, &stats.Completed); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return &stats, nil
+}
+
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrEmptyID
+	}
+
+	if r.tx == nil {
+		return r.WithinTx(ctx, func(repo TodoRepository) error { return repo.Delete(ctx, id) })
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	pos, err := nextSQLiteChange(ctx, r.q)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM todos WHERE id = ?"
+	result, err := r.q.ExecContext(ctx, query, id)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO todo_tombstones (id, change_xid, change_seq, deleted_at) VALUES (?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET change_xid = excluded.change_xid, change_seq = excluded.change_seq, deleted_at = excluded.deleted_at`
+	if _, err := r.q.ExecContext(ctx, query, id, pos.Tx, pos.Seq, time.Now().UTC()); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// Search использует индекс FTS5 todos_fts. bm25 возвращает тем меньшее число,
+// чем лучше совпадение, поэтому ранг берётся с обратным знаком.
+func (r *SQLiteRepository) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	terms, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	sqlQuery := `
+SELECT ` + sqliteTodoColumnsOf("t") + `,
+       -bm25(todos_fts, 1.0, 0.4) AS relevance,
+       highlight(todos_fts, 0, '<mark>', '</mark>'),
+       snippet(todos_fts, 1, '<mark>', '</mark>', '', 35)
+FROM todos_fts
+JOIN todos AS t ON t.rowid = todos_fts.rowid
+WHERE todos_fts MATCH ?
+ORDER BY relevance DESC, t.created_at, t.rowid
+LIMIT ?`
+
+	rows, err := r.q.QueryContext(ctx, sqlQuery, ftsQuery(terms), limit)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	return scanSearchResults(ctx, rows, scanSQLiteTodo)
+}
+
+func (r *SQLiteRepository) WithinTx(ctx context.Context, fn func(repo TodoRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	return withinSQLTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		return fn(&SQLiteRepository{
+			db:           r.db,
+			q:            traceQuerier(tx, semconv.DBSystemSqlite),
+			tx:           tx,
+			queryTimeout: r.queryTimeout,
+		})
+	})
+}
+
+func (r *SQLiteRepository) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := validateEvents(events); err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := "INSERT INTO webhook_outbox (id, event_type, payload, created_at) VALUES (?, ?, ?, ?)"
+
+	for _, event := range events {
+		createdAt := time.Now().UTC()
+		if _, err := r.q.ExecContext(ctx, query, event.ID, event.Type, string(event.Payload), createdAt); err != nil {
+			return contextError(ctx, err)
+		}
+		event.CreatedAt = createdAt
+	}
+
+	return nil
+}
+
+const sqliteTodoColumns = "id, task_name, description, completed, created_at, completed_at, due_at, priority, tags, project, version, field_changes"
+
+func sqliteTodoColumnsOf(table string) string {
+	columns := strings.Split(sqliteTodoColumns, ", ")
+	for i, column := range columns {
+		columns[i] = table + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// scanSQLiteTodo читает столбцы sqliteTodoColumns. Теги хранятся JSON-массивом.
+func scanSQLiteTodo(scan func(dest ...any) error, extra ...any) (*models.Todo, error) {
+	var todo models.Todo
+	var tags string
+
+	dest := []any{
+		&todo.ID, &todo.TaskName, &todo.Description, &todo.Completed, &todo.CreatedAt,
+		&todo.CompletedAt, &todo.DueAt, &todo.Priority, &tags, &todo.Project, &todo.Version,
+		&fieldChangesColumn{dst: &todo.FieldChanges},
+	}
+
+	if err := scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(tags), &todo.Tags); err != nil {
+		return nil, fmt.Errorf("некорректные теги задачи %s: %w", todo.ID, err)
+	}
+
+	if len(todo.Tags) == 0 {
+		todo.Tags = nil
+	}
+
+	return &todo, nil
+}
+
+func sqlitePlaceholder(int) string {
+	return "?"
+}
+
+var sqliteFilterDialect = filterDialect{
+	placeholder: sqlitePlaceholder,
+	hasTag:      "EXISTS (SELECT 1 FROM json_each(tags) WHERE value = %s)",
+	inProjects:  "project IN (SELECT value FROM json_each(%s))",
+	hasAnyTag:   "EXISTS (SELECT 1 FROM json_each(tags) WHERE value IN (SELECT value FROM json_each(%s)))",
+	list:        func(values []string) any { return encodeTags(values) },
+	tagGroups:   "json_each(f.tags) AS g WHERE g.value IN (SELECT value FROM json_each(%s))",
+}
+
+func encodeTags(tags []string) string {
+	data, _ := json.Marshal(nonNilTags(tags))
+	return string(data)
+}
+
+// utcTime приводит время к UTC, как created_at, чтобы значения сравнивались как строки.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
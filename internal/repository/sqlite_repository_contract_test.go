@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+	"todo-api/internal/database"
+	"todo-api/internal/repository"
+	"todo-api/internal/repository/repotest"
+	"todo-api/migrations"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteRepository_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.TodoRepository {
+		db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		require.NoError(t, migrations.RunSQLiteMigrations(db))
+
+		return repository.NewSQLiteRepository(db, 0)
+	})
+}
+
+func TestSQLiteWebhookRepository_Contract(t *testing.T) {
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.TodoRepository, repository.WebhookRepository) {
+		db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		require.NoError(t, migrations.RunSQLiteMigrations(db))
+
+		return repository.NewSQLiteRepository(db, 0), repository.NewSQLiteWebhookRepository(db, 0)
+	})
+}
+
+func TestSQLiteSyncRepository_Contract(t *testing.T) {
+	repotest.RunSync(t, func(t *testing.T) (repository.TodoRepository, repository.SyncRepository) {
+		db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		require.NoError(t, migrations.RunSQLiteMigrations(db))
+
+		return repository.NewSQLiteRepository(db, 0), repository.NewSQLiteSyncRepository(db, 0)
+	})
+}
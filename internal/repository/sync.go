@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+)
+
+// SyncRepository отдаёт изменения задач по позициям, которые TodoRepository
+// присваивает каждому созданию, изменению и удалению. Изменение попадает в
+// ленту только тогда, когда ни одно изменение с меньшей позицией уже не может
+// появиться: клиент, запомнивший позицию последнего полученного изменения,
+// следующим запросом получит всё, что изменилось после него.
+type SyncRepository interface {
+	// Changes возвращает до limit изменений с позицией больше since по
+	// возрастанию позиций: задачи в текущем виде и записи об удалениях. При
+	// нулевой since удаления не возвращаются: клиенту, который загружает
+	// задачи впервые, они не нужны. Если удаления после since уже вычищены,
+	// возвращает ErrSyncTokenExpired.
+	Changes(ctx context.Context, since models.SyncPosition, limit int) ([]*models.SyncChange, error)
+	// PurgeTombstones удаляет записи об удалениях раньше before и возвращает
+	// их число.
+	PurgeTombstones(ctx context.Context, before time.Time) (int, error)
+}
+
+var ErrSyncTokenExpired = errors.New("токен синхронизации устарел, задачи нужно загрузить заново")
+
+// fieldChanges возвращает FieldChanges задачи task после обновления: поля,
+// значение которых действительно меняется, получают версию version и время now.
+func fieldChanges(task *models.Todo, updateData *models.UpdateTodoRequest, version int64, now time.Time) map[string]models.FieldChange {
+	changes := maps.Clone(task.FieldChanges)
+
+	for _, field := range ChangedFields(task, updateData) {
+		if changes == nil {
+			changes = make(map[string]models.FieldChange)
+		}
+		changes[field] = models.FieldChange{Version: version, At: now}
+	}
+
+	return changes
+}
+
+// ChangedFields возвращает имена полей, значение которых updateData меняет
+// в задаче task. Поле, которому передано текущее значение, не меняется.
+func ChangedFields(task *models.Todo, updateData *models.UpdateTodoRequest) []string {
+	var fields []string
+
+	mark := func(field string, changed bool) {
+		if changed {
+			fields = append(fields, field)
+		}
+	}
+
+	var description string
+	if task.Description != nil {
+		description = *task.Description
+	}
+
+	mark(models.FieldTaskName, updateData.TaskName != nil && strings.TrimSpace(*updateData.TaskName) != task.TaskName)
+	mark(models.FieldDescription, updateData.Description != nil && *updateData.Description != description)
+	mark(models.FieldCompleted, updateData.Completed != nil && *updateData.Completed != task.Completed)
+	mark(models.FieldDueAt, updateData.DueAt != nil && (task.DueAt == nil || !task.DueAt.Equal(*updateData.DueAt)) ||
+		updateData.ClearDueAt && task.DueAt != nil)
+	mark(models.FieldPriority, updateData.Priority != nil && *updateData.Priority != task.Priority)
+	mark(models.FieldTags, updateData.Tags != nil && !slices.Equal(updateData.Tags, task.Tags))
+	mark(models.FieldProject, updateData.Project != nil && *updateData.Project != task.Project)
+
+	return fields
+}
+
+// sortChanges упорядочивает изменения по позиции и оставляет первые limit.
+func sortChanges(changes []*models.SyncChange, limit int) []*models.SyncChange {
+	slices.SortFunc(changes, func(a, b *models.SyncChange) int {
+		return a.Position.Compare(b.Position)
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return changes
+}
+
+func encodeFieldChanges(changes map[string]models.FieldChange) string {
+	if len(changes) == 0 {
+		return "{}"
+	}
+	data, _ := json.Marshal(changes)
+	return string(data)
+}
+
+// fieldChangesColumn читает FieldChanges, хранящиеся JSON-объектом.
+type fieldChangesColumn struct {
+	dst *map[string]models.FieldChange
+}
+
+func (f *fieldChangesColumn) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("неожиданный тип изменений полей: %T", src)
+	}
+
+	var changes map[string]models.FieldChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		changes = nil
+	}
+	*f.dst = changes
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todo-api/internal/models"
+)
+
+// MemorySyncRepository отдаёт изменения хранилища задач в памяти.
+type MemorySyncRepository struct {
+	todos *StorageRepository
+}
+
+func NewMemorySyncRepository(todos *StorageRepository) *MemorySyncRepository {
+	return &MemorySyncRepository{todos: todos}
+}
+
+func (r *MemorySyncRepository) Changes(ctx context.Context, since models.SyncPosition, limit int) ([]*models.SyncChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.todos
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if since != (models.SyncPosition{}) && since.Seq < s.purgedSeq {
+		return nil, ErrSyncTokenExpired
+	}
+
+	var changes []*models.SyncChange
+	for id, seq := range s.seqs {
+		if seq > since.Seq {
+			changes = append(changes, &models.SyncChange{Position: models.SyncPosition{Seq: seq}, Todo: cloneTodo(s.todos[id])})
+		}
+	}
+	if since != (models.SyncPosition{}) {
+		for id, tomb := range s.tombstones {
+			if tomb.seq > since.Seq {
+				changes = append(changes, &models.SyncChange{
+					Position: models.SyncPosition{Seq: tomb.seq},
+					Deleted:  &models.Tombstone{ID: id, DeletedAt: tomb.deletedAt},
+				})
+			}
+		}
+	}
+
+	return sortChanges(changes, limit), nil
+}
+
+func (r *MemorySyncRepository) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s := r.todos
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, tomb := range s.tombstones {
+		if tomb.deletedAt.Before(before) {
+			delete(s.tombstones, id)
+			s.purgedSeq = max(s.purgedSeq, tomb.seq)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// nextPostgresChange выдаёт позицию следующего изменения задач: номер из
+// последовательности и айди текущей транзакции. Последовательность не
+// блокирует другие транзакции, поэтому номера фиксируются не по порядку;
+// Changes восстанавливает порядок по айди транзакции.
+func nextPostgresChange(ctx context.Context, q querier) (models.SyncPosition, error) {
+	var pos models.SyncPosition
+	query := "SELECT pg_current_xact_id()::text::bigint, nextval('todo_change_seq')"
+	if err := q.QueryRowContext(ctx, query).Scan(&pos.Tx, &pos.Seq); err != nil {
+		return pos, contextError(ctx, fmt.Errorf("не удалось получить номер изменения: %w", err))
+	}
+	return pos, nil
+}
+
+// nextSQLiteChange выдаёт позицию следующего изменения задач. В SQLite пишет
+// одна транзакция за раз, поэтому номера из sync_state фиксируются по
+// порядку и айди транзакции не нужен.
+func nextSQLiteChange(ctx context.Context, q querier) (models.SyncPosition, error) {
+	var pos models.SyncPosition
+	if err := q.QueryRowContext(ctx, "UPDATE sync_state SET seq = seq + 1 RETURNING seq").Scan(&pos.Seq); err != nil {
+		return pos, contextError(ctx, fmt.Errorf("не удалось получить номер изменения: %w", err))
+	}
+	return pos, nil
+}
+
+// syncDialect описывает, чем Postgres и SQLite различаются для чтения
+// изменений.
+type syncDialect struct {
+	system      attribute.KeyValue
+	placeholder func(n int) string
+	columns     string
+	scanTodo    scanTodoFunc
+	// txOptions — транзакция, в которой задачи и удаления читаются из одного
+	// снимка базы. В SQLite любая транзакция видит один снимок.
+	txOptions *sql.TxOptions
+	// settled отбирает изменения, перед которыми в ленте уже ничего не
+	// появится. В Postgres это изменения транзакций старше самой ранней
+	// незавершённой в снимке: все следующие транзакции получат айди больше.
+	// Долгая транзакция задерживает выдачу изменений, но не теряет их.
+	settled string
+}
+
+var postgresSyncDialect = syncDialect{
+	system:      semconv.DBSystemPostgreSQL,
+	placeholder: postgresPlaceholder,
+	columns:     postgresTodoColumns,
+	scanTodo:    scanPostgresTodo,
+	txOptions:   &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+	settled:     " AND change_xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint",
+}
+
+var sqliteSyncDialect = syncDialect{
+	system:      semconv.DBSystemSqlite,
+	placeholder: sqlitePlaceholder,
+	columns:     sqliteTodoColumns,
+	scanTodo:    scanSQLiteTodo,
+}
+
+// SQLSyncRepository читает номера изменений и записи об удалениях, которые
+// пишут PostgresRepository и SQLiteRepository.
+type SQLSyncRepository struct {
+	db           *sql.DB
+	dialect      syncDialect
+	queryTimeout time.Duration
+}
+
+func NewPostgresSyncRepository(db *sql.DB, queryTimeout time.Duration) *SQLSyncRepository {
+	return &SQLSyncRepository{db: db, dialect: postgresSyncDialect, queryTimeout: queryTimeout}
+}
+
+func NewSQLiteSyncRepository(db *sql.DB, queryTimeout time.Duration) *SQLSyncRepository {
+	return &SQLSyncRepository{db: db, dialect: sqliteSyncDialect, queryTimeout: queryTimeout}
+}
+
+// sql подставляет обозначения параметров диалекта вместо $1, $2, ...
+func (r *SQLSyncRepository) sql(query string) string {
+	for n := strings.Count(query, "$"); n > 0; n-- {
+		query = strings.ReplaceAll(query, fmt.Sprintf("$%d", n), r.dialect.placeholder(n))
+	}
+	return query
+}
+
+func (r *SQLSyncRepository) withinTx(ctx context.Context, opts *sql.TxOptions, fn func(q querier) error) error {
+	return withinSQLTx(ctx, r.db, opts, func(tx *sql.Tx) error {
+		return fn(traceQuerier(tx, r.dialect.system))
+	})
+}
+
+func (r *SQLSyncRepository) Changes(ctx context.Context, since models.SyncPosition, limit int) ([]*models.SyncChange, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var changes []*models.SyncChange
+
+	err := r.withinTx(ctx, r.dialect.txOptions, func(q querier) error {
+		var purged models.SyncPosition
+		if err := q.QueryRowContext(ctx, "SELECT purged_xid, purged_seq FROM sync_state").Scan(&purged.Tx, &purged.Seq); err != nil {
+			return contextError(ctx, err)
+		}
+		if since != (models.SyncPosition{}) && since.Compare(purged) < 0 {
+			return ErrSyncTokenExpired
+		}
+
+		query := r.sql("SELECT " + r.dialect.columns + ", change_xid, change_seq FROM todos" +
+			" WHERE (change_xid, change_seq) > ($1, $2)" + r.dialect.settled + " ORDER BY change_xid, change_seq LIMIT $3")
+		rows, err := q.QueryContext(ctx, query, since.Tx, since.Seq, limit)
+		if err != nil {
+			return contextError(ctx, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			change := &models.SyncChange{}
+			if change.Todo, err = r.dialect.scanTodo(rows.Scan, &change.Position.Tx, &change.Position.Seq); err != nil {
+				return contextError(ctx, err)
+			}
+			changes = append(changes, change)
+		}
+		if err := rows.Err(); err != nil {
+			return contextError(ctx, err)
+		}
+
+		if since == (models.SyncPosition{}) {
+			return nil
+		}
+		return r.tombstones(ctx, q, since, limit, &changes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sortChanges(changes, limit), nil
+}
+
+func (r *SQLSyncRepository) tombstones(ctx context.Context, q querier, since models.SyncPosition, limit int, changes *[]*models.SyncChange) error {
+	query := r.sql("SELECT id, change_xid, change_seq, deleted_at FROM todo_tombstones" +
+		" WHERE (change_xid, change_seq) > ($1, $2)" + r.dialect.settled + " ORDER BY change_xid, change_seq LIMIT $3")
+	rows, err := q.QueryContext(ctx, query, since.Tx, since.Seq, limit)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change := &models.SyncChange{Deleted: &models.Tombstone{}}
+		if err := rows.Scan(&change.Deleted.ID, &change.Position.Tx, &change.Position.Seq, &change.Deleted.DeletedAt); err != nil {
+			return contextError(ctx, err)
+		}
+		*changes = append(*changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return nil
+}
+
+// PurgeTombstones запоминает наибольшую позицию вычищенного удаления: токены
+// меньше неё больше не дают полной картины изменений.
+func (r *SQLSyncRepository) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	purged := 0
+
+	err := r.withinTx(ctx, nil, func(q querier) error {
+		query := r.sql("DELETE FROM todo_tombstones WHERE deleted_at < $1 RETURNING change_xid, change_seq")
+		rows, err := q.QueryContext(ctx, query, before.UTC())
+		if err != nil {
+			return contextError(ctx, err)
+		}
+		defer rows.Close()
+
+		var last models.SyncPosition
+		for rows.Next() {
+			var pos models.SyncPosition
+			if err := rows.Scan(&pos.Tx, &pos.Seq); err != nil {
+				return contextError(ctx, err)
+			}
+			if pos.Compare(last) > 0 {
+				last = pos
+			}
+			purged++
+		}
+		if err := rows.Err(); err != nil {
+			return contextError(ctx, err)
+		}
+		rows.Close()
+
+		if purged == 0 {
+			return nil
+		}
+		query = r.sql("UPDATE sync_state SET purged_xid = $1, purged_seq = $2 WHERE (purged_xid, purged_seq) < ($3, $4)")
+		if _, err := q.ExecContext(ctx, query, last.Tx, last.Seq, last.Tx, last.Seq); err != nil {
+			return contextError(ctx, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
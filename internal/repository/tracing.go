@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "todo-api/internal/repository"
+
+// tracedQuerier открывает span на каждый SQL-запрос. Span родительского запроса
+// берётся из ctx, поэтому запросы внутри транзакции попадают в тот же trace.
+type tracedQuerier struct {
+	q      querier
+	system attribute.KeyValue
+}
+
+func traceQuerier(q querier, system attribute.KeyValue) querier {
+	return &tracedQuerier{q: q, system: system}
+}
+
+func (t *tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	result, err := t.q.ExecContext(ctx, query, args...)
+	recordError(span, err)
+
+	return result, err
+}
+
+// QueryContext завершает span, когда запрос выполнен; чтение строк в него не входит.
+func (t *tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	recordError(span, err)
+
+	return rows, err
+}
+
+func (t *tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	row := t.q.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != sql.ErrNoRows {
+		recordError(span, err)
+	}
+
+	return row
+}
+
+func (t *tracedQuerier) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := sqlOperation(query)
+
+	return otel.Tracer(tracerName).Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			t.system,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// sqlOperation возвращает первое слово запроса: SELECT, INSERT и т. д.
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
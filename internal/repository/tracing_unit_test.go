@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+	"todo-api/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSQLiteRepository_TracesStatements(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, migrations.RunSQLiteMigrations(db))
+
+	repo := repository.NewSQLiteRepository(db, 0)
+
+	ctx, parent := provider.Tracer("test").Start(t.Context(), "parent")
+	require.NoError(t, repo.Create(ctx, &models.Todo{ID: "1", TaskName: "test"}))
+	err = repo.WithinTx(ctx, func(tx repository.TodoRepository) error {
+		return tx.Delete(ctx, "missing")
+	})
+	assert.ErrorIs(t, err, repository.ErrInvalidID)
+	parent.End()
+
+	var statements []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() != "parent" {
+			statements = append(statements, span)
+		}
+	}
+
+	// Каждое изменение сначала получает номер синхронизации; создание снимает
+	// запись об удалении задачи с тем же айди.
+	names := []string{}
+	for _, span := range statements {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"UPDATE", "INSERT", "DELETE", "UPDATE", "DELETE"}, names)
+
+	for _, span := range statements {
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.String("db.system", "sqlite"))
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/netguard"
+)
+
+// WebhookRepository хранит подписки на события и очередь их доставки. События
+// попадают в неё из outbox, который пишет TodoRepository.AddEvents.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, hook *models.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	// ListWebhooks возвращает вебхуки в порядке создания.
+	ListWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	// DeleteWebhook удаляет вебхук вместе с его доставками.
+	DeleteWebhook(ctx context.Context, id string) error
+	// DispatchEvents забирает из outbox до limit самых старых событий и в той
+	// же транзакции создаёт по доставке на каждый подписанный вебхук со сроком
+	// now. Возвращает число забранных событий; события без подписчиков просто
+	// удаляются.
+	DispatchEvents(ctx context.Context, limit int, now time.Time) (int, error)
+	// ClaimDeliveries возвращает до limit ожидающих доставок со сроком не позже
+	// now и переносит их срок на now+lease. Две реплики не получат одну
+	// доставку, а если воркер упадёт, не записав результат, доставка вернётся
+	// в очередь по истечении lease.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error)
+	// SaveDelivery записывает результат попытки: статус, число попыток, срок
+	// следующей, время, код ответа и ошибку.
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ListDeliveries возвращает журнал доставок вебхука, новые первыми.
+	ListDeliveries(ctx context.Context, webhookID string, filter *models.DeliveryFilter) ([]*models.WebhookDelivery, error)
+	// Redeliver возвращает доставку в очередь со сроком now и полным запасом
+	// попыток. Результат последней попытки остаётся в журнале.
+	Redeliver(ctx context.Context, webhookID, deliveryID string, now time.Time) (*models.WebhookDelivery, error)
+}
+
+var ErrWebhookNotFound = errors.New("вебхук не найден")
+var ErrDeliveryNotFound = errors.New("доставка не найдена")
+var ErrInvalidWebhookURL = errors.New("адрес вебхука должен быть абсолютным URL http или https")
+var ErrPrivateWebhookURL = errors.New("адрес вебхука не может указывать во внутреннюю сеть")
+var ErrUnknownEvent = errors.New("неизвестное событие")
+var ErrEmptySecret = errors.New("не задан секрет вебхука")
+var ErrInvalidEvent = errors.New("у события нет айди или типа")
+var ErrInvalidDeliveryStatus = errors.New("статус доставки должен быть pending, succeeded или failed")
+
+// defaultDeliveryLimit ограничивает журнал, если лимит не передан.
+const defaultDeliveryLimit = 50
+
+func validateWebhook(hook *models.Webhook) error {
+	if hook == nil {
+		return ErrEmptyTask
+	}
+	if hook.ID == "" {
+		return ErrEmptyID
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	// Имена проверяются при отправке, после разрешения в адрес.
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && netguard.Forbidden(addr) {
+		return ErrPrivateWebhookURL
+	}
+
+	for _, event := range hook.Events {
+		if !slices.Contains(models.EventTypes, event) {
+			return fmt.Errorf("%w: %q", ErrUnknownEvent, event)
+		}
+	}
+
+	if hook.Secret == "" {
+		return ErrEmptySecret
+	}
+
+	return nil
+}
+
+func validateEvents(events []*models.Event) error {
+	for _, event := range events {
+		if event == nil || event.ID == "" || event.Type == "" {
+			return ErrInvalidEvent
+		}
+	}
+	return nil
+}
+
+// deliveryFilter проверяет фильтр журнала и подставляет лимит по умолчанию.
+func deliveryFilter(filter *models.DeliveryFilter) (models.DeliveryFilter, error) {
+	var result models.DeliveryFilter
+	if filter != nil {
+		result = *filter
+	}
+
+	switch result.Status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return result, ErrInvalidDeliveryStatus
+	}
+
+	if result.Limit <= 0 {
+		result.Limit = defaultDeliveryLimit
+	}
+
+	return result, nil
+}
+
+// newDeliveries создаёт доставки события для подписанных на него вебхуков.
+func newDeliveries(event *models.Event, hooks []*models.Webhook, now time.Time, newID func() string) []*models.WebhookDelivery {
+	var deliveries []*models.WebhookDelivery
+
+	for _, hook := range hooks {
+		if !hook.Subscribed(event.Type) {
+			continue
+		}
+
+		next := now
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			ID:            newID(),
+			WebhookID:     hook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &next,
+			Payload:       slices.Clone(event.Payload),
+			CreatedAt:     now,
+		})
+	}
+
+	return deliveries
+}
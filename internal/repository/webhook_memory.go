@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+	"todo-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryWebhookRepository хранит вебхуки и доставки в памяти процесса, а
+// события забирает из outbox хранилища задач в памяти.
+type MemoryWebhookRepository struct {
+	todos *StorageRepository
+
+	mu         sync.Mutex
+	webhooks   map[string]*models.Webhook
+	hookOrder  []string
+	deliveries map[string]*models.WebhookDelivery
+	// deliveryOrder — айди доставок в порядке создания.
+	deliveryOrder []string
+}
+
+func NewMemoryWebhookRepository(todos *StorageRepository) *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		todos:      todos,
+		webhooks:   make(map[string]*models.Webhook),
+		deliveries: make(map[string]*models.WebhookDelivery),
+	}
+}
+
+func (r *MemoryWebhookRepository) CreateWebhook(ctx context.Context, hook *models.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateWebhook(hook); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.webhooks[hook.ID]; exists {
+		return ErrAlreadyExist
+	}
+
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now()
+	}
+	r.webhooks[hook.ID] = cloneWebhook(hook)
+	r.hookOrder = append(r.hookOrder, hook.ID)
+
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hook, ok := r.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return cloneWebhook(hook), nil
+}
+
+func (r *MemoryWebhookRepository) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.listWebhooks(), nil
+}
+
+func (r *MemoryWebhookRepository) listWebhooks() []*models.Webhook {
+	hooks := make([]*models.Webhook, 0, len(r.hookOrder))
+	for _, id := range r.hookOrder {
+		hooks = append(hooks, cloneWebhook(r.webhooks[id]))
+	}
+	return hooks
+}
+
+func (r *MemoryWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+
+	delete(r.webhooks, id)
+	r.hookOrder = slices.DeleteFunc(r.hookOrder, func(hookID string) bool { return hookID == id })
+	r.deliveryOrder = slices.DeleteFunc(r.deliveryOrder, func(deliveryID string) bool {
+		if r.deliveries[deliveryID].WebhookID != id {
+			return false
+		}
+		delete(r.deliveries, deliveryID)
+		return true
+	})
+
+	return nil
+}
+
+func (r *MemoryWebhookRepository) DispatchEvents(ctx context.Context, limit int, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	events := r.todos.takeEvents(limit)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hooks := r.listWebhooks()
+	for _, event := range events {
+		for _, delivery := range newDeliveries(event, hooks, now, uuid.NewString) {
+			r.deliveries[delivery.ID] = delivery
+			r.deliveryOrder = append(r.deliveryOrder, delivery.ID)
+		}
+	}
+
+	return len(events), nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*models.WebhookDelivery
+	for _, id := range r.deliveryOrder {
+		delivery := r.deliveries[id]
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b *models.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(*b.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.WebhookDelivery, len(due))
+	for i, delivery := range due {
+		until := now.Add(lease)
+		delivery.NextAttemptAt = &until
+		claimed[i] = cloneDelivery(delivery)
+	}
+
+	return claimed, nil
+}
+
+func (r *MemoryWebhookRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+
+	saved := cloneDelivery(delivery)
+	stored.Status = saved.Status
+	stored.Attempts = saved.Attempts
+	stored.NextAttemptAt = saved.NextAttemptAt
+	stored.LastAttemptAt = saved.LastAttemptAt
+	stored.ResponseStatus = saved.ResponseStatus
+	stored.Error = saved.Error
+
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, filter *models.DeliveryFilter) ([]*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := deliveryFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+
+	deliveries := []*models.WebhookDelivery{}
+	for i := len(r.deliveryOrder) - 1; i >= 0 && len(deliveries) < f.Limit; i-- {
+		delivery := r.deliveries[r.deliveryOrder[i]]
+		if delivery.WebhookID != webhookID || (f.Status != "" && delivery.Status != f.Status) {
+			continue
+		}
+		deliveries = append(deliveries, cloneDelivery(delivery))
+	}
+
+	return deliveries, nil
+}
+
+func (r *MemoryWebhookRepository) Redeliver(ctx context.Context, webhookID, deliveryID string, now time.Time) (*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+
+	delivery, ok := r.deliveries[deliveryID]
+	if !ok || delivery.WebhookID != webhookID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+
+	return cloneDelivery(delivery), nil
+}
+
+func cloneWebhook(hook *models.Webhook) *models.Webhook {
+	clone := *hook
+	clone.Events = slices.Clone(hook.Events)
+	return &clone
+}
+
+func cloneDelivery(delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	clone := *delivery
+	if delivery.NextAttemptAt != nil {
+		next := *delivery.NextAttemptAt
+		clone.NextAttemptAt = &next
+	}
+	if delivery.LastAttemptAt != nil {
+		last := *delivery.LastAttemptAt
+		clone.LastAttemptAt = &last
+	}
+	clone.Payload = slices.Clone(delivery.Payload)
+	return &clone
+}
@@ -0,0 +1,467 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// webhookDialect описывает, чем Postgres и SQLite различаются для хранилища
+// вебхуков.
+type webhookDialect struct {
+	system      attribute.KeyValue
+	placeholder func(n int) string
+	// lock дописывается к выборке строк, которые транзакция затем изменит:
+	// в Postgres так две реплики не заберут одно событие или доставку. SQLite
+	// блокирует базу целиком на время транзакции.
+	lock         string
+	duplicate    string
+	encodeEvents func(events []string) any
+	scanEvents   func(dst *[]string) any
+}
+
+var postgresWebhookDialect = webhookDialect{
+	system:       semconv.DBSystemPostgreSQL,
+	placeholder:  postgresPlaceholder,
+	lock:         " FOR UPDATE SKIP LOCKED",
+	duplicate:    "duplicate key",
+	encodeEvents: func(events []string) any { return pq.Array(nonNilTags(events)) },
+	scanEvents:   func(dst *[]string) any { return pq.Array(dst) },
+}
+
+var sqliteWebhookDialect = webhookDialect{
+	system:       semconv.DBSystemSqlite,
+	placeholder:  sqlitePlaceholder,
+	duplicate:    "UNIQUE constraint failed",
+	encodeEvents: func(events []string) any { return encodeTags(events) },
+	scanEvents:   func(dst *[]string) any { return &jsonStrings{dst: dst} },
+}
+
+// jsonStrings читает список строк, хранящийся JSON-массивом.
+type jsonStrings struct {
+	dst *[]string
+}
+
+func (j *jsonStrings) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("неожиданный тип списка: %T", src)
+	}
+	return json.Unmarshal(data, j.dst)
+}
+
+// SQLWebhookRepository хранит вебхуки в той же базе, что и задачи, и забирает
+// события из таблицы webhook_outbox.
+type SQLWebhookRepository struct {
+	db           *sql.DB
+	q            querier
+	dialect      webhookDialect
+	queryTimeout time.Duration
+}
+
+func NewPostgresWebhookRepository(db *sql.DB, queryTimeout time.Duration) *SQLWebhookRepository {
+	return newSQLWebhookRepository(db, postgresWebhookDialect, queryTimeout)
+}
+
+func NewSQLiteWebhookRepository(db *sql.DB, queryTimeout time.Duration) *SQLWebhookRepository {
+	return newSQLWebhookRepository(db, sqliteWebhookDialect, queryTimeout)
+}
+
+func newSQLWebhookRepository(db *sql.DB, dialect webhookDialect, queryTimeout time.Duration) *SQLWebhookRepository {
+	return &SQLWebhookRepository{
+		db:           db,
+		q:            traceQuerier(db, dialect.system),
+		dialect:      dialect,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// sql подставляет обозначения параметров диалекта вместо $1, $2, ...
+func (r *SQLWebhookRepository) sql(query string) string {
+	for n := strings.Count(query, "$"); n > 0; n-- {
+		query = strings.ReplaceAll(query, fmt.Sprintf("$%d", n), r.dialect.placeholder(n))
+	}
+	return query
+}
+
+func (r *SQLWebhookRepository) withinTx(ctx context.Context, fn func(q querier) error) error {
+	return withinSQLTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		return fn(traceQuerier(tx, r.dialect.system))
+	})
+}
+
+const webhookColumns = "id, url, events, secret, created_at"
+
+func (r *SQLWebhookRepository) scanWebhook(scan func(dest ...any) error) (*models.Webhook, error) {
+	var hook models.Webhook
+	if err := scan(&hook.ID, &hook.URL, r.dialect.scanEvents(&hook.Events), &hook.Secret, &hook.CreatedAt); err != nil {
+		return nil, err
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	return &hook, nil
+}
+
+func (r *SQLWebhookRepository) CreateWebhook(ctx context.Context, hook *models.Webhook) error {
+	if err := validateWebhook(hook); err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	createdAt := hook.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	createdAt = createdAt.UTC()
+
+	query := r.sql("INSERT INTO webhooks (" + webhookColumns + ") VALUES ($1, $2, $3, $4, $5)")
+	_, err := r.q.ExecContext(ctx, query, hook.ID, hook.URL, r.dialect.encodeEvents(hook.Events), hook.Secret, createdAt)
+	if err != nil {
+		if strings.Contains(err.Error(), r.dialect.duplicate) {
+			return ErrAlreadyExist
+		}
+		return contextError(ctx, err)
+	}
+
+	hook.CreatedAt = createdAt
+	return nil
+}
+
+func (r *SQLWebhookRepository) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := r.sql("SELECT " + webhookColumns + " FROM webhooks WHERE id = $1")
+	hook, err := r.scanWebhook(r.q.QueryRowContext(ctx, query, id).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return hook, nil
+}
+
+func (r *SQLWebhookRepository) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.listWebhooks(ctx, r.q)
+}
+
+func (r *SQLWebhookRepository) listWebhooks(ctx context.Context, q querier) ([]*models.Webhook, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY created_at, id")
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	hooks := []*models.Webhook{}
+	for rows.Next() {
+		hook, err := r.scanWebhook(rows.Scan)
+		if err != nil {
+			return nil, contextError(ctx, err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return hooks, nil
+}
+
+func (r *SQLWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.q.ExecContext(ctx, r.sql("DELETE FROM webhooks WHERE id = $1"), id)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	if errors.Is(checkAffected(result), ErrInvalidID) {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *SQLWebhookRepository) DispatchEvents(ctx context.Context, limit int, now time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dispatched int
+
+	err := r.withinTx(ctx, func(q querier) error {
+		events, seqs, err := r.outboxEvents(ctx, q, limit)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		hooks, err := r.listWebhooks(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		insert := r.sql(`INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (webhook_id, event_id) DO NOTHING`)
+		remove := r.sql("DELETE FROM webhook_outbox WHERE seq = $1")
+
+		for i, event := range events {
+			for _, delivery := range newDeliveries(event, hooks, now.UTC(), uuid.NewString) {
+				_, err := q.ExecContext(ctx, insert,
+					delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventType,
+					string(delivery.Payload), delivery.Status, delivery.NextAttemptAt, delivery.CreatedAt,
+				)
+				if err != nil {
+					return contextError(ctx, err)
+				}
+			}
+
+			if _, err := q.ExecContext(ctx, remove, seqs[i]); err != nil {
+				return contextError(ctx, err)
+			}
+		}
+
+		dispatched = len(events)
+		return nil
+	})
+
+	return dispatched, err
+}
+
+func (r *SQLWebhookRepository) outboxEvents(ctx context.Context, q querier, limit int) ([]*models.Event, []int64, error) {
+	query := r.sql("SELECT seq, id, event_type, payload, created_at FROM webhook_outbox ORDER BY seq LIMIT $1" + r.dialect.lock)
+
+	rows, err := q.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, nil, contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	var events []*models.Event
+	var seqs []int64
+
+	for rows.Next() {
+		var event models.Event
+		var seq int64
+		var payload string
+
+		if err := rows.Scan(&seq, &event.ID, &event.Type, &payload, &event.CreatedAt); err != nil {
+			return nil, nil, contextError(ctx, err)
+		}
+
+		event.Payload = []byte(payload)
+		events = append(events, &event)
+		seqs = append(seqs, seq)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, contextError(ctx, err)
+	}
+
+	return events, seqs, nil
+}
+
+const deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, error, created_at"
+
+func scanDelivery(scan func(dest ...any) error) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload string
+
+	err := scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastAttemptAt, &delivery.ResponseStatus,
+		&delivery.Error, &delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = json.RawMessage(payload)
+	return &delivery, nil
+}
+
+func scanDeliveries(ctx context.Context, rows *sql.Rows) ([]*models.WebhookDelivery, error) {
+	deliveries := []*models.WebhookDelivery{}
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows.Scan)
+		if err != nil {
+			return nil, contextError(ctx, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *SQLWebhookRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	until := now.Add(lease).UTC()
+	var claimed []*models.WebhookDelivery
+
+	err := r.withinTx(ctx, func(q querier) error {
+		query := r.sql(`SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+WHERE status = $1 AND next_attempt_at <= $2
+ORDER BY next_attempt_at, seq
+LIMIT $3` + r.dialect.lock)
+
+		rows, err := q.QueryContext(ctx, query, models.DeliveryPending, now.UTC(), limit)
+		if err != nil {
+			return contextError(ctx, err)
+		}
+
+		// Курсор закрывается до обновления: внутри транзакции соединение одно.
+		due, err := scanDeliveries(ctx, rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		update := r.sql("UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = $2")
+		for _, delivery := range due {
+			if _, err := q.ExecContext(ctx, update, until, delivery.ID); err != nil {
+				return contextError(ctx, err)
+			}
+			next := until
+			delivery.NextAttemptAt = &next
+		}
+
+		claimed = due
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+func (r *SQLWebhookRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := r.sql(`UPDATE webhook_deliveries
+SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4, response_status = $5, error = $6
+WHERE id = $7`)
+
+	result, err := r.q.ExecContext(ctx, query,
+		delivery.Status, delivery.Attempts, utcTime(delivery.NextAttemptAt), utcTime(delivery.LastAttemptAt),
+		delivery.ResponseStatus, delivery.Error, delivery.ID,
+	)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	if errors.Is(checkAffected(result), ErrInvalidID) {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *SQLWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, filter *models.DeliveryFilter) ([]*models.WebhookDelivery, error) {
+	f, err := deliveryFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	if err := r.webhookExists(ctx, r.q, webhookID); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE webhook_id = $1"
+	args := []any{webhookID}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY seq DESC LIMIT $%d", len(args))
+
+	rows, err := r.q.QueryContext(ctx, r.sql(query), args...)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(ctx, rows)
+}
+
+func (r *SQLWebhookRepository) webhookExists(ctx context.Context, q querier, id string) error {
+	var exists int
+	err := q.QueryRowContext(ctx, r.sql("SELECT 1 FROM webhooks WHERE id = $1"), id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	return contextError(ctx, err)
+}
+
+func (r *SQLWebhookRepository) Redeliver(ctx context.Context, webhookID, deliveryID string, now time.Time) (*models.WebhookDelivery, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var delivery *models.WebhookDelivery
+
+	err := r.withinTx(ctx, func(q querier) error {
+		if err := r.webhookExists(ctx, q, webhookID); err != nil {
+			return err
+		}
+
+		query := r.sql("SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2")
+		found, err := scanDelivery(q.QueryRowContext(ctx, query, deliveryID, webhookID).Scan)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDeliveryNotFound
+		}
+		if err != nil {
+			return contextError(ctx, err)
+		}
+
+		next := now.UTC()
+		update := r.sql("UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = $2 WHERE id = $3")
+		if _, err := q.ExecContext(ctx, update, models.DeliveryPending, next, deliveryID); err != nil {
+			return contextError(ctx, err)
+		}
+
+		found.Status = models.DeliveryPending
+		found.Attempts = 0
+		found.NextAttemptAt = &next
+		delivery = found
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
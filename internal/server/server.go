@@ -0,0 +1,69 @@
+// Package server запускает HTTP-сервер и корректно останавливает его по сигналу.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+	"todo-api/internal/config"
+)
+
+func New(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Run обслуживает запросы, пока не будет отменён ctx. После отмены сервер ещё
+// ShutdownDelay продолжает принимать запросы, чтобы балансировщик успел увидеть
+// неготовность через /readyz, затем перестаёт принимать новые соединения и ждёт
+// завершения текущих запросов не дольше ShutdownTimeout.
+func Run(ctx context.Context, srv *http.Server, cfg config.ServerConfig) error {
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть порт %s: %w", srv.Addr, err)
+	}
+
+	return serve(ctx, srv, listener, cfg.ShutdownDelay, cfg.ShutdownTimeout)
+}
+
+func serve(ctx context.Context, srv *http.Server, listener net.Listener, shutdownDelay, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	if shutdownDelay > 0 {
+		time.Sleep(shutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("не удалось дождаться завершения запросов: %w", err)
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
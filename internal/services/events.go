@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"todo-api/internal/identity"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// addEvent пишет событие об изменении задачи в outbox. Вызывается внутри той
+// же транзакции, что и изменение, чтобы событие не потерялось и не ушло
+// об откаченном изменении.
+func addEvent(ctx context.Context, repo repository.TodoRepository, eventType string, tasks ...*models.Todo) error {
+	events := make([]*models.Event, len(tasks))
+
+	for i, task := range tasks {
+		event, err := newEvent(ctx, models.EventPayload{Type: eventType, Data: task})
+		if err != nil {
+			return err
+		}
+		events[i] = event
+	}
+
+	return repo.AddEvents(ctx, events...)
+}
+
+// addChangeEvent пишет событие об изменении задачи before в after. Если
+// задача перенесена в другой проект, событие хранит прежний проект.
+func addChangeEvent(ctx context.Context, repo repository.TodoRepository, before, after *models.Todo) error {
+	payload := models.EventPayload{Type: changeEvent(before, after), Data: after}
+	if before.Project != after.Project {
+		payload.PreviousProject = before.Project
+	}
+
+	event, err := newEvent(ctx, payload)
+	if err != nil {
+		return err
+	}
+	return repo.AddEvents(ctx, event)
+}
+
+func newEvent(ctx context.Context, payload models.EventPayload) (*models.Event, error) {
+	payload.ID = uuid.NewString()
+	payload.Actor = identity.User(ctx)
+	payload.OccurredAt = time.Now().UTC()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Event{ID: payload.ID, Type: payload.Type, Payload: data}, nil
+}
+
+// changeEvent выбирает событие для изменения задачи before в after.
+func changeEvent(before, after *models.Todo) string {
+	if !before.Completed && after.Completed {
+		return models.EventTodoCompleted
+	}
+	return models.EventTodoUpdated
+}
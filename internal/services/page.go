@@ -0,0 +1,38 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+var ErrInvalidPageToken = errors.New("некорректный токен страницы")
+
+// encodePageToken строит токен из последней задачи страницы. Следующая
+// страница начинается с первой задачи после неё, даже если саму задачу уже
+// удалили.
+func encodePageToken(cursor models.TodoCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor.CreatedAt.Format(time.RFC3339Nano) + " " + cursor.ID))
+}
+
+func decodePageToken(token string) (models.TodoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.TodoCursor{}, ErrInvalidPageToken
+	}
+
+	createdAt, id, ok := strings.Cut(string(data), " ")
+	if !ok || id == "" {
+		return models.TodoCursor{}, ErrInvalidPageToken
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return models.TodoCursor{}, ErrInvalidPageToken
+	}
+
+	return models.TodoCursor{CreatedAt: parsed, ID: id}, nil
+}
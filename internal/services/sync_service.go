@@ -0,0 +1,454 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// MaxPushChanges ограничивает число изменений в одном запросе на отправку.
+const MaxPushChanges = 500
+
+var ErrInvalidSyncToken = errors.New("некорректный токен синхронизации")
+var ErrUnknownStrategy = errors.New("стратегия должна быть lastWriterWins или report")
+var ErrTooManyChanges = fmt.Errorf("за один запрос можно отправить не больше %d изменений", MaxPushChanges)
+var ErrUnknownOp = errors.New("операция должна быть create, update или delete")
+var ErrInvalidField = errors.New("некорректное поле")
+var ErrInvalidSyncID = errors.New("айди задачи должен быть UUID")
+
+// syncFields — поля, которые можно менять через синхронизацию, в порядке
+// проверки конфликтов.
+var syncFields = []string{
+	models.FieldTaskName, models.FieldDescription, models.FieldCompleted, models.FieldDueAt,
+	models.FieldPriority, models.FieldTags, models.FieldProject,
+}
+
+// SyncService синхронизирует клиентов, которые работают без связи: клиент
+// забирает изменения после своего токена и отправляет изменения, накопленные
+// у себя.
+type SyncService interface {
+	// Pull возвращает до limit изменений после токена token. Пустой токен —
+	// все задачи.
+	Pull(ctx context.Context, token string, limit int) (*models.SyncPullResponse, error)
+	// Push применяет изменения клиента по порядку, каждое в своей транзакции.
+	// Некорректное изменение отклоняется, не мешая остальным; ошибка хранилища
+	// прерывает запрос, и уже применённые изменения остаются. Повторная
+	// отправка тех же изменений безопасна: значения, совпадающие с текущими,
+	// не считаются ни изменением, ни конфликтом.
+	Push(ctx context.Context, request *models.SyncPushRequest) (*models.SyncPushResponse, error)
+}
+
+type syncService struct {
+	repo    repository.TodoRepository
+	changes repository.SyncRepository
+}
+
+func NewSyncService(repo repository.TodoRepository, changes repository.SyncRepository) SyncService {
+	return &syncService{repo: repo, changes: changes}
+}
+
+func (s *syncService) Pull(ctx context.Context, token string, limit int) (*models.SyncPullResponse, error) {
+	since, err := parseSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Лишнее изменение показывает, что за этой страницей есть ещё.
+	changes, err := s.changes.Changes(ctx, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SyncPullResponse{Changes: changes}
+	if len(changes) > limit {
+		response.Changes = changes[:limit]
+		response.HasMore = true
+	}
+	if len(response.Changes) > 0 {
+		since = response.Changes[len(response.Changes)-1].Position
+	}
+	if response.Changes == nil {
+		response.Changes = []*models.SyncChange{}
+	}
+	response.Token = encodeSyncToken(since)
+
+	return response, nil
+}
+
+// encodeSyncToken строит токен из позиции последнего полученного изменения:
+// номер изменения или, если позиция содержит айди транзакции, «айди.номер».
+// Клиенту токен непрозрачен.
+func encodeSyncToken(pos models.SyncPosition) string {
+	if pos.Tx == 0 {
+		return strconv.FormatInt(pos.Seq, 10)
+	}
+	return strconv.FormatInt(pos.Tx, 10) + "." + strconv.FormatInt(pos.Seq, 10)
+}
+
+// parseSyncToken читает токен, построенный encodeSyncToken.
+func parseSyncToken(token string) (models.SyncPosition, error) {
+	var pos models.SyncPosition
+	if token == "" {
+		return pos, nil
+	}
+
+	tx, seq, found := strings.Cut(token, ".")
+	if !found {
+		tx, seq = "0", tx
+	}
+
+	var err error
+	if pos.Tx, err = strconv.ParseInt(tx, 10, 64); err != nil || pos.Tx < 0 {
+		return models.SyncPosition{}, ErrInvalidSyncToken
+	}
+	if pos.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil || pos.Seq < 0 {
+		return models.SyncPosition{}, ErrInvalidSyncToken
+	}
+	return pos, nil
+}
+
+func (s *syncService) Push(ctx context.Context, request *models.SyncPushRequest) (*models.SyncPushResponse, error) {
+	strategy := request.Strategy
+	if strategy == "" {
+		strategy = models.SyncLastWriterWins
+	}
+	if strategy != models.SyncLastWriterWins && strategy != models.SyncReport {
+		return nil, ErrUnknownStrategy
+	}
+	if len(request.Changes) > MaxPushChanges {
+		return nil, ErrTooManyChanges
+	}
+
+	response := &models.SyncPushResponse{Results: make([]models.SyncPushResult, len(request.Changes))}
+
+	for i := range request.Changes {
+		change := &request.Changes[i]
+
+		result, err := s.apply(ctx, change, strategy)
+		if err != nil {
+			if !rejected(err) {
+				return nil, err
+			}
+			result = &models.SyncPushResult{ID: change.ID, Status: models.SyncRejected, Reason: err.Error()}
+		}
+		response.Results[i] = *result
+	}
+
+	return response, nil
+}
+
+// rejected отличает ошибки в самом изменении от ошибок хранилища.
+func rejected(err error) bool {
+	for _, target := range []error{
+		ErrUnknownOp, ErrInvalidField, ErrInvalidSyncID, repository.ErrEmptyID, repository.ErrEmptyName, repository.ErrEmptyData,
+		repository.ErrInvalidPriority, repository.ErrDueAtConflict, repository.ErrAlreadyExist,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *syncService) apply(ctx context.Context, change *models.SyncPushChange, strategy string) (*models.SyncPushResult, error) {
+	if change.ID == "" {
+		return nil, repository.ErrEmptyID
+	}
+	// Postgres хранит айди как UUID и на другое значение ответит ошибкой
+	// запроса, а не «не найдено».
+	if _, err := uuid.Parse(change.ID); err != nil {
+		return nil, ErrInvalidSyncID
+	}
+
+	var update *models.UpdateTodoRequest
+	switch change.Op {
+	case models.SyncCreate, models.SyncUpdate:
+		var err error
+		if update, err = decodeSyncFields(change.Fields); err != nil {
+			return nil, err
+		}
+	case models.SyncDelete:
+	default:
+		return nil, ErrUnknownOp
+	}
+
+	var result *models.SyncPushResult
+
+	err := s.repo.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		current, err := repo.GetById(ctx, change.ID)
+		if errors.Is(err, repository.ErrInvalidID) {
+			current, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case change.Op == models.SyncDelete:
+			result, err = pushDelete(ctx, repo, current, change, strategy)
+		case current == nil && change.Op == models.SyncCreate:
+			result, err = pushCreate(ctx, repo, change, update)
+		case current == nil:
+			result = &models.SyncPushResult{ID: change.ID, Status: models.SyncConflict, Reason: "задача удалена"}
+		default:
+			// Повторно отправленное создание уже созданной задачи
+			// применяется как изменение.
+			result, err = pushUpdate(ctx, repo, current, change, update, strategy)
+		}
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func pushCreate(ctx context.Context, repo repository.TodoRepository, change *models.SyncPushChange, update *models.UpdateTodoRequest) (*models.SyncPushResult, error) {
+	request := &models.CreateTodoRequest{Description: update.Description, DueAt: update.DueAt}
+	if update.TaskName != nil {
+		request.TaskName = *update.TaskName
+	}
+	if update.Priority != nil {
+		request.Priority = *update.Priority
+	}
+	if update.Project != nil {
+		request.Project = *update.Project
+	}
+	request.Tags = update.Tags
+
+	task, err := newTodo(request)
+	if err != nil {
+		return nil, err
+	}
+	task.ID = change.ID
+	task.Completed = update.Completed != nil && *update.Completed
+
+	if err := repo.Create(ctx, task); err != nil {
+		return nil, err
+	}
+	if err := addEvent(ctx, repo, models.EventTodoCreated, task); err != nil {
+		return nil, err
+	}
+
+	return &models.SyncPushResult{ID: task.ID, Status: models.SyncApplied, Todo: task}, nil
+}
+
+// pushUpdate применяет поля по одному. Поле, которое на сервере менялось
+// после BaseVersion, — конфликт: при lastWriterWins остаётся значение,
+// изменённое позже, при report — значение сервера.
+func pushUpdate(ctx context.Context, repo repository.TodoRepository, current *models.Todo, change *models.SyncPushChange,
+	update *models.UpdateTodoRequest, strategy string) (*models.SyncPushResult, error) {
+	result := &models.SyncPushResult{ID: current.ID, Status: models.SyncApplied, Todo: current}
+	changed := repository.ChangedFields(current, update)
+
+	for _, field := range syncFields {
+		server, ok := current.FieldChanges[field]
+		if !ok || server.Version <= change.BaseVersion || !slices.Contains(changed, field) {
+			continue
+		}
+
+		conflict := models.FieldConflict{
+			Field:  field,
+			Client: change.Fields[field],
+			Server: fieldValue(current, field),
+			Winner: "server",
+		}
+		if strategy == models.SyncLastWriterWins && change.ChangedAt.After(server.At) {
+			conflict.Winner = "client"
+		} else {
+			dropField(update, field)
+			result.Status = models.SyncConflict
+		}
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+
+	if len(repository.ChangedFields(current, update)) == 0 {
+		return result, nil
+	}
+
+	if err := repo.Update(ctx, current.ID, update); err != nil {
+		return nil, err
+	}
+
+	task, err := repo.GetById(ctx, current.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := addChangeEvent(ctx, repo, current, task); err != nil {
+		return nil, err
+	}
+
+	result.Todo = task
+	return result, nil
+}
+
+// pushDelete удаляет задачу, если на сервере её не меняли после BaseVersion.
+// Иначе при lastWriterWins задача удаляется, только если клиент удалил её
+// позже последнего изменения на сервере.
+func pushDelete(ctx context.Context, repo repository.TodoRepository, current *models.Todo, change *models.SyncPushChange,
+	strategy string) (*models.SyncPushResult, error) {
+	if current == nil {
+		return &models.SyncPushResult{ID: change.ID, Status: models.SyncApplied}, nil
+	}
+
+	var latest time.Time
+	changed := false
+	for _, server := range current.FieldChanges {
+		if server.Version > change.BaseVersion {
+			changed = true
+			if server.At.After(latest) {
+				latest = server.At
+			}
+		}
+	}
+
+	if changed && (strategy == models.SyncReport || !change.ChangedAt.After(latest)) {
+		return &models.SyncPushResult{
+			ID:     current.ID,
+			Status: models.SyncConflict,
+			Todo:   current,
+			Reason: "задача изменилась после версии клиента",
+		}, nil
+	}
+
+	if err := repo.Delete(ctx, current.ID); err != nil {
+		return nil, err
+	}
+	if err := addEvent(ctx, repo, models.EventTodoDeleted, current); err != nil {
+		return nil, err
+	}
+
+	return &models.SyncPushResult{ID: current.ID, Status: models.SyncApplied}, nil
+}
+
+// decodeSyncFields переводит поля изменения в UpdateTodoRequest с теми же
+// нормализациями, что UpdateTodo. null очищает поле, кроме taskName и
+// completed.
+func decodeSyncFields(fields map[string]json.RawMessage) (*models.UpdateTodoRequest, error) {
+	update := &models.UpdateTodoRequest{}
+
+	for field, raw := range fields {
+		null := string(raw) == "null"
+		var err error
+
+		switch field {
+		case models.FieldTaskName:
+			if null {
+				return nil, repository.ErrEmptyName
+			}
+			var name string
+			err = json.Unmarshal(raw, &name)
+			update.TaskName = &name
+		case models.FieldDescription:
+			var description string
+			if !null {
+				err = json.Unmarshal(raw, &description)
+			}
+			update.Description = &description
+		case models.FieldCompleted:
+			if null {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidField, field)
+			}
+			var completed bool
+			err = json.Unmarshal(raw, &completed)
+			update.Completed = &completed
+		case models.FieldDueAt:
+			if null {
+				update.ClearDueAt = true
+				break
+			}
+			var dueAt time.Time
+			err = json.Unmarshal(raw, &dueAt)
+			update.DueAt = &dueAt
+		case models.FieldPriority:
+			var priority string
+			if !null {
+				err = json.Unmarshal(raw, &priority)
+			}
+			priority = strings.ToLower(strings.TrimSpace(priority))
+			update.Priority = &priority
+		case models.FieldTags:
+			var tags []string
+			if !null {
+				err = json.Unmarshal(raw, &tags)
+			}
+			update.Tags = normalizeTags(tags)
+			if update.Tags == nil {
+				update.Tags = []string{}
+			}
+		case models.FieldProject:
+			var project string
+			if !null {
+				err = json.Unmarshal(raw, &project)
+			}
+			project = NormalizeProject(project)
+			update.Project = &project
+		default:
+			return nil, fmt.Errorf("%w: неизвестное поле %s", ErrInvalidField, field)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidField, field)
+		}
+	}
+
+	return update, nil
+}
+
+// fieldValue — текущее значение поля задачи в том виде, в каком оно уходит
+// в JSON.
+func fieldValue(task *models.Todo, field string) any {
+	switch field {
+	case models.FieldTaskName:
+		return task.TaskName
+	case models.FieldDescription:
+		return task.Description
+	case models.FieldCompleted:
+		return task.Completed
+	case models.FieldDueAt:
+		return task.DueAt
+	case models.FieldPriority:
+		return task.Priority
+	case models.FieldTags:
+		if task.Tags == nil {
+			return []string{}
+		}
+		return task.Tags
+	case models.FieldProject:
+		return task.Project
+	}
+	return nil
+}
+
+// dropField убирает поле из изменения, чтобы на сервере осталось его текущее
+// значение.
+func dropField(update *models.UpdateTodoRequest, field string) {
+	switch field {
+	case models.FieldTaskName:
+		update.TaskName = nil
+	case models.FieldDescription:
+		update.Description = nil
+	case models.FieldCompleted:
+		update.Completed = nil
+	case models.FieldDueAt:
+		update.DueAt = nil
+		update.ClearDueAt = false
+	case models.FieldPriority:
+		update.Priority = nil
+	case models.FieldTags:
+		update.Tags = nil
+	case models.FieldProject:
+		update.Project = nil
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSyncServices() (TodoService, SyncService) {
+	repo := repository.Constructor()
+	return NewTodoService(repo), NewSyncService(repo, repository.NewMemorySyncRepository(repo))
+}
+
+func syncFieldsOf(t *testing.T, fields map[string]any) map[string]json.RawMessage {
+	t.Helper()
+
+	raw := make(map[string]json.RawMessage, len(fields))
+	for field, value := range fields {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		raw[field] = data
+	}
+	return raw
+}
+
+func pushOne(t *testing.T, service SyncService, strategy string, change models.SyncPushChange) models.SyncPushResult {
+	t.Helper()
+
+	response, err := service.Push(t.Context(), &models.SyncPushRequest{Strategy: strategy, Changes: []models.SyncPushChange{change}})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	return response.Results[0]
+}
+
+func TestSyncService_Pull(t *testing.T) {
+	todos, service := newSyncServices()
+	ctx := t.Context()
+
+	var created []*models.Todo
+	for _, name := range []string{"first", "second", "third"} {
+		task, err := todos.CreateTodo(ctx, &models.CreateTodoRequest{TaskName: name})
+		require.NoError(t, err)
+		created = append(created, task)
+	}
+
+	page, err := service.Pull(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 2)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, created[0].ID, page.Changes[0].Todo.ID)
+
+	page, err = service.Pull(ctx, page.Token, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 1)
+	assert.False(t, page.HasMore)
+	assert.Equal(t, created[2].ID, page.Changes[0].Todo.ID)
+	token := page.Token
+
+	require.NoError(t, todos.DeleteTodo(ctx, created[1].ID))
+
+	page, err = service.Pull(ctx, token, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 1)
+	require.NotNil(t, page.Changes[0].Deleted)
+	assert.Equal(t, created[1].ID, page.Changes[0].Deleted.ID)
+
+	page, err = service.Pull(ctx, page.Token, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Changes)
+	assert.NotNil(t, page.Changes)
+
+	for _, token := range []string{"abc", "-1", "1.", ".1", "1.-1", "1.2.3"} {
+		_, err = service.Pull(ctx, token, 2)
+		assert.ErrorIs(t, err, ErrInvalidSyncToken)
+	}
+}
+
+func TestSyncToken(t *testing.T) {
+	for _, pos := range []models.SyncPosition{{}, {Seq: 7}, {Tx: 731, Seq: 7}} {
+		parsed, err := parseSyncToken(encodeSyncToken(pos))
+		require.NoError(t, err)
+		assert.Equal(t, pos, parsed)
+	}
+
+	assert.Equal(t, "7", encodeSyncToken(models.SyncPosition{Seq: 7}), "токены без айди транзакции не меняются")
+}
+
+func TestSyncService_PushCreate(t *testing.T) {
+	todos, service := newSyncServices()
+	id := uuid.NewString()
+
+	change := models.SyncPushChange{
+		Op: models.SyncCreate,
+		ID: id,
+		Fields: syncFieldsOf(t, map[string]any{
+			"taskName": " offline ", "priority": "HIGH", "tags": []string{"Work"}, "completed": true,
+		}),
+	}
+
+	result := pushOne(t, service, "", change)
+	assert.Equal(t, models.SyncApplied, result.Status)
+	require.NotNil(t, result.Todo)
+	assert.Equal(t, "offline", result.Todo.TaskName)
+	assert.Equal(t, models.PriorityHigh, result.Todo.Priority)
+	assert.Equal(t, []string{"work"}, result.Todo.Tags)
+	assert.True(t, result.Todo.Completed)
+
+	// Клиент не получил ответ и отправил то же изменение ещё раз.
+	result = pushOne(t, service, "", change)
+	assert.Equal(t, models.SyncApplied, result.Status)
+	assert.Empty(t, result.Conflicts)
+
+	stored, err := todos.GetById(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored.Version, "повтор ничего не меняет")
+}
+
+func TestSyncService_PushUpdate(t *testing.T) {
+	todos, service := newSyncServices()
+	ctx := t.Context()
+
+	task, err := todos.CreateTodo(ctx, &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	editedOffline := time.Now()
+	name := "renamed on server"
+	_, err = todos.UpdateTodo(ctx, task.ID, &models.UpdateTodoRequest{TaskName: &name})
+	require.NoError(t, err)
+
+	t.Run("NoConflict", func(t *testing.T) {
+		result := pushOne(t, service, "", models.SyncPushChange{
+			Op: models.SyncUpdate, ID: task.ID, BaseVersion: 1, ChangedAt: editedOffline,
+			Fields: syncFieldsOf(t, map[string]any{"priority": "low"}),
+		})
+		assert.Equal(t, models.SyncApplied, result.Status)
+		assert.Empty(t, result.Conflicts)
+		assert.Equal(t, models.PriorityLow, result.Todo.Priority)
+		assert.Equal(t, "renamed on server", result.Todo.TaskName, "поля, которых нет в изменении, не трогаются")
+	})
+
+	t.Run("ServerIsNewer", func(t *testing.T) {
+		result := pushOne(t, service, models.SyncLastWriterWins, models.SyncPushChange{
+			Op: models.SyncUpdate, ID: task.ID, BaseVersion: 1, ChangedAt: editedOffline,
+			Fields: syncFieldsOf(t, map[string]any{"taskName": "renamed offline", "project": " Home "}),
+		})
+		assert.Equal(t, models.SyncConflict, result.Status)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, models.FieldTaskName, result.Conflicts[0].Field)
+		assert.Equal(t, "server", result.Conflicts[0].Winner)
+		assert.Equal(t, "renamed on server", result.Conflicts[0].Server)
+		assert.JSONEq(t, `"renamed offline"`, string(result.Conflicts[0].Client))
+		assert.Equal(t, "renamed on server", result.Todo.TaskName)
+		assert.Equal(t, "Home", result.Todo.Project, "поле без конфликта применяется")
+	})
+
+	t.Run("ClientIsNewer", func(t *testing.T) {
+		result := pushOne(t, service, models.SyncLastWriterWins, models.SyncPushChange{
+			Op: models.SyncUpdate, ID: task.ID, BaseVersion: 1, ChangedAt: time.Now().Add(time.Minute),
+			Fields: syncFieldsOf(t, map[string]any{"taskName": "renamed offline"}),
+		})
+		assert.Equal(t, models.SyncApplied, result.Status)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, "client", result.Conflicts[0].Winner)
+		assert.Equal(t, "renamed offline", result.Todo.TaskName)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		result := pushOne(t, service, models.SyncReport, models.SyncPushChange{
+			Op: models.SyncUpdate, ID: task.ID, BaseVersion: 1, ChangedAt: time.Now().Add(time.Hour),
+			Fields: syncFieldsOf(t, map[string]any{"taskName": "reported", "dueAt": nil}),
+		})
+		assert.Equal(t, models.SyncConflict, result.Status)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, "server", result.Conflicts[0].Winner)
+		assert.Equal(t, "renamed offline", result.Todo.TaskName)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		result := pushOne(t, service, "", models.SyncPushChange{
+			Op: models.SyncUpdate, ID: uuid.NewString(), BaseVersion: 1,
+			Fields: syncFieldsOf(t, map[string]any{"taskName": "lost"}),
+		})
+		assert.Equal(t, models.SyncConflict, result.Status)
+		assert.NotEmpty(t, result.Reason)
+	})
+}
+
+func TestSyncService_PushDelete(t *testing.T) {
+	todos, service := newSyncServices()
+	ctx := t.Context()
+
+	task, err := todos.CreateTodo(ctx, &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+	deletedOffline := time.Now()
+	name := "renamed"
+	_, err = todos.UpdateTodo(ctx, task.ID, &models.UpdateTodoRequest{TaskName: &name})
+	require.NoError(t, err)
+
+	change := models.SyncPushChange{Op: models.SyncDelete, ID: task.ID, BaseVersion: 1, ChangedAt: deletedOffline}
+
+	result := pushOne(t, service, models.SyncLastWriterWins, change)
+	assert.Equal(t, models.SyncConflict, result.Status, "на сервере задачу изменили позже")
+	assert.Equal(t, "renamed", result.Todo.TaskName)
+
+	change.ChangedAt = time.Now().Add(time.Minute)
+	result = pushOne(t, service, models.SyncReport, change)
+	assert.Equal(t, models.SyncConflict, result.Status)
+
+	result = pushOne(t, service, models.SyncLastWriterWins, change)
+	assert.Equal(t, models.SyncApplied, result.Status)
+	_, err = todos.GetById(ctx, task.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidID)
+
+	result = pushOne(t, service, "", change)
+	assert.Equal(t, models.SyncApplied, result.Status, "удалённую задачу можно удалить ещё раз")
+}
+
+func TestSyncService_PushRejected(t *testing.T) {
+	todos, service := newSyncServices()
+	id := uuid.NewString()
+
+	response, err := service.Push(t.Context(), &models.SyncPushRequest{Changes: []models.SyncPushChange{
+		{Op: "move", ID: uuid.NewString()},
+		{Op: models.SyncCreate, ID: uuid.NewString(), Fields: syncFieldsOf(t, map[string]any{"title": "test"})},
+		{Op: models.SyncCreate, ID: uuid.NewString(), Fields: syncFieldsOf(t, map[string]any{"taskName": "  "})},
+		{Op: models.SyncCreate, ID: uuid.NewString(), Fields: syncFieldsOf(t, map[string]any{"taskName": "test", "completed": "yes"})},
+		{Op: models.SyncDelete, ID: "not-a-uuid"},
+		{Op: models.SyncCreate, ID: id, Fields: syncFieldsOf(t, map[string]any{"taskName": "test"})},
+	}})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 6)
+
+	for _, result := range response.Results[:5] {
+		assert.Equal(t, models.SyncRejected, result.Status)
+		assert.NotEmpty(t, result.Reason)
+	}
+	assert.Equal(t, ErrInvalidSyncID.Error(), response.Results[4].Reason)
+	assert.Equal(t, models.SyncApplied, response.Results[5].Status, "отклонённые изменения не мешают остальным")
+
+	_, err = todos.GetById(t.Context(), id)
+	require.NoError(t, err)
+
+	_, err = service.Push(t.Context(), &models.SyncPushRequest{Strategy: "merge"})
+	assert.ErrorIs(t, err, ErrUnknownStrategy)
+
+	_, err = service.Push(t.Context(), &models.SyncPushRequest{Changes: make([]models.SyncPushChange, MaxPushChanges+1)})
+	assert.ErrorIs(t, err, ErrTooManyChanges)
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"strings"
 
 	"todo-api/internal/models"
@@ -10,11 +12,23 @@ import (
 )
 
 type TodoService interface {
-	CreateTodo(request *models.CreateTodoRequest) (*models.Todo, error)
-	GetById(id string) (*models.Todo, error)
-	GetAllTodos() ([]*models.Todo, error)
-	UpdateTodo(id string, request *models.UpdateTodoRequest) (*models.Todo, error)
-	DeleteTodo(id string) error
+	CreateTodo(ctx context.Context, request *models.CreateTodoRequest) (*models.Todo, error)
+	GetById(ctx context.Context, id string) (*models.Todo, error)
+	GetAllTodos(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error)
+	// ExportTodos передаёт в fn задачи по одной, не загружая их все в память.
+	ExportTodos(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error
+	// ListTodos возвращает страницу из pageSize задач после pageToken — токена
+	// предыдущей страницы; пустой токен означает первую страницу.
+	ListTodos(ctx context.Context, filter *models.TodoFilter, pageSize int, pageToken string) (*models.TodoPage, error)
+	UpdateTodo(ctx context.Context, id string, request *models.UpdateTodoRequest) (*models.Todo, error)
+	// ReplaceTodo заменяет задачу целиком или создаёт её с айди id; created
+	// сообщает, что задача создана.
+	ReplaceTodo(ctx context.Context, id string, request *models.ReplaceTodoRequest) (task *models.Todo, created bool, err error)
+	DeleteTodo(ctx context.Context, id string) error
+	SearchTodos(ctx context.Context, query string, limit int) ([]*models.SearchResult, error)
+	// ImportTodos проверяет строки импорта и, если ошибок нет и это не пробный
+	// запуск, создаёт все задачи в одной транзакции.
+	ImportTodos(ctx context.Context, lines []models.ImportLine, dryRun bool) (*models.ImportReport, error)
 }
 
 type todoService struct {
@@ -25,47 +39,357 @@ func NewTodoService(repo repository.TodoRepository) TodoService {
 	return &todoService{repo: repo}
 }
 
-func (s *todoService) CreateTodo(request *models.CreateTodoRequest) (*models.Todo, error) {
+func (s *todoService) CreateTodo(ctx context.Context, request *models.CreateTodoRequest) (*models.Todo, error) {
+	task, err := newTodo(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		if err := repo.Create(ctx, task); err != nil {
+			return err
+		}
+		return addEvent(ctx, repo, models.EventTodoCreated, task)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// newTodo проверяет запрос и собирает из него задачу с новым айди.
+func newTodo(request *models.CreateTodoRequest) (*models.Todo, error) {
 	name := strings.TrimSpace(request.TaskName)
 
 	if name == "" {
 		return nil, repository.ErrEmptyName
 	}
 
-	task := models.Todo{
+	priority := strings.ToLower(strings.TrimSpace(request.Priority))
+	if !repository.ValidPriority(priority) {
+		return nil, repository.ErrInvalidPriority
+	}
+
+	return &models.Todo{
 		ID:          uuid.New().String(),
 		TaskName:    name,
 		Description: request.Description,
 		Completed:   false,
+		DueAt:       request.DueAt,
+		Priority:    priority,
+		Tags:        normalizeTags(request.Tags),
+		Project:     NormalizeProject(request.Project),
+	}, nil
+}
+
+func (s *todoService) ImportTodos(ctx context.Context, lines []models.ImportLine, dryRun bool) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: dryRun, Total: len(lines), Lines: lines}
+	tasks := make([]*models.Todo, len(lines))
+
+	for i := range report.Lines {
+		line := &report.Lines[i]
+		if line.Error == "" {
+			task, err := newTodo(line.Todo)
+			if err != nil {
+				line.Error = err.Error()
+			} else {
+				task.Completed = line.Completed
+				tasks[i] = task
+			}
+		}
+
+		if line.Error != "" {
+			report.Invalid++
+		}
+	}
+
+	if dryRun || report.Invalid > 0 {
+		return report, nil
 	}
 
-	err := s.repo.Create(&task)
+	err := s.repo.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		for _, task := range tasks {
+			if err := repo.Create(ctx, task); err != nil {
+				return err
+			}
+		}
+		return addEvent(ctx, repo, models.EventTodoCreated, tasks...)
+	})
 
 	if err != nil {
 		return nil, err
 	}
-	return &task, err
+
+	for i, task := range tasks {
+		report.Lines[i].ID = task.ID
+	}
+	report.Imported = len(tasks)
+
+	return report, nil
 }
 
-func (s *todoService) GetById(id string) (*models.Todo, error) {
-	task, err := s.repo.GetById(id)
+func (s *todoService) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	task, err := s.repo.GetById(ctx, id)
 
 	return task, err
 }
 
-func (s *todoService) GetAllTodos() ([]*models.Todo, error) {
-	return s.repo.GetAllTask()
+func (s *todoService) GetAllTodos(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAllTask(ctx, filter)
+}
+
+func (s *todoService) ExportTodos(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Each(ctx, filter, fn)
 }
 
-func (s *todoService) UpdateTodo(id string, request *models.UpdateTodoRequest) (*models.Todo, error) {
-	err := s.repo.Update(id, request)
+// ListTodos читает из хранилища только задачи страницы и ещё одну: по ней
+// видно, что за страницей есть следующая.
+func (s *todoService) ListTodos(ctx context.Context, filter *models.TodoFilter, pageSize int, pageToken string) (*models.TodoPage, error) {
+	var query models.TodoFilter
+	if filter != nil {
+		query = *filter
+	}
+	query.Limit = pageSize + 1
+
+	if pageToken != "" {
+		cursor, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, err
+		}
+		query.After = &cursor
+	}
+
+	todos, err := s.GetAllTodos(ctx, &query)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repo.GetById(id)
+	page := &models.TodoPage{Todos: todos}
+	if len(todos) > pageSize {
+		page.Todos = todos[:pageSize]
+		last := page.Todos[pageSize-1]
+		page.NextPageToken = encodePageToken(models.TodoCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	if page.Todos == nil {
+		page.Todos = []*models.Todo{}
+	}
+
+	return page, nil
+}
+
+func (s *todoService) UpdateTodo(ctx context.Context, id string, request *models.UpdateTodoRequest) (*models.Todo, error) {
+	if request != nil {
+		normalized := *request
+		if request.Priority != nil {
+			priority := strings.ToLower(strings.TrimSpace(*request.Priority))
+			normalized.Priority = &priority
+		}
+		if request.Tags != nil {
+			normalized.Tags = normalizeTags(request.Tags)
+			if normalized.Tags == nil {
+				normalized.Tags = []string{}
+			}
+		}
+		if request.Project != nil {
+			project := NormalizeProject(*request.Project)
+			normalized.Project = &project
+		}
+		request = &normalized
+	}
+
+	var task *models.Todo
+
+	err := s.repo.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		before, err := repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.Update(ctx, id, request); err != nil {
+			return err
+		}
+
+		task, err = repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		return addChangeEvent(ctx, repo, before, task)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *todoService) ReplaceTodo(ctx context.Context, id string, request *models.ReplaceTodoRequest) (*models.Todo, bool, error) {
+	if id == "" {
+		return nil, false, repository.ErrEmptyID
+	}
+
+	task, err := newTodo(&request.CreateTodoRequest)
+	if err != nil {
+		return nil, false, err
+	}
+	task.ID = id
+	task.Completed = request.Completed
+
+	created := false
+
+	err = s.repo.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		current, err := repo.GetById(ctx, id)
+		if errors.Is(err, repository.ErrInvalidID) {
+			if request.IfVersion != nil && *request.IfVersion != 0 {
+				return repository.ErrVersionConflict
+			}
+			created = true
+			if err := repo.Create(ctx, task); err != nil {
+				return err
+			}
+			return addEvent(ctx, repo, models.EventTodoCreated, task)
+		}
+		if err != nil {
+			return err
+		}
+
+		if request.IfVersion != nil && *request.IfVersion != current.Version {
+			return repository.ErrVersionConflict
+		}
+
+		// Версия ещё раз проверяется в самом обновлении: чтение и запись
+		// в Postgres не защищены от параллельной транзакции.
+		update := replacement(task)
+		update.IfVersion = &current.Version
+		if err := repo.Update(ctx, id, update); err != nil {
+			return err
+		}
+
+		task, err = repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		return addChangeEvent(ctx, repo, current, task)
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return task, created, nil
+}
+
+// replacement собирает изменение, которое переписывает все поля задачи.
+func replacement(task *models.Todo) *models.UpdateTodoRequest {
+	description := ""
+	if task.Description != nil {
+		description = *task.Description
+	}
+
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return &models.UpdateTodoRequest{
+		TaskName:    &task.TaskName,
+		Description: &description,
+		Completed:   &task.Completed,
+		DueAt:       task.DueAt,
+		ClearDueAt:  task.DueAt == nil,
+		Priority:    &task.Priority,
+		Tags:        tags,
+		Project:     &task.Project,
+	}
+}
+
+func (s *todoService) DeleteTodo(ctx context.Context, id string) error {
+	if id == "" {
+		return repository.ErrEmptyID
+	}
+
+	return s.repo.WithinTx(ctx, func(repo repository.TodoRepository) error {
+		task, err := repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return addEvent(ctx, repo, models.EventTodoDeleted, task)
+	})
+}
+
+func (s *todoService) SearchTodos(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return s.repo.Search(ctx, query, limit)
+}
+
+// normalizeTags убирает решётки и пробелы, приводит теги к нижнему регистру
+// и оставляет первое вхождение каждого тега.
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+// normalizeFilter приводит значения фильтра к виду, в котором они хранятся.
+func normalizeFilter(filter *models.TodoFilter) (*models.TodoFilter, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	normalized := *filter
+	normalized.Priority = strings.ToLower(strings.TrimSpace(filter.Priority))
+	if !repository.ValidPriority(normalized.Priority) {
+		return nil, repository.ErrInvalidPriority
+	}
+
+	normalized.Tag = NormalizeTag(filter.Tag)
+	normalized.Project = NormalizeProject(filter.Project)
+
+	if filter.Projects != nil {
+		normalized.Projects = make([]string, len(filter.Projects))
+		for i, project := range filter.Projects {
+			normalized.Projects[i] = NormalizeProject(project)
+		}
+	}
+	if filter.Tags != nil {
+		normalized.Tags = normalizeTags(filter.Tags)
+	}
+
+	return &normalized, nil
+}
+
+// NormalizeTag приводит тег к виду, в котором он хранится: без решётки и
+// пробелов, в нижнем регистре.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#")))
 }
 
-func (s *todoService) DeleteTodo(id string) error {
-	return s.repo.Delete(id)
+// NormalizeProject приводит проект к виду, в котором он хранится: без
+// пробелов по краям и ведущего @ из быстрого ввода.
+func NormalizeProject(project string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(project), "@"))
 }
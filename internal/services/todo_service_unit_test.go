@@ -1,11 +1,15 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 	"todo-api/internal/models"
 	"todo-api/internal/repository"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockRepo struct {
@@ -15,26 +19,46 @@ type mockRepo struct {
 	deleteErr  error
 }
 
-func (m *mockRepo) Create(task *models.Todo) error {
+func (m *mockRepo) Create(ctx context.Context, task *models.Todo) error {
 	return m.createErr
 }
 
-func (m *mockRepo) GetById(id string) (*models.Todo, error) {
+func (m *mockRepo) GetById(ctx context.Context, id string) (*models.Todo, error) {
 	return nil, m.getByIdErr
 
 }
-func (m *mockRepo) GetAllTask() ([]*models.Todo, error) {
+func (m *mockRepo) GetAllTask(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
 	return nil, nil
 }
 
-func (m *mockRepo) Update(id string, req *models.UpdateTodoRequest) error {
+func (m *mockRepo) Each(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	return nil
+}
+
+func (m *mockRepo) Update(ctx context.Context, id string, req *models.UpdateTodoRequest) error {
 	return m.updateErr
 }
 
-func (m *mockRepo) Delete(id string) error {
+func (m *mockRepo) Delete(ctx context.Context, id string) error {
 	return m.deleteErr
 }
 
+func (m *mockRepo) Count(ctx context.Context) (*models.TodoStats, error) {
+	return &models.TodoStats{}, nil
+}
+
+func (m *mockRepo) Search(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) AddEvents(ctx context.Context, events ...*models.Event) error {
+	return nil
+}
+
+func (m *mockRepo) WithinTx(ctx context.Context, fn func(repo repository.TodoRepository) error) error {
+	return fn(m)
+}
+
 func TestTodoService_CreateTodo(t *testing.T) {
 	repo := repository.Constructor()
 	services := NewTodoService(repo)
@@ -42,13 +66,35 @@ func TestTodoService_CreateTodo(t *testing.T) {
 
 	req := &models.CreateTodoRequest{TaskName: "test", Description: &description}
 
-	todo, err := services.CreateTodo(req)
+	todo, err := services.CreateTodo(t.Context(), req)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "test", todo.TaskName)
 	assert.Equal(t, "test text", *todo.Description)
 }
 
+func TestTodoService_CreateTodo_Planning(t *testing.T) {
+	repo := repository.Constructor()
+	services := NewTodoService(repo)
+
+	req := &models.CreateTodoRequest{
+		TaskName: "test",
+		Priority: " High ",
+		Tags:     []string{"#Work", "work", " ", "home"},
+		Project:  " @acme",
+	}
+
+	todo, err := services.CreateTodo(t.Context(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PriorityHigh, todo.Priority)
+	assert.Equal(t, []string{"work", "home"}, todo.Tags)
+	assert.Equal(t, "acme", todo.Project)
+
+	_, err = services.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test", Priority: "urgent"})
+	assert.Equal(t, repository.ErrInvalidPriority, err)
+}
+
 func TestTodoService_CreateTodo_ErrEmptyName(t *testing.T) {
 	repo := repository.Constructor()
 	services := NewTodoService(repo)
@@ -56,7 +102,7 @@ func TestTodoService_CreateTodo_ErrEmptyName(t *testing.T) {
 
 	req := &models.CreateTodoRequest{TaskName: "", Description: &description}
 
-	_, err := services.CreateTodo(req)
+	_, err := services.CreateTodo(t.Context(), req)
 
 	assert.ErrorIs(t, err, repository.ErrEmptyName)
 }
@@ -66,12 +112,12 @@ func TestTodoService_CreateTodo_ErrRepo(t *testing.T) {
 
 	repo := &mockRepo{createErr: repository.ErrAlreadyExist}
 	services := NewTodoService(repo)
-	_, err := services.CreateTodo(req)
+	_, err := services.CreateTodo(t.Context(), req)
 	assert.ErrorIs(t, err, repository.ErrAlreadyExist)
 
 	repo = &mockRepo{createErr: repository.ErrEmptyTask}
 	services = NewTodoService(repo)
-	_, err = services.CreateTodo(req)
+	_, err = services.CreateTodo(t.Context(), req)
 	assert.ErrorIs(t, err, repository.ErrEmptyTask)
 }
 
@@ -81,9 +127,9 @@ func TestTodoService_GetById(t *testing.T) {
 
 	description := "test text"
 	req := &models.CreateTodoRequest{TaskName: "test", Description: &description}
-	todo, _ := services.CreateTodo(req)
+	todo, _ := services.CreateTodo(t.Context(), req)
 
-	todo, err := services.GetById(todo.ID)
+	todo, err := services.GetById(t.Context(), todo.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "test", todo.TaskName)
@@ -95,14 +141,14 @@ func TestTodoService_GetById_ErrRepo(t *testing.T) {
 	services := NewTodoService(repo)
 
 	id := ""
-	_, err := services.GetById(id)
+	_, err := services.GetById(t.Context(), id)
 	assert.Error(t, err, repository.ErrEmptyID)
 
 	repo = &mockRepo{getByIdErr: repository.ErrInvalidID}
 	services = NewTodoService(repo)
 
 	id = "test"
-	_, err = services.GetById(id)
+	_, err = services.GetById(t.Context(), id)
 	assert.Error(t, err, repository.ErrInvalidID)
 }
 
@@ -112,13 +158,13 @@ func TestTodoService_GetAllTodos(t *testing.T) {
 
 	description1 := "test text 1"
 	req := &models.CreateTodoRequest{TaskName: "test1", Description: &description1}
-	_, _ = services.CreateTodo(req)
+	_, _ = services.CreateTodo(t.Context(), req)
 
 	description2 := "test text 2"
 	req = &models.CreateTodoRequest{TaskName: "test2", Description: &description2}
-	_, _ = services.CreateTodo(req)
+	_, _ = services.CreateTodo(t.Context(), req)
 
-	todos, err := services.GetAllTodos()
+	todos, err := services.GetAllTodos(t.Context(), nil)
 
 	assert.NoError(t, err)
 	assert.Len(t, todos, 2)
@@ -128,36 +174,187 @@ func TestTodoService_GetAllTodos(t *testing.T) {
 	assert.Equal(t, "test text 2", *todos[1].Description)
 }
 
+func TestTodoService_GetAllTodos_Filter(t *testing.T) {
+	repo := repository.Constructor()
+	services := NewTodoService(repo)
+
+	_, err := services.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "report", Priority: "high", Tags: []string{"finance"}, Project: "acme"})
+	assert.NoError(t, err)
+	_, err = services.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "groceries"})
+	assert.NoError(t, err)
+
+	todos, err := services.GetAllTodos(t.Context(), &models.TodoFilter{Priority: " HIGH", Tag: "#Finance", Project: "@acme"})
+	assert.NoError(t, err)
+	assert.Len(t, todos, 1)
+
+	var exported []string
+	err = services.ExportTodos(t.Context(), &models.TodoFilter{Tag: " "}, func(task *models.Todo) error {
+		exported = append(exported, task.TaskName)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"report", "groceries"}, exported)
+
+	_, err = services.GetAllTodos(t.Context(), &models.TodoFilter{Priority: "urgent"})
+	assert.Equal(t, repository.ErrInvalidPriority, err)
+
+	todos, err = services.GetAllTodos(t.Context(), &models.TodoFilter{Projects: []string{"@acme", "home"}, Tags: []string{"#Finance"}})
+	assert.NoError(t, err)
+	assert.Len(t, todos, 1)
+}
+
+func TestTodoService_ListTodos(t *testing.T) {
+	services := NewTodoService(repository.Constructor())
+
+	var names []string
+	for _, name := range []string{"one", "two", "three"} {
+		_, err := services.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: name})
+		require.NoError(t, err)
+	}
+
+	var pages int
+	var token string
+	for {
+		page, err := services.ListTodos(t.Context(), nil, 2, token)
+		require.NoError(t, err)
+		pages++
+		for _, task := range page.Todos {
+			names = append(names, task.TaskName)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		token = page.NextPageToken
+	}
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, []string{"one", "two", "three"}, names)
+
+	page, err := services.ListTodos(t.Context(), &models.TodoFilter{Project: "missing"}, 2, "")
+	require.NoError(t, err)
+	assert.NotNil(t, page.Todos)
+	assert.Empty(t, page.Todos)
+
+	_, err = services.ListTodos(t.Context(), nil, 2, "???")
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestTodoService_ImportTodos(t *testing.T) {
+	repo := repository.Constructor()
+	services := NewTodoService(repo)
+
+	lines := func() []models.ImportLine {
+		return []models.ImportLine{
+			{Line: 1, Todo: &models.CreateTodoRequest{TaskName: "первая", Tags: []string{"#Work"}}, Completed: true},
+			{Line: 2, Todo: &models.CreateTodoRequest{TaskName: "вторая"}},
+		}
+	}
+
+	report, err := services.ImportTodos(t.Context(), lines(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Total)
+	assert.Zero(t, report.Imported)
+	assert.Empty(t, report.Lines[0].ID)
+
+	invalid := append(lines(),
+		models.ImportLine{Line: 3, Todo: &models.CreateTodoRequest{TaskName: " "}},
+		models.ImportLine{Line: 4, Error: "некорректная задача"},
+	)
+	report, err = services.ImportTodos(t.Context(), invalid, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Invalid)
+	assert.Equal(t, repository.ErrEmptyName.Error(), report.Lines[2].Error)
+	assert.Zero(t, report.Imported)
+
+	todos, err := services.GetAllTodos(t.Context(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, todos, "ни пробный запуск, ни файл с ошибками ничего не создают")
+
+	report, err = services.ImportTodos(t.Context(), lines(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Imported)
+
+	todos, err = services.GetAllTodos(t.Context(), nil)
+	assert.NoError(t, err)
+	assert.Len(t, todos, 2)
+	assert.Equal(t, report.Lines[0].ID, todos[0].ID)
+	assert.True(t, todos[0].Completed)
+	assert.Equal(t, []string{"work"}, todos[0].Tags)
+	assert.False(t, todos[1].Completed)
+}
+
 func TestTodoService_Update(t *testing.T) {
 	repo := repository.Constructor()
 	services := NewTodoService(repo)
 
 	description := "test text 1"
 	req := &models.CreateTodoRequest{TaskName: "test1", Description: &description}
-	todo, _ := services.CreateTodo(req)
+	todo, _ := services.CreateTodo(t.Context(), req)
 
 	descriptionNew := "test text new"
 	taskNameNew := "testNew"
 	updReq := &models.UpdateTodoRequest{TaskName: &taskNameNew, Description: &descriptionNew}
-	newTodo, err := services.UpdateTodo(todo.ID, updReq)
+	newTodo, err := services.UpdateTodo(t.Context(), todo.ID, updReq)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "testNew", newTodo.TaskName)
 	assert.Equal(t, "test text new", *newTodo.Description)
 }
 
+func TestTodoService_ReplaceTodo(t *testing.T) {
+	repo := repository.Constructor()
+	services := NewTodoService(repo)
+
+	description := "позвонить до обеда"
+	dueAt := time.Date(2026, 11, 5, 14, 0, 0, 0, time.UTC)
+	replace := func(req models.CreateTodoRequest, completed bool, ifVersion *int64) (*models.Todo, bool, error) {
+		return services.ReplaceTodo(t.Context(), "id-1", &models.ReplaceTodoRequest{CreateTodoRequest: req, Completed: completed, IfVersion: ifVersion})
+	}
+	version := func(v int64) *int64 { return &v }
+
+	_, _, err := replace(models.CreateTodoRequest{TaskName: "маме"}, false, version(1))
+	assert.ErrorIs(t, err, repository.ErrVersionConflict, "задачи ещё нет")
+
+	todo, created, err := replace(models.CreateTodoRequest{
+		TaskName: "маме", Description: &description, DueAt: &dueAt, Priority: "high", Tags: []string{"#Семья"},
+	}, false, version(0))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "id-1", todo.ID)
+	assert.Equal(t, int64(1), todo.Version)
+	assert.Equal(t, []string{"семья"}, todo.Tags)
+
+	_, _, err = replace(models.CreateTodoRequest{TaskName: "маме"}, false, version(0))
+	assert.ErrorIs(t, err, repository.ErrVersionConflict, "задача уже есть")
+	_, _, err = replace(models.CreateTodoRequest{TaskName: "маме"}, false, version(2))
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	todo, created, err = replace(models.CreateTodoRequest{TaskName: "папе"}, true, version(1))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "папе", todo.TaskName)
+	assert.True(t, todo.Completed)
+	assert.Equal(t, int64(2), todo.Version)
+	assert.Nil(t, todo.DueAt, "незаданные поля сбрасываются")
+	assert.Empty(t, todo.Priority)
+	assert.Empty(t, todo.Tags)
+	assert.True(t, todo.Description == nil || *todo.Description == "")
+
+	_, _, err = replace(models.CreateTodoRequest{TaskName: " "}, false, nil)
+	assert.ErrorIs(t, err, repository.ErrEmptyName)
+}
+
 func TestTodoService_Update_ErrRepo(t *testing.T) {
 	repo := &mockRepo{updateErr: repository.ErrEmptyTask}
 	services := NewTodoService(repo)
 
-	_, err := services.UpdateTodo("1", nil)
+	_, err := services.UpdateTodo(t.Context(), "1", nil)
 
 	assert.ErrorIs(t, err, repository.ErrEmptyTask)
 
 	repo = &mockRepo{updateErr: repository.ErrEmptyID}
 	services = NewTodoService(repo)
 
-	_, err = services.UpdateTodo("", nil)
+	_, err = services.UpdateTodo(t.Context(), "", nil)
 
 	assert.ErrorIs(t, err, repository.ErrEmptyID)
 
@@ -166,12 +363,12 @@ func TestTodoService_Update_ErrRepo(t *testing.T) {
 
 	description := "test text 1"
 	req := &models.CreateTodoRequest{TaskName: "test1", Description: &description}
-	todo, _ := services.CreateTodo(req)
+	todo, _ := services.CreateTodo(t.Context(), req)
 
 	descriptionNew := "test text new"
 	taskNameNew := ""
 	updReq := &models.UpdateTodoRequest{TaskName: &taskNameNew, Description: &descriptionNew}
-	_, err = services.UpdateTodo(todo.ID, updReq)
+	_, err = services.UpdateTodo(t.Context(), todo.ID, updReq)
 
 	assert.ErrorIs(t, err, repository.ErrEmptyName)
 
@@ -179,7 +376,7 @@ func TestTodoService_Update_ErrRepo(t *testing.T) {
 	services = NewTodoService(repo)
 
 	updReq = &models.UpdateTodoRequest{TaskName: &taskNameNew, Description: &descriptionNew}
-	_, err = services.UpdateTodo("231", updReq)
+	_, err = services.UpdateTodo(t.Context(), "231", updReq)
 
 	assert.ErrorIs(t, err, repository.ErrInvalidID)
 }
@@ -191,10 +388,10 @@ func TestTodoService_Delete(t *testing.T) {
 
 	req := &models.CreateTodoRequest{TaskName: "test", Description: &description}
 
-	todo, _ := services.CreateTodo(req)
+	todo, _ := services.CreateTodo(t.Context(), req)
 
-	err := services.DeleteTodo(todo.ID)
-	todos, _ := services.GetAllTodos()
+	err := services.DeleteTodo(t.Context(), todo.ID)
+	todos, _ := services.GetAllTodos(t.Context(), nil)
 
 	assert.NoError(t, err)
 	assert.Len(t, todos, 0)
@@ -204,12 +401,89 @@ func TestTodoService_Delete_ErrRepo(t *testing.T) {
 	repo := &mockRepo{deleteErr: repository.ErrEmptyID}
 	services := NewTodoService(repo)
 
-	err := services.DeleteTodo("")
+	err := services.DeleteTodo(t.Context(), "")
 	assert.ErrorIs(t, err, repository.ErrEmptyID)
 
 	repo = &mockRepo{deleteErr: repository.ErrInvalidID}
 	services = NewTodoService(repo)
 
-	err = services.DeleteTodo("213")
+	err = services.DeleteTodo(t.Context(), "213")
 	assert.ErrorIs(t, err, repository.ErrInvalidID)
 }
+
+func TestTodoService_Events(t *testing.T) {
+	repo := repository.Constructor()
+	hooks := repository.NewMemoryWebhookRepository(repo)
+	services := NewTodoService(repo)
+	require.NoError(t, hooks.CreateWebhook(t.Context(), &models.Webhook{ID: "hook", URL: "https://example.com", Secret: "s"}))
+
+	todo, err := services.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	name := "renamed"
+	_, err = services.UpdateTodo(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name})
+	require.NoError(t, err)
+
+	completed := true
+	_, err = services.UpdateTodo(t.Context(), todo.ID, &models.UpdateTodoRequest{Completed: &completed})
+	require.NoError(t, err)
+
+	empty := ""
+	_, err = services.UpdateTodo(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &empty})
+	require.ErrorIs(t, err, repository.ErrEmptyName)
+
+	require.NoError(t, services.DeleteTodo(t.Context(), todo.ID))
+	assert.ErrorIs(t, services.DeleteTodo(t.Context(), todo.ID), repository.ErrInvalidID)
+
+	_, err = hooks.DispatchEvents(t.Context(), 10, time.Now())
+	require.NoError(t, err)
+	deliveries, err := hooks.ListDeliveries(t.Context(), "hook", nil)
+	require.NoError(t, err)
+
+	var types []string
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		types = append(types, deliveries[i].EventType)
+	}
+	assert.Equal(t, []string{models.EventTodoCreated, models.EventTodoUpdated, models.EventTodoCompleted, models.EventTodoDeleted}, types,
+		"неудачное изменение не порождает событие")
+
+	var payload models.EventPayload
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	assert.Equal(t, deliveries[0].EventID, payload.ID)
+	assert.Equal(t, models.EventTodoDeleted, payload.Type)
+	require.NotNil(t, payload.Data)
+	assert.Equal(t, "renamed", payload.Data.TaskName, "удалённая задача передаётся в последнем состоянии")
+	assert.True(t, payload.Data.Completed)
+}
+
+func TestTodoService_EventsPreviousProject(t *testing.T) {
+	repo := repository.Constructor()
+	hooks := repository.NewMemoryWebhookRepository(repo)
+	services := NewTodoService(repo)
+	require.NoError(t, hooks.CreateWebhook(t.Context(), &models.Webhook{ID: "hook", URL: "https://example.com", Secret: "s"}))
+
+	todo, err := services.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test", Project: "work"})
+	require.NoError(t, err)
+
+	name := "renamed"
+	_, err = services.UpdateTodo(t.Context(), todo.ID, &models.UpdateTodoRequest{TaskName: &name})
+	require.NoError(t, err)
+
+	project := "@home"
+	_, err = services.UpdateTodo(t.Context(), todo.ID, &models.UpdateTodoRequest{Project: &project})
+	require.NoError(t, err)
+
+	_, err = hooks.DispatchEvents(t.Context(), 10, time.Now())
+	require.NoError(t, err)
+	deliveries, err := hooks.ListDeliveries(t.Context(), "hook", nil)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+
+	var moved, renamed models.EventPayload
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &moved))
+	require.NoError(t, json.Unmarshal(deliveries[1].Payload, &renamed))
+
+	assert.Equal(t, "home", moved.Data.Project)
+	assert.Equal(t, "work", moved.PreviousProject)
+	assert.Empty(t, renamed.PreviousProject, "проект не менялся")
+}
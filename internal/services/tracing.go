@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"todo-api/internal/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "todo-api/internal/services"
+
+// Trace оборачивает сервис и открывает span на каждый вызов. Span становится
+// родителем для запросов репозитория, выполненных с переданным ctx.
+func Trace(service TodoService) TodoService {
+	return &tracedService{next: service, tracer: otel.Tracer(tracerName)}
+}
+
+type tracedService struct {
+	next   TodoService
+	tracer trace.Tracer
+}
+
+func (s *tracedService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "TodoService."+method, trace.WithAttributes(attrs...))
+}
+
+func finish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedService) CreateTodo(ctx context.Context, request *models.CreateTodoRequest) (*models.Todo, error) {
+	ctx, span := s.start(ctx, "CreateTodo")
+
+	task, err := s.next.CreateTodo(ctx, request)
+	if task != nil {
+		span.SetAttributes(attribute.String("todo.id", task.ID))
+	}
+
+	finish(span, err)
+	return task, err
+}
+
+func (s *tracedService) GetById(ctx context.Context, id string) (*models.Todo, error) {
+	ctx, span := s.start(ctx, "GetById", attribute.String("todo.id", id))
+
+	task, err := s.next.GetById(ctx, id)
+
+	finish(span, err)
+	return task, err
+}
+
+func (s *tracedService) GetAllTodos(ctx context.Context, filter *models.TodoFilter) ([]*models.Todo, error) {
+	ctx, span := s.start(ctx, "GetAllTodos")
+
+	tasks, err := s.next.GetAllTodos(ctx, filter)
+	span.SetAttributes(attribute.Int("todo.count", len(tasks)))
+
+	finish(span, err)
+	return tasks, err
+}
+
+func (s *tracedService) ExportTodos(ctx context.Context, filter *models.TodoFilter, fn func(task *models.Todo) error) error {
+	ctx, span := s.start(ctx, "ExportTodos")
+
+	count := 0
+	err := s.next.ExportTodos(ctx, filter, func(task *models.Todo) error {
+		count++
+		return fn(task)
+	})
+	span.SetAttributes(attribute.Int("todo.count", count))
+
+	finish(span, err)
+	return err
+}
+
+func (s *tracedService) ListTodos(ctx context.Context, filter *models.TodoFilter, pageSize int, pageToken string) (*models.TodoPage, error) {
+	ctx, span := s.start(ctx, "ListTodos")
+
+	page, err := s.next.ListTodos(ctx, filter, pageSize, pageToken)
+	if page != nil {
+		span.SetAttributes(attribute.Int("todo.count", len(page.Todos)))
+	}
+
+	finish(span, err)
+	return page, err
+}
+
+func (s *tracedService) UpdateTodo(ctx context.Context, id string, request *models.UpdateTodoRequest) (*models.Todo, error) {
+	ctx, span := s.start(ctx, "UpdateTodo", attribute.String("todo.id", id))
+
+	task, err := s.next.UpdateTodo(ctx, id, request)
+
+	finish(span, err)
+	return task, err
+}
+
+func (s *tracedService) ReplaceTodo(ctx context.Context, id string, request *models.ReplaceTodoRequest) (*models.Todo, bool, error) {
+	ctx, span := s.start(ctx, "ReplaceTodo", attribute.String("todo.id", id))
+
+	task, created, err := s.next.ReplaceTodo(ctx, id, request)
+	span.SetAttributes(attribute.Bool("todo.created", created))
+
+	finish(span, err)
+	return task, created, err
+}
+
+func (s *tracedService) DeleteTodo(ctx context.Context, id string) error {
+	ctx, span := s.start(ctx, "DeleteTodo", attribute.String("todo.id", id))
+
+	err := s.next.DeleteTodo(ctx, id)
+
+	finish(span, err)
+	return err
+}
+
+func (s *tracedService) SearchTodos(ctx context.Context, query string, limit int) ([]*models.SearchResult, error) {
+	ctx, span := s.start(ctx, "SearchTodos", attribute.Int("search.limit", limit))
+
+	results, err := s.next.SearchTodos(ctx, query, limit)
+	span.SetAttributes(attribute.Int("todo.count", len(results)))
+
+	finish(span, err)
+	return results, err
+}
+
+func (s *tracedService) ImportTodos(ctx context.Context, lines []models.ImportLine, dryRun bool) (*models.ImportReport, error) {
+	ctx, span := s.start(ctx, "ImportTodos",
+		attribute.Int("import.lines", len(lines)),
+		attribute.Bool("import.dry_run", dryRun),
+	)
+
+	report, err := s.next.ImportTodos(ctx, lines, dryRun)
+	if report != nil {
+		span.SetAttributes(
+			attribute.Int("import.invalid", report.Invalid),
+			attribute.Int("todo.count", report.Imported),
+		)
+	}
+
+	finish(span, err)
+	return report, err
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"todo-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTrace_RecordsSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	service := Trace(NewTodoService(&mockRepo{getByIdErr: repository.ErrInvalidID}))
+
+	_, err := service.GetAllTodos(t.Context(), nil)
+	require.NoError(t, err)
+
+	_, err = service.GetById(t.Context(), "1")
+	require.ErrorIs(t, err, repository.ErrInvalidID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "TodoService.GetAllTodos", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "TodoService.GetById", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1)
+}
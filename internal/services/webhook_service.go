@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// WebhookService управляет подписками на события и журналом их доставки.
+// Секрет подписи возвращается только при создании вебхука.
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, request *models.CreateWebhookRequest) (*models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, webhookID string, filter *models.DeliveryFilter) ([]*models.WebhookDelivery, error)
+	// Redeliver ставит доставку в очередь на немедленную отправку.
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo repository.WebhookRepository
+}
+
+func NewWebhookService(repo repository.WebhookRepository) WebhookService {
+	return &webhookService{repo: repo}
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, request *models.CreateWebhookRequest) (*models.Webhook, error) {
+	secret := strings.TrimSpace(request.Secret)
+	if secret == "" {
+		secret = newSecret()
+	}
+
+	hook := &models.Webhook{
+		ID:     uuid.NewString(),
+		URL:    strings.TrimSpace(request.URL),
+		Events: normalizeEvents(request.Events),
+		Secret: secret,
+	}
+
+	if err := s.repo.CreateWebhook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	hook, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hook.Secret = ""
+	return hook, nil
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	hooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	return hooks, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id string) error {
+	return s.repo.DeleteWebhook(ctx, id)
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, webhookID string, filter *models.DeliveryFilter) ([]*models.WebhookDelivery, error) {
+	if filter != nil {
+		normalized := *filter
+		normalized.Status = strings.ToLower(strings.TrimSpace(filter.Status))
+		filter = &normalized
+	}
+
+	return s.repo.ListDeliveries(ctx, webhookID, filter)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	return s.repo.Redeliver(ctx, webhookID, deliveryID, time.Now())
+}
+
+// normalizeEvents приводит события к нижнему регистру и убирает повторы.
+// Пустой список означает подписку на все события.
+func normalizeEvents(events []string) []string {
+	result := []string{}
+	seen := make(map[string]bool, len(events))
+
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" || seen[event] {
+			continue
+		}
+		seen[event] = true
+		result = append(result, event)
+	}
+
+	return result
+}
+
+func newSecret() string {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return hex.EncodeToString(secret)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookService_CreateWebhook(t *testing.T) {
+	service := NewWebhookService(repository.NewMemoryWebhookRepository(repository.Constructor()))
+
+	hook, err := service.CreateWebhook(t.Context(), &models.CreateWebhookRequest{
+		URL:    " https://example.com/hooks ",
+		Events: []string{" Todo.Created", "todo.created", "todo.deleted"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hooks", hook.URL)
+	assert.Equal(t, []string{models.EventTodoCreated, models.EventTodoDeleted}, hook.Events)
+	assert.Len(t, hook.Secret, 64, "секрет генерируется, если не передан")
+
+	stored, err := service.GetWebhook(t.Context(), hook.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Secret, "секрет показывается только при создании")
+
+	list, err := service.ListWebhooks(t.Context())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Empty(t, list[0].Secret)
+
+	hook, err = service.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: "http://localhost:9000", Secret: "мой секрет"})
+	require.NoError(t, err)
+	assert.Equal(t, "мой секрет", hook.Secret)
+	assert.Equal(t, []string{}, hook.Events)
+
+	_, err = service.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: "example.com"})
+	assert.ErrorIs(t, err, repository.ErrInvalidWebhookURL)
+	_, err = service.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: "https://example.com", Events: []string{"todo.moved"}})
+	assert.ErrorIs(t, err, repository.ErrUnknownEvent)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	todos := repository.Constructor()
+	hooks := repository.NewMemoryWebhookRepository(todos)
+	service := NewWebhookService(hooks)
+
+	hook, err := service.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: "https://example.com"})
+	require.NoError(t, err)
+	_, err = NewTodoService(todos).CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+	_, err = hooks.DispatchEvents(t.Context(), 10, time.Now())
+	require.NoError(t, err)
+
+	claimed, err := hooks.ClaimDeliveries(t.Context(), time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	claimed[0].Status = models.DeliveryFailed
+	claimed[0].Attempts = 3
+	require.NoError(t, hooks.SaveDelivery(t.Context(), claimed[0]))
+
+	deliveries, err := service.ListDeliveries(t.Context(), hook.ID, &models.DeliveryFilter{Status: " Failed "})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	delivery, err := service.Redeliver(t.Context(), hook.ID, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Zero(t, delivery.Attempts)
+
+	require.NoError(t, service.DeleteWebhook(t.Context(), hook.ID))
+	_, err = service.Redeliver(t.Context(), hook.ID, deliveries[0].ID)
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+}
@@ -0,0 +1,160 @@
+// Package storage собирает репозиторий задач под бэкенд, выбранный в конфигурации.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/health"
+	"todo-api/internal/repository"
+	"todo-api/migrations"
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+	BackendSQLite   = "sqlite"
+	BackendFile     = "file"
+)
+
+var ErrUnknownBackend = errors.New("неизвестный тип хранилища")
+
+// Storage владеет репозиторием и ресурсами, которые нужны для его работы.
+type Storage struct {
+	Repo repository.TodoRepository
+	// Webhooks равен nil, если бэкенд не поддерживает вебхуки.
+	Webhooks repository.WebhookRepository
+	// Sync равен nil, если бэкенд не ведёт журнал изменений для синхронизации.
+	Sync repository.SyncRepository
+	// DB равен nil, если бэкенд не использует базу данных.
+	DB *sql.DB
+
+	checks  []health.Check
+	closers []func() error
+}
+
+func Open(cfg *config.Config) (*Storage, error) {
+	backend := strings.ToLower(strings.TrimSpace(cfg.Storage.Backend))
+
+	switch backend {
+	case BackendPostgres:
+		return openPostgres(cfg.Database)
+	case BackendMemory:
+		repo := repository.Constructor()
+		return &Storage{
+			Repo:     repo,
+			Webhooks: repository.NewMemoryWebhookRepository(repo),
+			Sync:     repository.NewMemorySyncRepository(repo),
+		}, nil
+	case BackendSQLite:
+		return openSQLite(cfg.Storage.SQLitePath, cfg.Database.QueryTimeout)
+	case BackendFile:
+		return openFile(cfg.Storage)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, cfg.Storage.Backend)
+	}
+}
+
+func openPostgres(cfg config.DatabaseConfig) (*Storage, error) {
+	db, err := database.Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrations.RunMigrations(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Storage{
+		Repo:     repository.NewPostgresRepository(db, cfg.QueryTimeout),
+		Webhooks: repository.NewPostgresWebhookRepository(db, cfg.QueryTimeout),
+		Sync:     repository.NewPostgresSyncRepository(db, cfg.QueryTimeout),
+		DB:       db,
+		checks:   databaseChecks(db, migrations.Pending),
+		closers:  []func() error{db.Close},
+	}, nil
+}
+
+func openSQLite(path string, queryTimeout time.Duration) (*Storage, error) {
+	db, err := database.ConnectSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrations.RunSQLiteMigrations(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Storage{
+		Repo:     repository.NewSQLiteRepository(db, queryTimeout),
+		Webhooks: repository.NewSQLiteWebhookRepository(db, queryTimeout),
+		Sync:     repository.NewSQLiteSyncRepository(db, queryTimeout),
+		DB:       db,
+		checks:   databaseChecks(db, migrations.PendingSQLite),
+		closers:  []func() error{db.Close},
+	}, nil
+}
+
+func openFile(cfg config.StorageConfig) (*Storage, error) {
+	repo, err := repository.NewFileRepository(cfg.FileDir, cfg.CompactInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Storage{
+		Repo: repo,
+		checks: []health.Check{{
+			Name: "workers",
+			Func: func(ctx context.Context) error { return repo.Healthy() },
+		}},
+		closers: []func() error{repo.Close},
+	}, nil
+}
+
+func databaseChecks(db *sql.DB, pending func(ctx context.Context, db *sql.DB) (int, error)) []health.Check {
+	return []health.Check{
+		{
+			Name: "database",
+			Func: db.PingContext,
+		},
+		{
+			Name: "migrations",
+			Func: func(ctx context.Context) error {
+				count, err := pending(ctx, db)
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					return fmt.Errorf("не применено миграций: %d", count)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Checks возвращает проверки готовности хранилища.
+func (s *Storage) Checks() []health.Check {
+	return s.checks
+}
+
+// Close освобождает ресурсы в порядке, обратном их открытию.
+func (s *Storage) Close() error {
+	var errs []error
+
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.closers = nil
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/health"
+	"todo-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_Memory(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageConfig{Backend: " Memory "}}
+
+	store, err := Open(cfg)
+	require.NoError(t, err)
+	defer store.Close()
+
+	assert.IsType(t, &repository.StorageRepository{}, store.Repo)
+	assert.IsType(t, &repository.MemoryWebhookRepository{}, store.Webhooks)
+	assert.IsType(t, &repository.MemorySyncRepository{}, store.Sync)
+	assert.Nil(t, store.DB)
+}
+
+func TestOpen_SQLite(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageConfig{
+		Backend:    "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "todos.db"),
+	}}
+
+	store, err := Open(cfg)
+	require.NoError(t, err)
+	defer store.Close()
+
+	assert.IsType(t, &repository.SQLiteRepository{}, store.Repo)
+	assert.IsType(t, &repository.SQLWebhookRepository{}, store.Webhooks)
+	assert.IsType(t, &repository.SQLSyncRepository{}, store.Sync)
+	assert.NotNil(t, store.DB)
+
+	report := health.NewChecker(time.Second, store.Checks()...).Ready(context.Background())
+	assert.True(t, report.Ready, report)
+	assert.Len(t, report.Checks, 2)
+}
+
+func TestOpen_File(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageConfig{
+		Backend: "file",
+		FileDir: t.TempDir(),
+	}}
+
+	store, err := Open(cfg)
+	require.NoError(t, err)
+
+	assert.IsType(t, &repository.FileRepository{}, store.Repo)
+	assert.Nil(t, store.Webhooks)
+	assert.Nil(t, store.Sync)
+	assert.Nil(t, store.DB)
+
+	checker := health.NewChecker(time.Second, store.Checks()...)
+	assert.True(t, checker.Ready(context.Background()).Ready)
+
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, repository.ErrStorageLocked)
+
+	assert.NoError(t, store.Close())
+	assert.False(t, checker.Ready(context.Background()).Ready)
+}
+
+func TestOpen_ErrUnknownBackend(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageConfig{Backend: "mongo"}}
+
+	_, err := Open(cfg)
+	assert.ErrorIs(t, err, ErrUnknownBackend)
+}
+
+func TestStorage_Close(t *testing.T) {
+	var closed []int
+	store := &Storage{closers: []func() error{
+		func() error { closed = append(closed, 1); return nil },
+		func() error { closed = append(closed, 2); return nil },
+	}}
+
+	assert.NoError(t, store.Close())
+	assert.Equal(t, []int{2, 1}, closed)
+	assert.NoError(t, store.Close())
+	assert.Equal(t, []int{2, 1}, closed)
+}
@@ -0,0 +1,206 @@
+// Package stream раздаёт события об изменениях задач подписчикам потока
+// изменений. Брокер хранит последние события, чтобы переподключившийся клиент
+// получил пропущенные, а при нескольких репликах события доходят до брокера
+// каждой реплики через Postgres LISTEN/NOTIFY.
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+	"todo-api/internal/models"
+)
+
+// TypeReset — служебное событие: брокер не может восстановить пропущенные
+// события, и клиенту нужно заново загрузить задачи.
+const TypeReset = "reset"
+
+// subscriberBuffer — сколько событий ждёт отправки одному подписчику. Если
+// подписчик не успевает их забирать, брокер отключает его: клиент
+// переподключится с Last-Event-ID и дочитает пропущенное из буфера.
+const subscriberBuffer = 64
+
+// Event — событие потока. Data — тело события, как его получают вебхуки.
+// Project и PreviousProject — проект задачи после изменения и до него.
+type Event struct {
+	ID              string
+	Type            string
+	Actor           string
+	Project         string
+	PreviousProject string
+	Data            []byte
+}
+
+// FromModel собирает событие потока из события outbox.
+func FromModel(event *models.Event) Event {
+	parsed, _ := parseEvent(event.Payload)
+	parsed.ID, parsed.Type, parsed.Data = event.ID, event.Type, event.Payload
+	return parsed
+}
+
+// parseEvent читает из тела события поля, нужные подписчикам.
+func parseEvent(data []byte) (Event, error) {
+	var payload struct {
+		ID              string `json:"id"`
+		Type            string `json:"type"`
+		Actor           string `json:"actor"`
+		PreviousProject string `json:"previousProject"`
+		Data            struct {
+			Project string `json:"project"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:              payload.ID,
+		Type:            payload.Type,
+		Actor:           payload.Actor,
+		Project:         payload.Data.Project,
+		PreviousProject: payload.PreviousProject,
+		Data:            data,
+	}, nil
+}
+
+// Subscription — подписка на поток. C закрывается, когда брокер отключает
+// подписчика: при остановке сервера или если подписчик не успевает читать.
+type Subscription struct {
+	C     <-chan Event
+	ch    chan Event
+	match func(event Event) bool
+}
+
+// Broker хранит последние size событий и рассылает новые подписчикам.
+type Broker struct {
+	mu     sync.Mutex
+	size   int
+	buffer []Event
+	// index — позиции событий буфера по айди, отсчитанные от начала потока;
+	// buffer[0] имеет позицию base.
+	index  map[string]uint64
+	base   uint64
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:  max(size, 1),
+		index: make(map[string]uint64),
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish добавляет события в буфер и рассылает подписчикам.
+func (b *Broker) Publish(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, event := range events {
+		if _, seen := b.index[event.ID]; seen {
+			continue
+		}
+
+		if len(b.buffer) == b.size {
+			delete(b.index, b.buffer[0].ID)
+			b.buffer = b.buffer[1:]
+			b.base++
+		}
+		b.index[event.ID] = b.base + uint64(len(b.buffer))
+		b.buffer = append(b.buffer, event)
+
+		for sub := range b.subs {
+			if sub.match(event) {
+				b.send(sub, event)
+			}
+		}
+	}
+}
+
+// send не ждёт медленного подписчика, а отключает его.
+func (b *Broker) send(sub *Subscription, event Event) {
+	select {
+	case sub.ch <- event:
+	default:
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscribe подписывает на события, для которых match возвращает true. Если
+// передан lastEventID, replay содержит подходящие события буфера после него,
+// а resumed сообщает, нашлось ли это событие: если нет, клиент мог пропустить
+// события и должен заново загрузить задачи.
+func (b *Broker) Subscribe(lastEventID string, match func(event Event) bool) (sub *Subscription, replay []Event, resumed bool) {
+	ch := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch, match: match}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return sub, nil, false
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	position, ok := b.index[lastEventID]
+	if !ok {
+		return sub, nil, false
+	}
+
+	for _, event := range b.buffer[position-b.base+1:] {
+		if match(event) {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, true
+}
+
+// Unsubscribe отключает подписчика. Повторный вызов ничего не делает.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(sub)
+}
+
+// Reset очищает буфер и отправляет подписчикам TypeReset. Вызывается, когда
+// брокер мог пропустить события, например при переподключении к базе.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.base += uint64(len(b.buffer))
+	b.buffer = nil
+	clear(b.index)
+
+	for sub := range b.subs {
+		b.send(sub, Event{Type: TypeReset})
+	}
+}
+
+// Close отключает всех подписчиков; новые подписки сразу закрываются.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
@@ -0,0 +1,142 @@
+package stream
+
+import (
+	"fmt"
+	"testing"
+	"todo-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(id, actor string) Event {
+	return Event{ID: id, Type: "todo.created", Actor: actor, Data: []byte(fmt.Sprintf(`{"id":%q}`, id))}
+}
+
+func all(Event) bool { return true }
+
+func ids(events []Event) []string {
+	result := make([]string, len(events))
+	for i, event := range events {
+		result[i] = event.ID
+	}
+	return result
+}
+
+func TestBroker_Publish(t *testing.T) {
+	broker := NewBroker(10)
+	sub, replay, resumed := broker.Subscribe("", all)
+	assert.True(t, resumed)
+	assert.Empty(t, replay)
+
+	broker.Publish(event("1", ""), event("2", ""), event("1", ""))
+
+	assert.Equal(t, "1", (<-sub.C).ID)
+	assert.Equal(t, "2", (<-sub.C).ID)
+	assert.Empty(t, sub.C, "повторное событие не рассылается")
+}
+
+func TestBroker_Resume(t *testing.T) {
+	broker := NewBroker(3)
+	broker.Publish(event("1", ""), event("2", ""), event("3", ""))
+
+	_, replay, resumed := broker.Subscribe("1", all)
+	assert.True(t, resumed)
+	assert.Equal(t, []string{"2", "3"}, ids(replay))
+
+	_, replay, resumed = broker.Subscribe("3", all)
+	assert.True(t, resumed)
+	assert.Empty(t, replay)
+
+	broker.Publish(event("4", ""))
+
+	_, replay, resumed = broker.Subscribe("1", all)
+	assert.False(t, resumed, "событие вытеснено из буфера")
+	assert.Empty(t, replay)
+
+	_, replay, resumed = broker.Subscribe("2", all)
+	assert.True(t, resumed)
+	assert.Equal(t, []string{"3", "4"}, ids(replay))
+}
+
+func TestBroker_Filter(t *testing.T) {
+	broker := NewBroker(10)
+	alice := func(event Event) bool { return event.Actor == "alice" }
+
+	broker.Publish(event("1", "alice"), event("2", "bob"))
+	sub, replay, _ := broker.Subscribe("", alice)
+	assert.Empty(t, replay)
+
+	_, replay, _ = broker.Subscribe("1", alice)
+	assert.Empty(t, replay)
+
+	broker.Publish(event("3", "bob"), event("4", "alice"))
+	assert.Equal(t, "4", (<-sub.C).ID)
+	assert.Empty(t, sub.C)
+}
+
+func TestBroker_SlowSubscriber(t *testing.T) {
+	broker := NewBroker(subscriberBuffer * 2)
+	sub, _, _ := broker.Subscribe("", all)
+
+	for i := range subscriberBuffer + 1 {
+		broker.Publish(event(fmt.Sprint(i), ""))
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received, "после переполнения канал закрыт")
+
+	_, replay, resumed := broker.Subscribe(fmt.Sprint(subscriberBuffer-1), all)
+	require.True(t, resumed)
+	assert.Equal(t, []string{fmt.Sprint(subscriberBuffer)}, ids(replay))
+}
+
+func TestBroker_Reset(t *testing.T) {
+	broker := NewBroker(10)
+	broker.Publish(event("1", ""))
+	sub, _, _ := broker.Subscribe("", func(event Event) bool { return event.Actor == "alice" })
+
+	broker.Reset()
+
+	assert.Equal(t, TypeReset, (<-sub.C).Type, "reset доходит до подписчика с любым фильтром")
+	_, _, resumed := broker.Subscribe("1", all)
+	assert.False(t, resumed)
+
+	broker.Publish(event("2", ""))
+	_, replay, resumed := broker.Subscribe("2", all)
+	assert.True(t, resumed)
+	assert.Empty(t, replay)
+}
+
+func TestBroker_Close(t *testing.T) {
+	broker := NewBroker(10)
+	sub, _, _ := broker.Subscribe("", all)
+
+	broker.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	late, _, _ := broker.Subscribe("", all)
+	_, ok = <-late.C
+	assert.False(t, ok)
+
+	broker.Unsubscribe(sub)
+	broker.Publish(event("1", ""))
+}
+
+func TestFromModel(t *testing.T) {
+	event := FromModel(&models.Event{
+		ID:      "1",
+		Type:    models.EventTodoUpdated,
+		Payload: []byte(`{"id":"1","type":"todo.updated","actor":"alice","data":{"id":"todo","project":"home"},"previousProject":"work"}`),
+	})
+
+	assert.Equal(t, "1", event.ID)
+	assert.Equal(t, models.EventTodoUpdated, event.Type)
+	assert.Equal(t, "alice", event.Actor)
+	assert.Equal(t, "home", event.Project)
+	assert.Equal(t, "work", event.PreviousProject)
+}
@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+	"todo-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Channel — канал NOTIFY, через который реплики обмениваются событиями.
+const Channel = "todo_events"
+
+// maxNotifyPayload — предел тела NOTIFY в Postgres (8000 байт) с запасом.
+const maxNotifyPayload = 7900
+
+// listenerPingInterval — как часто проверяется соединение LISTEN, пока
+// событий нет: оборванное соединение иначе обнаружится только при записи.
+const listenerPingInterval = 90 * time.Second
+
+// notification — тело NOTIFY. Событие целиком не помещается в NOTIFY, если
+// у задачи длинное описание; тогда передаётся ссылка на задачу, и получатель
+// читает её из базы сам.
+type notification struct {
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Ref     *reference      `json:"ref,omitempty"`
+}
+
+type reference struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Actor           string    `json:"actor,omitempty"`
+	OccurredAt      time.Time `json:"occurredAt"`
+	TodoID          string    `json:"todoId"`
+	PreviousProject string    `json:"previousProject,omitempty"`
+}
+
+// PostgresPublisher рассылает события брокерам всех реплик через NOTIFY,
+// включая брокер своей реплики.
+type PostgresPublisher struct {
+	db *sql.DB
+}
+
+func NewPostgresPublisher(db *sql.DB) *PostgresPublisher {
+	return &PostgresPublisher{db: db}
+}
+
+// Publish вызывается после фиксации изменения и рассылает все события одним
+// запросом, поэтому ошибка только пишется в лог: изменение уже сохранено, а
+// подписчики, пропустившие событие, получат его при следующей загрузке задач.
+// Если процесс упадёт между фиксацией и этим запросом, другие реплики
+// событий не увидят и узнать о пропуске не смогут.
+func (p *PostgresPublisher) Publish(ctx context.Context, events ...*models.Event) {
+	payloads := make([]string, 0, len(events))
+	for _, event := range events {
+		payload, err := encodeNotification(event)
+		if err != nil {
+			slog.WarnContext(ctx, "не удалось опубликовать событие в поток изменений", "event_id", event.ID, "error", err)
+			continue
+		}
+		payloads = append(payloads, payload)
+	}
+	if len(payloads) == 0 {
+		return
+	}
+
+	query := "SELECT pg_notify($1, payload) FROM unnest($2::text[]) WITH ORDINALITY AS n(payload, position) ORDER BY position"
+	if _, err := p.db.ExecContext(context.WithoutCancel(ctx), query, Channel, pq.Array(payloads)); err != nil {
+		slog.WarnContext(ctx, "не удалось опубликовать события в поток изменений", "count", len(payloads), "error", err)
+	}
+}
+
+func encodeNotification(event *models.Event) (string, error) {
+	data, err := json.Marshal(notification{Payload: event.Payload})
+	if err != nil || len(data) <= maxNotifyPayload {
+		return string(data), err
+	}
+
+	var payload models.EventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return "", err
+	}
+
+	ref := &reference{
+		ID:              payload.ID,
+		Type:            payload.Type,
+		Actor:           payload.Actor,
+		OccurredAt:      payload.OccurredAt,
+		PreviousProject: payload.PreviousProject,
+	}
+	if payload.Data != nil {
+		ref.TodoID = payload.Data.ID
+	}
+
+	data, err = json.Marshal(notification{Ref: ref})
+	return string(data), err
+}
+
+// Listener получает события других реплик через LISTEN и публикует их в
+// брокер. fetch читает задачу, если в NOTIFY пришла только ссылка на неё.
+type Listener struct {
+	dsn    string
+	broker *Broker
+	fetch  func(ctx context.Context, id string) (*models.Todo, error)
+}
+
+func NewListener(dsn string, broker *Broker, fetch func(ctx context.Context, id string) (*models.Todo, error)) *Listener {
+	return &Listener{dsn: dsn, broker: broker, fetch: fetch}
+}
+
+// Run слушает канал, пока не отменён ctx. После переподключения к базе
+// брокер сбрасывается: события, отправленные без соединения, потеряны.
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("соединение потока изменений с базой прервано", "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil {
+				// pq присылает nil после переподключения.
+				l.broker.Reset()
+				continue
+			}
+			l.handle(ctx, n.Extra)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
+
+func (l *Listener) handle(ctx context.Context, payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		slog.Warn("некорректное событие в потоке изменений", "error", err)
+		return
+	}
+
+	if n.Ref != nil {
+		data, err := l.resolve(ctx, n.Ref)
+		if err != nil {
+			slog.Warn("не удалось прочитать задачу события", "event_id", n.Ref.ID, "error", err)
+			return
+		}
+		n.Payload = data
+	}
+
+	event, err := parseEvent(n.Payload)
+	if err != nil || event.ID == "" {
+		slog.Warn("некорректное событие в потоке изменений", "error", err)
+		return
+	}
+	l.broker.Publish(event)
+}
+
+// resolve собирает тело события по ссылке. Удалённая задача уже не читается,
+// поэтому от неё остаётся только айди.
+func (l *Listener) resolve(ctx context.Context, ref *reference) ([]byte, error) {
+	todo := &models.Todo{ID: ref.TodoID}
+	if ref.Type != models.EventTodoDeleted {
+		found, err := l.fetch(ctx, ref.TodoID)
+		if err != nil {
+			return nil, err
+		}
+		todo = found
+	}
+
+	return json.Marshal(models.EventPayload{
+		ID:              ref.ID,
+		Type:            ref.Type,
+		Actor:           ref.Actor,
+		OccurredAt:      ref.OccurredAt,
+		Data:            todo,
+		PreviousProject: ref.PreviousProject,
+	})
+}
@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func payloadEvent(t *testing.T, eventType, description string) *models.Event {
+	t.Helper()
+
+	payload, err := json.Marshal(models.EventPayload{
+		ID:         "event-1",
+		Type:       eventType,
+		Actor:      "alice",
+		OccurredAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:       &models.Todo{ID: "todo-1", TaskName: "Задача", Description: &description},
+	})
+	require.NoError(t, err)
+
+	return &models.Event{ID: "event-1", Type: eventType, Payload: payload}
+}
+
+func TestEncodeNotification(t *testing.T) {
+	event := payloadEvent(t, models.EventTodoCreated, "коротко")
+
+	encoded, err := encodeNotification(event)
+	require.NoError(t, err)
+
+	var n notification
+	require.NoError(t, json.Unmarshal([]byte(encoded), &n))
+	assert.Nil(t, n.Ref)
+	assert.JSONEq(t, string(event.Payload), string(n.Payload))
+}
+
+func TestEncodeNotification_Reference(t *testing.T) {
+	event := payloadEvent(t, models.EventTodoUpdated, strings.Repeat("д", maxNotifyPayload))
+
+	encoded, err := encodeNotification(event)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(encoded), maxNotifyPayload)
+
+	var n notification
+	require.NoError(t, json.Unmarshal([]byte(encoded), &n))
+	assert.Empty(t, n.Payload)
+	require.NotNil(t, n.Ref)
+	assert.Equal(t, "event-1", n.Ref.ID)
+	assert.Equal(t, models.EventTodoUpdated, n.Ref.Type)
+	assert.Equal(t, "alice", n.Ref.Actor)
+	assert.Equal(t, "todo-1", n.Ref.TodoID)
+}
+
+func TestListener_Handle(t *testing.T) {
+	todo := &models.Todo{ID: "todo-1", TaskName: "Из базы"}
+	fetched := 0
+	fetch := func(ctx context.Context, id string) (*models.Todo, error) {
+		fetched++
+		if id != todo.ID {
+			return nil, repository.ErrInvalidID
+		}
+		return todo, nil
+	}
+
+	encode := func(event *models.Event) string {
+		encoded, err := encodeNotification(event)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	t.Run("тело целиком", func(t *testing.T) {
+		broker := NewBroker(10)
+		sub, _, _ := broker.Subscribe("", all)
+		listener := NewListener("", broker, fetch)
+
+		event := payloadEvent(t, models.EventTodoCreated, "")
+		listener.handle(context.Background(), encode(event))
+
+		got := <-sub.C
+		assert.Equal(t, "event-1", got.ID)
+		assert.Equal(t, "alice", got.Actor)
+		assert.JSONEq(t, string(event.Payload), string(got.Data))
+		assert.Zero(t, fetched)
+	})
+
+	t.Run("ссылка", func(t *testing.T) {
+		broker := NewBroker(10)
+		sub, _, _ := broker.Subscribe("", all)
+		listener := NewListener("", broker, fetch)
+
+		listener.handle(context.Background(), encode(payloadEvent(t, models.EventTodoUpdated, strings.Repeat("д", maxNotifyPayload))))
+
+		got := <-sub.C
+		assert.Equal(t, models.EventTodoUpdated, got.Type)
+		var payload models.EventPayload
+		require.NoError(t, json.Unmarshal(got.Data, &payload))
+		assert.Equal(t, "Из базы", payload.Data.TaskName)
+		assert.Equal(t, 1, fetched)
+	})
+
+	t.Run("ссылка на удалённую задачу", func(t *testing.T) {
+		broker := NewBroker(10)
+		sub, _, _ := broker.Subscribe("", all)
+		listener := NewListener("", broker, func(ctx context.Context, id string) (*models.Todo, error) {
+			return nil, errors.New("задача не должна читаться")
+		})
+
+		listener.handle(context.Background(), encode(payloadEvent(t, models.EventTodoDeleted, strings.Repeat("д", maxNotifyPayload))))
+
+		var payload models.EventPayload
+		require.NoError(t, json.Unmarshal((<-sub.C).Data, &payload))
+		assert.Equal(t, "todo-1", payload.Data.ID)
+		assert.Empty(t, payload.Data.TaskName)
+	})
+
+	t.Run("некорректное тело", func(t *testing.T) {
+		broker := NewBroker(10)
+		sub, _, _ := broker.Subscribe("", all)
+		listener := NewListener("", broker, fetch)
+
+		listener.handle(context.Background(), "не json")
+		listener.handle(context.Background(), `{"payload":{"type":"todo.created"}}`)
+
+		assert.Empty(t, sub.C)
+	})
+}
@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"context"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+)
+
+// Publisher доставляет события брокерам. Broker публикует только в своём
+// процессе, PostgresPublisher — брокерам всех реплик.
+type Publisher interface {
+	Publish(ctx context.Context, events ...*models.Event)
+}
+
+type localPublisher struct {
+	broker *Broker
+}
+
+// Local возвращает Publisher, который публикует события в broker напрямую.
+func Local(broker *Broker) Publisher {
+	return &localPublisher{broker: broker}
+}
+
+func (p *localPublisher) Publish(ctx context.Context, events ...*models.Event) {
+	stream := make([]Event, len(events))
+	for i, event := range events {
+		stream[i] = FromModel(event)
+	}
+	p.broker.Publish(stream...)
+}
+
+// Repository оборачивает репозиторий так, что события, записанные через
+// AddEvents, публикуются в поток. События транзакции публикуются только
+// после её фиксации, чтобы подписчики не увидели откаченных изменений.
+func Repository(repo repository.TodoRepository, publisher Publisher) repository.TodoRepository {
+	return &publishingRepository{TodoRepository: repo, publisher: publisher}
+}
+
+type publishingRepository struct {
+	repository.TodoRepository
+	publisher Publisher
+	// pending собирает события транзакции; nil вне транзакции.
+	pending *[]*models.Event
+}
+
+func (r *publishingRepository) AddEvents(ctx context.Context, events ...*models.Event) error {
+	if err := r.TodoRepository.AddEvents(ctx, events...); err != nil {
+		return err
+	}
+
+	if r.pending != nil {
+		*r.pending = append(*r.pending, events...)
+	} else {
+		r.publisher.Publish(ctx, events...)
+	}
+
+	return nil
+}
+
+func (r *publishingRepository) WithinTx(ctx context.Context, fn func(repo repository.TodoRepository) error) error {
+	if r.pending != nil {
+		return r.TodoRepository.WithinTx(ctx, func(tx repository.TodoRepository) error {
+			return fn(&publishingRepository{TodoRepository: tx, publisher: r.publisher, pending: r.pending})
+		})
+	}
+
+	var pending []*models.Event
+
+	err := r.TodoRepository.WithinTx(ctx, func(tx repository.TodoRepository) error {
+		return fn(&publishingRepository{TodoRepository: tx, publisher: r.publisher, pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(pending) > 0 {
+		r.publisher.Publish(ctx, pending...)
+	}
+	return nil
+}
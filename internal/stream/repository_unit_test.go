@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"todo-api/internal/models"
+	"todo-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	events []*models.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, events ...*models.Event) {
+	p.events = append(p.events, events...)
+}
+
+func modelEvent(id string) *models.Event {
+	return &models.Event{ID: id, Type: models.EventTodoCreated, Payload: []byte(`{"id":"` + id + `","actor":"alice"}`)}
+}
+
+func TestRepository_PublishesAfterCommit(t *testing.T) {
+	publisher := &recordingPublisher{}
+	repo := Repository(repository.Constructor(), publisher)
+
+	err := repo.WithinTx(context.Background(), func(tx repository.TodoRepository) error {
+		require.NoError(t, tx.AddEvents(context.Background(), modelEvent("1")))
+		require.NoError(t, tx.WithinTx(context.Background(), func(nested repository.TodoRepository) error {
+			return nested.AddEvents(context.Background(), modelEvent("2"))
+		}))
+		assert.Empty(t, publisher.events, "до фиксации события не публикуются")
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, publisher.events, 2)
+	assert.Equal(t, "1", publisher.events[0].ID)
+	assert.Equal(t, "2", publisher.events[1].ID)
+}
+
+func TestRepository_RollbackDropsEvents(t *testing.T) {
+	publisher := &recordingPublisher{}
+	repo := Repository(repository.Constructor(), publisher)
+	failure := errors.New("откат")
+
+	err := repo.WithinTx(context.Background(), func(tx repository.TodoRepository) error {
+		require.NoError(t, tx.AddEvents(context.Background(), modelEvent("1")))
+		return failure
+	})
+
+	assert.ErrorIs(t, err, failure)
+	assert.Empty(t, publisher.events)
+}
+
+func TestRepository_OutsideTx(t *testing.T) {
+	publisher := &recordingPublisher{}
+	repo := Repository(repository.Constructor(), publisher)
+
+	require.NoError(t, repo.AddEvents(context.Background(), modelEvent("1")))
+	assert.Len(t, publisher.events, 1)
+
+	assert.ErrorIs(t, repo.AddEvents(context.Background(), &models.Event{}), repository.ErrInvalidEvent)
+	assert.Len(t, publisher.events, 1, "невалидное событие не публикуется")
+}
+
+func TestLocal(t *testing.T) {
+	broker := NewBroker(10)
+	sub, _, _ := broker.Subscribe("", all)
+
+	Local(broker).Publish(context.Background(), modelEvent("1"))
+
+	got := <-sub.C
+	assert.Equal(t, "1", got.ID)
+	assert.Equal(t, models.EventTodoCreated, got.Type)
+	assert.Equal(t, "alice", got.Actor)
+}
@@ -0,0 +1,38 @@
+package integration_tests
+
+import (
+	"testing"
+	"todo-api/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStore_SharedLimit(t *testing.T) {
+	db := SetUpTest(t)
+	_, err := db.Exec("TRUNCATE TABLE rate_limits")
+	require.NoError(t, err)
+
+	// Два лимитера с общей таблицей ведут себя как две реплики сервиса.
+	limit := ratelimit.Limit{Rate: 0.001, Burst: 3}
+	first, err := ratelimit.NewLimiter(ratelimit.NewPostgresStore(db), limit)
+	require.NoError(t, err)
+	second, err := ratelimit.NewLimiter(ratelimit.NewPostgresStore(db), limit)
+	require.NoError(t, err)
+
+	for i, limiter := range []*ratelimit.Limiter{first, second, first} {
+		result, err := limiter.Allow(t.Context(), "ip:10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := second.Allow(t.Context(), "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+
+	result, err = second.Allow(t.Context(), "ip:10.0.0.2")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,20 @@
+package integration_tests
+
+import (
+	"testing"
+	"todo-api/internal/repository"
+	"todo-api/internal/repository/repotest"
+)
+
+func TestPostgresRepository_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.TodoRepository {
+		return repository.NewPostgresRepository(SetUpTest(t), 0)
+	})
+}
+
+func TestPostgresSyncRepository_Contract(t *testing.T) {
+	repotest.RunSync(t, func(t *testing.T) (repository.TodoRepository, repository.SyncRepository) {
+		db := SetUpTest(t)
+		return repository.NewPostgresRepository(db, 0), repository.NewPostgresSyncRepository(db, 0)
+	})
+}
@@ -42,7 +42,7 @@ func TestMain(m *testing.M) {
 func SetUpTest(t *testing.T) *sql.DB {
 	t.Helper()
 
-	_, err := testDB.Exec("TRUNCATE TABLE todos RESTART IDENTITY CASCADE")
+	_, err := testDB.Exec("TRUNCATE TABLE todos, todo_tombstones RESTART IDENTITY CASCADE")
 	if err != nil {
 		t.Fatalf("Failed to truncate table: %v", err)
 	}
@@ -51,7 +51,7 @@ func SetUpTest(t *testing.T) *sql.DB {
 }
 
 func CleanUpDatabase() {
-	testDB.Exec("DROP TABLE IF EXISTS todos CASCADE")
+	testDB.Exec("DROP TABLE IF EXISTS todos, rate_limits, schema_migrations CASCADE")
 }
 
 func CreateTestTodo(db *sql.DB, taskName string, description *string) *models.Todo {
@@ -20,7 +20,7 @@ func setUpRouter(db *sql.DB) *gin.Engine {
 
 	router := gin.New()
 
-	todoRepo := repository.NewPostgresRepository(db)
+	todoRepo := repository.NewPostgresRepository(db, 0)
 	todoService := services.NewTodoService(todoRepo)
 	todoHandler := handlers.NewTodoHandler(todoService)
 
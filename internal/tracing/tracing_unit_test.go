@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(t.Context(), config.TracingConfig{Exporter: "zipkin"})
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+}
+
+func TestSetup_PropagatesTraceparent(t *testing.T) {
+	shutdown, err := Setup(t.Context(), config.TracingConfig{Exporter: "none"})
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdown(context.Background()) })
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx := otel.GetTextMapPropagator().Extract(t.Context(), propagation.HeaderCarrier(header))
+	spanContext := trace.SpanContextFromContext(ctx)
+
+	assert.True(t, spanContext.IsRemote())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(t.Context(), config.TracingConfig{
+		Exporter:    "file",
+		FilePath:    path,
+		SampleRatio: 1,
+		ServiceName: "todo-api-test",
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(t.Context(), "operation")
+	span.End()
+
+	require.NoError(t, shutdown(t.Context()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"operation"`)
+	assert.Contains(t, string(data), "todo-api-test")
+}
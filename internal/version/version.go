@@ -0,0 +1,28 @@
+// Package version хранит сведения о сборке. Значения подставляются при сборке:
+//
+//	go build -ldflags "-X todo-api/internal/version.Version=1.2.0 -X todo-api/internal/version.Commit=$(git rev-parse --short HEAD)"
+package version
+
+import "runtime"
+
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,70 @@
+// Package webhook рассылает события об изменениях задач на адреса подписчиков.
+// Каждый запрос подписан секретом вебхука, чтобы получатель мог проверить,
+// что запрос отправлен сервисом и не изменён по дороге.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса к вебхуку. Подпись — "sha256=" и HMAC-SHA256 в hex от
+// строки "<timestamp>.<тело>", где timestamp — значение X-Webhook-Timestamp
+// в секундах Unix. Метка времени входит в подпись, поэтому перехваченный
+// запрос нельзя повторить позже допустимого окна.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+var ErrInvalidSignature = errors.New("неверная подпись вебхука")
+var ErrExpiredSignature = errors.New("подпись вебхука устарела")
+
+// Sign возвращает значение заголовка X-Webhook-Signature.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verify проверяет подпись запроса к вебхуку и то, что метка времени отстоит
+// от now не больше чем на tolerance. Нулевой tolerance отключает проверку
+// времени.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	signature, ok := strings.CutPrefix(header.Get(HeaderSignature), signaturePrefix)
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+
+	if tolerance > 0 && now.Sub(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
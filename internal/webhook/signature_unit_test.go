@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+
+	// echo -n '1700000000.{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54", Sign("secret", timestamp, body))
+}
+
+func TestVerify(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+
+	header := func(signature string) http.Header {
+		h := http.Header{}
+		h.Set(HeaderTimestamp, "1700000000")
+		h.Set(HeaderSignature, signature)
+		return h
+	}
+	valid := header(Sign("secret", timestamp, body))
+
+	assert.NoError(t, Verify("secret", valid, body, 5*time.Minute, timestamp.Add(time.Minute)))
+	assert.NoError(t, Verify("secret", valid, body, 0, timestamp.Add(24*time.Hour)))
+
+	assert.ErrorIs(t, Verify("secret", valid, body, 5*time.Minute, timestamp.Add(10*time.Minute)), ErrExpiredSignature)
+	assert.ErrorIs(t, Verify("other", valid, body, 0, timestamp), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", valid, []byte(`{"id":"2"}`), 0, timestamp), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header("md5=00"), body, 0, timestamp), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header("sha256=zz"), body, 0, timestamp), ErrInvalidSignature)
+
+	shifted := valid.Clone()
+	shifted.Set(HeaderTimestamp, "1700000001")
+	assert.ErrorIs(t, Verify("secret", shifted, body, 0, timestamp), ErrInvalidSignature, "метка времени входит в подпись")
+}
@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/models"
+	"todo-api/internal/netguard"
+	"todo-api/internal/repository"
+	"todo-api/internal/version"
+)
+
+var ErrInvalidConfig = errors.New("некорректные настройки рассылки вебхуков")
+
+const (
+	// dispatchBatch — сколько событий из outbox разбирается за одну транзакцию.
+	dispatchBatch = 100
+	// leaseMargin добавляется к таймауту запроса: пока не истёк lease, другие
+	// реплики не возьмут доставку, даже если запрос ещё не завершился.
+	leaseMargin = 30 * time.Second
+	// maxResponseBody — сколько тела ответа читается, чтобы соединение можно
+	// было переиспользовать.
+	maxResponseBody = 64 << 10
+)
+
+// Worker разбирает outbox в доставки и отправляет их. Неудачная попытка
+// повторяется с экспоненциальной задержкой, после MaxAttempts доставка
+// помечается неудавшейся и остаётся в журнале для ручной повторной отправки.
+// Несколько реплик могут работать с одной базой: доставки разбираются
+// с арендой, и одну доставку отправляет только одна реплика.
+type Worker struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	cfg    config.WebhookConfig
+	now    func() time.Time
+}
+
+func NewWorker(repo repository.WebhookRepository, cfg config.WebhookConfig) (*Worker, error) {
+	if cfg.PollInterval <= 0 || cfg.Timeout <= 0 || cfg.MaxAttempts < 1 || cfg.Concurrency < 1 ||
+		cfg.BackoffMin <= 0 || cfg.BackoffMax < cfg.BackoffMin {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidConfig, cfg)
+	}
+
+	return &Worker{
+		repo:   repo,
+		client: newClient(cfg),
+		cfg:    cfg,
+		now:    time.Now,
+	}, nil
+}
+
+// newClient собирает клиент, который подключается только к внешним адресам
+// и не следует перенаправлениям: иначе вебхук с внешним адресом мог бы
+// перенаправить запрос во внутреннюю сеть. Прокси из окружения не
+// используется, чтобы адрес проверялся у самого получателя.
+func newClient(cfg config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = netguard.Control
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run раз в PollInterval разбирает очередь, пока не отменён ctx.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.Tick(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("не удалось разобрать очередь вебхуков", "error", err)
+			}
+		}
+	}
+}
+
+// Tick переносит новые события в доставки и отправляет все доставки, срок
+// которых наступил. Возвращает число сделанных попыток.
+func (w *Worker) Tick(ctx context.Context) (int, error) {
+	for {
+		n, err := w.repo.DispatchEvents(ctx, dispatchBatch, w.now())
+		if err != nil {
+			return 0, err
+		}
+		if n < dispatchBatch {
+			break
+		}
+	}
+
+	attempts := 0
+	lease := w.cfg.Timeout + leaseMargin
+
+	for {
+		claimed, err := w.repo.ClaimDeliveries(ctx, w.now(), lease, w.cfg.Concurrency)
+		if err != nil {
+			return attempts, err
+		}
+		if len(claimed) == 0 {
+			return attempts, nil
+		}
+
+		if err := w.deliverAll(ctx, claimed); err != nil {
+			return attempts, err
+		}
+		attempts += len(claimed)
+
+		if len(claimed) < w.cfg.Concurrency {
+			return attempts, nil
+		}
+	}
+}
+
+func (w *Worker) deliverAll(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	hooks := make(map[string]*models.Webhook)
+	for _, delivery := range deliveries {
+		if _, ok := hooks[delivery.WebhookID]; ok {
+			continue
+		}
+
+		hook, err := w.repo.GetWebhook(ctx, delivery.WebhookID)
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			// Вебхук удалён вместе с доставками после того, как их забрали.
+			hook = nil
+		} else if err != nil {
+			return err
+		}
+		hooks[delivery.WebhookID] = hook
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(deliveries))
+
+	for i, delivery := range deliveries {
+		hook := hooks[delivery.WebhookID]
+		if hook == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.attempt(ctx, hook, delivery)
+
+			err := w.repo.SaveDelivery(ctx, delivery)
+			if err != nil && !errors.Is(err, repository.ErrDeliveryNotFound) {
+				errs[i] = err
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// attempt отправляет доставку и записывает в неё результат попытки.
+func (w *Worker) attempt(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) {
+	now := w.now()
+	status, err := w.send(ctx, hook, delivery, now)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+
+	if err == nil {
+		delivery.Status = models.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.Error = ""
+		return
+	}
+
+	delivery.Error = err.Error()
+
+	if delivery.Attempts >= w.cfg.MaxAttempts {
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		slog.Warn("вебхук не доставлен", "webhook_id", hook.ID, "delivery_id", delivery.ID,
+			"attempts", delivery.Attempts, "error", err)
+		return
+	}
+
+	next := now.Add(w.backoff(delivery.Attempts))
+	delivery.Status = models.DeliveryPending
+	delivery.NextAttemptAt = &next
+}
+
+// send возвращает код ответа и ошибку, если вебхук не ответил кодом 2xx.
+func (w *Worker) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-webhook/"+version.Version)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, now, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("вебхук ответил %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает задержку перед попыткой после attempts неудачных:
+// BackoffMin·2^(attempts-1), но не больше BackoffMax. Задержка случайно
+// уменьшается до половины, чтобы повторы к одному адресу не шли пачкой.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.cfg.BackoffMax
+	if shift := attempts - 1; shift < 32 {
+		if d := w.cfg.BackoffMin << shift; d > 0 && d < delay {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	return delay - half + rand.N(half+1)
+}
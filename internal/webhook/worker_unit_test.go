@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"todo-api/internal/config"
+	"todo-api/internal/models"
+	"todo-api/internal/netguard"
+	"todo-api/internal/repository"
+	"todo-api/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver — тестовый получатель вебхуков, отвечающий кодами из statuses по
+// очереди, а после них — 200.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, received{header: req.Header.Clone(), body: body})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+type fixture struct {
+	worker   *Worker
+	todos    services.TodoService
+	hooks    services.WebhookService
+	receiver *receiver
+	hook     *models.Webhook
+	now      time.Time
+}
+
+func newFixture(t *testing.T, maxAttempts int, statuses ...int) *fixture {
+	t.Helper()
+
+	repo := repository.Constructor()
+	webhooks := repository.NewMemoryWebhookRepository(repo)
+
+	f := &fixture{
+		todos:    services.NewTodoService(repo),
+		hooks:    services.NewWebhookService(webhooks),
+		receiver: &receiver{statuses: statuses},
+		now:      time.Now(),
+	}
+
+	server := httptest.NewServer(f.receiver)
+	t.Cleanup(server.Close)
+
+	worker, err := NewWorker(webhooks, config.WebhookConfig{
+		PollInterval: time.Second,
+		Timeout:      time.Second,
+		MaxAttempts:  maxAttempts,
+		BackoffMin:   time.Second,
+		BackoffMax:   time.Minute,
+		Concurrency:  2,
+
+		AllowPrivateNetworks: true,
+	})
+	require.NoError(t, err)
+	worker.now = func() time.Time { return f.now }
+	f.worker = worker
+
+	f.hook, err = f.hooks.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: localURL(server) + "/hooks", Secret: "secret"})
+	require.NoError(t, err)
+
+	return f
+}
+
+// localURL заменяет адрес тестового сервера именем: адреса во внутренней сети
+// не принимаются при создании вебхука.
+func localURL(server *httptest.Server) string {
+	return strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+func (f *fixture) tick(t *testing.T) int {
+	t.Helper()
+	n, err := f.worker.Tick(t.Context())
+	require.NoError(t, err)
+	return n
+}
+
+func (f *fixture) delivery(t *testing.T) *models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := f.hooks.ListDeliveries(t.Context(), f.hook.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	return deliveries[0]
+}
+
+func TestWorker_Deliver(t *testing.T) {
+	f := newFixture(t, 3)
+
+	todo, err := f.todos.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "Купить хлеб"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, f.tick(t))
+	assert.Zero(t, f.tick(t), "доставленное событие не отправляется повторно")
+
+	require.Len(t, f.receiver.requests, 1)
+	request := f.receiver.requests[0]
+	assert.Equal(t, models.EventTodoCreated, request.header.Get(HeaderEvent))
+	assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+	assert.NoError(t, Verify("secret", request.header, request.body, time.Minute, f.now))
+
+	delivery := f.delivery(t)
+	assert.Equal(t, delivery.ID, request.header.Get(HeaderDelivery))
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, 200, delivery.ResponseStatus)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	assert.JSONEq(t, string(delivery.Payload), string(request.body))
+	assert.Contains(t, string(request.body), todo.ID)
+}
+
+func TestWorker_Retry(t *testing.T) {
+	f := newFixture(t, 5, 500, 503)
+
+	_, err := f.todos.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, f.tick(t))
+	delivery := f.delivery(t)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, 500, delivery.ResponseStatus)
+	assert.Contains(t, delivery.Error, "500")
+	require.NotNil(t, delivery.NextAttemptAt)
+	delay := delivery.NextAttemptAt.Sub(f.now)
+	assert.True(t, delay >= 500*time.Millisecond && delay <= time.Second, delay)
+
+	assert.Zero(t, f.tick(t), "до срока повтор не отправляется")
+
+	f.now = f.now.Add(time.Second)
+	assert.Equal(t, 1, f.tick(t))
+	delivery = f.delivery(t)
+	assert.Equal(t, 2, delivery.Attempts)
+	delay = delivery.NextAttemptAt.Sub(f.now)
+	assert.True(t, delay >= time.Second && delay <= 2*time.Second, "задержка растёт вдвое: %s", delay)
+
+	f.now = f.now.Add(2 * time.Second)
+	assert.Equal(t, 1, f.tick(t))
+	delivery = f.delivery(t)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Empty(t, delivery.Error)
+	assert.Len(t, f.receiver.requests, 3)
+}
+
+func TestWorker_Exhausted(t *testing.T) {
+	f := newFixture(t, 2, 500, 500)
+
+	_, err := f.todos.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	f.tick(t)
+	f.now = f.now.Add(time.Minute)
+	f.tick(t)
+
+	delivery := f.delivery(t)
+	assert.Equal(t, models.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	f.now = f.now.Add(time.Hour)
+	assert.Zero(t, f.tick(t), "после последней попытки доставка не повторяется")
+
+	_, err = f.hooks.Redeliver(t.Context(), f.hook.ID, delivery.ID)
+	require.NoError(t, err)
+	f.now = time.Now()
+	assert.Equal(t, 1, f.tick(t))
+
+	delivery = f.delivery(t)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Len(t, f.receiver.requests, 3)
+	assert.Equal(t, f.receiver.requests[0].body, f.receiver.requests[2].body, "повторная доставка отправляет то же событие")
+}
+
+func TestWorker_Unreachable(t *testing.T) {
+	f := newFixture(t, 3)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	dead, err := f.hooks.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: localURL(server)})
+	require.NoError(t, err)
+	_, err = f.todos.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, f.tick(t))
+	assert.Equal(t, models.DeliverySucceeded, f.delivery(t).Status, "недоступный адрес не мешает другим вебхукам")
+
+	deliveries, err := f.hooks.ListDeliveries(t.Context(), dead.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+	assert.Zero(t, deliveries[0].ResponseStatus)
+	assert.NotEmpty(t, deliveries[0].Error)
+}
+
+func TestWorker_Backoff(t *testing.T) {
+	worker := &Worker{cfg: config.WebhookConfig{BackoffMin: 10 * time.Second, BackoffMax: time.Hour}}
+
+	for attempts, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 5: 160 * time.Second, 20: time.Hour, 100: time.Hour} {
+		for range 20 {
+			delay := worker.backoff(attempts)
+			assert.True(t, delay >= want/2 && delay <= want, "попытка %d: %s", attempts, delay)
+		}
+	}
+}
+
+func TestNewWorker_InvalidConfig(t *testing.T) {
+	_, err := NewWorker(nil, config.WebhookConfig{PollInterval: time.Second, Timeout: time.Second, MaxAttempts: 1,
+		Concurrency: 1, BackoffMin: time.Minute, BackoffMax: time.Second})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestWorker_PrivateNetwork(t *testing.T) {
+	f := newFixture(t, 3)
+	f.worker.client = newClient(config.WebhookConfig{Timeout: time.Second})
+
+	_, err := f.todos.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, f.tick(t))
+	delivery := f.delivery(t)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Contains(t, delivery.Error, netguard.ErrForbiddenAddress.Error(), "имя localhost проверяется после разрешения")
+	assert.Empty(t, f.receiver.requests)
+}
+
+func TestWorker_NoRedirects(t *testing.T) {
+	f := newFixture(t, 3)
+
+	target := httptest.NewServer(f.receiver)
+	t.Cleanup(target.Close)
+	redirect := httptest.NewServer(http.RedirectHandler(localURL(target), http.StatusFound))
+	t.Cleanup(redirect.Close)
+
+	// Вебхук фикстуры заменяется адресом, который перенаправляет на получателя.
+	require.NoError(t, f.hooks.DeleteWebhook(t.Context(), f.hook.ID))
+	hook, err := f.hooks.CreateWebhook(t.Context(), &models.CreateWebhookRequest{URL: localURL(redirect), Secret: "secret"})
+	require.NoError(t, err)
+	f.hook = hook
+
+	_, err = f.todos.CreateTodo(t.Context(), &models.CreateTodoRequest{TaskName: "test"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, f.tick(t))
+	delivery := f.delivery(t)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusFound, delivery.ResponseStatus)
+	assert.Empty(t, f.receiver.requests, "перенаправление не выполняется")
+}
@@ -1,60 +1,353 @@
 package main
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 	_ "todo-api/docs"
+	"todo-api/internal/calendar"
 	"todo-api/internal/config"
-	"todo-api/internal/database"
+	"todo-api/internal/graphqlapi"
+	"todo-api/internal/grpcapi"
 	"todo-api/internal/handlers"
+	"todo-api/internal/health"
+	"todo-api/internal/identity"
+	"todo-api/internal/logging"
+	"todo-api/internal/metrics"
+	"todo-api/internal/ratelimit"
+	"todo-api/internal/realtime"
 	"todo-api/internal/repository"
+	"todo-api/internal/server"
 	"todo-api/internal/services"
-	"todo-api/migrations"
+	"todo-api/internal/storage"
+	"todo-api/internal/stream"
+	"todo-api/internal/tracing"
+	"todo-api/internal/version"
+	"todo-api/internal/webhook"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// tracingShutdownTimeout ограничивает выгрузку оставшихся span'ов при остановке.
+const tracingShutdownTimeout = 5 * time.Second
+
 // @title TODO API
 // @version 1.0
 // @description API для управления задачами
 // @host localhost:8080
 // @BasePath /
 func main() {
+	if err := run(); err != nil {
+		slog.Error("сервер завершился с ошибкой", "error", err)
+		os.Exit(1)
+	}
+}
 
+func run() error {
 	cfg := config.Load()
 
-	db, err := database.Connect(cfg.Database)
+	logger, err := logging.New(cfg.Log, os.Stdout)
 	if err != nil {
-		log.Fatal("ошибка при подключении к базе данных: ", err)
+		return err
 	}
+	slog.SetDefault(logger)
 
-	defer db.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	err = migrations.RunMigrations(db)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatal("ошибка миграции: ", err)
+		return err
 	}
 
-	repo := repository.NewPostgresRepository(db)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
 
-	service := services.NewTodoService(repo)
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("ошибка при выгрузке трейсов", "error", err)
+		}
+	}()
+
+	store, err := storage.Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Хранилище закрывается после остановки HTTP-сервера: сначала дожидаемся
+	// текущих запросов, затем останавливаем фоновые задачи и пул соединений.
+	defer func() {
+		if err := store.Close(); err != nil {
+			slog.Error("ошибка при закрытии хранилища", "error", err)
+		}
+	}()
+
+	collector := metrics.New()
+	if store.DB != nil {
+		collector.RegisterDB(store.DB, cfg.Storage.Backend)
+	}
+	collector.RegisterTodoStats(store.Repo)
 
-	handlers := handlers.NewTodoHandler(service)
+	// Брокер закрывается при остановке, чтобы открытые потоки изменений
+	// завершились и не задерживали остановку HTTP-сервера.
+	broker := stream.NewBroker(cfg.Events.BufferSize)
+	go func() {
+		<-ctx.Done()
+		broker.Close()
+	}()
+
+	publisher := newPublisher(ctx, cfg, store, broker)
+	repo := stream.Repository(collector.InstrumentRepository(store.Repo), publisher)
+	service := services.Trace(services.NewTodoService(repo))
+
+	checker := health.NewChecker(cfg.Server.HealthCheckTimeout, store.Checks()...)
+	go func() {
+		<-ctx.Done()
+		checker.MarkShuttingDown()
+	}()
+
+	healthHandler := handlers.NewHealthHandler(checker)
+	todoHandler := handlers.NewTodoHandler(service)
+	eventsHandler := handlers.NewEventsHandler(broker, cfg.Events.Heartbeat)
+
+	hub := realtime.NewHub(service, broker, cfg.Realtime)
+	go hub.Run(ctx)
+	realtimeHandler := handlers.NewRealtimeHandler(hub)
+
+	graphqlServer := graphqlapi.New(service, broker, cfg.GraphQL, cfg.Realtime)
+	go func() {
+		<-ctx.Done()
+		graphqlServer.Close()
+	}()
+	graphqlHandler := handlers.NewGraphQLHandler(graphqlServer)
 
 	gin.SetMode(cfg.Server.Mode)
-	router := gin.Default()
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("некорректный SERVER_TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(logging.RequestIDMiddleware())
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithGinFilter(tracedRoute)))
+	router.Use(logging.AccessLogMiddleware(logger, "/metrics", "/healthz", "/readyz"))
+	router.Use(collector.Middleware())
+	router.Use(handlers.Recovery())
+	router.Use(identity.Middleware(cfg.Events.UserHeader))
+	router.NoRoute(handlers.NoRoute)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(collector.Handler()))
+
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+	router.GET("/version", healthHandler.Version)
+
+	// Один ограничитель на задачи, GraphQL, календарь, вебхуки и синхронизацию: лимит общий для клиента.
+	var limited []gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		rateLimit, err := newRateLimit(ctx, cfg, store)
+		if err != nil {
+			return err
+		}
+		limited = append(limited, rateLimit)
+	}
 
-	todosGroup := router.Group("/todos")
+	todosGroup := router.Group("/todos", limited...)
 	{
-		todosGroup.POST("", handlers.CreateTodo)
-		todosGroup.GET("", handlers.GetAllTask)
-		todosGroup.GET("/:id", handlers.GetById)
-		todosGroup.PATCH("/:id", handlers.Update)
-		todosGroup.DELETE("/:id", handlers.Delete)
+		todosGroup.POST("", todoHandler.CreateTodo)
+		todosGroup.GET("", todoHandler.GetAllTask)
+		todosGroup.GET("/search", todoHandler.Search)
+		todosGroup.GET("/events", eventsHandler.Stream)
+		todosGroup.GET("/ws", realtimeHandler.Connect)
+		todosGroup.GET("/export", todoHandler.Export)
+		todosGroup.POST("/import", todoHandler.Import)
+		todosGroup.POST("/quick", todoHandler.QuickAdd)
+		todosGroup.GET("/:id", todoHandler.GetById)
+		todosGroup.PATCH("/:id", todoHandler.Update)
+		todosGroup.DELETE("/:id", todoHandler.Delete)
+	}
+
+	graphqlGroup := router.Group("/graphql", limited...)
+	{
+		graphqlGroup.GET("", graphqlHandler.Get)
+		graphqlGroup.POST("", graphqlHandler.Post)
+	}
+
+	if cfg.Calendar.Secret != "" {
+		calendarHandler := handlers.NewCalendarHandler(service, calendar.NewTokens(cfg.Calendar.Secret),
+			cfg.Calendar.UserHeader, cfg.Calendar.BaseURL, cfg.Calendar.Name)
+
+		calendarGroup := router.Group("", limited...)
+		calendarGroup.GET("/calendar.ics", calendarHandler.Feed)
+		calendarGroup.GET("/calendar/subscription", calendarHandler.Subscription)
+
+		router.GET("/.well-known/caldav", calendarHandler.WellKnown)
+		router.Handle("PROPFIND", "/.well-known/caldav", calendarHandler.WellKnown)
+
+		caldavGroup := router.Group("/caldav", append(limited, calendarHandler.CalDAVAuth)...)
+		for _, method := range handlers.CalDAVMethods {
+			caldavGroup.Handle(method, "/*path", calendarHandler.CalDAV)
+		}
+	} else {
+		slog.Info("подписка на календарь отключена: не задан CALENDAR_SECRET")
+	}
+
+	if store.Webhooks != nil {
+		worker, err := webhook.NewWorker(store.Webhooks, cfg.Webhook)
+		if err != nil {
+			return err
+		}
+		go worker.Run(ctx)
+
+		webhookHandler := handlers.NewWebhookHandler(services.NewWebhookService(store.Webhooks))
+
+		webhooksGroup := router.Group("/webhooks", limited...)
+		{
+			webhooksGroup.POST("", webhookHandler.Create)
+			webhooksGroup.GET("", webhookHandler.List)
+			webhooksGroup.GET("/:id", webhookHandler.Get)
+			webhooksGroup.DELETE("/:id", webhookHandler.Delete)
+			webhooksGroup.GET("/:id/deliveries", webhookHandler.Deliveries)
+			webhooksGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+		}
+	} else {
+		slog.Info("вебхуки отключены: хранилище их не поддерживает", "backend", cfg.Storage.Backend)
+	}
+
+	if store.Sync != nil {
+		go purgeTombstones(ctx, store.Sync, cfg.Sync)
+
+		syncHandler := handlers.NewSyncHandler(services.NewSyncService(repo, store.Sync))
+
+		syncGroup := router.Group("/sync", limited...)
+		{
+			syncGroup.GET("", syncHandler.Pull)
+			syncGroup.POST("", syncHandler.Push)
+		}
+	} else {
+		slog.Info("синхронизация отключена: хранилище не ведёт журнал изменений", "backend", cfg.Storage.Backend)
 	}
 
-	router.Run(":" + cfg.Server.Port)
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть порт gRPC :%s: %w", cfg.GRPC.Port, err)
+	}
+	grpcServer := grpcapi.New(grpcapi.NewServer(service, broker), logger, cfg.Events.UserHeader)
+
+	// gRPC-сервер останавливается вместе с HTTP; run дожидается его, чтобы
+	// хранилище закрылось после последнего вызова.
+	grpcDone := make(chan struct{})
+	defer func() {
+		stop()
+		<-grpcDone
+	}()
+	go func() {
+		defer close(grpcDone)
+		if err := grpcapi.Serve(ctx, grpcServer, grpcListener, cfg.Server.ShutdownTimeout); err != nil {
+			slog.Error("gRPC-сервер завершился с ошибкой", "error", err)
+		}
+	}()
+
+	slog.Info("gRPC-сервер запущен", "addr", grpcListener.Addr().String())
+
+	srv := server.New(cfg.Server, router)
+
+	srv.ErrorLog = slog.NewLogLogger(logger.Handler(), slog.LevelError)
+
+	slog.Info("сервер запущен", "version", version.Version, "addr", srv.Addr)
+
+	if err := server.Run(ctx, srv, cfg.Server); err != nil {
+		return err
+	}
+
+	slog.Info("сервер остановлен")
+
+	return nil
+}
+
+// newRateLimit собирает ограничитель запросов. Общий счётчик хранится в той
+// же базе, что и задачи, поэтому RATE_LIMIT_STORE должен совпадать с бэкендом.
+func newRateLimit(ctx context.Context, cfg *config.Config, store *storage.Storage) (gin.HandlerFunc, error) {
+	var db *sql.DB
+	if strings.EqualFold(cfg.Storage.Backend, cfg.RateLimit.Store) {
+		db = store.DB
+	}
+
+	limiter, err := ratelimit.New(cfg.RateLimit, db)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ratelimit.KeyFunc(cfg.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	go limiter.PurgeLoop(ctx, cfg.RateLimit.PurgeInterval)
+
+	return handlers.RateLimit(limiter, key), nil
+}
+
+// purgeTombstones удаляет записи об удалённых задачах старше TombstoneTTL.
+func purgeTombstones(ctx context.Context, changes repository.SyncRepository, cfg config.SyncConfig) {
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := changes.PurgeTombstones(ctx, time.Now().Add(-cfg.TombstoneTTL))
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("не удалось очистить записи об удалённых задачах", "error", err)
+				continue
+			}
+			if purged > 0 {
+				slog.Debug("очищены записи об удалённых задачах", "count", purged)
+			}
+		}
+	}
+}
+
+// newPublisher выбирает, как события попадают в поток изменений. С Postgres
+// события рассылаются через NOTIFY, и каждая реплика получает их из LISTEN,
+// поэтому клиенты видят изменения, сделанные на любой реплике.
+func newPublisher(ctx context.Context, cfg *config.Config, store *storage.Storage, broker *stream.Broker) stream.Publisher {
+	if !strings.EqualFold(cfg.Storage.Backend, storage.BackendPostgres) {
+		return stream.Local(broker)
+	}
+
+	listener := stream.NewListener(cfg.Database.DSN(), broker, store.Repo.GetById)
+	go func() {
+		if err := listener.Run(ctx); err != nil {
+			slog.Error("поток изменений не получает события других реплик", "error", err)
+		}
+	}()
+
+	return stream.NewPostgresPublisher(store.DB)
+}
+
+// tracedRoute исключает из трейсов служебные маршруты, которые опрашиваются
+// постоянно и только засоряют хранилище трейсов, и потоки изменений, запрос
+// которых длится часами. Подписки GraphQL идут по тому же /graphql, что и
+// обычные запросы, поэтому узнаются по открытию WebSocket.
+func tracedRoute(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/metrics", "/healthz", "/readyz", "/todos/events", "/todos/ws":
+		return false
+	case "/graphql":
+		return !websocket.IsWebSocketUpgrade(c.Request)
+	}
+	return true
 }
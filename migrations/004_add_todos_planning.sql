@@ -0,0 +1,8 @@
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT ''
+        CHECK (priority IN ('', 'low', 'medium', 'high')),
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS project TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS todos_due_at_idx ON todos (due_at);
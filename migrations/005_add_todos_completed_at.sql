@@ -0,0 +1,2 @@
+-- Для задач, выполненных до миграции, время выполнения неизвестно и остаётся NULL.
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
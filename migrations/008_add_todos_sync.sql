@@ -0,0 +1,32 @@
+-- Номер последнего изменения задач на момент миграции и purged_seq —
+-- наибольший номер вычищенной записи об удалении. С миграции 009 номера
+-- выдаёт последовательность todo_change_seq вместе с айди транзакции, а seq
+-- больше не увеличивается.
+CREATE TABLE IF NOT EXISTS sync_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    seq BIGINT NOT NULL,
+    purged_seq BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS change_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS field_changes JSONB NOT NULL DEFAULT '{}';
+
+-- Существующие задачи получают номера в порядке создания.
+UPDATE todos SET change_seq = numbered.seq
+FROM (SELECT id, row_number() OVER (ORDER BY created_at, id) AS seq FROM todos) AS numbered
+WHERE todos.id = numbered.id;
+
+INSERT INTO sync_state (id, seq)
+SELECT 1, COALESCE(MAX(change_seq), 0) FROM todos
+ON CONFLICT (id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS todos_change_seq_idx ON todos (change_seq);
+
+CREATE TABLE IF NOT EXISTS todo_tombstones (
+    id TEXT PRIMARY KEY,
+    change_seq BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS todo_tombstones_change_seq_idx ON todo_tombstones (change_seq);
+CREATE INDEX IF NOT EXISTS todo_tombstones_deleted_at_idx ON todo_tombstones (deleted_at);
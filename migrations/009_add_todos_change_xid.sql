@@ -0,0 +1,19 @@
+-- Номера изменений выдаёт последовательность, а не строка sync_state: её
+-- блокировка выстраивала в очередь все транзакции, меняющие задачи. Номера из
+-- последовательности фиксируются не по порядку, поэтому рядом с номером
+-- хранится айди транзакции, которая его записала: читатель отдаёт изменения в
+-- порядке (change_xid, change_seq) и только от транзакций старше самой ранней
+-- ещё не завершённой. Изменения, записанные до этой миграции, получают
+-- change_xid = 0 и идут первыми. sync_state.seq больше не увеличивается.
+CREATE SEQUENCE IF NOT EXISTS todo_change_seq;
+SELECT setval('todo_change_seq', seq + 1, false) FROM sync_state;
+
+ALTER TABLE sync_state ADD COLUMN IF NOT EXISTS purged_xid BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS change_xid BIGINT NOT NULL DEFAULT 0;
+DROP INDEX IF EXISTS todos_change_seq_idx;
+CREATE INDEX IF NOT EXISTS todos_change_position_idx ON todos (change_xid, change_seq);
+
+ALTER TABLE todo_tombstones ADD COLUMN IF NOT EXISTS change_xid BIGINT NOT NULL DEFAULT 0;
+DROP INDEX IF EXISTS todo_tombstones_change_seq_idx;
+CREATE INDEX IF NOT EXISTS todo_tombstones_change_position_idx ON todo_tombstones (change_xid, change_seq);
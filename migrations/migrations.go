@@ -1,25 +1,240 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-//go:embed 001_create_todos.sql
-var createTodosSQL string
+//go:embed *.sql
+var postgresFS embed.FS
 
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+const createSchemaMigrationsSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// advisoryLockID — ключ pg_advisory_lock, под которым реплики по очереди
+// применяют миграции Postgres.
+const advisoryLockID = 0x746f646f
+
+// querier — соединение или транзакция, в которых выполняются миграции.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// RunMigrations применяет миграции Postgres под pg_advisory_lock: реплики,
+// запущенные одновременно, ждут друг друга, а не выполняют одни и те же
+// миграции параллельно. Каждая миграция выполняется в своей транзакции.
 func RunMigrations(db *sql.DB) error {
-	_, err := db.Exec(createTodosSQL)
+	list, err := load(postgresFS, ".")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	// Advisory lock принадлежит сессии, поэтому всё выполняется в одном соединении.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return fmt.Errorf("не удалось взять блокировку миграций: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	return run(ctx, conn, list, func(m migration) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := apply(ctx, tx, m); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// RunSQLiteMigrations применяет миграции SQLite в одной транзакции BEGIN
+// IMMEDIATE: она сразу берёт блокировку базы на запись, и другой процесс
+// дождётся её окончания. Ошибка любой миграции откатывает весь запуск.
+func RunSQLiteMigrations(db *sql.DB) error {
+	list, err := load(sqliteFS, "sqlite")
+	if err != nil {
+		return err
+	}
 
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			fmt.Println("Таблица todos уже существует")
-			return nil
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("не удалось взять блокировку миграций: %w", err)
+	}
+
+	err = run(ctx, conn, list, func(m migration) error {
+		return apply(ctx, conn, m)
+	})
+	if err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("не удалось зафиксировать миграции: %w", err)
+	}
+
+	return nil
+}
+
+// Pending возвращает число ещё не применённых миграций Postgres.
+func Pending(ctx context.Context, db *sql.DB) (int, error) {
+	return pending(ctx, db, postgresFS, ".")
+}
+
+// PendingSQLite возвращает число ещё не применённых миграций SQLite.
+func PendingSQLite(ctx context.Context, db *sql.DB) (int, error) {
+	return pending(ctx, db, sqliteFS, "sqlite")
+}
+
+// run применяет миграции по порядку версий. Вызывается под блокировкой
+// миграций, поэтому таблица schema_migrations читается уже после того, как
+// предыдущий процесс закончил.
+func run(ctx context.Context, db querier, list []migration, applyOne func(m migration) error) error {
+	if _, err := db.ExecContext(ctx, createSchemaMigrationsSQL); err != nil {
+		return fmt.Errorf("ошибка создания таблицы миграций: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		if applied[m.version] {
+			continue
 		}
-		return fmt.Errorf("ошибка выполнения миграции: %w", err)
+
+		if err := applyOne(m); err != nil {
+			return fmt.Errorf("ошибка выполнения миграции %s: %w", m.name, err)
+		}
+
+		slog.Info("применена миграция", "migration", m.name)
 	}
 
 	return nil
 }
+
+// apply выполняет миграцию и записывает её версию в schema_migrations.
+func apply(ctx context.Context, db querier, m migration) error {
+	if _, err := db.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", m.version)
+	return err
+}
+
+func pending(ctx context.Context, db *sql.DB, fsys fs.FS, dir string) (int, error) {
+	list, err := load(fsys, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range list {
+		if !applied[m.version] {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func appliedVersions(ctx context.Context, db querier) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать применённые миграции: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// load читает файлы вида 001_name.sql и сортирует их по номеру версии.
+func load(fsys fs.FS, dir string) ([]migration, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]migration, 0, len(names))
+	seen := make(map[int]string)
+
+	for _, name := range names {
+		base := path.Base(name)
+		prefix, _, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("имя миграции %s должно начинаться с номера версии", base)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("имя миграции %s должно начинаться с номера версии", base)
+		}
+
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("миграции %s и %s имеют одинаковую версию", other, base)
+		}
+		seen[version] = base
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, migration{version: version, name: base, sql: string(content)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+
+	return list, nil
+}
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"todo-api/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSQLiteMigrations(t *testing.T) {
+	db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(createSchemaMigrationsSQL)
+	require.NoError(t, err)
+
+	count, err := PendingSQLite(context.Background(), db)
+	require.NoError(t, err)
+	assert.NotZero(t, count)
+
+	require.NoError(t, RunSQLiteMigrations(db))
+	require.NoError(t, RunSQLiteMigrations(db))
+
+	count, err = PendingSQLite(context.Background(), db)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	_, err = db.Exec("SELECT id, task_name, description, completed, created_at FROM todos")
+	assert.NoError(t, err)
+}
+
+func TestRunSQLiteMigrations_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.db")
+
+	// Отдельные пулы соединений к одному файлу ведут себя как разные реплики.
+	errs := make(chan error, 4)
+	for range cap(errs) {
+		go func() {
+			db, err := database.ConnectSQLite(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer db.Close()
+			errs <- RunSQLiteMigrations(db)
+		}()
+	}
+
+	for range cap(errs) {
+		require.NoError(t, <-errs)
+	}
+}
+
+func TestLoad_Order(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_third.sql":  {Data: []byte("SELECT 3")},
+		"002_second.sql": {Data: []byte("SELECT 2")},
+		"001_first.sql":  {Data: []byte("SELECT 1")},
+	}
+
+	list, err := load(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, []int{1, 2, 10}, []int{list[0].version, list[1].version, list[2].version})
+}
+
+func TestLoad_InvalidName(t *testing.T) {
+	_, err := load(fstest.MapFS{"create.sql": {Data: []byte("SELECT 1")}}, ".")
+	assert.Error(t, err)
+
+	_, err = load(fstest.MapFS{
+		"001_a.sql": {Data: []byte("SELECT 1")},
+		"1_b.sql":   {Data: []byte("SELECT 1")},
+	}, ".")
+	assert.Error(t, err)
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	postgres, err := load(postgresFS, ".")
+	require.NoError(t, err)
+
+	sqlite, err := load(sqliteFS, "sqlite")
+	require.NoError(t, err)
+
+	assert.Equal(t, len(postgres), len(sqlite), "у каждой миграции Postgres должна быть пара для SQLite")
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS todos (
+    id TEXT PRIMARY KEY,
+    task_name VARCHAR(255) NOT NULL,
+    description TEXT,
+    completed BOOLEAN NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,25 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS todos_fts USING fts5(
+    task_name,
+    description,
+    content = 'todos',
+    content_rowid = 'rowid'
+);
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_insert AFTER INSERT ON todos BEGIN
+    INSERT INTO todos_fts (rowid, task_name, description)
+    VALUES (new.rowid, new.task_name, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_delete AFTER DELETE ON todos BEGIN
+    INSERT INTO todos_fts (todos_fts, rowid, task_name, description)
+    VALUES ('delete', old.rowid, old.task_name, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_update AFTER UPDATE ON todos BEGIN
+    INSERT INTO todos_fts (todos_fts, rowid, task_name, description)
+    VALUES ('delete', old.rowid, old.task_name, old.description);
+    INSERT INTO todos_fts (rowid, task_name, description)
+    VALUES (new.rowid, new.task_name, new.description);
+END;
+
+INSERT INTO todos_fts (todos_fts) VALUES ('rebuild');
@@ -0,0 +1,7 @@
+ALTER TABLE todos ADD COLUMN due_at TIMESTAMP;
+ALTER TABLE todos ADD COLUMN priority TEXT NOT NULL DEFAULT ''
+    CHECK (priority IN ('', 'low', 'medium', 'high'));
+ALTER TABLE todos ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE todos ADD COLUMN project TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS todos_due_at_idx ON todos (due_at);
@@ -0,0 +1,30 @@
+-- Номер последнего изменения задач. Репозиторий увеличивает его в той же
+-- транзакции, что меняет задачу. purged_seq — наибольший номер вычищенной
+-- записи об удалении.
+CREATE TABLE IF NOT EXISTS sync_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    seq INTEGER NOT NULL,
+    purged_seq INTEGER NOT NULL DEFAULT 0
+);
+
+ALTER TABLE todos ADD COLUMN change_seq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos ADD COLUMN field_changes TEXT NOT NULL DEFAULT '{}';
+
+-- Существующие задачи получают номера в порядке создания.
+UPDATE todos SET change_seq = (
+    SELECT COUNT(*) FROM todos AS t
+    WHERE t.created_at < todos.created_at OR (t.created_at = todos.created_at AND t.rowid <= todos.rowid)
+);
+
+INSERT OR IGNORE INTO sync_state (id, seq) SELECT 1, COALESCE(MAX(change_seq), 0) FROM todos;
+
+CREATE INDEX IF NOT EXISTS todos_change_seq_idx ON todos (change_seq);
+
+CREATE TABLE IF NOT EXISTS todo_tombstones (
+    id TEXT PRIMARY KEY,
+    change_seq INTEGER NOT NULL,
+    deleted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS todo_tombstones_change_seq_idx ON todo_tombstones (change_seq);
+CREATE INDEX IF NOT EXISTS todo_tombstones_deleted_at_idx ON todo_tombstones (deleted_at);
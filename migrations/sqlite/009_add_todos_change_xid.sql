@@ -0,0 +1,12 @@
+-- Столбцы повторяют Postgres, чтобы изменения читались одним запросом. В
+-- SQLite пишет одна транзакция за раз, номера по-прежнему выдаёт sync_state,
+-- а change_xid всегда 0.
+ALTER TABLE sync_state ADD COLUMN purged_xid INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE todos ADD COLUMN change_xid INTEGER NOT NULL DEFAULT 0;
+DROP INDEX IF EXISTS todos_change_seq_idx;
+CREATE INDEX IF NOT EXISTS todos_change_position_idx ON todos (change_xid, change_seq);
+
+ALTER TABLE todo_tombstones ADD COLUMN change_xid INTEGER NOT NULL DEFAULT 0;
+DROP INDEX IF EXISTS todo_tombstones_change_seq_idx;
+CREATE INDEX IF NOT EXISTS todo_tombstones_change_position_idx ON todo_tombstones (change_xid, change_seq);